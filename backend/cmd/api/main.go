@@ -1,11 +1,19 @@
 package main
 
 import (
+	"crypto/ed25519"
 	"digitalwallet/backend/config"
+	"digitalwallet/backend/internal/approval"
 	"digitalwallet/backend/internal/auth"
+	"digitalwallet/backend/internal/database"
+	"digitalwallet/backend/internal/idempotency"
 	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/schedule"
+	"digitalwallet/backend/internal/statement"
+	"digitalwallet/backend/internal/transaction"
 	"digitalwallet/backend/internal/user"
 	"digitalwallet/backend/internal/wallet"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
@@ -21,6 +29,9 @@ func main() {
 	if config.REFRESH_TOKEN_SECRET == "" {
 		log.Fatal("No REFRESH_TOKEN_SECRET found")
 	}
+	if config.LEDGER_STORE == "postgres" && config.DATABASE_URL == "" {
+		log.Fatal("No DATABASE_URL found (required when LEDGER_STORE=postgres)")
+	}
 
 	// Initialize Gin router
 	r := gin.Default()
@@ -32,26 +43,97 @@ func main() {
 	userRepo := user.NewRepository()
 	authRepo := auth.NewRepository()
 	walletRepo := wallet.NewRepository()
-	ledgerRepo := ledger.NewRepository()
+	ledgerRepo := newLedgerRepository()
+	idempotencyRepo := idempotency.NewRepository()
+	transactionRepo := transaction.NewRepository()
+	statementRepo := statement.NewRepository()
+	scheduleRepo := schedule.NewRepository()
+	approvalRepo := approval.NewRepository()
 
 	// Initialize services
 	userService := user.NewService(userRepo)
 	authService := auth.NewService(authRepo, userService, config.ACCESS_TOKEN_SECRET, config.REFRESH_TOKEN_SECRET)
-	walletService := wallet.NewService(walletRepo)
-	ledgerService := ledger.NewService(ledgerRepo)
+	ledgerOptions := []ledger.Option{
+		ledger.WithFX(newRateProvider(), ledger.FXConfig{
+			SpreadBps: config.FX_SPREAD_BPS,
+			QuoteTTL:  config.FX_QUOTE_TTL,
+		}),
+		ledger.WithLimits(ledger.DefaultLimitTiers()),
+		ledger.WithApprovalThreshold(config.APPROVAL_WITHDRAWAL_THRESHOLD),
+		ledger.WithApprovals(approval.NewLedgerApprovals(approvalRepo)),
+	}
+	if config.LEDGER_SIGNING_KEY != "" {
+		ledgerOptions = append(ledgerOptions, ledger.WithSigningKey(newSigningKey()))
+	}
+	if config.FEE_SCHEDULE_FILE != "" {
+		fees, err := ledger.LoadFeeSchedule(config.FEE_SCHEDULE_FILE)
+		if err != nil {
+			log.Fatal("Failed to load fee schedule:", err)
+		}
+		ledgerOptions = append(ledgerOptions, ledger.WithFees(fees))
+	}
+	ledgerService := ledger.NewService(ledgerRepo, ledgerOptions...)
+	registerLedgerAccounts(ledgerService)
+	walletService := wallet.NewService(walletRepo, ledgerService)
+	idempotencyService := idempotency.NewService(idempotencyRepo, idempotency.DefaultTTL)
+	transactionService := transaction.NewService(transactionRepo, ledgerService)
+	statementService := statement.NewService(statementRepo, ledgerService, walletService, userService)
+	scheduleService := schedule.NewService(scheduleRepo, ledgerService, walletService, schedule.RetryPolicy{
+		MaxAttempts: config.SCHEDULE_RETRY_ATTEMPTS,
+		Backoff:     config.SCHEDULE_RETRY_BACKOFF,
+	}, schedule.SystemClock)
+	approvalService := approval.NewService(approvalRepo, ledgerService, walletService, config.APPROVAL_TTL, approval.SystemClock,
+		approval.WithListener(transactionService))
+
+	// Release expired holds in the background
+	stopHoldSweeper := ledgerService.StartHoldSweeper(config.HOLD_SWEEP_INTERVAL)
+	defer stopHoldSweeper()
+
+	// Checkpoint balances so balance-as-of queries don't re-sum the full history
+	stopCheckpointer := ledgerService.StartBalanceCheckpointer(config.BALANCE_CHECKPOINT_INTERVAL)
+	defer stopCheckpointer()
+
+	// Audit the whole ledger and raise alerts on drift
+	stopAuditJob := ledgerService.StartAuditJob(config.LEDGER_AUDIT_INTERVAL)
+	defer stopAuditJob()
+
+	// Delete idempotency records whose keys have expired
+	stopIdempotencyPurge := idempotencyService.StartPurgeJob(config.IDEMPOTENCY_PURGE_INTERVAL)
+	defer stopIdempotencyPurge()
+
+	// Store last month's statements once the month has ended
+	stopStatementJob := statementService.StartMonthEndJob(config.STATEMENT_JOB_INTERVAL)
+	defer stopStatementJob()
+
+	// Run standing orders as they fall due
+	stopScheduleRunner := scheduleService.StartRunner(config.SCHEDULE_RUN_INTERVAL)
+	defer stopScheduleRunner()
+
+	// Expire approval requests nobody decided in time
+	stopApprovalExpiry := approvalService.StartExpiryJob(config.APPROVAL_EXPIRY_INTERVAL)
+	defer stopApprovalExpiry()
 
 	// Initialize handlers
 	authHandler := auth.NewHandler(authService)
-	authMiddleware := auth.NewMiddleware(authService)
+	authMiddleware := auth.NewMiddleware(authService, config.ADMIN_USER_IDS)
 	userHandler := user.NewHandler(userService)
 	walletHandler := wallet.NewHandler(walletService)
-	ledgerHandler := ledger.NewHandler(ledgerService)
+	ledgerHandler := ledger.NewHandler(ledgerService, walletService, approvalService)
+	idempotencyMiddleware := idempotency.NewMiddleware(idempotencyService)
+	transactionHandler := transaction.NewHandler(transactionService, walletService, approvalService)
+	statementHandler := statement.NewHandler(statementService, walletService)
+	scheduleHandler := schedule.NewHandler(scheduleService, walletService)
+	approvalHandler := approval.NewHandler(approvalService, walletService)
 
 	// Register routes
 	auth.RegisterRoutes(r, authHandler, authMiddleware)
 	user.RegisterRoutes(r, userHandler, authMiddleware)
 	wallet.RegisterRoutes(r, walletHandler, authMiddleware)
-	ledger.RegisterRoutes(r, ledgerHandler, authMiddleware)
+	ledger.RegisterRoutes(r, ledgerHandler, authMiddleware, idempotencyMiddleware)
+	transaction.RegisterRoutes(r, transactionHandler, authMiddleware, idempotencyMiddleware)
+	statement.RegisterRoutes(r, statementHandler, authMiddleware)
+	schedule.RegisterRoutes(r, scheduleHandler, authMiddleware)
+	approval.RegisterRoutes(r, approvalHandler, authMiddleware, idempotencyMiddleware)
 
 	// Start server
 	fmt.Println("Server started at PORT 8080")
@@ -60,12 +142,79 @@ func main() {
 	}
 }
 
+// newRateProvider loads exchange rates from FX_RATES_FILE, or uses the built-in development rates
+func newRateProvider() ledger.RateProvider {
+	if config.FX_RATES_FILE == "" {
+		provider, err := ledger.NewStaticRateProvider(ledger.DefaultRates)
+		if err != nil {
+			log.Fatal("Invalid default exchange rates:", err)
+		}
+		return provider
+	}
+
+	provider, err := ledger.NewFileRateProvider(config.FX_RATES_FILE)
+	if err != nil {
+		log.Fatal("Failed to load exchange rates:", err)
+	}
+	return provider
+}
+
+// registerLedgerAccounts makes sure the default chart of accounts and the accounts in
+// LEDGER_ACCOUNTS_FILE (e.g. funding accounts per deposit source) are registered
+func registerLedgerAccounts(ledgerService *ledger.Service) {
+	accounts := ledger.DefaultChart()
+	if config.LEDGER_ACCOUNTS_FILE != "" {
+		configured, err := ledger.LoadAccountsFile(config.LEDGER_ACCOUNTS_FILE)
+		if err != nil {
+			log.Fatal("Failed to load ledger accounts:", err)
+		}
+		accounts = append(accounts, configured...)
+	}
+
+	opened, err := ledgerService.RegisterAccounts(accounts)
+	if err != nil {
+		log.Fatal("Failed to register ledger accounts:", err)
+	}
+	if opened > 0 {
+		log.Printf("Registered %d ledger accounts", opened)
+	}
+}
+
+// newSigningKey decodes LEDGER_SIGNING_KEY into the key that signs daily ledger root hashes
+func newSigningKey() ed25519.PrivateKey {
+	seed, err := base64.StdEncoding.DecodeString(config.LEDGER_SIGNING_KEY)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		log.Fatal("Invalid LEDGER_SIGNING_KEY: expected a base64-encoded 32-byte Ed25519 seed")
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+// newLedgerRepository selects the ledger storage backend from configuration
+func newLedgerRepository() ledger.Repository {
+	switch config.LEDGER_STORE {
+	case "memory":
+		return ledger.NewRepository()
+	case "postgres":
+		db, err := database.Open(config.DATABASE_URL)
+		if err != nil {
+			log.Fatal("Failed to connect to database:", err)
+		}
+		if err := database.Migrate(db); err != nil {
+			log.Fatal("Failed to run database migrations:", err)
+		}
+		return ledger.NewPostgresRepository(db)
+	default:
+		log.Fatalf("Unknown LEDGER_STORE %q (expected \"memory\" or \"postgres\")", config.LEDGER_STORE)
+		return nil
+	}
+}
+
 // corsMiddleware handles CORS for the frontend
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "http://localhost:5173")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		// Handle preflight OPTIONS request
@@ -0,0 +1,90 @@
+// Command ledgertool runs maintenance tasks against the PostgreSQL ledger
+//
+//	ledgertool rebuild-balances [-repair] [-by NAME]
+//
+// rebuild-balances recomputes every cached account balance from the entry log and prints the
+// accounts whose balance differs. Without -repair nothing is written and the exit status is 1
+// when differences were found; with -repair the balances are overwritten and the rebuild is
+// stored as an audit record.
+package main
+
+import (
+	"digitalwallet/backend/config"
+	"digitalwallet/backend/internal/database"
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/pkg/currency"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "rebuild-balances" {
+		fmt.Fprintln(os.Stderr, "usage: ledgertool rebuild-balances [-repair] [-by NAME]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet("rebuild-balances", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "overwrite the differing balances (default: dry run)")
+	by := flags.String("by", defaultOperator(), "who is running the rebuild, stored on the audit record")
+	flags.Parse(os.Args[2:])
+
+	if config.DATABASE_URL == "" {
+		log.Fatal("No DATABASE_URL found")
+	}
+	db, err := database.Open(config.DATABASE_URL)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+	if err := database.Migrate(db); err != nil {
+		log.Fatal("Failed to run database migrations:", err)
+	}
+
+	service := ledger.NewService(ledger.NewPostgresRepository(db))
+	rebuild, err := service.RebuildBalances(*by, !*repair, time.Now())
+	if err != nil {
+		log.Fatal("Failed to rebuild balances:", err)
+	}
+
+	printRebuild(rebuild)
+	if rebuild.DryRun && len(rebuild.Corrections) > 0 {
+		os.Exit(1)
+	}
+}
+
+// printRebuild writes the corrections as a table followed by a summary line
+func printRebuild(rebuild *ledger.BalanceRebuild) {
+	if len(rebuild.Corrections) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "ACCOUNT\tTYPE\tCURRENCY\tCACHED\tREBUILT\tDELTA\t")
+		for _, c := range rebuild.Corrections {
+			cached := currency.New(c.Cached, c.Currency).String()
+			if c.Missing {
+				cached = "missing"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", c.AccountID, c.AccountType, c.Currency, cached,
+				currency.New(c.Rebuilt, c.Currency), currency.New(c.Delta(), c.Currency))
+		}
+		w.Flush()
+	}
+
+	if rebuild.DryRun {
+		fmt.Printf("Dry run: %d of %d accounts differ from their entries; rerun with -repair to fix them\n",
+			len(rebuild.Corrections), rebuild.AccountsChecked)
+		return
+	}
+	fmt.Printf("Repaired %d of %d accounts (rebuild %s)\n", len(rebuild.Corrections), rebuild.AccountsChecked, rebuild.ID)
+}
+
+// defaultOperator names the OS user running the tool
+func defaultOperator() string {
+	if current, err := user.Current(); err == nil {
+		return "ledgertool:" + current.Username
+	}
+	return "ledgertool"
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -10,6 +13,53 @@ import (
 var ACCESS_TOKEN_SECRET string
 var REFRESH_TOKEN_SECRET string
 
+// Storage backend for the ledger: "memory" (default) or "postgres"
+var LEDGER_STORE string
+var DATABASE_URL string
+
+// Currency conversion pricing; FX_RATES_FILE is a JSON map like {"EUR/GBP": "0.85"}
+var FX_RATES_FILE string
+var FX_SPREAD_BPS int64
+var FX_QUOTE_TTL time.Duration
+
+// JSON file of fee rules charged on transfers, withdrawals and deposits; no fees are charged when unset
+var FEE_SCHEDULE_FILE string
+
+// JSON file of extra ledger accounts to register at startup, e.g. funding accounts per deposit source
+var LEDGER_ACCOUNTS_FILE string
+
+// Base64 Ed25519 seed (32 bytes) used to sign daily ledger root hashes; signing is disabled when unset
+var LEDGER_SIGNING_KEY string
+
+// How often expired ledger holds are released
+var HOLD_SWEEP_INTERVAL time.Duration
+
+// How often account balances are checkpointed for balance-as-of queries
+var BALANCE_CHECKPOINT_INTERVAL time.Duration
+
+// How often the whole ledger is audited for drift
+var LEDGER_AUDIT_INTERVAL time.Duration
+
+// How often expired idempotency records are deleted
+var IDEMPOTENCY_PURGE_INTERVAL time.Duration
+
+// How often the month-end statement job checks for statements to store
+var STATEMENT_JOB_INTERVAL time.Duration
+
+// How often due scheduled transfers are run, and how a run short of funds is retried
+var SCHEDULE_RUN_INTERVAL time.Duration
+var SCHEDULE_RETRY_ATTEMPTS int
+var SCHEDULE_RETRY_BACKOFF time.Duration
+
+// Comma-separated user IDs allowed to use admin-only endpoints such as the approval queue
+var ADMIN_USER_IDS []string
+
+// Withdrawals above this amount, in major units of the withdrawal's currency, need an admin's approval; 0 disables the check.
+// Requests in the approval queue expire after APPROVAL_TTL and are swept every APPROVAL_EXPIRY_INTERVAL
+var APPROVAL_WITHDRAWAL_THRESHOLD int64
+var APPROVAL_TTL time.Duration
+var APPROVAL_EXPIRY_INTERVAL time.Duration
+
 func init() {
 	// Load .env file (optional in production where env vars are set by platform)
 	if err := godotenv.Load(".env"); err != nil {
@@ -18,4 +68,54 @@ func init() {
 
 	ACCESS_TOKEN_SECRET = os.Getenv("ACCESS_TOKEN_SECRET")
 	REFRESH_TOKEN_SECRET = os.Getenv("REFRESH_TOKEN_SECRET")
+
+	LEDGER_STORE = os.Getenv("LEDGER_STORE")
+	if LEDGER_STORE == "" {
+		LEDGER_STORE = "memory"
+	}
+	DATABASE_URL = os.Getenv("DATABASE_URL")
+
+	FX_RATES_FILE = os.Getenv("FX_RATES_FILE")
+	LEDGER_SIGNING_KEY = os.Getenv("LEDGER_SIGNING_KEY")
+	LEDGER_ACCOUNTS_FILE = os.Getenv("LEDGER_ACCOUNTS_FILE")
+	FEE_SCHEDULE_FILE = os.Getenv("FEE_SCHEDULE_FILE")
+	FX_SPREAD_BPS = int64(intFromEnv("FX_SPREAD_BPS", 50))
+	FX_QUOTE_TTL = time.Duration(intFromEnv("FX_QUOTE_TTL_SECONDS", 30)) * time.Second
+	HOLD_SWEEP_INTERVAL = time.Duration(intFromEnv("HOLD_SWEEP_INTERVAL_SECONDS", 60)) * time.Second
+	BALANCE_CHECKPOINT_INTERVAL = time.Duration(intFromEnv("BALANCE_CHECKPOINT_INTERVAL_SECONDS", 86400)) * time.Second
+	LEDGER_AUDIT_INTERVAL = time.Duration(intFromEnv("LEDGER_AUDIT_INTERVAL_SECONDS", 3600)) * time.Second
+	IDEMPOTENCY_PURGE_INTERVAL = time.Duration(intFromEnv("IDEMPOTENCY_PURGE_INTERVAL_SECONDS", 3600)) * time.Second
+	STATEMENT_JOB_INTERVAL = time.Duration(intFromEnv("STATEMENT_JOB_INTERVAL_SECONDS", 3600)) * time.Second
+	SCHEDULE_RUN_INTERVAL = time.Duration(intFromEnv("SCHEDULE_RUN_INTERVAL_SECONDS", 60)) * time.Second
+	SCHEDULE_RETRY_ATTEMPTS = intFromEnv("SCHEDULE_RETRY_ATTEMPTS", 3)
+	SCHEDULE_RETRY_BACKOFF = time.Duration(intFromEnv("SCHEDULE_RETRY_BACKOFF_SECONDS", 3600)) * time.Second
+	ADMIN_USER_IDS = listFromEnv("ADMIN_USER_IDS")
+	APPROVAL_WITHDRAWAL_THRESHOLD = int64(intFromEnv("APPROVAL_WITHDRAWAL_THRESHOLD", 5000))
+	APPROVAL_TTL = time.Duration(intFromEnv("APPROVAL_TTL_SECONDS", 259200)) * time.Second
+	APPROVAL_EXPIRY_INTERVAL = time.Duration(intFromEnv("APPROVAL_EXPIRY_INTERVAL_SECONDS", 300)) * time.Second
+}
+
+// intFromEnv reads an integer environment variable, falling back to def when unset or invalid
+func intFromEnv(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %d", key, value, def)
+		return def
+	}
+	return parsed
+}
+
+// listFromEnv reads a comma-separated environment variable, dropping blank items
+func listFromEnv(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
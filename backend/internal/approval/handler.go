@@ -0,0 +1,215 @@
+package approval
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/pkg"
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service       *Service
+	walletService WalletService
+}
+
+func NewHandler(service *Service, walletService WalletService) *Handler {
+	return &Handler{service: service, walletService: walletService}
+}
+
+// RequestJournal queues a manual journal for a second admin to approve
+// POST /approvals/journals
+func (h *Handler) RequestJournal(c *gin.Context) {
+	var req JournalRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	cur := req.Currency
+	if cur == "" {
+		cur = currency.CurrencyUSD
+	}
+	if !currency.IsSupported(cur) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ledger.ErrUnsupportedCurrency.Error()})
+		return
+	}
+
+	// Amounts are positive on the wire; debits are negative in the ledger
+	legs := make([]ledger.JournalLeg, len(req.Legs))
+	for i, leg := range req.Legs {
+		amount, err := currency.Parse(leg.Amount, cur)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("leg %d: %v", i, err)})
+			return
+		}
+		legs[i] = ledger.JournalLeg{AccountID: leg.AccountID, EntryType: leg.EntryType, Amount: amount.Amount(), Description: leg.Description}
+		if leg.EntryType == ledger.EntryTypeDebit {
+			legs[i].Amount = -legs[i].Amount
+		}
+	}
+
+	request, err := h.service.RequestJournal(ledger.JournalRequest{
+		Legs:        legs,
+		Currency:    cur,
+		ReasonCode:  req.ReasonCode,
+		Description: req.Description,
+	}, c.GetString("userId"))
+	if err != nil {
+		switch {
+		case err == ledger.ErrInvalidJournal, err == ledger.ErrInvalidReasonCode, err == ledger.ErrTransactionNotBalanced,
+			errors.Is(err, ledger.ErrInvalidEntryType), errors.Is(err, ledger.ErrInvalidDebitAmount),
+			errors.Is(err, ledger.ErrInvalidCreditAmount), errors.Is(err, ledger.ErrMissingAccountID):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error requesting journal approval: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, request.ToDTO())
+}
+
+// RequestWithdrawal queues a withdrawal from a customer's wallet for a second admin to approve
+// POST /approvals/withdrawals
+func (h *Handler) RequestWithdrawal(c *gin.Context) {
+	var req WithdrawalRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	from, err := h.walletService.GetWalletByID(req.WalletID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+			return
+		}
+		log.Printf("Error getting wallet %s: %v", req.WalletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	amount, err := currency.Parse(req.Amount, from.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	request, err := h.service.RequestWithdrawal(ledger.WithdrawalRequest{
+		AccountID:   from.ID,
+		Amount:      amount.Amount(),
+		Currency:    req.Currency,
+		Destination: req.Destination,
+		Description: req.Description,
+	}, c.GetString("userId"))
+	if err != nil {
+		switch err {
+		case ErrInvalidAmount, ErrCurrencyMismatch:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error requesting withdrawal approval: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, request.ToDTO())
+}
+
+// List returns requests with a status, oldest first; pending ones by default
+// GET /approvals?status=
+func (h *Handler) List(c *gin.Context) {
+	status := c.DefaultQuery("status", StatusPending)
+	requests, err := h.service.ListRequests(status)
+	if err != nil {
+		if err == ErrInvalidStatus {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error listing approval requests: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	dtos := make([]*RequestDTO, 0, len(requests))
+	for _, request := range requests {
+		dtos = append(dtos, request.ToDTO())
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": dtos, "count": len(dtos)})
+}
+
+// Get returns a request with its audit trail
+// GET /approvals/:id
+func (h *Handler) Get(c *gin.Context) {
+	id := c.Param("id")
+	request, err := h.service.GetRequest(id)
+	if err != nil {
+		h.writeError(c, id, err)
+		return
+	}
+	events, err := h.service.ListEvents(id)
+	if err != nil {
+		h.writeError(c, id, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"request": request.ToDTO(), "events": events})
+}
+
+// Approve posts a pending request; the approver must not be the admin who requested it
+// POST /approvals/:id/approve
+func (h *Handler) Approve(c *gin.Context) {
+	var req DecisionDTO
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	request, err := h.service.Approve(c.Param("id"), c.GetString("userId"), req.Note)
+	if err != nil {
+		h.writeError(c, c.Param("id"), err)
+		return
+	}
+	if request.Status == StatusFailed {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": request.Error, "request": request.ToDTO()})
+		return
+	}
+	c.JSON(http.StatusOK, request.ToDTO())
+}
+
+// Reject closes a pending request without posting it; a note is required
+// POST /approvals/:id/reject
+func (h *Handler) Reject(c *gin.Context) {
+	var req DecisionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	request, err := h.service.Reject(c.Param("id"), c.GetString("userId"), req.Note)
+	if err != nil {
+		h.writeError(c, c.Param("id"), err)
+		return
+	}
+	c.JSON(http.StatusOK, request.ToDTO())
+}
+
+// writeError maps errors from looking up or deciding a request to HTTP status codes
+func (h *Handler) writeError(c *gin.Context, id string, err error) {
+	switch err {
+	case ErrRequestNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrSelfApproval:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case ErrNotPending:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case ErrRequestExpired:
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case ErrMissingNote:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error handling approval request %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
@@ -0,0 +1,158 @@
+package approval
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/pkg/currency"
+)
+
+// Kinds of posting that need a second admin
+const (
+	KindJournal    = "JOURNAL"    // Manual journal, always approved
+	KindWithdrawal = "WITHDRAWAL" // Withdrawal requested by an admin on a customer's behalf
+)
+
+// Request statuses
+const (
+	StatusPending  = "PENDING"
+	StatusApproved = "APPROVED" // Approved, being posted
+	StatusExecuted = "EXECUTED" // Approved and posted
+	StatusFailed   = "FAILED"   // Approved, but the ledger refused the posting
+	StatusRejected = "REJECTED"
+	StatusExpired  = "EXPIRED" // Not decided in time
+)
+
+// Audit trail actions
+const (
+	ActionRequested = "REQUESTED"
+	ActionApproved  = "APPROVED"
+	ActionRejected  = "REJECTED"
+	ActionExecuted  = "EXECUTED"
+	ActionFailed    = "FAILED"
+	ActionExpired   = "EXPIRED"
+)
+
+// SystemActor is recorded as the actor of things nobody did by hand, such as expiry
+const SystemActor = "approval-service"
+
+// Request is a posting one admin asked for and a different admin must approve before it reaches the ledger
+type Request struct {
+	ID            string                    `json:"id"`
+	Kind          string                    `json:"kind"`
+	Status        string                    `json:"status"`
+	Journal       *ledger.JournalRequest    `json:"journal,omitempty"`    // Set for JOURNAL
+	Withdrawal    *ledger.WithdrawalRequest `json:"withdrawal,omitempty"` // Set for WITHDRAWAL
+	Amount        int64                     `json:"amount"`               // Withdrawal amount, or a journal's total debits (minor units)
+	Currency      string                    `json:"currency"`
+	RequestedBy   string                    `json:"requested_by"`
+	RequestedAt   int64                     `json:"requested_at"`
+	ExpiresAt     int64                     `json:"expires_at"`
+	DecidedBy     string                    `json:"decided_by,omitempty"`
+	DecidedAt     int64                     `json:"decided_at,omitempty"`
+	DecisionNote  string                    `json:"decision_note,omitempty"`
+	TransactionID string                    `json:"transaction_id,omitempty"` // Always the request ID, so approval can't post twice
+	Error         string                    `json:"error,omitempty"`          // Why the ledger refused the posting
+}
+
+// Event is one entry in a request's audit trail
+type Event struct {
+	ID        string `json:"id"`
+	RequestID string `json:"request_id"`
+	Action    string `json:"action"`
+	Actor     string `json:"actor"` // Admin user ID, or SystemActor
+	Note      string `json:"note,omitempty"`
+	At        int64  `json:"at"`
+}
+
+// JournalRequestDTO is the body of POST /approvals/journals
+type JournalRequestDTO struct {
+	Currency    string          `json:"currency"`    // Optional, defaults to USD
+	ReasonCode  string          `json:"reason_code"` // GOODWILL, WRITE_OFF or CORRECTION
+	Description string          `json:"description"`
+	Legs        []JournalLegDTO `json:"legs"`
+}
+
+// JournalLegDTO is one leg of a manual journal
+type JournalLegDTO struct {
+	AccountID   string `json:"account_id"`
+	EntryType   string `json:"entry_type"` // DEBIT or CREDIT
+	Amount      string `json:"amount"`     // Positive decimal string (e.g., "25.00"); the entry type gives the side
+	Description string `json:"description,omitempty"`
+}
+
+// WithdrawalRequestDTO is the body of POST /approvals/withdrawals
+type WithdrawalRequestDTO struct {
+	WalletID    string `json:"wallet_id" binding:"required"`
+	Amount      string `json:"amount" binding:"required"` // Decimal string in the wallet's currency
+	Currency    string `json:"currency"`                  // Optional, must match the wallet
+	Destination string `json:"destination"`
+	Description string `json:"description"`
+}
+
+// DecisionDTO is the body of an approval or rejection
+type DecisionDTO struct {
+	Note string `json:"note"` // Optional when approving, required when rejecting
+}
+
+// RequestDTO is a request as returned by the API, with amounts as decimal strings
+type RequestDTO struct {
+	ID            string          `json:"id"`
+	Kind          string          `json:"kind"`
+	Status        string          `json:"status"`
+	Amount        string          `json:"amount"`
+	Currency      string          `json:"currency"`
+	ReasonCode    string          `json:"reason_code,omitempty"`
+	Description   string          `json:"description,omitempty"`
+	Legs          []JournalLegDTO `json:"legs,omitempty"`
+	WalletID      string          `json:"wallet_id,omitempty"`
+	Destination   string          `json:"destination,omitempty"`
+	RequestedBy   string          `json:"requested_by"`
+	RequestedAt   int64           `json:"requested_at"`
+	ExpiresAt     int64           `json:"expires_at"`
+	DecidedBy     string          `json:"decided_by,omitempty"`
+	DecidedAt     int64           `json:"decided_at,omitempty"`
+	DecisionNote  string          `json:"decision_note,omitempty"`
+	TransactionID string          `json:"transaction_id,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// ToDTO converts the request to the API response format
+func (r *Request) ToDTO() *RequestDTO {
+	dto := &RequestDTO{
+		ID:            r.ID,
+		Kind:          r.Kind,
+		Status:        r.Status,
+		Amount:        currency.New(r.Amount, r.Currency).String(),
+		Currency:      r.Currency,
+		RequestedBy:   r.RequestedBy,
+		RequestedAt:   r.RequestedAt,
+		ExpiresAt:     r.ExpiresAt,
+		DecidedBy:     r.DecidedBy,
+		DecidedAt:     r.DecidedAt,
+		DecisionNote:  r.DecisionNote,
+		TransactionID: r.TransactionID,
+		Error:         r.Error,
+	}
+	switch {
+	case r.Journal != nil:
+		dto.ReasonCode = r.Journal.ReasonCode
+		dto.Description = r.Journal.Description
+		dto.Legs = make([]JournalLegDTO, len(r.Journal.Legs))
+		for i, leg := range r.Journal.Legs {
+			amount := leg.Amount
+			if amount < 0 {
+				amount = -amount
+			}
+			dto.Legs[i] = JournalLegDTO{
+				AccountID:   leg.AccountID,
+				EntryType:   leg.EntryType,
+				Amount:      currency.New(amount, r.Currency).String(),
+				Description: leg.Description,
+			}
+		}
+	case r.Withdrawal != nil:
+		dto.WalletID = r.Withdrawal.AccountID
+		dto.Destination = r.Withdrawal.Destination
+		dto.Description = r.Withdrawal.Description
+	}
+	return dto
+}
@@ -0,0 +1,133 @@
+package approval
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrRequestNotFound = errors.New("approval request not found")
+
+// Repository stores approval requests and their audit trail
+type Repository interface {
+	Save(request *Request) error // Creates or replaces the request
+	GetByID(id string) (*Request, error)
+	List(status string) ([]*Request, error)        // Oldest first; every status when empty
+	ListExpired(now int64) ([]*Request, error)     // Pending requests whose ExpiresAt is at or before now
+	SaveEvent(event *Event) error                  // Events are only ever appended
+	ListEvents(requestID string) ([]*Event, error) // Oldest first
+}
+
+// inMemoryRepository implements Repository using in-memory storage
+type inMemoryRepository struct {
+	mu       sync.RWMutex
+	requests map[string]*Request
+	events   map[string][]*Event // request ID -> events in the order they were saved
+}
+
+// NewRepository creates a new in-memory approval repository
+func NewRepository() Repository {
+	return &inMemoryRepository{
+		requests: make(map[string]*Request),
+		events:   make(map[string][]*Event),
+	}
+}
+
+// Save stores a copy of the request
+func (r *inMemoryRepository) Save(request *Request) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[request.ID] = copyRequest(request)
+	return nil
+}
+
+// GetByID retrieves a request by ID
+func (r *inMemoryRepository) GetByID(id string) (*Request, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	request, exists := r.requests[id]
+	if !exists {
+		return nil, ErrRequestNotFound
+	}
+	return copyRequest(request), nil
+}
+
+// List retrieves the requests with a status, or all of them, oldest first
+func (r *inMemoryRepository) List(status string) ([]*Request, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var requests []*Request
+	for _, request := range r.requests {
+		if status == "" || request.Status == status {
+			requests = append(requests, copyRequest(request))
+		}
+	}
+	sortOldestFirst(requests)
+	return requests, nil
+}
+
+// ListExpired retrieves the pending requests that expired at or before now, oldest first
+func (r *inMemoryRepository) ListExpired(now int64) ([]*Request, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var requests []*Request
+	for _, request := range r.requests {
+		if request.Status == StatusPending && request.ExpiresAt <= now {
+			requests = append(requests, copyRequest(request))
+		}
+	}
+	sortOldestFirst(requests)
+	return requests, nil
+}
+
+// SaveEvent appends an event to its request's audit trail
+func (r *inMemoryRepository) SaveEvent(event *Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *event
+	r.events[event.RequestID] = append(r.events[event.RequestID], &stored)
+	return nil
+}
+
+// ListEvents retrieves a request's audit trail, oldest first
+func (r *inMemoryRepository) ListEvents(requestID string) ([]*Event, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := make([]*Event, 0, len(r.events[requestID]))
+	for _, event := range r.events[requestID] {
+		result := *event
+		events = append(events, &result)
+	}
+	return events, nil
+}
+
+func sortOldestFirst(requests []*Request) {
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].RequestedAt != requests[j].RequestedAt {
+			return requests[i].RequestedAt < requests[j].RequestedAt
+		}
+		return requests[i].ID < requests[j].ID
+	})
+}
+
+// copyRequest copies a request together with the posting it carries
+func copyRequest(request *Request) *Request {
+	result := *request
+	if request.Journal != nil {
+		journal := *request.Journal
+		journal.Legs = append([]ledger.JournalLeg(nil), request.Journal.Legs...)
+		result.Journal = &journal
+	}
+	if request.Withdrawal != nil {
+		withdrawal := *request.Withdrawal
+		result.Withdrawal = &withdrawal
+	}
+	return &result
+}
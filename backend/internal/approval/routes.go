@@ -0,0 +1,21 @@
+package approval
+
+import (
+	"digitalwallet/backend/internal/auth"
+	"digitalwallet/backend/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes sets up the approval queue routes; every route is admin only
+func RegisterRoutes(router *gin.Engine, approvalHandler *Handler, authMiddleware *auth.Middleware, idempotencyMiddleware *idempotency.Middleware) {
+	// Makers queue postings (retry-safe via Idempotency-Key)
+	router.POST("/approvals/journals", authMiddleware.Authenticate, authMiddleware.RequireAdmin, idempotencyMiddleware.Enforce, approvalHandler.RequestJournal)
+	router.POST("/approvals/withdrawals", authMiddleware.Authenticate, authMiddleware.RequireAdmin, idempotencyMiddleware.Enforce, approvalHandler.RequestWithdrawal)
+
+	// Checkers review the queue and decide
+	router.GET("/approvals", authMiddleware.Authenticate, authMiddleware.RequireAdmin, approvalHandler.List)
+	router.GET("/approvals/:id", authMiddleware.Authenticate, authMiddleware.RequireAdmin, approvalHandler.Get)
+	router.POST("/approvals/:id/approve", authMiddleware.Authenticate, authMiddleware.RequireAdmin, approvalHandler.Approve)
+	router.POST("/approvals/:id/reject", authMiddleware.Authenticate, authMiddleware.RequireAdmin, approvalHandler.Reject)
+}
@@ -0,0 +1,471 @@
+package approval
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMissingActor     = errors.New("admin user ID is required")
+	ErrInvalidAmount    = errors.New("amount must be positive")
+	ErrInvalidStatus    = errors.New("status must be PENDING, APPROVED, EXECUTED, FAILED, REJECTED or EXPIRED")
+	ErrCurrencyMismatch = errors.New("currency does not match the wallet")
+	ErrNotPending       = errors.New("approval request has already been decided")
+	ErrRequestExpired   = errors.New("approval request has expired")
+	ErrSelfApproval     = errors.New("requests must be approved or rejected by a different admin")
+	ErrMissingNote      = errors.New("a note is required to reject a request")
+	ErrDuplicateRequest = errors.New("an approval request with this ID already exists")
+)
+
+// DefaultTTL is how long a request waits for a decision before it expires
+const DefaultTTL = 72 * time.Hour
+
+// LedgerService is the subset of ledger.Service approved requests are posted through
+type LedgerService interface {
+	PostJournal(req *ledger.JournalRequest) (string, error)
+	RecordWithdrawal(req *ledger.WithdrawalRequest) (string, error)
+	GetTransactionDetails(transactionID string) ([]*ledger.LedgerEntry, error)
+}
+
+// WalletService resolves the wallets withdrawals are requested from
+type WalletService interface {
+	GetWalletByID(walletID string) (*wallet.Wallet, error)
+}
+
+// Listener is told when a request reaches its final state, e.g. to settle a transaction waiting on it
+type Listener interface {
+	// ApprovalDecided reports whether the request was posted, or why not
+	ApprovalDecided(requestID string, executed bool, reason string) error
+}
+
+// Clock tells the queue what time it is; tests substitute one they can move forward
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock reads the wall clock
+var SystemClock Clock = systemClock{}
+
+// Service is the maker-checker queue: one admin requests a posting, a different admin approves or rejects it
+// Nothing reaches the ledger until it is approved
+type Service struct {
+	mu       sync.Mutex // Serializes decisions and expiry so a request is decided once
+	repo     Repository
+	ledger   LedgerService
+	wallets  WalletService
+	ttl      time.Duration
+	clock    Clock
+	listener Listener // Optional
+}
+
+// Option configures optional behaviour of the Service
+type Option func(*Service)
+
+// WithListener tells listener about every request that is executed, fails, is rejected or expires
+func WithListener(listener Listener) Option {
+	return func(s *Service) {
+		s.listener = listener
+	}
+}
+
+// NewService creates a new approval service; requests expire ttl after they are made
+func NewService(repo Repository, ledgerService LedgerService, walletService WalletService, ttl time.Duration, clock Clock, opts ...Option) *Service {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	s := &Service{repo: repo, ledger: ledgerService, wallets: walletService, ttl: ttl, clock: clock}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// RequestJournal queues a manual journal for approval once it passes the ledger's own checks
+func (s *Service) RequestJournal(journal ledger.JournalRequest, requestedBy string) (*Request, error) {
+	if requestedBy == "" {
+		return nil, ErrMissingActor
+	}
+	if journal.Currency == "" {
+		journal.Currency = currency.CurrencyUSD
+	}
+	journal.CreatedBy = requestedBy
+	journal.ApprovalID = ""
+	if err := journal.Validate(); err != nil {
+		return nil, err
+	}
+
+	var debits int64
+	for _, leg := range journal.Legs {
+		if leg.Amount < 0 {
+			debits -= leg.Amount
+		}
+	}
+	request := s.newRequest(KindJournal, debits, journal.Currency, requestedBy)
+	journal.TransactionID = request.ID
+	request.Journal = &journal
+	return request, s.create(request)
+}
+
+// RequestWithdrawal queues a withdrawal from a customer's wallet for approval
+// A TransactionID set by the caller becomes the request ID, so a transaction waiting on the request shares its ID
+func (s *Service) RequestWithdrawal(withdrawal ledger.WithdrawalRequest, requestedBy string) (*Request, error) {
+	if requestedBy == "" {
+		return nil, ErrMissingActor
+	}
+	if withdrawal.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	from, err := s.wallets.GetWalletByID(withdrawal.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal.Currency != "" && withdrawal.Currency != from.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+	withdrawal.Currency = from.Currency
+	withdrawal.ApprovalID = ""
+
+	request := s.newRequest(KindWithdrawal, withdrawal.Amount, withdrawal.Currency, requestedBy)
+	if withdrawal.TransactionID != "" {
+		if _, err := s.repo.GetByID(withdrawal.TransactionID); err != ErrRequestNotFound {
+			if err == nil {
+				return nil, ErrDuplicateRequest
+			}
+			return nil, err
+		}
+		request.ID = withdrawal.TransactionID
+	}
+	withdrawal.TransactionID = request.ID
+	request.Withdrawal = &withdrawal
+	return request, s.create(request)
+}
+
+// QueueWithdrawal queues a customer's own withdrawal that is above the approval threshold, returning the request ID
+// Any admin can approve it, since the customer who requested it can't
+func (s *Service) QueueWithdrawal(withdrawal ledger.WithdrawalRequest, requestedBy string) (string, error) {
+	request, err := s.RequestWithdrawal(withdrawal, requestedBy)
+	if err != nil {
+		return "", err
+	}
+	return request.ID, nil
+}
+
+func (s *Service) newRequest(kind string, amount int64, cur, requestedBy string) *Request {
+	now := s.clock.Now()
+	return &Request{
+		ID:          uuid.New().String(),
+		Kind:        kind,
+		Status:      StatusPending,
+		Amount:      amount,
+		Currency:    cur,
+		RequestedBy: requestedBy,
+		RequestedAt: now.Unix(),
+		ExpiresAt:   now.Add(s.ttl).Unix(),
+	}
+}
+
+// create stores a new request and opens its audit trail
+func (s *Service) create(request *Request) error {
+	if err := s.repo.Save(request); err != nil {
+		return err
+	}
+	if err := s.record(request.ID, ActionRequested, request.RequestedBy, "", request.RequestedAt); err != nil {
+		return err
+	}
+	log.Printf("Approval requested: %s %s for %d %s by %s", request.Kind, request.ID, request.Amount, request.Currency, request.RequestedBy)
+	return nil
+}
+
+// Approve posts a pending request through the ledger on behalf of a second admin
+// The request is stored APPROVED first: the ledger only posts what the queue shows as approved
+// A posting the ledger refuses leaves the request FAILED with the reason; it isn't retried
+// A request left APPROVED, because its outcome wasn't saved, can be approved again to finish it
+func (s *Service) Approve(id, approvedBy, note string) (*Request, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, err := s.decidable(id, approvedBy, true)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().Unix()
+	request.Status = StatusApproved
+	request.DecidedBy = approvedBy
+	request.DecidedAt = now
+	request.DecisionNote = note
+	if err := s.repo.Save(request); err != nil {
+		return nil, err
+	}
+	if err := s.record(request.ID, ActionApproved, approvedBy, note, now); err != nil {
+		return nil, err
+	}
+
+	transactionID, err := s.post(request)
+	if err != nil {
+		log.Printf("Approved request %s could not be posted: %v", request.ID, err)
+		request.Status = StatusFailed
+		request.Error = err.Error()
+		if err := s.record(request.ID, ActionFailed, SystemActor, request.Error, now); err != nil {
+			return nil, err
+		}
+	} else {
+		request.Status = StatusExecuted
+		request.TransactionID = transactionID
+		if err := s.record(request.ID, ActionExecuted, SystemActor, transactionID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Save(request); err != nil {
+		return nil, err
+	}
+	log.Printf("Approval %s %s by %s: %s", request.Kind, request.ID, approvedBy, request.Status)
+	s.settled(request)
+	return request, nil
+}
+
+// post sends an approved request to the ledger under the request's ID, naming it as the approval
+// A request already posted by an approval that didn't get to save its outcome isn't posted again
+func (s *Service) post(request *Request) (string, error) {
+	posted, err := s.ledger.GetTransactionDetails(request.ID)
+	if err != nil {
+		return "", fmt.Errorf("error checking for an earlier posting: %w", err)
+	}
+	if len(posted) > 0 {
+		return request.ID, nil
+	}
+
+	switch request.Kind {
+	case KindJournal:
+		journal := *request.Journal
+		journal.ApprovalID = request.ID
+		return s.ledger.PostJournal(&journal)
+	case KindWithdrawal:
+		withdrawal := *request.Withdrawal
+		withdrawal.ApprovalID = request.ID
+		return s.ledger.RecordWithdrawal(&withdrawal)
+	default:
+		return "", fmt.Errorf("unknown approval kind %q", request.Kind)
+	}
+}
+
+// Reject closes a pending request without posting it; the reason is required for the audit trail
+func (s *Service) Reject(id, rejectedBy, note string) (*Request, error) {
+	if note == "" {
+		return nil, ErrMissingNote
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, err := s.decidable(id, rejectedBy, false)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().Unix()
+	request.Status = StatusRejected
+	request.DecidedBy = rejectedBy
+	request.DecidedAt = now
+	request.DecisionNote = note
+	if err := s.repo.Save(request); err != nil {
+		return nil, err
+	}
+	if err := s.record(request.ID, ActionRejected, rejectedBy, note, now); err != nil {
+		return nil, err
+	}
+	log.Printf("Approval %s %s rejected by %s", request.Kind, request.ID, rejectedBy)
+	s.settled(request)
+	return request, nil
+}
+
+// decidable loads a request a decision can be made on; callers must hold mu
+// A request found past its expiry is expired on the spot; approving also takes a request left APPROVED
+func (s *Service) decidable(id, actor string, approving bool) (*Request, error) {
+	if actor == "" {
+		return nil, ErrMissingActor
+	}
+	request, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now().Unix()
+	switch {
+	case request.Status == StatusApproved && approving:
+		// Finishing an approval whose outcome wasn't saved; it was decided before it could expire
+	case request.Status != StatusPending:
+		return nil, ErrNotPending
+	case request.ExpiresAt <= now:
+		if err := s.expire(request, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrRequestExpired
+	}
+	if actor == request.RequestedBy {
+		log.Printf("Error: %s tried to decide their own request %s", actor, request.ID)
+		return nil, ErrSelfApproval
+	}
+	return request, nil
+}
+
+// ExpireStale expires every pending request past its expiry and returns how many it expired
+func (s *Service) ExpireStale() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now().Unix()
+	expired, err := s.repo.ListExpired(now)
+	if err != nil {
+		return 0, err
+	}
+	for _, request := range expired {
+		if err := s.expire(request, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// expire marks a pending request EXPIRED; callers must hold mu
+func (s *Service) expire(request *Request, now int64) error {
+	request.Status = StatusExpired
+	request.DecidedAt = now
+	if err := s.repo.Save(request); err != nil {
+		return err
+	}
+	log.Printf("Approval %s %s expired undecided", request.Kind, request.ID)
+	if err := s.record(request.ID, ActionExpired, SystemActor, "", now); err != nil {
+		return err
+	}
+	s.settled(request)
+	return nil
+}
+
+// settled tells the listener a request reached its final state; the decision stands if the listener fails
+func (s *Service) settled(request *Request) {
+	if s.listener == nil {
+		return
+	}
+	var reason string
+	switch request.Status {
+	case StatusFailed:
+		reason = request.Error
+	case StatusRejected:
+		reason = "approval rejected: " + request.DecisionNote
+	case StatusExpired:
+		reason = "approval expired"
+	}
+	if err := s.listener.ApprovalDecided(request.ID, request.Status == StatusExecuted, reason); err != nil {
+		log.Printf("Error telling the listener approval %s is %s: %v", request.ID, request.Status, err)
+	}
+}
+
+func (s *Service) record(requestID, action, actor, note string, at int64) error {
+	return s.repo.SaveEvent(&Event{
+		ID:        uuid.New().String(),
+		RequestID: requestID,
+		Action:    action,
+		Actor:     actor,
+		Note:      note,
+		At:        at,
+	})
+}
+
+// GetRequest retrieves a request
+func (s *Service) GetRequest(id string) (*Request, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListRequests retrieves the requests with a status, oldest first; expired requests are swept first
+func (s *Service) ListRequests(status string) ([]*Request, error) {
+	switch status {
+	case "", StatusPending, StatusApproved, StatusExecuted, StatusFailed, StatusRejected, StatusExpired:
+	default:
+		return nil, ErrInvalidStatus
+	}
+	if _, err := s.ExpireStale(); err != nil {
+		return nil, err
+	}
+	return s.repo.List(status)
+}
+
+// ListEvents retrieves a request's audit trail, oldest first
+func (s *Service) ListEvents(id string) ([]*Event, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvents(id)
+}
+
+// StartExpiryJob expires undecided requests every interval until the returned stop function is called
+func (s *Service) StartExpiryJob(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				expired, err := s.ExpireStale()
+				if err != nil {
+					log.Printf("Error expiring approval requests: %v", err)
+				}
+				if expired > 0 {
+					log.Printf("Expired %d approval requests", expired)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// LedgerApprovals lets the ledger check a posting against the request approved for it
+type LedgerApprovals struct {
+	repo Repository
+}
+
+// NewLedgerApprovals resolves the ledger's approval IDs against the queue stored in repo
+func NewLedgerApprovals(repo Repository) *LedgerApprovals {
+	return &LedgerApprovals{repo: repo}
+}
+
+// GetApproved returns the request if it is approved and being posted
+// Pending, decided and unknown requests all read as not approved
+func (a *LedgerApprovals) GetApproved(approvalID string) (*ledger.Approval, error) {
+	request, err := a.repo.GetByID(approvalID)
+	if err == ErrRequestNotFound {
+		return nil, ledger.ErrApprovalRequired
+	}
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusApproved {
+		return nil, ledger.ErrApprovalRequired
+	}
+	return &ledger.Approval{
+		ID:          request.ID,
+		RequestedBy: request.RequestedBy,
+		ApprovedBy:  request.DecidedBy,
+		Withdrawal:  request.Withdrawal,
+		Journal:     request.Journal,
+	}, nil
+}
@@ -0,0 +1,349 @@
+package approval
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock the tests move forward by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestService(t *testing.T) (*Service, *ledger.Service, *fakeClock, string) {
+	t.Helper()
+	repo := NewRepository()
+	ledgerService := ledger.NewService(ledger.NewRepository(), ledger.WithApprovalThreshold(100),
+		ledger.WithApprovals(NewLedgerApprovals(repo)))
+	walletService := wallet.NewService(wallet.NewRepository(), ledgerService)
+	walletID, err := walletService.CreateWallet("customer", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	if _, err := ledgerService.RecordDeposit(&ledger.DepositRequest{AccountID: walletID, Amount: 50000, Currency: "USD", Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	clock := &fakeClock{now: time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)}
+	return NewService(repo, ledgerService, walletService, 24*time.Hour, clock), ledgerService, clock, walletID
+}
+
+func goodwill(walletID string, amount int64) ledger.JournalRequest {
+	return ledger.JournalRequest{
+		ReasonCode:  ledger.JournalReasonGoodwill,
+		Description: "Delayed withdrawal",
+		Legs: []ledger.JournalLeg{
+			{AccountID: ledger.FeeAccountID("USD"), EntryType: ledger.EntryTypeDebit, Amount: -amount},
+			{AccountID: walletID, EntryType: ledger.EntryTypeCredit, Amount: amount},
+		},
+	}
+}
+
+func actions(t *testing.T, service *Service, id string) []string {
+	t.Helper()
+	events, err := service.ListEvents(id)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action+" by "+event.Actor)
+	}
+	return actions
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestApproveJournal tests that a journal is only posted once a different admin approves it
+func TestApproveJournal(t *testing.T) {
+	service, ledgerService, _, walletID := newTestService(t)
+
+	request, err := service.RequestJournal(goodwill(walletID, 2500), "admin-1")
+	if err != nil {
+		t.Fatalf("Failed to request journal: %v", err)
+	}
+	if request.Status != StatusPending || request.Amount != 2500 {
+		t.Fatalf("Expected a pending request for 25.00, got %+v", request)
+	}
+	if entries, _ := ledgerService.GetTransactionDetails(request.ID); len(entries) != 0 {
+		t.Fatal("Expected nothing to be posted before approval")
+	}
+
+	if _, err := service.Approve(request.ID, "admin-1", ""); err != ErrSelfApproval {
+		t.Errorf("Expected ErrSelfApproval, got %v", err)
+	}
+	request, err = service.Approve(request.ID, "admin-2", "Checked the ticket")
+	if err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+	if request.Status != StatusExecuted || request.TransactionID != request.ID || request.DecidedBy != "admin-2" {
+		t.Fatalf("Expected the request to be executed, got %+v", request)
+	}
+	entries, _ := ledgerService.GetTransactionDetails(request.TransactionID)
+	if len(entries) != 2 || entries[0].CreatedBy != "admin-1" || entries[0].Metadata["approved_by"] != "admin-2" {
+		t.Errorf("Expected the journal to record both admins, got %+v", entries)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 52500 {
+		t.Errorf("Expected the wallet to be credited once, got %d", balance.Balance)
+	}
+
+	if _, err := service.Approve(request.ID, "admin-3", ""); err != ErrNotPending {
+		t.Errorf("Expected ErrNotPending approving twice, got %v", err)
+	}
+	want := []string{"REQUESTED by admin-1", "APPROVED by admin-2", "EXECUTED by " + SystemActor}
+	if got := actions(t, service, request.ID); !equal(got, want) {
+		t.Errorf("Expected audit trail %v, got %v", want, got)
+	}
+}
+
+// TestLedgerOnlyPostsApprovedRequests tests that the ledger refuses a posting that names a request the queue
+// hasn't approved, or one already executed, whatever it claims about who approved it
+func TestLedgerOnlyPostsApprovedRequests(t *testing.T) {
+	service, ledgerService, _, walletID := newTestService(t)
+
+	request, err := service.RequestWithdrawal(ledger.WithdrawalRequest{AccountID: walletID, Amount: 20000, Destination: "bank"}, "admin-1")
+	if err != nil {
+		t.Fatalf("Failed to request withdrawal: %v", err)
+	}
+	withdrawal := *request.Withdrawal
+	withdrawal.ApprovalID, withdrawal.TransactionID = request.ID, request.ID
+	if _, err := ledgerService.RecordWithdrawal(&withdrawal); err != ledger.ErrApprovalRequired {
+		t.Errorf("Expected a pending request to be refused, got %v", err)
+	}
+	journal := goodwill(walletID, 2500)
+	journal.CreatedBy, journal.ApprovalID = "admin-1", "made-up"
+	if _, err := ledgerService.PostJournal(&journal); err != ledger.ErrApprovalRequired {
+		t.Errorf("Expected an unknown approval to be refused, got %v", err)
+	}
+
+	if request, err = service.Approve(request.ID, "admin-2", ""); err != nil || request.Status != StatusExecuted {
+		t.Fatalf("Expected the withdrawal to be executed, got %+v (%v)", request, err)
+	}
+	withdrawal.TransactionID = ""
+	if _, err := ledgerService.RecordWithdrawal(&withdrawal); err != ledger.ErrApprovalRequired {
+		t.Errorf("Expected an executed request not to be posted again, got %v", err)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 30000 {
+		t.Errorf("Expected the withdrawal posted once, got a balance of %d", balance.Balance)
+	}
+}
+
+// TestApproveResumesApprovedRequest tests that a request left APPROVED, its outcome unsaved, can be approved
+// again to finish it without posting twice
+func TestApproveResumesApprovedRequest(t *testing.T) {
+	service, ledgerService, clock, walletID := newTestService(t)
+
+	request, err := service.RequestWithdrawal(ledger.WithdrawalRequest{AccountID: walletID, Amount: 20000, Destination: "bank"}, "admin-1")
+	if err != nil {
+		t.Fatalf("Failed to request withdrawal: %v", err)
+	}
+	// Approved and posted, but the service stopped before saving the outcome
+	request.Status, request.DecidedBy = StatusApproved, "admin-2"
+	if err := service.repo.Save(request); err != nil {
+		t.Fatalf("Failed to save request: %v", err)
+	}
+	withdrawal := *request.Withdrawal
+	withdrawal.ApprovalID, withdrawal.TransactionID = request.ID, request.ID
+	if _, err := ledgerService.RecordWithdrawal(&withdrawal); err != nil {
+		t.Fatalf("Failed to post the approved withdrawal: %v", err)
+	}
+
+	clock.now = clock.now.Add(48 * time.Hour)
+	if _, err := service.Reject(request.ID, "admin-3", "Too late"); err != ErrNotPending {
+		t.Errorf("Expected an approved request not to be rejected, got %v", err)
+	}
+	if request, err = service.Approve(request.ID, "admin-3", ""); err != nil || request.Status != StatusExecuted {
+		t.Fatalf("Expected the request to be executed, got %+v (%v)", request, err)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 30000 {
+		t.Errorf("Expected the withdrawal posted once, got a balance of %d", balance.Balance)
+	}
+}
+
+// TestApproveWithdrawal tests approved withdrawals above the threshold and postings the ledger refuses
+func TestApproveWithdrawal(t *testing.T) {
+	service, ledgerService, _, walletID := newTestService(t)
+
+	request, err := service.RequestWithdrawal(ledger.WithdrawalRequest{AccountID: walletID, Amount: 20000, Destination: "bank"}, "admin-1")
+	if err != nil {
+		t.Fatalf("Failed to request withdrawal: %v", err)
+	}
+	if request, err = service.Approve(request.ID, "admin-2", ""); err != nil || request.Status != StatusExecuted {
+		t.Fatalf("Expected the withdrawal to be executed, got %+v (%v)", request, err)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 30000 {
+		t.Errorf("Expected 300.00 left, got %d", balance.Balance)
+	}
+
+	// More than the wallet holds: approved, but refused by the ledger
+	request, err = service.RequestWithdrawal(ledger.WithdrawalRequest{AccountID: walletID, Amount: 40000, Destination: "bank"}, "admin-1")
+	if err != nil {
+		t.Fatalf("Failed to request withdrawal: %v", err)
+	}
+	request, err = service.Approve(request.ID, "admin-2", "")
+	if err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+	if request.Status != StatusFailed || request.Error != ledger.ErrInsufficientBalance.Error() {
+		t.Errorf("Expected the request to fail for lack of funds, got %+v", request)
+	}
+	want := []string{"REQUESTED by admin-1", "APPROVED by admin-2", "FAILED by " + SystemActor}
+	if got := actions(t, service, request.ID); !equal(got, want) {
+		t.Errorf("Expected audit trail %v, got %v", want, got)
+	}
+
+	if _, err := service.RequestWithdrawal(ledger.WithdrawalRequest{AccountID: walletID, Amount: 100, Currency: "EUR"}, "admin-1"); err != ErrCurrencyMismatch {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+}
+
+// TestQueueCustomerWithdrawal tests that a customer's withdrawal above the threshold is posted once an admin approves it
+func TestQueueCustomerWithdrawal(t *testing.T) {
+	service, ledgerService, _, walletID := newTestService(t)
+
+	if !ledgerService.NeedsApproval(20000, "USD") {
+		t.Fatal("Expected 200.00 to be above the 100 USD threshold")
+	}
+	id, err := service.QueueWithdrawal(ledger.WithdrawalRequest{AccountID: walletID, Amount: 20000, Destination: "bank"}, "customer")
+	if err != nil {
+		t.Fatalf("Failed to queue withdrawal: %v", err)
+	}
+	if _, err := service.Approve(id, "customer", ""); err != ErrSelfApproval {
+		t.Errorf("Expected the customer not to approve their own withdrawal, got %v", err)
+	}
+	request, err := service.Approve(id, "admin-1", "")
+	if err != nil || request.Status != StatusExecuted {
+		t.Fatalf("Expected the withdrawal to be executed, got %+v (%v)", request, err)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 30000 {
+		t.Errorf("Expected 300.00 left, got %d", balance.Balance)
+	}
+	want := []string{"REQUESTED by customer", "APPROVED by admin-1", "EXECUTED by " + SystemActor}
+	if got := actions(t, service, id); !equal(got, want) {
+		t.Errorf("Expected audit trail %v, got %v", want, got)
+	}
+}
+
+// TestRejectAndExpire tests rejection, expiry and that neither posts anything
+func TestRejectAndExpire(t *testing.T) {
+	service, ledgerService, clock, walletID := newTestService(t)
+
+	rejected, _ := service.RequestJournal(goodwill(walletID, 100), "admin-1")
+	if _, err := service.Reject(rejected.ID, "admin-2", ""); err != ErrMissingNote {
+		t.Errorf("Expected ErrMissingNote, got %v", err)
+	}
+	if _, err := service.Reject(rejected.ID, "admin-1", "Changed my mind"); err != ErrSelfApproval {
+		t.Errorf("Expected ErrSelfApproval rejecting one's own request, got %v", err)
+	}
+	if rejected, err := service.Reject(rejected.ID, "admin-2", "No ticket"); err != nil || rejected.Status != StatusRejected {
+		t.Errorf("Expected the request to be rejected, got %+v (%v)", rejected, err)
+	}
+
+	swept, _ := service.RequestJournal(goodwill(walletID, 200), "admin-1")
+	clock.now = clock.now.Add(12 * time.Hour)
+	stale, _ := service.RequestJournal(goodwill(walletID, 300), "admin-1")
+	clock.now = clock.now.Add(12 * time.Hour)
+
+	pending, err := service.ListRequests(StatusPending)
+	if err != nil || len(pending) != 1 || pending[0].ID != stale.ID {
+		t.Fatalf("Expected only the newest request to be pending, got %d (%v)", len(pending), err)
+	}
+	if request, _ := service.GetRequest(swept.ID); request.Status != StatusExpired {
+		t.Errorf("Expected the oldest request to have expired, got %s", request.Status)
+	}
+
+	clock.now = clock.now.Add(12 * time.Hour)
+	if _, err := service.Approve(stale.ID, "admin-2", ""); err != ErrRequestExpired {
+		t.Errorf("Expected ErrRequestExpired, got %v", err)
+	}
+	want := []string{"REQUESTED by admin-1", "EXPIRED by " + SystemActor}
+	if got := actions(t, service, stale.ID); !equal(got, want) {
+		t.Errorf("Expected audit trail %v, got %v", want, got)
+	}
+
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 50000 {
+		t.Errorf("Expected nothing to be posted, got a balance of %d", balance.Balance)
+	}
+}
+
+// recordingListener records the outcomes it is told about, by request ID
+type recordingListener map[string]string
+
+func (l recordingListener) ApprovalDecided(requestID string, executed bool, reason string) error {
+	if executed {
+		reason = "executed"
+	}
+	l[requestID] = reason
+	return nil
+}
+
+// TestListenerToldOfOutcomes tests that a withdrawal queued under a caller's ID keeps it, and that the listener
+// hears how each request ends
+func TestListenerToldOfOutcomes(t *testing.T) {
+	service, _, clock, walletID := newTestService(t)
+	listener := recordingListener{}
+	service.listener = listener
+
+	withdrawal := ledger.WithdrawalRequest{AccountID: walletID, Amount: 20000, Destination: "bank", TransactionID: "txn-1"}
+	approvalID, err := service.QueueWithdrawal(withdrawal, "customer")
+	if err != nil || approvalID != "txn-1" {
+		t.Fatalf("Expected the withdrawal queued as txn-1, got %q (%v)", approvalID, err)
+	}
+	if _, err := service.QueueWithdrawal(withdrawal, "customer"); err != ErrDuplicateRequest {
+		t.Errorf("Expected ErrDuplicateRequest queueing the same ID twice, got %v", err)
+	}
+	if _, err := service.Approve(approvalID, "admin-1", ""); err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+
+	rejected, _ := service.RequestJournal(goodwill(walletID, 100), "admin-1")
+	if _, err := service.Reject(rejected.ID, "admin-2", "No ticket"); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+	stale, _ := service.RequestJournal(goodwill(walletID, 100), "admin-1")
+	clock.now = clock.now.Add(48 * time.Hour)
+	if _, err := service.ExpireStale(); err != nil {
+		t.Fatalf("Failed to expire requests: %v", err)
+	}
+
+	want := map[string]string{"txn-1": "executed", rejected.ID: "approval rejected: No ticket", stale.ID: "approval expired"}
+	for id, outcome := range want {
+		if listener[id] != outcome {
+			t.Errorf("Expected %s to be %q, got %q", id, outcome, listener[id])
+		}
+	}
+}
+
+// TestRequestJournalValidation tests that journals the ledger would refuse are never queued
+func TestRequestJournalValidation(t *testing.T) {
+	service, _, _, walletID := newTestService(t)
+
+	unbalanced := goodwill(walletID, 100)
+	unbalanced.Legs[1].Amount = 99
+	if _, err := service.RequestJournal(unbalanced, "admin-1"); err != ledger.ErrTransactionNotBalanced {
+		t.Errorf("Expected ErrTransactionNotBalanced, got %v", err)
+	}
+	noReason := goodwill(walletID, 100)
+	noReason.ReasonCode = ""
+	if _, err := service.RequestJournal(noReason, "admin-1"); err != ledger.ErrInvalidReasonCode {
+		t.Errorf("Expected ErrInvalidReasonCode, got %v", err)
+	}
+	if requests, _ := service.ListRequests(""); len(requests) != 0 {
+		t.Errorf("Expected nothing to be queued, got %d requests", len(requests))
+	}
+}
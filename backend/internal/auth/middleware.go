@@ -11,11 +11,16 @@ import (
 // Middleware handles authentication for protected routes
 type Middleware struct {
 	service *Service
+	admins  map[string]bool // User IDs allowed through RequireAdmin
 }
 
-// NewMiddleware creates a new auth middleware
-func NewMiddleware(service *Service) *Middleware {
-	return &Middleware{service: service}
+// NewMiddleware creates a new auth middleware; adminUserIDs may be empty, in which case no one is an admin
+func NewMiddleware(service *Service, adminUserIDs []string) *Middleware {
+	admins := make(map[string]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+	return &Middleware{service: service, admins: admins}
 }
 
 // Authenticate validates the JWT token and sets user context
@@ -43,7 +48,25 @@ func (m *Middleware) Authenticate(c *gin.Context) {
 		return
 	}
 
-	// Set user ID in context
+	// Set user ID in context, and whether handlers may let the user act on other users' accounts
 	c.Set("userId", userID)
+	c.Set("isAdmin", m.IsAdmin(userID))
 	c.Next()
 }
+
+// RequireAdmin lets only configured admins through; it must run after Authenticate
+func (m *Middleware) RequireAdmin(c *gin.Context) {
+	userID := c.GetString("userId")
+	if !m.IsAdmin(userID) {
+		log.Printf("Error: User %q is not an admin", userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// IsAdmin reports whether the user is a configured admin
+func (m *Middleware) IsAdmin(userID string) bool {
+	return userID != "" && m.admins[userID]
+}
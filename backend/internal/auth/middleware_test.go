@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRequireAdmin tests that only configured admins get through and that handlers can see who is one
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := NewService(NewRepository(), nil, "access-secret", "refresh-secret")
+	middleware := NewMiddleware(service, []string{"admin-1"})
+
+	r := gin.New()
+	r.GET("/admin", middleware.Authenticate, middleware.RequireAdmin, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"isAdmin": c.GetBool("isAdmin")})
+	})
+
+	for user, want := range map[string]int{"admin-1": http.StatusOK, "user-1": http.StatusForbidden} {
+		tokens, err := service.GenerateTokens(user, user+"@example.com")
+		if err != nil {
+			t.Fatalf("Failed to generate tokens: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.AddCookie(&http.Cookie{Name: "access_token", Value: tokens.AccessToken})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", user, want, w.Code, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", w.Code)
+	}
+}
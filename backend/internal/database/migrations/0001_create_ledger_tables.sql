@@ -0,0 +1,29 @@
+-- Ledger entries are append-only: rows are never updated or deleted
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    seq              BIGSERIAL PRIMARY KEY,
+    id               TEXT        NOT NULL UNIQUE,
+    account_id       TEXT        NOT NULL,
+    account_type     TEXT        NOT NULL,
+    amount           BIGINT      NOT NULL,
+    currency         CHAR(3)     NOT NULL,
+    entry_type       TEXT        NOT NULL CHECK (entry_type IN ('DEBIT', 'CREDIT')),
+    transaction_id   TEXT        NOT NULL,
+    transaction_type TEXT        NOT NULL,
+    created_at       BIGINT      NOT NULL,
+    created_by       TEXT        NOT NULL,
+    description      TEXT        NOT NULL DEFAULT '',
+    metadata         JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_id ON ledger_entries (account_id, seq);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+
+-- Cached balances, always recomputable from ledger_entries
+CREATE TABLE IF NOT EXISTS account_balances (
+    account_id    TEXT    PRIMARY KEY,
+    account_type  TEXT    NOT NULL,
+    balance       BIGINT  NOT NULL,
+    currency      CHAR(3) NOT NULL,
+    updated_at    BIGINT  NOT NULL,
+    last_entry_id TEXT    NOT NULL
+);
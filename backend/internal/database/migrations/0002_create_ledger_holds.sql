@@ -0,0 +1,21 @@
+-- Amount reserved by active holds; the available balance is balance - held
+ALTER TABLE account_balances ADD COLUMN IF NOT EXISTS held BIGINT NOT NULL DEFAULT 0;
+
+-- Authorization holds: funds reserved on an account without posting entries
+CREATE TABLE IF NOT EXISTS ledger_holds (
+    id                     TEXT    PRIMARY KEY,
+    account_id             TEXT    NOT NULL,
+    amount                 BIGINT  NOT NULL CHECK (amount > 0),
+    currency               CHAR(3) NOT NULL,
+    destination            TEXT    NOT NULL DEFAULT '',
+    description            TEXT    NOT NULL DEFAULT '',
+    status                 TEXT    NOT NULL CHECK (status IN ('ACTIVE', 'CAPTURED', 'RELEASED', 'EXPIRED')),
+    captured_amount        BIGINT  NOT NULL DEFAULT 0,
+    capture_transaction_id TEXT    NOT NULL DEFAULT '',
+    created_at             BIGINT  NOT NULL,
+    expires_at             BIGINT  NOT NULL,
+    updated_at             BIGINT  NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_holds_account_id ON ledger_holds (account_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_holds_expiry ON ledger_holds (expires_at) WHERE status = 'ACTIVE';
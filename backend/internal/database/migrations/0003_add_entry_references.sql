@@ -0,0 +1,5 @@
+-- Links reversal and refund entries to the transaction they compensate
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS reference_transaction_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reference ON ledger_entries (reference_transaction_id)
+    WHERE reference_transaction_id <> '';
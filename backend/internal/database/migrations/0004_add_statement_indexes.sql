@@ -0,0 +1,15 @@
+-- Running balance of the account right after each entry, written when the entry is posted
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS balance_after BIGINT;
+
+UPDATE ledger_entries e
+SET balance_after = r.running
+FROM (
+    SELECT seq, SUM(amount) OVER (PARTITION BY account_id ORDER BY seq) AS running
+    FROM ledger_entries
+) r
+WHERE e.seq = r.seq AND e.balance_after IS NULL;
+
+ALTER TABLE ledger_entries ALTER COLUMN balance_after SET NOT NULL;
+
+-- Statements look up an account's entries by period
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_time ON ledger_entries (account_id, created_at, seq);
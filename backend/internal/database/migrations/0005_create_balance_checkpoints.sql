@@ -0,0 +1,7 @@
+-- Periodic snapshots of account balances, so balance-as-of queries only sum the entries since the nearest one
+CREATE TABLE IF NOT EXISTS balance_checkpoints (
+    account_id TEXT   NOT NULL,
+    as_of      BIGINT NOT NULL,
+    balance    BIGINT NOT NULL,
+    PRIMARY KEY (account_id, as_of)
+);
@@ -0,0 +1,7 @@
+-- Hash chain over all entries in posting order: each entry stores its own hash and the previous entry's
+-- Entries posted before this migration keep empty hashes; the chain starts at the first entry posted after it
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS hash TEXT NOT NULL DEFAULT '';
+
+-- Daily root hashes look up the last entry created before the end of a day
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_created_at ON ledger_entries (created_at, seq);
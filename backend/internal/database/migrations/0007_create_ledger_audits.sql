@@ -0,0 +1,23 @@
+-- Full-ledger audit runs; the report (including the trial balance) is kept as it was produced
+CREATE TABLE IF NOT EXISTS ledger_audit_reports (
+    seq     BIGSERIAL PRIMARY KEY,
+    id      TEXT      NOT NULL UNIQUE,
+    run_at  BIGINT    NOT NULL,
+    passed  BOOLEAN   NOT NULL,
+    report  JSONB     NOT NULL
+);
+
+-- One row per problem an audit found
+CREATE TABLE IF NOT EXISTS ledger_audit_alerts (
+    seq            BIGSERIAL PRIMARY KEY,
+    id             TEXT      NOT NULL UNIQUE,
+    report_id      TEXT      NOT NULL REFERENCES ledger_audit_reports (id),
+    kind           TEXT      NOT NULL,
+    account_id     TEXT      NOT NULL DEFAULT '',
+    transaction_id TEXT      NOT NULL DEFAULT '',
+    currency       CHAR(3)   NOT NULL,
+    expected       BIGINT    NOT NULL,
+    actual         BIGINT    NOT NULL,
+    message        TEXT      NOT NULL,
+    created_at     BIGINT    NOT NULL
+);
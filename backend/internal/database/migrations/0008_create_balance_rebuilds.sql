@@ -0,0 +1,9 @@
+-- Audit record of every balance repair: the cached and rebuilt balance of each account that changed
+CREATE TABLE IF NOT EXISTS balance_rebuilds (
+    seq              BIGSERIAL PRIMARY KEY,
+    id               TEXT      NOT NULL UNIQUE,
+    run_at           BIGINT    NOT NULL,
+    requested_by     TEXT      NOT NULL,
+    accounts_checked INTEGER   NOT NULL,
+    corrections      JSONB     NOT NULL
+);
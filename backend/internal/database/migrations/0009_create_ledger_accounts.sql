@@ -0,0 +1,44 @@
+-- Chart of accounts: entries can only be posted to registered, non-group accounts that aren't closed
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id             TEXT    PRIMARY KEY,
+    name           TEXT    NOT NULL DEFAULT '',
+    type           TEXT    NOT NULL,
+    currency       TEXT    NOT NULL DEFAULT '', -- Empty on group accounts
+    normal_balance TEXT    NOT NULL CHECK (normal_balance IN ('DEBIT', 'CREDIT')),
+    status         TEXT    NOT NULL CHECK (status IN ('OPEN', 'FROZEN', 'CLOSED')),
+    parent_id      TEXT    REFERENCES ledger_accounts (id),
+    is_group       BOOLEAN NOT NULL DEFAULT FALSE,
+    funding_source TEXT    NOT NULL DEFAULT '', -- EXTERNAL_BANK accounts settling one deposit source or withdrawal destination
+    created_at     BIGINT  NOT NULL,
+    updated_at     BIGINT  NOT NULL
+);
+
+-- Postings resolve system accounts by type, currency and funding source, so each combination is unique
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_accounts_system
+    ON ledger_accounts (type, currency, funding_source) WHERE NOT is_group AND type <> 'USER_WALLET';
+
+-- Group accounts of the default chart (the system accounts under them are registered at startup)
+INSERT INTO ledger_accounts (id, name, type, normal_balance, status, is_group, created_at, updated_at)
+VALUES
+    ('wallets',           'User wallets',      'USER_WALLET',   'CREDIT', 'OPEN', TRUE, EXTRACT(EPOCH FROM NOW())::BIGINT, EXTRACT(EPOCH FROM NOW())::BIGINT),
+    ('external-funding',  'External funding',  'EXTERNAL_BANK', 'DEBIT',  'OPEN', TRUE, EXTRACT(EPOCH FROM NOW())::BIGINT, EXTRACT(EPOCH FROM NOW())::BIGINT),
+    ('fees',              'Fee revenue',       'SYSTEM_FEE',    'CREDIT', 'OPEN', TRUE, EXTRACT(EPOCH FROM NOW())::BIGINT, EXTRACT(EPOCH FROM NOW())::BIGINT),
+    ('fx-positions',      'FX positions',      'FX_POSITION',   'DEBIT',  'OPEN', TRUE, EXTRACT(EPOCH FROM NOW())::BIGINT, EXTRACT(EPOCH FROM NOW())::BIGINT),
+    ('fx-spread-revenue', 'FX spread revenue', 'FX_REVENUE',    'CREDIT', 'OPEN', TRUE, EXTRACT(EPOCH FROM NOW())::BIGINT, EXTRACT(EPOCH FROM NOW())::BIGINT)
+ON CONFLICT (id) DO NOTHING;
+
+-- Every account that already has postings is registered, under the group for its type
+INSERT INTO ledger_accounts (id, name, type, currency, normal_balance, status, parent_id, created_at, updated_at)
+SELECT b.account_id, b.account_id, b.account_type, b.currency,
+    CASE WHEN b.account_type IN ('EXTERNAL_BANK', 'FX_POSITION') THEN 'DEBIT' ELSE 'CREDIT' END,
+    'OPEN',
+    CASE b.account_type
+        WHEN 'USER_WALLET' THEN 'wallets'
+        WHEN 'EXTERNAL_BANK' THEN 'external-funding'
+        WHEN 'SYSTEM_FEE' THEN 'fees'
+        WHEN 'FX_POSITION' THEN 'fx-positions'
+        WHEN 'FX_REVENUE' THEN 'fx-spread-revenue'
+    END,
+    b.updated_at, b.updated_at
+FROM account_balances b
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,20 @@
+-- Freezes keep the reason and whether credits are still accepted on the account itself
+ALTER TABLE ledger_accounts ADD COLUMN IF NOT EXISTS status_reason TEXT    NOT NULL DEFAULT '';
+ALTER TABLE ledger_accounts ADD COLUMN IF NOT EXISTS allow_credits BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Every freeze, unfreeze and closure, with who made it and why
+CREATE TABLE IF NOT EXISTS ledger_account_status_changes (
+    seq                  BIGSERIAL PRIMARY KEY,
+    id                   TEXT      NOT NULL UNIQUE,
+    account_id           TEXT      NOT NULL REFERENCES ledger_accounts (id),
+    from_status          TEXT      NOT NULL,
+    to_status            TEXT      NOT NULL,
+    allow_credits        BOOLEAN   NOT NULL DEFAULT FALSE,
+    reason               TEXT      NOT NULL,
+    changed_by           TEXT      NOT NULL,
+    sweep_account_id     TEXT      NOT NULL DEFAULT '',
+    sweep_transaction_id TEXT      NOT NULL DEFAULT '',
+    created_at           BIGINT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_account_status_changes_account ON ledger_account_status_changes (account_id, seq);
@@ -0,0 +1,8 @@
+-- Each account's KYC level and the limits admins have overridden for it; accounts without a row are at NONE
+CREATE TABLE IF NOT EXISTS ledger_account_limits (
+    account_id TEXT   PRIMARY KEY REFERENCES ledger_accounts (id),
+    kyc_level  TEXT   NOT NULL,
+    overrides  JSONB  NOT NULL,
+    updated_by TEXT   NOT NULL,
+    updated_at BIGINT NOT NULL
+);
@@ -0,0 +1,10 @@
+-- Batch transfers and the outcome of each item; items are kept in the batch document
+CREATE TABLE IF NOT EXISTS ledger_batches (
+    id              TEXT    PRIMARY KEY,
+    from_account_id TEXT    NOT NULL REFERENCES ledger_accounts (id),
+    status          TEXT    NOT NULL,
+    created_at      BIGINT  NOT NULL,
+    batch           JSONB   NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_batches_from_account ON ledger_batches (from_account_id, created_at);
@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderKey is the request header carrying the client-generated idempotency key
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed is set on responses that were replayed from a stored result
+const HeaderReplayed = "Idempotent-Replayed"
+
+// Middleware makes money-moving endpoints safe to retry
+type Middleware struct {
+	service *Service
+}
+
+// NewMiddleware creates a new idempotency middleware
+func NewMiddleware(service *Service) *Middleware {
+	return &Middleware{service: service}
+}
+
+// Enforce requires an Idempotency-Key header and replays the stored response on retries
+// Must run after auth.Middleware.Authenticate, since keys are scoped per user
+func (m *Middleware) Enforce(c *gin.Context) {
+	key := c.GetHeader(HeaderKey)
+	if key == "" {
+		log.Println("Error: Request missing Idempotency-Key header")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required header: " + HeaderKey})
+		c.Abort()
+		return
+	}
+	if len(key) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": HeaderKey + " must be at most 255 characters"})
+		c.Abort()
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Println("Error reading request body:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		c.Abort()
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	scopedKey := c.GetString("userId") + ":" + key
+	fingerprint := fingerprintRequest(c.Request.Method, c.FullPath(), body)
+
+	record, err := m.service.Begin(scopedKey, fingerprint)
+	if err != nil {
+		switch err {
+		case ErrKeyReused:
+			c.JSON(http.StatusConflict, gin.H{"error": HeaderKey + " has already been used with a different request"})
+		case ErrRequestInProgress:
+			c.JSON(http.StatusConflict, gin.H{"error": "A request with this " + HeaderKey + " is still being processed"})
+		default:
+			log.Println("Error checking idempotency key:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		c.Abort()
+		return
+	}
+
+	// Already executed: replay the original response
+	if record != nil {
+		c.Header(HeaderReplayed, "true")
+		c.Data(record.ResponseStatus, record.ContentType, record.ResponseBody)
+		c.Abort()
+		return
+	}
+
+	// Release the key unless a response gets stored, including when the handler panics
+	stored := false
+	defer func() {
+		if !stored {
+			if err := m.service.Abandon(scopedKey); err != nil {
+				log.Println("Error releasing idempotency key:", err)
+			}
+		}
+	}()
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	// Server errors are not stored so the client can retry them; everything else is final
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError {
+		return
+	}
+	if err := m.service.Complete(scopedKey, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+		log.Println("Error storing idempotent response:", err)
+		return
+	}
+	stored = true
+}
+
+// fingerprintRequest identifies a request by its method, route and body
+func fingerprintRequest(method, route string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + route + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder captures the response body while still writing it to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
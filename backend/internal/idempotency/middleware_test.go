@@ -0,0 +1,132 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter builds a router whose deposit handler counts how often it really executes
+func newTestRouter(status int) (*gin.Engine, *int) {
+	gin.SetMode(gin.TestMode)
+	middleware := NewMiddleware(NewService(NewRepository(), DefaultTTL))
+
+	executions := 0
+	r := gin.New()
+	r.POST("/deposits",
+		func(c *gin.Context) { c.Set("userId", c.GetHeader("X-Test-User")); c.Next() },
+		middleware.Enforce,
+		func(c *gin.Context) {
+			executions++
+			c.JSON(status, gin.H{"transaction_id": "txn", "execution": executions})
+		},
+	)
+	return r, &executions
+}
+
+func postDeposit(r *gin.Engine, user, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/deposits", strings.NewReader(body))
+	req.Header.Set("X-Test-User", user)
+	if key != "" {
+		req.Header.Set(HeaderKey, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestReplayOnRetry tests that a retried request returns the stored response without re-executing
+func TestReplayOnRetry(t *testing.T) {
+	r, executions := newTestRouter(http.StatusCreated)
+
+	first := postDeposit(r, "alice", "key-1", `{"amount":"10.00"}`)
+	retry := postDeposit(r, "alice", "key-1", `{"amount":"10.00"}`)
+
+	if *executions != 1 {
+		t.Errorf("Expected handler to execute once, executed %d times", *executions)
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected replayed response %d %s, got %d %s", first.Code, first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get(HeaderReplayed) != "true" {
+		t.Errorf("Expected %s header on replayed response", HeaderReplayed)
+	}
+	t.Logf("✓ Retry replayed: %d %s", retry.Code, retry.Body)
+}
+
+// TestKeyReusedWithDifferentPayload tests that a key cannot be reused for a different request
+func TestKeyReusedWithDifferentPayload(t *testing.T) {
+	r, executions := newTestRouter(http.StatusCreated)
+
+	postDeposit(r, "alice", "key-1", `{"amount":"10.00"}`)
+	w := postDeposit(r, "alice", "key-1", `{"amount":"99.00"}`)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409, got %d", w.Code)
+	}
+	if *executions != 1 {
+		t.Errorf("Expected handler to execute once, executed %d times", *executions)
+	}
+}
+
+// TestKeysAreScopedPerUser tests that two users can use the same key independently
+func TestKeysAreScopedPerUser(t *testing.T) {
+	r, executions := newTestRouter(http.StatusCreated)
+
+	postDeposit(r, "alice", "key-1", `{"amount":"10.00"}`)
+	w := postDeposit(r, "bob", "key-1", `{"amount":"10.00"}`)
+
+	if w.Code != http.StatusCreated || w.Header().Get(HeaderReplayed) != "" {
+		t.Errorf("Expected Bob's request to execute, got %d (replayed: %q)", w.Code, w.Header().Get(HeaderReplayed))
+	}
+	if *executions != 2 {
+		t.Errorf("Expected handler to execute twice, executed %d times", *executions)
+	}
+}
+
+// TestMissingKey tests that money-moving requests without a key are rejected
+func TestMissingKey(t *testing.T) {
+	r, executions := newTestRouter(http.StatusCreated)
+
+	w := postDeposit(r, "alice", "", `{"amount":"10.00"}`)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", w.Code)
+	}
+	if *executions != 0 {
+		t.Errorf("Expected handler not to execute, executed %d times", *executions)
+	}
+}
+
+// TestServerErrorIsNotStored tests that a failed request can be retried with the same key
+func TestServerErrorIsNotStored(t *testing.T) {
+	r, executions := newTestRouter(http.StatusInternalServerError)
+
+	postDeposit(r, "alice", "key-1", `{"amount":"10.00"}`)
+	postDeposit(r, "alice", "key-1", `{"amount":"10.00"}`)
+
+	if *executions != 2 {
+		t.Errorf("Expected handler to execute twice, executed %d times", *executions)
+	}
+}
+
+// TestExpiredKey tests that a key can be reused once its record has expired
+func TestExpiredKey(t *testing.T) {
+	service := NewService(NewRepository(), -time.Second)
+
+	if record, err := service.Begin("alice:key-1", "fingerprint-a"); err != nil || record != nil {
+		t.Fatalf("Expected first Begin to claim the key, got %v, %v", record, err)
+	}
+	if err := service.Complete("alice:key-1", http.StatusCreated, "application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+
+	// Already expired, so a different request may claim the key
+	if record, err := service.Begin("alice:key-1", "fingerprint-b"); err != nil || record != nil {
+		t.Errorf("Expected expired key to be claimable, got %v, %v", record, err)
+	}
+}
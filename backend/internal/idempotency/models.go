@@ -0,0 +1,20 @@
+package idempotency
+
+// Record states
+const (
+	StatusInProgress = "IN_PROGRESS" // The original request is still being handled
+	StatusCompleted  = "COMPLETED"   // The response has been stored and can be replayed
+)
+
+// Record stores the outcome of a request made with an Idempotency-Key
+// so that a retry with the same key receives the original response instead of re-executing
+type Record struct {
+	Key            string `json:"key"`         // Scoped key: user ID + client-supplied Idempotency-Key
+	Fingerprint    string `json:"fingerprint"` // SHA-256 of method, route and body of the original request
+	Status         string `json:"status"`      // IN_PROGRESS or COMPLETED
+	ResponseStatus int    `json:"response_status"`
+	ResponseBody   []byte `json:"response_body"`
+	ContentType    string `json:"content_type"`
+	CreatedAt      int64  `json:"created_at"`
+	ExpiresAt      int64  `json:"expires_at"` // After this the key may be reused
+}
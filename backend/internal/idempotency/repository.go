@@ -0,0 +1,109 @@
+package idempotency
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	ErrRecordNotFound    = errors.New("idempotency record not found")
+	ErrRecordExists      = errors.New("idempotency record already exists")
+	ErrKeyReused         = errors.New("idempotency key reused with a different request")
+	ErrRequestInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrMissingKey        = errors.New("idempotency key is required")
+)
+
+// Repository defines the interface for idempotency record storage
+type Repository interface {
+	Create(record *Record) error // Fails with ErrRecordExists if the key is already stored
+	Get(key string) (*Record, error)
+	Update(record *Record) error
+	Delete(key string) error
+	DeleteExpired(key string, now int64) error // Deletes the record only if it has expired by now
+	PurgeExpired(now int64) (int, error)       // Deletes every record expired by now, returning how many
+}
+
+// inMemoryRepository implements Repository using in-memory storage
+type inMemoryRepository struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewRepository creates a new in-memory idempotency repository
+func NewRepository() Repository {
+	return &inMemoryRepository{
+		records: make(map[string]Record),
+	}
+}
+
+// Create stores a new record if no record exists for its key
+func (r *inMemoryRepository) Create(record *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.records[record.Key]; exists {
+		return ErrRecordExists
+	}
+	r.records[record.Key] = *record
+	return nil
+}
+
+// Get retrieves a record by key
+func (r *inMemoryRepository) Get(key string) (*Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, exists := r.records[key]
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+// Update replaces an existing record
+func (r *inMemoryRepository) Update(record *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.records[record.Key]; !exists {
+		return ErrRecordNotFound
+	}
+	r.records[record.Key] = *record
+	return nil
+}
+
+// Delete removes a record so its key can be used again
+func (r *inMemoryRepository) Delete(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, key)
+	return nil
+}
+
+// DeleteExpired removes the record for key if it expired at or before now
+// A record that replaced the expired one in the meantime is left alone
+func (r *inMemoryRepository) DeleteExpired(key string, now int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, exists := r.records[key]; exists && record.ExpiresAt <= now {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+// PurgeExpired removes every record that expired at or before now
+func (r *inMemoryRepository) PurgeExpired(now int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for key, record := range r.records {
+		if record.ExpiresAt <= now {
+			delete(r.records, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
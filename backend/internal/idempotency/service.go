@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"log"
+	"time"
+)
+
+// DefaultTTL is how long a stored response can be replayed
+const DefaultTTL = 24 * time.Hour
+
+// Service handles idempotency key bookkeeping
+type Service struct {
+	repo Repository
+	ttl  time.Duration
+}
+
+// NewService creates a new idempotency service
+func NewService(repo Repository, ttl time.Duration) *Service {
+	return &Service{repo: repo, ttl: ttl}
+}
+
+// Begin claims a key for a request with the given fingerprint
+// It returns (nil, nil) when the caller should execute the request, or the completed record
+// when the request was already executed and its response should be replayed
+func (s *Service) Begin(key, fingerprint string) (*Record, error) {
+	now := time.Now()
+	record := &Record{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Status:      StatusInProgress,
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   now.Add(s.ttl).Unix(),
+	}
+
+	for {
+		err := s.repo.Create(record)
+		if err == nil {
+			return nil, nil
+		}
+		if err != ErrRecordExists {
+			return nil, err
+		}
+
+		existing, err := s.repo.Get(key)
+		if err == ErrRecordNotFound {
+			// Abandoned between Create and Get, try to claim it again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Expired keys behave as if they were never used
+		// Only the expired record is deleted: another retry may already have claimed the key afresh
+		if existing.ExpiresAt <= now.Unix() {
+			if err := s.repo.DeleteExpired(key, now.Unix()); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if existing.Fingerprint != fingerprint {
+			log.Printf("Error: Idempotency key %s reused with a different request", key)
+			return nil, ErrKeyReused
+		}
+		if existing.Status != StatusCompleted {
+			return nil, ErrRequestInProgress
+		}
+
+		log.Printf("Replaying stored response for idempotency key %s", key)
+		return existing, nil
+	}
+}
+
+// Complete stores the response of a request so retries can replay it
+func (s *Service) Complete(key string, responseStatus int, contentType string, responseBody []byte) error {
+	record, err := s.repo.Get(key)
+	if err != nil {
+		return err
+	}
+
+	record.Status = StatusCompleted
+	record.ResponseStatus = responseStatus
+	record.ContentType = contentType
+	record.ResponseBody = responseBody
+	return s.repo.Update(record)
+}
+
+// Abandon releases a key whose request did not produce a replayable response
+// (e.g. a server error), so the client can retry it
+func (s *Service) Abandon(key string) error {
+	return s.repo.Delete(key)
+}
+
+// PurgeExpired deletes the records whose keys have expired, returning how many were deleted
+func (s *Service) PurgeExpired() (int, error) {
+	return s.repo.PurgeExpired(time.Now().Unix())
+}
+
+// StartPurgeJob deletes expired records every interval until the returned stop function is called
+func (s *Service) StartPurgeJob(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				purged, err := s.PurgeExpired()
+				if err != nil {
+					log.Printf("Error purging expired idempotency records: %v", err)
+				}
+				if purged > 0 {
+					log.Printf("Purged %d expired idempotency records", purged)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
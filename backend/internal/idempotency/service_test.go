@@ -0,0 +1,86 @@
+package idempotency
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockstepRepository holds both callers at their first Get until each has read the record,
+// so two retries see the same expired record before either deletes it
+type lockstepRepository struct {
+	Repository
+	mu      sync.Mutex
+	calls   int
+	arrived sync.WaitGroup
+}
+
+func (r *lockstepRepository) Get(key string) (*Record, error) {
+	record, err := r.Repository.Get(key)
+	r.mu.Lock()
+	r.calls++
+	first := r.calls <= 2
+	r.mu.Unlock()
+	if first {
+		r.arrived.Done()
+		r.arrived.Wait()
+	}
+	return record, err
+}
+
+// TestBeginExpiredKeyClaimedOnce tests that two retries finding the same expired record can't both claim the key
+func TestBeginExpiredKeyClaimedOnce(t *testing.T) {
+	repo := &lockstepRepository{Repository: NewRepository()}
+	repo.arrived.Add(2)
+	past := time.Now().Add(-time.Hour).Unix()
+	if err := repo.Create(&Record{Key: "alice:key-1", Fingerprint: "deposit", Status: StatusCompleted, CreatedAt: past, ExpiresAt: past}); err != nil {
+		t.Fatalf("Failed to store the expired record: %v", err)
+	}
+	service := NewService(repo, DefaultTTL)
+
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	claimed := make([]bool, 2)
+	for i := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record, err := service.Begin("alice:key-1", "deposit")
+			results[i], claimed[i] = err, err == nil && record == nil
+		}()
+	}
+	wg.Wait()
+
+	if claimed[0] == claimed[1] {
+		t.Fatalf("Expected exactly one caller to claim the key, got claims %v and errors %v", claimed, results)
+	}
+	for i, err := range results {
+		if !claimed[i] && err != ErrRequestInProgress {
+			t.Errorf("Expected the other caller to see the request in progress, got %v", err)
+		}
+	}
+}
+
+// TestPurgeExpired tests that only expired records are purged
+func TestPurgeExpired(t *testing.T) {
+	repo := NewRepository()
+	service := NewService(repo, DefaultTTL)
+	past := time.Now().Add(-time.Hour).Unix()
+	if err := repo.Create(&Record{Key: "alice:old", Status: StatusCompleted, CreatedAt: past, ExpiresAt: past}); err != nil {
+		t.Fatalf("Failed to store the expired record: %v", err)
+	}
+	if _, err := service.Begin("alice:new", "deposit"); err != nil {
+		t.Fatalf("Failed to claim a key: %v", err)
+	}
+
+	purged, err := service.PurgeExpired()
+	if err != nil || purged != 1 {
+		t.Fatalf("Expected 1 record purged, got %d (%v)", purged, err)
+	}
+	if _, err := repo.Get("alice:old"); err != ErrRecordNotFound {
+		t.Errorf("Expected the expired record to be gone, got %v", err)
+	}
+	if _, err := repo.Get("alice:new"); err != nil {
+		t.Errorf("Expected the live record to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,299 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Account statuses
+const (
+	AccountStatusOpen   = "OPEN"
+	AccountStatusFrozen = "FROZEN" // No debits; credits only if AllowCredits is set
+	AccountStatusClosed = "CLOSED" // No further postings
+)
+
+// Group accounts of the default chart; every posting account sits under one of them
+const (
+	GroupWallets         = "wallets"
+	GroupExternalFunding = "external-funding"
+	GroupFees            = "fees"
+	GroupFXPositions     = "fx-positions"
+	GroupFXRevenue       = "fx-spread-revenue"
+)
+
+var (
+	ErrAccountNotFound     = errors.New("ledger account not found")
+	ErrAccountExists       = errors.New("ledger account already exists")
+	ErrAccountClosed       = errors.New("ledger account is closed")
+	ErrAccountFrozen       = errors.New("ledger account is frozen")
+	ErrAccountNotPostable  = errors.New("group accounts cannot receive postings")
+	ErrAccountTypeMismatch = errors.New("entry account type does not match the registered account")
+	ErrInvalidAccount      = errors.New("invalid ledger account")
+	ErrNoSystemAccount     = errors.New("no system account is configured for this currency")
+)
+
+// accountTypes maps every account type to its normal balance: the side postings increase it on
+// Wallets, fees and revenue are owed by the platform (credit); the external pools and FX
+// positions mirror money held elsewhere (debit)
+var accountTypes = map[string]string{
+	AccountTypeUserWallet:   EntryTypeCredit,
+	AccountTypeSystemFee:    EntryTypeCredit,
+	AccountTypeExternalBank: EntryTypeDebit,
+	AccountTypeFXPosition:   EntryTypeDebit,
+	AccountTypeFXRevenue:    EntryTypeCredit,
+}
+
+// groupByType is the default parent for each account type
+var groupByType = map[string]string{
+	AccountTypeUserWallet:   GroupWallets,
+	AccountTypeSystemFee:    GroupFees,
+	AccountTypeExternalBank: GroupExternalFunding,
+	AccountTypeFXPosition:   GroupFXPositions,
+	AccountTypeFXRevenue:    GroupFXRevenue,
+}
+
+// Account is an entry in the chart of accounts
+// Only registered, non-group accounts that are not closed can receive postings
+type Account struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	Currency      string `json:"currency,omitempty"` // Empty on group accounts
+	NormalBalance string `json:"normal_balance"`     // DEBIT or CREDIT, defaults from the type
+	Status        string `json:"status"`
+	StatusReason  string `json:"status_reason,omitempty"` // Why the account was last frozen, unfrozen or closed
+	AllowCredits  bool   `json:"allow_credits,omitempty"` // FROZEN only: incoming postings are still accepted
+	ParentID      string `json:"parent_id,omitempty"`
+	Group         bool   `json:"group"`                    // Groups organise the chart and hold no entries
+	FundingSource string `json:"funding_source,omitempty"` // EXTERNAL_BANK only: the deposit source or withdrawal destination it settles; empty for the currency's default pool
+	CreatedAt     int64  `json:"created_at"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+// Validate fills in defaults and checks the account's own fields; the parent is checked on registration
+func (a *Account) Validate() error {
+	if a.ID == "" {
+		return ErrMissingAccountID
+	}
+	normal, known := accountTypes[a.Type]
+	if !known {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAccount, a.Type)
+	}
+	if a.NormalBalance == "" {
+		a.NormalBalance = normal
+	}
+	if a.NormalBalance != EntryTypeDebit && a.NormalBalance != EntryTypeCredit {
+		return fmt.Errorf("%w: normal balance must be DEBIT or CREDIT", ErrInvalidAccount)
+	}
+	if a.Status == "" {
+		a.Status = AccountStatusOpen
+	}
+	if a.Status != AccountStatusOpen && a.Status != AccountStatusFrozen && a.Status != AccountStatusClosed {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidAccount, a.Status)
+	}
+
+	if a.Group {
+		if a.Currency != "" || a.FundingSource != "" {
+			return fmt.Errorf("%w: group accounts have no currency or funding source", ErrInvalidAccount)
+		}
+		return nil
+	}
+	if !currency.IsSupported(a.Currency) {
+		return ErrUnsupportedCurrency
+	}
+	if a.FundingSource != "" && a.Type != AccountTypeExternalBank {
+		return fmt.Errorf("%w: only EXTERNAL_BANK accounts have a funding source", ErrInvalidAccount)
+	}
+	a.FundingSource = strings.ToLower(a.FundingSource)
+	if a.ParentID == "" {
+		a.ParentID = groupByType[a.Type]
+	}
+	return nil
+}
+
+// checkPosting verifies the account can receive the entry and stamps the entry with the account's type
+func (a *Account) checkPosting(entry *LedgerEntry) error {
+	if a.Group {
+		return ErrAccountNotPostable
+	}
+	if err := a.checkStatus(entry.EntryType, entry.TransactionType); err != nil {
+		return err
+	}
+	switch {
+	case a.Currency != entry.Currency:
+		log.Printf("Error: Account %s holds %s, entry is in %s", a.ID, a.Currency, entry.Currency)
+		return ErrCurrencyMismatch
+	case entry.AccountType != "" && entry.AccountType != a.Type:
+		log.Printf("Error: Account %s is %s, entry says %s", a.ID, a.Type, entry.AccountType)
+		return ErrAccountTypeMismatch
+	}
+	entry.AccountType = a.Type
+	return nil
+}
+
+// checkStatus verifies the account's status lets it take a posting on the given side
+// The sweep that empties a frozen account on closure is the only debit a frozen account takes
+func (a *Account) checkStatus(entryType, transactionType string) error {
+	switch a.Status {
+	case AccountStatusClosed:
+		return ErrAccountClosed
+	case AccountStatusFrozen:
+		if entryType == EntryTypeCredit && a.AllowCredits {
+			return nil
+		}
+		if entryType == EntryTypeDebit && transactionType == TransactionTypeClosureSweep {
+			return nil
+		}
+		log.Printf("Error: Account %s is frozen, refusing %s", a.ID, entryType)
+		return ErrAccountFrozen
+	}
+	return nil
+}
+
+// DefaultChart returns the group accounts and, for every supported currency, the fee account,
+// the default external funding pool and the FX position and revenue accounts
+func DefaultChart() []*Account {
+	chart := []*Account{
+		{ID: GroupWallets, Name: "User wallets", Type: AccountTypeUserWallet, Group: true},
+		{ID: GroupExternalFunding, Name: "External funding", Type: AccountTypeExternalBank, Group: true},
+		{ID: GroupFees, Name: "Fee revenue", Type: AccountTypeSystemFee, Group: true},
+		{ID: GroupFXPositions, Name: "FX positions", Type: AccountTypeFXPosition, Group: true},
+		{ID: GroupFXRevenue, Name: "FX spread revenue", Type: AccountTypeFXRevenue, Group: true},
+	}
+	for _, cur := range currency.Supported() {
+		chart = append(chart,
+			&Account{ID: FeeAccountID(cur), Name: "Fees " + cur, Type: AccountTypeSystemFee, Currency: cur, ParentID: GroupFees},
+			&Account{ID: ExternalBankAccountID(cur), Name: "External bank pool " + cur, Type: AccountTypeExternalBank, Currency: cur, ParentID: GroupExternalFunding},
+			&Account{ID: FXPositionAccountID(cur), Name: "FX position " + cur, Type: AccountTypeFXPosition, Currency: cur, ParentID: GroupFXPositions},
+			&Account{ID: FXRevenueAccountID(cur), Name: "FX spread " + cur, Type: AccountTypeFXRevenue, Currency: cur, ParentID: GroupFXRevenue},
+		)
+	}
+	for _, account := range chart {
+		account.Validate()
+	}
+	return chart
+}
+
+// LoadAccountsFile reads account definitions from a JSON array, e.g. funding-source accounts:
+// [{"id": "stripe-clearing-eur", "name": "Stripe clearing EUR", "type": "EXTERNAL_BANK",
+// "currency": "EUR", "parent_id": "external-funding", "funding_source": "stripe"}]
+func LoadAccountsFile(path string) ([]*Account, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading accounts file: %w", err)
+	}
+
+	var accounts []*Account
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, fmt.Errorf("error parsing accounts file: %w", err)
+	}
+	for i, account := range accounts {
+		if err := account.Validate(); err != nil {
+			return nil, fmt.Errorf("account %d (%s): %w", i, account.ID, err)
+		}
+	}
+	return accounts, nil
+}
+
+// OpenAccount registers an account in the chart
+// System accounts must be unique per type, currency and funding source, so postings route unambiguously
+func (s *Service) OpenAccount(account *Account) error {
+	if err := account.Validate(); err != nil {
+		return err
+	}
+
+	if account.ParentID != "" {
+		parent, err := s.repo.GetAccount(account.ParentID)
+		if err == ErrAccountNotFound {
+			return fmt.Errorf("%w: parent %s does not exist", ErrInvalidAccount, account.ParentID)
+		}
+		if err != nil {
+			return err
+		}
+		if !parent.Group || parent.Type != account.Type {
+			return fmt.Errorf("%w: parent %s is not a %s group", ErrInvalidAccount, account.ParentID, account.Type)
+		}
+	}
+
+	if !account.Group && account.Type != AccountTypeUserWallet {
+		existing, err := s.repo.FindSystemAccount(account.Type, account.Currency, account.FundingSource)
+		if err != nil && err != ErrNoSystemAccount {
+			return err
+		}
+		if existing != nil && existing.FundingSource == account.FundingSource {
+			return fmt.Errorf("%w: %s already routes %s %s postings", ErrAccountExists, existing.ID, account.Type, account.Currency)
+		}
+	}
+
+	now := time.Now().Unix()
+	account.CreatedAt, account.UpdatedAt = now, now
+	if err := s.repo.CreateAccount(account); err != nil {
+		return err
+	}
+	log.Printf("Ledger account opened: %s (%s, %s)", account.ID, account.Type, account.Currency)
+	return nil
+}
+
+// OpenWalletAccount registers the ledger account behind a newly created wallet
+func (s *Service) OpenWalletAccount(walletID, cur string) error {
+	return s.OpenAccount(&Account{
+		ID:       walletID,
+		Name:     "Wallet " + walletID,
+		Type:     AccountTypeUserWallet,
+		Currency: cur,
+		ParentID: GroupWallets,
+	})
+}
+
+// RegisterAccounts opens every account that isn't registered yet, e.g. the default chart at startup
+// Accounts that already exist must have the same type and currency
+func (s *Service) RegisterAccounts(accounts []*Account) (int, error) {
+	opened := 0
+	for _, account := range accounts {
+		existing, err := s.repo.GetAccount(account.ID)
+		if err == nil {
+			if existing.Type != account.Type || existing.Currency != account.Currency || existing.Group != account.Group {
+				return opened, fmt.Errorf("%w: %s is registered as %s %s", ErrAccountExists, account.ID, existing.Type, existing.Currency)
+			}
+			continue
+		}
+		if err != ErrAccountNotFound {
+			return opened, err
+		}
+
+		if err := s.OpenAccount(account); err != nil {
+			return opened, fmt.Errorf("error opening %s: %w", account.ID, err)
+		}
+		opened++
+	}
+	return opened, nil
+}
+
+// GetAccount returns a registered account
+func (s *Service) GetAccount(accountID string) (*Account, error) {
+	return s.repo.GetAccount(accountID)
+}
+
+// ListAccounts returns the whole chart of accounts, ordered by ID
+func (s *Service) ListAccounts() ([]*Account, error) {
+	return s.repo.ListAccounts()
+}
+
+// systemAccount resolves the account a posting of the given type uses in a currency
+// For external funding, an account registered for the source wins over the currency's default pool
+func (s *Service) systemAccount(accountType, cur, source string) (string, error) {
+	account, err := s.repo.FindSystemAccount(accountType, cur, strings.ToLower(source))
+	if err != nil {
+		if err == ErrNoSystemAccount {
+			log.Printf("Error: No %s account is configured for %s", accountType, cur)
+		}
+		return "", err
+	}
+	return account.ID, nil
+}
@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openWallets registers user wallet accounts in a currency so tests can post to them
+func openWallets(t *testing.T, service *Service, cur string, accountIDs ...string) {
+	t.Helper()
+	for _, accountID := range accountIDs {
+		if err := service.OpenWalletAccount(accountID, cur); err != nil {
+			t.Fatalf("Failed to open account %s: %v", accountID, err)
+		}
+	}
+}
+
+// TestPostingRequiresRegisteredAccount tests that postings to unknown, group and closed accounts are refused
+func TestPostingRequiresRegisteredAccount(t *testing.T) {
+	repo := NewRepository().(*inMemoryRepository)
+	service := NewService(repo)
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 1000, Source: "bank"}); err != ErrAccountNotFound {
+		t.Errorf("Expected ErrAccountNotFound for an unregistered account, got %v", err)
+	}
+
+	openWallets(t, service, "USD", "alice")
+	txnID, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 1000, Source: "bank"})
+	if err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	entries, _ := service.GetTransactionDetails(txnID)
+	if entries[1].AccountID != ExternalBankAccountID("USD") || entries[1].AccountType != AccountTypeExternalBank {
+		t.Errorf("Expected the deposit to be funded from the USD pool, got %s (%s)", entries[1].AccountID, entries[1].AccountType)
+	}
+
+	// The chart, not the entry, decides the account type
+	err = repo.CreateEntries([]*LedgerEntry{
+		{AccountID: "alice", AccountType: AccountTypeSystemFee, Amount: -100, Currency: "USD", EntryType: EntryTypeDebit, TransactionID: "txn-typed"},
+		{AccountID: FeeAccountID("USD"), Amount: 100, Currency: "USD", EntryType: EntryTypeCredit, TransactionID: "txn-typed"},
+	})
+	if err != ErrAccountTypeMismatch {
+		t.Errorf("Expected ErrAccountTypeMismatch, got %v", err)
+	}
+	err = repo.CreateEntries([]*LedgerEntry{
+		{AccountID: "alice", Amount: -100, Currency: "USD", EntryType: EntryTypeDebit, TransactionID: "txn-group"},
+		{AccountID: GroupFees, Amount: 100, Currency: "USD", EntryType: EntryTypeCredit, TransactionID: "txn-group"},
+	})
+	if err != ErrAccountNotPostable {
+		t.Errorf("Expected ErrAccountNotPostable, got %v", err)
+	}
+	if balance, _ := service.GetBalance("alice"); balance.Balance != 1000 {
+		t.Errorf("Expected refused postings to leave the balance at 1000, got %d", balance.Balance)
+	}
+
+	repo.accounts["alice"].Status = AccountStatusClosed
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 1000, Source: "bank"}); err != ErrAccountClosed {
+		t.Errorf("Expected ErrAccountClosed, got %v", err)
+	}
+}
+
+// TestFundingSourceRouting tests that deposits settle through the account registered for their source
+func TestFundingSourceRouting(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "EUR", "alice-eur")
+
+	stripe := &Account{ID: "stripe-clearing-eur", Type: AccountTypeExternalBank, Currency: "EUR", ParentID: GroupExternalFunding, FundingSource: "Stripe"}
+	if err := service.OpenAccount(stripe); err != nil {
+		t.Fatalf("Failed to open funding account: %v", err)
+	}
+	if stripe.NormalBalance != EntryTypeDebit || stripe.Status != AccountStatusOpen || stripe.FundingSource != "stripe" {
+		t.Errorf("Expected defaults to be filled in, got %+v", stripe)
+	}
+
+	for source, expected := range map[string]string{"stripe": "stripe-clearing-eur", "bank": ExternalBankAccountID("EUR")} {
+		txnID, err := service.RecordDeposit(&DepositRequest{AccountID: "alice-eur", Amount: 500, Currency: "EUR", Source: source})
+		if err != nil {
+			t.Fatalf("Failed to record %s deposit: %v", source, err)
+		}
+		if entries, _ := service.GetTransactionDetails(txnID); entries[1].AccountID != expected {
+			t.Errorf("Expected a %s deposit to be funded from %s, got %s", source, expected, entries[1].AccountID)
+		}
+	}
+
+	duplicate := &Account{ID: "second-pool-eur", Type: AccountTypeExternalBank, Currency: "EUR"}
+	if err := service.OpenAccount(duplicate); !errors.Is(err, ErrAccountExists) {
+		t.Errorf("Expected a second default EUR pool to be refused, got %v", err)
+	}
+	misplaced := &Account{ID: "fees-under-wallets", Type: AccountTypeSystemFee, Currency: "GBP", ParentID: GroupWallets, FundingSource: ""}
+	if err := service.OpenAccount(misplaced); !errors.Is(err, ErrInvalidAccount) {
+		t.Errorf("Expected a fee account under the wallets group to be refused, got %v", err)
+	}
+}
+
+// TestLoadAccountsFile tests reading and validating account definitions
+func TestLoadAccountsFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "accounts.json")
+	os.WriteFile(valid, []byte(`[{"id": "paypal-clearing-gbp", "type": "EXTERNAL_BANK", "currency": "GBP", "parent_id": "external-funding", "funding_source": "paypal"}]`), 0o600)
+	accounts, err := LoadAccountsFile(valid)
+	if err != nil || len(accounts) != 1 || accounts[0].NormalBalance != EntryTypeDebit {
+		t.Fatalf("Expected one validated account, got %v (%v)", accounts, err)
+	}
+
+	service := NewService(NewRepository())
+	opened, err := service.RegisterAccounts(append(DefaultChart(), accounts...))
+	if err != nil || opened != 1 {
+		t.Errorf("Expected only the new account to be opened, got %d (%v)", opened, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"id": "fee-jpy", "type": "SYSTEM_FEE", "currency": "JPY"}, {"id": "x", "type": "ASSET"}]`), 0o600)
+	if _, err := LoadAccountsFile(invalid); err == nil {
+		t.Error("Expected an unsupported currency to be rejected")
+	}
+}
@@ -0,0 +1,101 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"log"
+	"strconv"
+)
+
+var (
+	ErrApprovalRequired = errors.New("this posting needs the approval of a second admin")
+	ErrSelfApproval     = errors.New("a posting can't be approved by the admin who requested it")
+	ErrApprovalMismatch = errors.New("the posting does not match what was approved")
+)
+
+// Approval is a request the approval queue has approved, as the ledger needs it to check a posting against it
+type Approval struct {
+	ID          string
+	RequestedBy string
+	ApprovedBy  string
+	Withdrawal  *WithdrawalRequest // Set when a withdrawal was approved
+	Journal     *JournalRequest    // Set when a journal was approved
+}
+
+// Approvals resolves approval IDs against the approval queue
+type Approvals interface {
+	// GetApproved returns the request if it was approved and is waiting to be posted, or ErrApprovalRequired
+	GetApproved(approvalID string) (*Approval, error)
+}
+
+// WithApprovals checks postings that need approval against the approval queue; without it they can't be posted
+func WithApprovals(approvals Approvals) Option {
+	return func(s *Service) {
+		s.approvals = approvals
+	}
+}
+
+// WithApprovalThreshold requires withdrawals above threshold, in major units of the withdrawal's currency,
+// to be approved in the queue; manual journals always need approval
+func WithApprovalThreshold(threshold int64) Option {
+	return func(s *Service) {
+		s.approvalThreshold = threshold
+	}
+}
+
+// NeedsApproval reports whether a withdrawal of amount (minor units of cur) must be approved before it is posted
+// A currency whose minor unit is unknown always needs approval
+func (s *Service) NeedsApproval(amount int64, cur string) bool {
+	if s.approvalThreshold <= 0 {
+		return false
+	}
+	threshold, err := currency.Parse(strconv.FormatInt(s.approvalThreshold, 10), cur)
+	if err != nil {
+		log.Printf("Error: Approval threshold in %s: %v", cur, err)
+		return true
+	}
+	return amount > threshold.Amount()
+}
+
+// approved resolves the approval a posting names, which a second person must have granted
+// Callers check the posting is the one that was approved
+func (s *Service) approved(approvalID string) (*Approval, error) {
+	if approvalID == "" || s.approvals == nil {
+		return nil, ErrApprovalRequired
+	}
+	approval, err := s.approvals.GetApproved(approvalID)
+	if err != nil {
+		return nil, err
+	}
+	if approval.ApprovedBy == "" {
+		return nil, ErrApprovalRequired
+	}
+	if approval.ApprovedBy == approval.RequestedBy {
+		log.Printf("Error: %s tried to approve their own posting", approval.ApprovedBy)
+		return nil, ErrSelfApproval
+	}
+	return approval, nil
+}
+
+// matchesWithdrawal reports whether a withdrawal is the one approved, posted under the approval's ID
+func (a *Approval) matchesWithdrawal(req *WithdrawalRequest, cur string) bool {
+	approved := a.Withdrawal
+	return approved != nil && req.TransactionID == a.ID &&
+		approved.AccountID == req.AccountID && approved.Amount == req.Amount && approved.Currency == cur
+}
+
+// matchesJournal reports whether a journal is the one approved, posted under the approval's ID
+func (a *Approval) matchesJournal(req *JournalRequest) bool {
+	approved := a.Journal
+	if approved == nil || req.TransactionID != a.ID || req.CreatedBy != a.RequestedBy ||
+		approved.ReasonCode != req.ReasonCode || approved.Currency != req.Currency || len(approved.Legs) != len(req.Legs) {
+		return false
+	}
+	for i, leg := range req.Legs {
+		if approved.Legs[i].AccountID != leg.AccountID || approved.Legs[i].EntryType != leg.EntryType ||
+			approved.Legs[i].Amount != leg.Amount {
+			return false
+		}
+	}
+	return true
+}
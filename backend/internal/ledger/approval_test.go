@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"digitalwallet/backend/internal/wallet"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubApprovals is an approval queue holding requests already approved, by approval ID
+type stubApprovals map[string]*Approval
+
+func (a stubApprovals) GetApproved(approvalID string) (*Approval, error) {
+	approval, exists := a[approvalID]
+	if !exists {
+		return nil, ErrApprovalRequired
+	}
+	return approval, nil
+}
+
+// approveWithdrawal approves req in the queue under id and points req at the approval
+func (a stubApprovals) approveWithdrawal(req *WithdrawalRequest, id, requestedBy, approvedBy string) {
+	approved := *req
+	approved.Currency = "USD"
+	a[id] = &Approval{ID: id, RequestedBy: requestedBy, ApprovedBy: approvedBy, Withdrawal: &approved}
+	req.ApprovalID, req.TransactionID = id, id
+}
+
+// approveJournal approves req in the queue under id and points req at the approval
+func (a stubApprovals) approveJournal(req *JournalRequest, id, approvedBy string) {
+	approved := *req
+	a[id] = &Approval{ID: id, RequestedBy: req.CreatedBy, ApprovedBy: approvedBy, Journal: &approved}
+	req.ApprovalID, req.TransactionID = id, id
+}
+
+// TestWithdrawalApprovalThreshold tests that withdrawals above the threshold only post as approved in the queue
+// by an admin other than the requester
+func TestWithdrawalApprovalThreshold(t *testing.T) {
+	approvals := stubApprovals{}
+	service := NewService(newTestRepository(t), WithApprovalThreshold(500), WithApprovals(approvals))
+	openWallets(t, service, "USD", "alice")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 200000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 50000, Destination: "bank"}); err != nil {
+		t.Errorf("Expected a withdrawal at the threshold to post, got %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 50001, Destination: "bank"}); err != ErrApprovalRequired {
+		t.Errorf("Expected ErrApprovalRequired above the threshold, got %v", err)
+	}
+	for name, tt := range map[string]struct {
+		approvedBy string
+		approved   int64
+		want       error
+	}{
+		"self-approved":        {"admin-1", 60000, ErrSelfApproval},
+		"different amount":     {"admin-2", 55000, ErrApprovalMismatch},
+		"approved by a second": {"admin-2", 60000, nil},
+	} {
+		req := &WithdrawalRequest{AccountID: "alice", Amount: 60000, Destination: "bank"}
+		approvals.approveWithdrawal(req, name, "admin-1", tt.approvedBy)
+		approvals[name].Withdrawal.Amount = tt.approved
+		if _, err := service.RecordWithdrawal(req); err != tt.want {
+			t.Errorf("%s: expected %v, got %v", name, tt.want, err)
+		}
+	}
+	// An approval posts only the withdrawal it was granted for, under its own ID
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{
+		AccountID: "alice", Amount: 60000, Destination: "bank", ApprovalID: "approved by a second",
+	}); err != ErrApprovalMismatch {
+		t.Errorf("Expected ErrApprovalMismatch for another transaction ID, got %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{
+		AccountID: "alice", Amount: 60000, Destination: "bank", ApprovalID: "unknown", TransactionID: "unknown",
+	}); err != ErrApprovalRequired {
+		t.Errorf("Expected ErrApprovalRequired for an unknown approval, got %v", err)
+	}
+
+	if balance, _ := service.GetBalance("alice"); balance.Balance != 90000 {
+		t.Errorf("Expected 900.00 left, got %d", balance.Balance)
+	}
+
+	// The threshold is in major units, so it scales with each currency's minor unit
+	for _, tc := range []struct {
+		amount int64
+		cur    string
+		want   bool
+	}{{50000, "USD", false}, {50001, "USD", true}, {500, "JPY", false}, {501, "JPY", true}, {500001, "BHD", true}} {
+		if got := service.NeedsApproval(tc.amount, tc.cur); got != tc.want {
+			t.Errorf("%d %s: expected NeedsApproval %v, got %v", tc.amount, tc.cur, tc.want, got)
+		}
+	}
+}
+
+// fakeApprovals records the withdrawals queued for approval and who requested them
+type fakeApprovals struct {
+	queued      []WithdrawalRequest
+	requestedBy []string
+}
+
+func (q *fakeApprovals) QueueWithdrawal(withdrawal WithdrawalRequest, requestedBy string) (string, error) {
+	q.queued = append(q.queued, withdrawal)
+	q.requestedBy = append(q.requestedBy, requestedBy)
+	return "approval-1", nil
+}
+
+// TestWithdrawalQueuedForApproval tests that a customer's withdrawal above the threshold is queued for an
+// admin rather than refused, and nothing is posted until it is approved
+func TestWithdrawalQueuedForApproval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := NewService(newTestRepository(t), WithApprovalThreshold(500))
+	walletService := wallet.NewService(wallet.NewRepository(), service)
+	approvals := &fakeApprovals{}
+	handler := NewHandler(service, walletService, approvals)
+	r := gin.New()
+	r.POST("/api/ledger/withdrawals", func(c *gin.Context) {
+		c.Set("userId", c.GetHeader("X-Test-User"))
+		c.Next()
+	}, handler.Withdraw)
+
+	walletID, err := walletService.CreateWallet("alice", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: walletID, Amount: 100000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	w := doPost(r, "/api/ledger/withdrawals", "alice", `{"amount": "600.00", "destination": "bank"}`)
+	if w.Code != http.StatusAccepted || len(approvals.queued) != 1 {
+		t.Fatalf("Expected the withdrawal to be queued, got %d: %s", w.Code, w.Body)
+	}
+	if queued := approvals.queued[0]; queued.AccountID != walletID || queued.Amount != 60000 || approvals.requestedBy[0] != "alice" {
+		t.Errorf("Expected 600.00 from alice's wallet requested by alice, got %+v", queued)
+	}
+	if balance, _ := service.GetBalance(walletID); balance.Balance != 100000 {
+		t.Errorf("Expected nothing posted before approval, got a balance of %d", balance.Balance)
+	}
+
+	if w := doPost(r, "/api/ledger/withdrawals", "alice", `{"amount": "500.00", "destination": "bank"}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected a withdrawal at the threshold to post, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,263 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit alert kinds
+const (
+	AlertUnbalancedTransaction = "UNBALANCED_TRANSACTION" // A transaction's entries don't sum to zero in a currency
+	AlertBalanceDrift          = "BALANCE_DRIFT"          // A cached balance differs from the sum of the account's entries
+	AlertSystemImbalance       = "SYSTEM_IMBALANCE"       // Balances across all accounts don't net to zero in a currency
+)
+
+var ErrAuditReportNotFound = errors.New("no audit report found")
+
+// Page sizes when listing audit alerts and balance repairs
+const (
+	DefaultAuditListLimit = 50
+	MaxAuditListLimit     = 500
+)
+
+// TransactionImbalance is a transaction whose entries don't sum to zero in one currency
+type TransactionImbalance struct {
+	TransactionID string
+	Currency      string
+	Sum           int64
+}
+
+// TrialBalanceLine totals the balances of one account type (or of every type) in one currency
+// Positive balances are credits and negative ones debits, matching the entry sign convention
+type TrialBalanceLine struct {
+	AccountType string `json:"account_type,omitempty"` // Empty on per-currency totals
+	Currency    string `json:"currency"`
+	Accounts    int    `json:"accounts"`
+	Debits      int64  `json:"debits"`  // Sum of negative balances as a positive number, in cents
+	Credits     int64  `json:"credits"` // Sum of positive balances, in cents
+	Net         int64  `json:"net"`     // Credits - Debits
+}
+
+// TrialBalance groups every account's cached balance by account type and currency
+type TrialBalance struct {
+	AsOf   int64               `json:"as_of"`
+	Lines  []*TrialBalanceLine `json:"lines"`
+	Totals []*TrialBalanceLine `json:"totals"` // One per currency across all account types; each must net to zero
+}
+
+// AuditAlert records one problem found by an audit run
+type AuditAlert struct {
+	ID            string `json:"id"`
+	ReportID      string `json:"report_id"`
+	Kind          string `json:"kind"`
+	AccountID     string `json:"account_id,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Currency      string `json:"currency"`
+	Expected      int64  `json:"expected"` // In cents
+	Actual        int64  `json:"actual"`   // In cents
+	Message       string `json:"message"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// AuditReport is the result of a full-ledger integrity audit
+type AuditReport struct {
+	ID              string        `json:"id"`
+	RunAt           int64         `json:"run_at"`
+	Passed          bool          `json:"passed"`
+	AccountsChecked int           `json:"accounts_checked"`
+	TrialBalance    *TrialBalance `json:"trial_balance"`
+	Alerts          []*AuditAlert `json:"alerts"`
+}
+
+// TrialBalance totals every account's cached balance by account type and currency
+func (s *Service) TrialBalance(now time.Time) (*TrialBalance, error) {
+	balances, err := s.repo.GetAllBalances()
+	if err != nil {
+		return nil, err
+	}
+	return buildTrialBalance(balances, now.Unix()), nil
+}
+
+// buildTrialBalance groups balances into lines ordered by currency, then account type
+func buildTrialBalance(balances []*AccountBalance, asOf int64) *TrialBalance {
+	lines := make(map[[2]string]*TrialBalanceLine)
+	totals := make(map[string]*TrialBalanceLine)
+	add := func(line *TrialBalanceLine, balance int64) {
+		line.Accounts++
+		if balance < 0 {
+			line.Debits -= balance
+		} else {
+			line.Credits += balance
+		}
+		line.Net += balance
+	}
+
+	for _, balance := range balances {
+		key := [2]string{balance.Currency, balance.AccountType}
+		if lines[key] == nil {
+			lines[key] = &TrialBalanceLine{AccountType: balance.AccountType, Currency: balance.Currency}
+		}
+		if totals[balance.Currency] == nil {
+			totals[balance.Currency] = &TrialBalanceLine{Currency: balance.Currency}
+		}
+		add(lines[key], balance.Balance)
+		add(totals[balance.Currency], balance.Balance)
+	}
+
+	trial := &TrialBalance{AsOf: asOf, Lines: []*TrialBalanceLine{}, Totals: []*TrialBalanceLine{}}
+	for _, line := range lines {
+		trial.Lines = append(trial.Lines, line)
+	}
+	for _, total := range totals {
+		trial.Totals = append(trial.Totals, total)
+	}
+	sort.Slice(trial.Lines, func(i, j int) bool {
+		if trial.Lines[i].Currency != trial.Lines[j].Currency {
+			return trial.Lines[i].Currency < trial.Lines[j].Currency
+		}
+		return trial.Lines[i].AccountType < trial.Lines[j].AccountType
+	})
+	sort.Slice(trial.Totals, func(i, j int) bool { return trial.Totals[i].Currency < trial.Totals[j].Currency })
+	return trial
+}
+
+// RunAudit checks the whole ledger and stores the report with an alert for every problem found:
+//   - every transaction sums to zero in each currency
+//   - every cached balance matches the sum of its account's entries
+//   - all balances net to zero per currency (USER_WALLET, SYSTEM_FEE and EXTERNAL_BANK, plus the
+//     FX accounts that carry the other side of conversions)
+func (s *Service) RunAudit(now time.Time) (*AuditReport, error) {
+	report := &AuditReport{ID: uuid.New().String(), RunAt: now.Unix(), Alerts: []*AuditAlert{}}
+	raise := func(alert *AuditAlert) {
+		alert.ID = uuid.New().String()
+		alert.ReportID = report.ID
+		alert.CreatedAt = report.RunAt
+		log.Printf("ALERT: Ledger audit %s: %s", report.ID, alert.Message)
+		report.Alerts = append(report.Alerts, alert)
+	}
+
+	imbalances, err := s.repo.GetUnbalancedTransactions()
+	if err != nil {
+		return nil, fmt.Errorf("error checking transactions: %w", err)
+	}
+	for _, imbalance := range imbalances {
+		raise(&AuditAlert{
+			Kind:          AlertUnbalancedTransaction,
+			TransactionID: imbalance.TransactionID,
+			Currency:      imbalance.Currency,
+			Actual:        imbalance.Sum,
+			Message: fmt.Sprintf("transaction %s sums to %s instead of zero",
+				imbalance.TransactionID, currency.New(imbalance.Sum, imbalance.Currency)),
+		})
+	}
+
+	balances, err := s.repo.GetAllBalances()
+	if err != nil {
+		return nil, fmt.Errorf("error reading balances: %w", err)
+	}
+	for _, balance := range balances {
+		cached, calculated, err := s.balanceDrift(balance)
+		if err != nil {
+			return nil, fmt.Errorf("error checking account %s: %w", balance.AccountID, err)
+		}
+		if cached != calculated {
+			raise(&AuditAlert{
+				Kind:      AlertBalanceDrift,
+				AccountID: balance.AccountID,
+				Currency:  balance.Currency,
+				Expected:  calculated,
+				Actual:    cached,
+				Message: fmt.Sprintf("account %s has a cached balance of %s but its entries sum to %s", balance.AccountID,
+					currency.New(cached, balance.Currency), currency.New(calculated, balance.Currency)),
+			})
+		}
+	}
+	report.AccountsChecked = len(balances)
+
+	report.TrialBalance = buildTrialBalance(balances, report.RunAt)
+	for _, total := range report.TrialBalance.Totals {
+		if total.Net != 0 {
+			raise(&AuditAlert{
+				Kind:     AlertSystemImbalance,
+				Currency: total.Currency,
+				Actual:   total.Net,
+				Message:  fmt.Sprintf("%s balances net to %s instead of zero", total.Currency, currency.New(total.Net, total.Currency)),
+			})
+		}
+	}
+
+	report.Passed = len(report.Alerts) == 0
+	if err := s.repo.SaveAuditReport(report); err != nil {
+		return nil, fmt.Errorf("error saving audit report: %w", err)
+	}
+	return report, nil
+}
+
+// balanceDrift compares an account's cached balance with the sum of its entries
+// A mismatch is read again once, so a posting that lands between the two reads isn't reported as drift
+func (s *Service) balanceDrift(balance *AccountBalance) (cached, calculated int64, err error) {
+	calculated, err = s.repo.CalculateBalanceFromEntries(balance.AccountID)
+	if err != nil || balance.Balance == calculated {
+		return balance.Balance, calculated, err
+	}
+
+	current, err := s.repo.GetBalance(balance.AccountID)
+	if err != nil {
+		return 0, 0, err
+	}
+	calculated, err = s.repo.CalculateBalanceFromEntries(balance.AccountID)
+	return current.Balance, calculated, err
+}
+
+// GetLatestAuditReport returns the most recent audit report
+func (s *Service) GetLatestAuditReport() (*AuditReport, error) {
+	return s.repo.GetLatestAuditReport()
+}
+
+// GetAuditAlerts returns the most recent audit alerts, newest first
+func (s *Service) GetAuditAlerts(limit int) ([]*AuditAlert, error) {
+	if limit <= 0 {
+		limit = DefaultAuditListLimit
+	}
+	if limit > MaxAuditListLimit {
+		limit = MaxAuditListLimit
+	}
+	return s.repo.GetAuditAlerts(limit)
+}
+
+// StartAuditJob audits the ledger every interval until the returned stop function is called
+func (s *Service) StartAuditJob(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				report, err := s.RunAudit(now)
+				if err != nil {
+					log.Printf("Error auditing the ledger: %v", err)
+					continue
+				}
+				if report.Passed {
+					log.Printf("Ledger audit passed: %d accounts checked", report.AccountsChecked)
+				} else {
+					log.Printf("WARNING: Ledger audit %s raised %d alerts", report.ID, len(report.Alerts))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRunAuditPasses tests a clean ledger and its trial balance
+func TestRunAuditPasses(t *testing.T) {
+	service := NewService(newTestRepository(t), flatTransferFee(t, "0.50"))
+	openWallets(t, service, "USD", "alice", "bob")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 2500}); err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+
+	report, err := service.RunAudit(time.Now())
+	if err != nil {
+		t.Fatalf("Failed to run audit: %v", err)
+	}
+	if !report.Passed || len(report.Alerts) != 0 || report.AccountsChecked != 4 {
+		t.Fatalf("Expected a clean audit of 4 accounts, got %+v", report)
+	}
+
+	expected := []TrialBalanceLine{
+		{AccountType: AccountTypeExternalBank, Currency: "USD", Accounts: 1, Debits: 10000, Net: -10000},
+		{AccountType: AccountTypeSystemFee, Currency: "USD", Accounts: 1, Credits: 50, Net: 50},
+		{AccountType: AccountTypeUserWallet, Currency: "USD", Accounts: 2, Credits: 9950, Net: 9950},
+	}
+	lines := report.TrialBalance.Lines
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d trial balance lines, got %d", len(expected), len(lines))
+	}
+	for i, line := range lines {
+		if *line != expected[i] {
+			t.Errorf("Line %d: expected %+v, got %+v", i, expected[i], *line)
+		}
+	}
+	if totals := report.TrialBalance.Totals; len(totals) != 1 || totals[0].Net != 0 || totals[0].Credits != 10000 {
+		t.Errorf("Expected USD totals netting to zero, got %+v", totals)
+	}
+
+	if latest, err := service.GetLatestAuditReport(); err != nil || latest.ID != report.ID {
+		t.Errorf("Expected the report to be stored, got %v (%v)", latest, err)
+	}
+}
+
+// TestRunAuditRaisesAlerts tests every kind of drift the audit looks for
+func TestRunAuditRaisesAlerts(t *testing.T) {
+	repo := NewRepository().(*inMemoryRepository)
+	service := NewService(repo)
+	openWallets(t, service, "USD", "alice")
+	if _, err := service.GetLatestAuditReport(); err != ErrAuditReportNotFound {
+		t.Errorf("Expected ErrAuditReportNotFound, got %v", err)
+	}
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := service.RunAudit(time.Now()); err != nil {
+		t.Fatalf("Failed to run audit: %v", err)
+	}
+
+	// A one-sided entry (which still updates alice's cached balance), and a cached balance edited directly
+	err := repo.CreateEntry(&LedgerEntry{
+		AccountID: "alice", AccountType: AccountTypeUserWallet, Amount: 700, Currency: "USD",
+		EntryType: EntryTypeCredit, TransactionID: "txn-stray", TransactionType: TransactionTypeDeposit,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create entry: %v", err)
+	}
+	repo.balances[ExternalBankAccountID("USD")].Balance += 100
+
+	report, err := service.RunAudit(time.Now())
+	if err != nil {
+		t.Fatalf("Failed to run audit: %v", err)
+	}
+	if report.Passed {
+		t.Fatal("Expected the audit to fail")
+	}
+
+	kinds := make(map[string]*AuditAlert)
+	for _, alert := range report.Alerts {
+		kinds[alert.Kind+":"+alert.AccountID+alert.TransactionID] = alert
+	}
+	if alert := kinds[AlertUnbalancedTransaction+":txn-stray"]; alert == nil || alert.Actual != 700 {
+		t.Errorf("Expected txn-stray to be unbalanced by 700, got %+v", alert)
+	}
+	if alert := kinds[AlertBalanceDrift+":"+ExternalBankAccountID("USD")]; alert == nil || alert.Actual != -9900 || alert.Expected != -10000 {
+		t.Errorf("Expected the bank pool's edited balance to drift, got %+v", alert)
+	}
+	if alert := kinds[AlertSystemImbalance+":"]; alert == nil || alert.Currency != "USD" || alert.Actual != 800 {
+		t.Errorf("Expected USD balances to net to 800, got %+v", alert)
+	}
+	if len(report.Alerts) != 3 {
+		t.Errorf("Expected 3 alerts, got %d", len(report.Alerts))
+	}
+
+	alerts, err := service.GetAuditAlerts(2)
+	if err != nil || len(alerts) != 2 || alerts[0] != report.Alerts[2] {
+		t.Errorf("Expected the 2 newest alerts, newest first, got %v (%v)", alerts, err)
+	}
+}
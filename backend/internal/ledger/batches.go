@@ -0,0 +1,422 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBatchNotFound    = errors.New("batch not found")
+	ErrInvalidBatch     = errors.New("invalid batch")
+	ErrInvalidBatchMode = errors.New("invalid batch mode: expected ALL_OR_NOTHING or BEST_EFFORT")
+)
+
+// MaxBatchItems caps how many transfers one batch can hold
+const MaxBatchItems = 1000
+
+// Batch modes
+const (
+	BatchModeAllOrNothing = "ALL_OR_NOTHING" // One multi-leg transaction: every item posts or none does
+	BatchModeBestEffort   = "BEST_EFFORT"    // One transfer per item; a failed item doesn't stop the rest
+)
+
+// Batch statuses
+const (
+	BatchStatusPending    = "PENDING" // Validated, waiting to be posted
+	BatchStatusProcessing = "PROCESSING"
+	BatchStatusCompleted  = "COMPLETED"           // Every item posted
+	BatchStatusPartial    = "PARTIALLY_COMPLETED" // Best effort only: some items failed
+	BatchStatusFailed     = "FAILED"              // No item posted
+)
+
+// Batch item statuses
+const (
+	BatchItemPending = "PENDING"
+	BatchItemPosted  = "POSTED"
+	BatchItemFailed  = "FAILED"
+)
+
+// BatchRequest pays several accounts from one account, e.g. a payroll run
+type BatchRequest struct {
+	FromAccountID string
+	Currency      string
+	Mode          string
+	Description   string // Used for items without a description of their own
+	Items         []BatchItemRequest
+	CreatedBy     string
+}
+
+// BatchItemRequest is one recipient of a batch
+type BatchItemRequest struct {
+	ToAccountID string
+	Amount      int64
+	Description string
+}
+
+// Batch is a set of transfers from one account and how far posting them has got
+type Batch struct {
+	ID            string       `json:"id"`
+	FromAccountID string       `json:"from_account_id"`
+	Currency      string       `json:"currency"`
+	Mode          string       `json:"mode"`
+	Description   string       `json:"description,omitempty"`
+	Status        string       `json:"status"`
+	Error         string       `json:"error,omitempty"`          // Why an all-or-nothing batch failed
+	TransactionID string       `json:"transaction_id,omitempty"` // All-or-nothing only: the single transaction posting every item
+	TotalAmount   int64        `json:"total_amount"`             // Sum of the item amounts, in minor units
+	TotalFees     int64        `json:"total_fees"`               // Fees of the posted items
+	Posted        int          `json:"posted"`
+	Failed        int          `json:"failed"`
+	Items         []*BatchItem `json:"items"`
+	CreatedBy     string       `json:"created_by"`
+	CreatedAt     int64        `json:"created_at"`
+	CompletedAt   int64        `json:"completed_at,omitempty"`
+}
+
+// BatchItem is one transfer of a batch
+type BatchItem struct {
+	Index         int    `json:"index"` // Position in the request, from 0
+	ToAccountID   string `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Description   string `json:"description,omitempty"`
+	Status        string `json:"status"`
+	Fee           int64  `json:"fee"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// BatchItemError is why one item of a batch failed validation
+type BatchItemError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// BatchValidationError lists every item of a batch that failed validation
+type BatchValidationError struct {
+	Items []BatchItemError
+}
+
+func (e *BatchValidationError) Error() string {
+	return fmt.Sprintf("%s: %d items failed validation", ErrInvalidBatch, len(e.Items))
+}
+
+// Is makes errors.Is(err, ErrInvalidBatch) match
+func (e *BatchValidationError) Is(target error) bool {
+	return target == ErrInvalidBatch
+}
+
+// CreateBatch validates every item of a batch up front and stores it for ProcessBatch
+// Nothing is stored if any item is invalid; a *BatchValidationError then lists them all
+func (s *Service) CreateBatch(req *BatchRequest) (*Batch, error) {
+	if req.FromAccountID == "" {
+		return nil, ErrMissingAccountID
+	}
+	if req.Mode != BatchModeAllOrNothing && req.Mode != BatchModeBestEffort {
+		return nil, ErrInvalidBatchMode
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: no items", ErrInvalidBatch)
+	}
+	if len(req.Items) > MaxBatchItems {
+		return nil, fmt.Errorf("%w: more than %d items", ErrInvalidBatch, MaxBatchItems)
+	}
+	cur, err := resolveCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := s.repo.GetAccount(req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := sender.checkStatus(EntryTypeDebit, ""); err != nil {
+		return nil, err
+	}
+	if sender.Currency != cur {
+		return nil, ErrCurrencyMismatch
+	}
+
+	batch := &Batch{
+		ID:            uuid.New().String(),
+		FromAccountID: req.FromAccountID,
+		Currency:      cur,
+		Mode:          req.Mode,
+		Description:   req.Description,
+		Status:        BatchStatusPending,
+		Items:         make([]*BatchItem, 0, len(req.Items)),
+		CreatedBy:     req.CreatedBy,
+		CreatedAt:     time.Now().Unix(),
+	}
+	invalid := &BatchValidationError{}
+	for i, itemReq := range req.Items {
+		if err := s.validateBatchItem(req.FromAccountID, cur, itemReq); err != nil {
+			invalid.Items = append(invalid.Items, BatchItemError{Index: i, Error: err.Error()})
+			continue
+		}
+		description := itemReq.Description
+		if description == "" {
+			description = req.Description
+		}
+		batch.Items = append(batch.Items, &BatchItem{
+			Index:       i,
+			ToAccountID: itemReq.ToAccountID,
+			Amount:      itemReq.Amount,
+			Description: description,
+			Status:      BatchItemPending,
+		})
+		batch.TotalAmount += itemReq.Amount
+	}
+	if len(invalid.Items) > 0 {
+		return nil, invalid
+	}
+
+	if err := s.repo.SaveBatch(batch); err != nil {
+		log.Printf("Error saving batch from %s: %v", batch.FromAccountID, err)
+		return nil, err
+	}
+	log.Printf("Batch created: %s (%s, %d items, %d %s from %s)",
+		batch.ID, batch.Mode, len(batch.Items), batch.TotalAmount, cur, batch.FromAccountID)
+	return batch, nil
+}
+
+// validateBatchItem checks one item's amount and that its recipient can be credited in the batch's currency
+func (s *Service) validateBatchItem(fromAccountID, cur string, item BatchItemRequest) error {
+	if item.ToAccountID == "" {
+		return ErrMissingAccountID
+	}
+	if item.ToAccountID == fromAccountID {
+		return ErrSameAccountTransfer
+	}
+	if item.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return s.checkCurrency(item.ToAccountID, cur)
+}
+
+// ProcessBatch posts a pending batch and records the outcome of each item
+// Batches that are already being or have been processed are returned as they are
+func (s *Service) ProcessBatch(batchID string) (*Batch, error) {
+	batch, err := s.repo.GetBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status != BatchStatusPending {
+		return batch, nil
+	}
+	batch.Status = BatchStatusProcessing
+	if err := s.repo.SaveBatch(batch); err != nil {
+		return nil, err
+	}
+
+	if batch.Mode == BatchModeAllOrNothing {
+		s.postBatch(batch)
+	} else {
+		s.postBatchItems(batch)
+	}
+
+	switch {
+	case batch.Failed == 0:
+		batch.Status = BatchStatusCompleted
+	case batch.Posted == 0:
+		batch.Status = BatchStatusFailed
+	default:
+		batch.Status = BatchStatusPartial
+	}
+	batch.CompletedAt = time.Now().Unix()
+	if err := s.repo.SaveBatch(batch); err != nil {
+		log.Printf("Error saving batch %s: %v", batch.ID, err)
+		return nil, err
+	}
+	log.Printf("Batch %s %s: %d posted, %d failed, fees: %d", batch.ID, batch.Status, batch.Posted, batch.Failed, batch.TotalFees)
+	return batch, nil
+}
+
+// GetBatch retrieves a batch and the status of its items
+func (s *Service) GetBatch(batchID string) (*Batch, error) {
+	return s.repo.GetBatch(batchID)
+}
+
+// postBatch posts every item as one transaction: a debit of the sender and a credit of the recipient
+// per item, and the fee legs. Limits and fee free tiers count each item as a transfer
+func (s *Service) postBatch(batch *Batch) {
+	accountIDs := []string{batch.FromAccountID}
+	amounts := make([]int64, len(batch.Items))
+	for i, item := range batch.Items {
+		accountIDs = append(accountIDs, item.ToAccountID)
+		amounts[i] = item.Amount
+	}
+	unlock := s.locks.Lock(accountIDs...)
+	defer unlock()
+
+	// The batch ID doubles as the transaction ID, so the batch can never post twice
+	transactionID := batch.ID
+	entries, quotes, err := s.batchEntries(batch, amounts, transactionID)
+	if err == nil {
+		err = s.repo.CreateEntries(entries)
+	}
+	if err != nil {
+		log.Printf("Error posting batch %s: %v", batch.ID, err)
+		batch.Error = err.Error()
+		for _, item := range batch.Items {
+			item.Status = BatchItemFailed
+		}
+		batch.Failed = len(batch.Items)
+		return
+	}
+
+	batch.TransactionID = transactionID
+	for i, item := range batch.Items {
+		item.Status = BatchItemPosted
+		item.Fee = quotes[i].Fee
+		item.TransactionID = transactionID
+		batch.TotalFees += item.Fee
+	}
+	batch.Posted = len(batch.Items)
+}
+
+// batchEntries checks an all-or-nothing batch against the sender's funds and limits and builds its entries
+// Callers hold the locks of the sender and every recipient
+func (s *Service) batchEntries(batch *Batch, amounts []int64, transactionID string) ([]*LedgerEntry, []*FeeQuote, error) {
+	now := time.Now().Unix()
+	quotes, err := s.quoteFees(TransactionTypeTransfer, batch.FromAccountID, batch.Currency, amounts, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	var total int64
+	totals := make([]int64, len(quotes))
+	for i, quote := range quotes {
+		total += quote.Total
+		totals[i] = quote.Total
+	}
+	if err := s.checkFunds(batch.FromAccountID, batch.Currency, total); err != nil {
+		return nil, nil, err
+	}
+	for _, item := range batch.Items {
+		if err := s.checkCurrency(item.ToAccountID, batch.Currency); err != nil {
+			return nil, nil, fmt.Errorf("item %d: %w", item.Index, err)
+		}
+	}
+	if err := s.checkLimitsEach(TransactionTypeTransfer, batch.FromAccountID, batch.Currency, totals, now); err != nil {
+		return nil, nil, err
+	}
+
+	var entries []*LedgerEntry
+	for i, item := range batch.Items {
+		// The sender is debited once per item (amount + fee), so each item counts as a transfer later on
+		entries = append(entries, &LedgerEntry{
+			ID:              uuid.New().String(),
+			AccountID:       batch.FromAccountID,
+			AccountType:     AccountTypeUserWallet,
+			Amount:          -totals[i], // Negative for debit
+			Currency:        batch.Currency,
+			EntryType:       EntryTypeDebit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeTransfer,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Batch transfer to %s: %s", item.ToAccountID, item.Description),
+		})
+		entries = append(entries, &LedgerEntry{
+			ID:              uuid.New().String(),
+			AccountID:       item.ToAccountID,
+			AccountType:     AccountTypeUserWallet,
+			Amount:          item.Amount, // Positive for credit
+			Currency:        batch.Currency,
+			EntryType:       EntryTypeCredit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeTransfer,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Transfer from %s: %s", batch.FromAccountID, item.Description),
+		})
+	}
+	for _, quote := range quotes {
+		if entries, err = s.withFeeEntry(entries, quote, transactionID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return entries, quotes, nil
+}
+
+// postBatchItems posts each item as its own transfer, recording which ones failed and why
+// Item transaction IDs are derived from the batch ID, so an item can never post twice
+func (s *Service) postBatchItems(batch *Batch) {
+	namespace := uuid.MustParse(batch.ID)
+	for _, item := range batch.Items {
+		transactionID := uuid.NewSHA1(namespace, []byte(strconv.Itoa(item.Index))).String()
+		_, fee, err := s.transfer(&TransferRequest{
+			FromAccountID: batch.FromAccountID,
+			ToAccountID:   item.ToAccountID,
+			Amount:        item.Amount,
+			Currency:      batch.Currency,
+			Description:   item.Description,
+			TransactionID: transactionID,
+		})
+		if err != nil {
+			item.Status = BatchItemFailed
+			item.Error = err.Error()
+			batch.Failed++
+			continue
+		}
+		item.Status = BatchItemPosted
+		item.Fee = fee.Fee
+		item.TransactionID = transactionID
+		batch.TotalFees += fee.Fee
+		batch.Posted++
+	}
+}
+
+// parseBatchCSV reads batch items from CSV with a header row naming the to_wallet_id, amount and
+// optional description columns, in any order
+func parseBatchCSV(r io.Reader) ([]BatchItemRequestDTO, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing CSV header", ErrInvalidBatch)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets often start the file with a byte order mark
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	toColumn, hasTo := columns["to_wallet_id"]
+	amountColumn, hasAmount := columns["amount"]
+	if !hasTo || !hasAmount {
+		return nil, fmt.Errorf("%w: CSV header must name to_wallet_id and amount", ErrInvalidBatch)
+	}
+	descriptionColumn, hasDescription := columns["description"]
+
+	var items []BatchItemRequestDTO
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBatch, err)
+		}
+		field := func(i int) string {
+			if i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		item := BatchItemRequestDTO{ToWalletID: field(toColumn), Amount: field(amountColumn)}
+		if hasDescription {
+			item.Description = field(descriptionColumn)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
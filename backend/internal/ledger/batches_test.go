@@ -0,0 +1,235 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestAllOrNothingBatch tests that a batch posts as one balanced transaction with a fee per item, or not at all
+func TestAllOrNothingBatch(t *testing.T) {
+	service := NewService(newTestRepository(t), flatTransferFee(t, "0.50"))
+	openWallets(t, service, "USD", "employer", "alice", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "employer", Amount: 100000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	batch, err := service.CreateBatch(&BatchRequest{
+		FromAccountID: "employer",
+		Mode:          BatchModeAllOrNothing,
+		Description:   "Payroll",
+		Items:         []BatchItemRequest{{ToAccountID: "alice", Amount: 30000}, {ToAccountID: "bob", Amount: 20000, Description: "Payroll and bonus"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create batch: %v", err)
+	}
+	if batch.Status != BatchStatusPending || batch.TotalAmount != 50000 {
+		t.Fatalf("Expected a pending batch of 500.00, got %+v", batch)
+	}
+
+	batch, err = service.ProcessBatch(batch.ID)
+	if err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+	if batch.Status != BatchStatusCompleted || batch.Posted != 2 || batch.TotalFees != 100 || batch.TransactionID != batch.ID {
+		t.Fatalf("Expected a completed batch with 1.00 of fees, got %+v", batch)
+	}
+	entries, _ := service.GetTransactionDetails(batch.TransactionID)
+	if len(entries) != 6 {
+		t.Errorf("Expected a debit and a credit per item and two fee legs, got %d entries", len(entries))
+	}
+	if err := service.repo.VerifyTransactionBalance(batch.TransactionID); err != nil {
+		t.Errorf("Expected the batch transaction to balance: %v", err)
+	}
+	if balance, _ := service.GetBalance("employer"); balance.Balance != 49900 {
+		t.Errorf("Expected the employer to have 499.00 left, got %d", balance.Balance)
+	}
+
+	// Processing again doesn't post again
+	if _, err := service.ProcessBatch(batch.ID); err != nil {
+		t.Fatalf("Failed to reprocess batch: %v", err)
+	}
+	if balance, _ := service.GetBalance("bob"); balance.Balance != 20000 {
+		t.Errorf("Expected bob to be paid once, got %d", balance.Balance)
+	}
+
+	// A batch the employer can't cover posts nothing
+	batch, err = service.CreateBatch(&BatchRequest{
+		FromAccountID: "employer",
+		Mode:          BatchModeAllOrNothing,
+		Items:         []BatchItemRequest{{ToAccountID: "alice", Amount: 40000}, {ToAccountID: "bob", Amount: 10000}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create batch: %v", err)
+	}
+	batch, _ = service.ProcessBatch(batch.ID)
+	if batch.Status != BatchStatusFailed || batch.Error != ErrInsufficientBalance.Error() || batch.Items[0].Status != BatchItemFailed {
+		t.Errorf("Expected the batch to fail for lack of funds, got %+v", batch)
+	}
+	if balance, _ := service.GetBalance("alice"); balance.Balance != 30000 {
+		t.Errorf("Expected alice's balance to be unchanged, got %d", balance.Balance)
+	}
+}
+
+// TestAllOrNothingBatchLimits tests that each item of a batch counts as a transfer against the sender's limits
+func TestAllOrNothingBatchLimits(t *testing.T) {
+	service := NewService(newTestRepository(t), WithLimits(LimitTiers{
+		KYCLevelNone: {
+			{Kind: LimitPerTransaction, TransactionType: TransactionTypeTransfer, Value: 5000},
+			{Kind: LimitHourlyCount, TransactionType: TransactionTypeTransfer, Value: 3},
+		},
+	}))
+	openWallets(t, service, "USD", "employer", "alice", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "employer", Amount: 100000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	process := func(items ...BatchItemRequest) *Batch {
+		t.Helper()
+		batch, err := service.CreateBatch(&BatchRequest{FromAccountID: "employer", Mode: BatchModeAllOrNothing, Items: items})
+		if err != nil {
+			t.Fatalf("Failed to create batch: %v", err)
+		}
+		if batch, err = service.ProcessBatch(batch.ID); err != nil {
+			t.Fatalf("Failed to process batch: %v", err)
+		}
+		return batch
+	}
+
+	if batch := process(BatchItemRequest{ToAccountID: "alice", Amount: 5001}, BatchItemRequest{ToAccountID: "bob", Amount: 100}); batch.Status != BatchStatusFailed {
+		t.Errorf("Expected an item over the per-transaction limit to fail the batch, got %s", batch.Status)
+	}
+	// The total is over the per-transaction limit, but no single item is
+	if batch := process(BatchItemRequest{ToAccountID: "alice", Amount: 5000}, BatchItemRequest{ToAccountID: "bob", Amount: 5000}); batch.Status != BatchStatusCompleted {
+		t.Fatalf("Expected the batch to post, got %s: %s", batch.Status, batch.Error)
+	}
+	if batch := process(BatchItemRequest{ToAccountID: "alice", Amount: 100}, BatchItemRequest{ToAccountID: "bob", Amount: 100}); !strings.Contains(batch.Error, LimitHourlyCount) {
+		t.Errorf("Expected the two posted items to leave room for one more transfer this hour, got %+v", batch)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "employer", ToAccountID: "alice", Amount: 100}); err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+	var limitErr *LimitError
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "employer", ToAccountID: "alice", Amount: 100}); !errors.As(err, &limitErr) || limitErr.Used != 3 {
+		t.Errorf("Expected the hourly count to include both batch items, got %v", err)
+	}
+}
+
+// TestBestEffortBatch tests that each item posts on its own and failures are reported per item
+func TestBestEffortBatch(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "employer", "alice", "bob", "carol")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "employer", Amount: 50000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	batch, err := service.CreateBatch(&BatchRequest{
+		FromAccountID: "employer",
+		Mode:          BatchModeBestEffort,
+		Items: []BatchItemRequest{
+			{ToAccountID: "alice", Amount: 30000},
+			{ToAccountID: "bob", Amount: 30000},
+			{ToAccountID: "carol", Amount: 20000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create batch: %v", err)
+	}
+	batch, err = service.ProcessBatch(batch.ID)
+	if err != nil {
+		t.Fatalf("Failed to process batch: %v", err)
+	}
+
+	if batch.Status != BatchStatusPartial || batch.Posted != 2 || batch.Failed != 1 {
+		t.Fatalf("Expected 2 of 3 items to post, got %+v", batch)
+	}
+	wantStatuses := []string{BatchItemPosted, BatchItemFailed, BatchItemPosted}
+	for i, item := range batch.Items {
+		if item.Status != wantStatuses[i] {
+			t.Errorf("Item %d: expected %s, got %s (%s)", i, wantStatuses[i], item.Status, item.Error)
+		}
+	}
+	if batch.Items[1].Error != ErrInsufficientBalance.Error() || batch.Items[1].TransactionID != "" {
+		t.Errorf("Expected bob's item to fail for lack of funds, got %+v", batch.Items[1])
+	}
+	if batch.Items[0].TransactionID == batch.Items[2].TransactionID {
+		t.Error("Expected each item to post its own transaction")
+	}
+
+	stored, err := service.GetBatch(batch.ID)
+	if err != nil || stored.Status != BatchStatusPartial || stored.Items[2].Status != BatchItemPosted {
+		t.Errorf("Expected the outcome to be stored, got %+v (%v)", stored, err)
+	}
+}
+
+// TestCreateBatchValidation tests that every invalid item is reported and nothing is stored
+func TestCreateBatchValidation(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "employer", "alice")
+	openWallets(t, service, "EUR", "euro")
+
+	_, err := service.CreateBatch(&BatchRequest{
+		FromAccountID: "employer",
+		Mode:          BatchModeBestEffort,
+		Items: []BatchItemRequest{
+			{ToAccountID: "alice", Amount: 100},
+			{ToAccountID: "", Amount: 100},
+			{ToAccountID: "employer", Amount: 100},
+			{ToAccountID: "alice", Amount: 0},
+			{ToAccountID: "missing", Amount: 100},
+			{ToAccountID: "euro", Amount: 100},
+		},
+	})
+	var invalid *BatchValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalidBatch) {
+		t.Fatalf("Expected a BatchValidationError, got %v", err)
+	}
+	want := []BatchItemError{
+		{1, ErrMissingAccountID.Error()},
+		{2, ErrSameAccountTransfer.Error()},
+		{3, ErrInvalidAmount.Error()},
+		{4, ErrAccountNotFound.Error()},
+		{5, ErrCurrencyMismatch.Error()},
+	}
+	if len(invalid.Items) != len(want) {
+		t.Fatalf("Expected %d invalid items, got %+v", len(want), invalid.Items)
+	}
+	for i := range want {
+		if invalid.Items[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], invalid.Items[i])
+		}
+	}
+
+	if _, err := service.CreateBatch(&BatchRequest{FromAccountID: "employer", Mode: "SOMETIMES", Items: []BatchItemRequest{{ToAccountID: "alice", Amount: 1}}}); err != ErrInvalidBatchMode {
+		t.Errorf("Expected ErrInvalidBatchMode, got %v", err)
+	}
+	if _, err := service.CreateBatch(&BatchRequest{FromAccountID: "employer", Mode: BatchModeBestEffort}); !errors.Is(err, ErrInvalidBatch) {
+		t.Errorf("Expected ErrInvalidBatch for an empty batch, got %v", err)
+	}
+}
+
+// TestParseBatchCSV tests column lookup by header, optional descriptions and malformed files
+func TestParseBatchCSV(t *testing.T) {
+	items, err := parseBatchCSV(strings.NewReader("\ufeffAmount, to_wallet_id\n1250.00,alice\n 99.5 , bob \n"))
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	want := []BatchItemRequestDTO{{ToWalletID: "alice", Amount: "1250.00"}, {ToWalletID: "bob", Amount: "99.5"}}
+	if len(items) != len(want) || items[0] != want[0] || items[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, items)
+	}
+
+	items, err = parseBatchCSV(strings.NewReader("to_wallet_id,amount,description\nalice,10,\"March, overtime\"\n"))
+	if err != nil || len(items) != 1 || items[0].Description != "March, overtime" {
+		t.Errorf("Expected a quoted description, got %+v (%v)", items, err)
+	}
+
+	for name, body := range map[string]string{
+		"empty file":      "",
+		"missing amount":  "to_wallet_id,description\nalice,rent\n",
+		"unclosed quotes": "to_wallet_id,amount\n\"alice,10\n",
+	} {
+		if _, err := parseBatchCSV(strings.NewReader(body)); !errors.Is(err, ErrInvalidBatch) {
+			t.Errorf("%s: expected ErrInvalidBatch, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package ledger
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSigningNotConfigured = errors.New("ledger root signing is not configured")
+	ErrDayNotEnded          = errors.New("a day's root hash can only be exported once the day has ended")
+)
+
+// GenesisHash is the previous hash of the first entry in the chain
+var GenesisHash = strings.Repeat("0", 64)
+
+// chainPageSize is how many entries VerifyChain reads at a time
+const chainPageSize = 1000
+
+// ChainLink is an entry with its sequence number in the global posting order
+type ChainLink struct {
+	Sequence int64
+	Entry    *LedgerEntry
+}
+
+// ChainBreak describes the first entry that doesn't fit the chain
+type ChainBreak struct {
+	Sequence int64  `json:"sequence"`
+	EntryID  string `json:"entry_id"`
+	Reason   string `json:"reason"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// ChainReport is the result of walking the hash chain
+type ChainReport struct {
+	Valid          bool        `json:"valid"`
+	EntriesChecked int64       `json:"entries_checked"`
+	Unsealed       int64       `json:"unsealed"` // Entries posted before hashing was introduced; they precede the chain
+	HeadSequence   int64       `json:"head_sequence"`
+	HeadHash       string      `json:"head_hash"`
+	FirstBreak     *ChainBreak `json:"first_break,omitempty"`
+}
+
+// DailyRoot is a signed commitment to every entry posted up to the end of a day (UTC)
+// Publishing it elsewhere anchors the chain: later tampering changes every hash after the edit
+type DailyRoot struct {
+	Date      string `json:"date"`      // YYYY-MM-DD
+	Sequence  int64  `json:"sequence"`  // Last entry of the day, 0 if there were none yet
+	RootHash  string `json:"root_hash"` // That entry's hash, or GenesisHash
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // Base64
+	Signature string `json:"signature"`  // Base64 signature of SignedMessage
+}
+
+// SignedMessage is the exact byte string the signature covers
+func (r *DailyRoot) SignedMessage() []byte {
+	return []byte(fmt.Sprintf("digitalwallet-ledger-root-v1\n%s\n%d\n%s\n", r.Date, r.Sequence, r.RootHash))
+}
+
+// WithSigningKey enables signed daily root hashes
+func WithSigningKey(key ed25519.PrivateKey) Option {
+	return func(s *Service) {
+		s.signingKey = key
+	}
+}
+
+// ComputeHash returns the hex SHA-256 of the entry's canonical content, including PrevHash
+// Fields are written in a fixed order and length-prefixed, so no two entries encode alike
+func (e *LedgerEntry) ComputeHash() (string, error) {
+	var metadata []byte
+	if e.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(e.Metadata); err != nil {
+			return "", fmt.Errorf("error encoding entry metadata: %w", err)
+		}
+	}
+
+	h := sha256.New()
+	for _, field := range []string{
+		"ledger-entry-v1", e.PrevHash, e.ID, e.AccountID, e.AccountType, strconv.FormatInt(e.Amount, 10),
+		e.Currency, e.EntryType, e.TransactionID, e.TransactionType, strconv.FormatInt(e.CreatedAt, 10),
+		e.CreatedBy, e.Description, string(metadata), e.ReferenceTransactionID,
+	} {
+		fmt.Fprintf(h, "%d:%s\n", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// seal links the entry to the previous entry's hash ("" when there is none) and stamps its own hash
+func (e *LedgerEntry) seal(prevHash string) error {
+	if prevHash == "" {
+		prevHash = GenesisHash
+	}
+	e.PrevHash = prevHash
+	hash, err := e.ComputeHash()
+	if err != nil {
+		return err
+	}
+	e.Hash = hash
+	return nil
+}
+
+// VerifyChain walks every entry in posting order and reports the first one that breaks the chain
+// An edited entry no longer matches its hash; a removed or inserted one breaks the next entry's link
+func (s *Service) VerifyChain() (*ChainReport, error) {
+	report := &ChainReport{Valid: true, HeadHash: GenesisHash}
+	previous := ""
+
+	var after int64
+	for {
+		links, err := s.repo.GetChain(after, chainPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, link := range links {
+			entry := link.Entry
+			if entry.Hash == "" && previous == "" {
+				report.Unsealed++
+				continue
+			}
+			report.EntriesChecked++
+
+			if chainBreak := checkLink(link, previous); chainBreak != nil {
+				report.Valid = false
+				report.FirstBreak = chainBreak
+				return report, nil
+			}
+			previous = entry.Hash
+			report.HeadSequence = link.Sequence
+			report.HeadHash = entry.Hash
+		}
+
+		if len(links) < chainPageSize {
+			return report, nil
+		}
+		after = links[len(links)-1].Sequence
+	}
+}
+
+// checkLink verifies one entry against the hash of the entry before it
+func checkLink(link *ChainLink, previous string) *ChainBreak {
+	entry := link.Entry
+	chainBreak := &ChainBreak{Sequence: link.Sequence, EntryID: entry.ID}
+
+	if entry.Hash == "" {
+		chainBreak.Reason = "entry is not sealed"
+		return chainBreak
+	}
+	if previous == "" {
+		previous = GenesisHash
+	}
+	if entry.PrevHash != previous {
+		chainBreak.Reason = "previous hash does not match the entry before it"
+		chainBreak.Expected, chainBreak.Actual = previous, entry.PrevHash
+		return chainBreak
+	}
+	hash, err := entry.ComputeHash()
+	if err != nil || hash != entry.Hash {
+		chainBreak.Reason = "content does not match the entry's hash"
+		chainBreak.Expected, chainBreak.Actual = hash, entry.Hash
+		return chainBreak
+	}
+	return nil
+}
+
+// ExportDailyRoot signs the chain head as of the end of day (UTC) for external anchoring
+func (s *Service) ExportDailyRoot(day time.Time) (*DailyRoot, error) {
+	if s.signingKey == nil {
+		return nil, ErrSigningNotConfigured
+	}
+
+	day = day.UTC()
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	if end.After(time.Now()) {
+		return nil, ErrDayNotEnded
+	}
+
+	root := &DailyRoot{
+		Date:      start.Format(time.DateOnly),
+		RootHash:  GenesisHash,
+		Algorithm: "ed25519",
+		PublicKey: base64.StdEncoding.EncodeToString(s.signingKey.Public().(ed25519.PublicKey)),
+	}
+	head, err := s.repo.GetChainHeadAt(end.Unix() - 1)
+	if err != nil {
+		return nil, err
+	}
+	if head != nil && head.Entry.Hash != "" {
+		root.Sequence = head.Sequence
+		root.RootHash = head.Entry.Hash
+	}
+
+	root.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.signingKey, root.SignedMessage()))
+	return root, nil
+}
@@ -0,0 +1,98 @@
+package ledger
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestVerifyChain tests that every posting extends the chain and that returned entries can't alter it
+func TestVerifyChain(t *testing.T) {
+	service := NewService(newTestRepository(t), flatTransferFee(t, "0.50"))
+	openWallets(t, service, "USD", "alice", "bob")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 2500}); err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+
+	// Callers get copies: changing them must not change the ledger
+	entries, _ := service.GetAccountStatement("alice")
+	entries[0].Amount = 1
+
+	report, err := service.VerifyChain()
+	if err != nil {
+		t.Fatalf("Failed to verify chain: %v", err)
+	}
+	if !report.Valid || report.EntriesChecked != 5 || report.FirstBreak != nil {
+		t.Fatalf("Expected a valid chain of 5 entries, got %+v", report)
+	}
+	entries, _ = service.GetAccountStatement("alice")
+	if entries[0].Amount != 10000 || entries[0].PrevHash != GenesisHash {
+		t.Errorf("Expected the first entry to be unchanged and chained to the genesis hash")
+	}
+	if last := entries[len(entries)-1]; report.HeadHash == GenesisHash || report.HeadHash == last.PrevHash {
+		t.Errorf("Expected the head hash to be the last posted entry's hash, got %s", report.HeadHash)
+	}
+}
+
+// TestVerifyChainDetectsTampering tests edits and deletions behind the repository's back
+func TestVerifyChainDetectsTampering(t *testing.T) {
+	repo := NewRepository().(*inMemoryRepository)
+	service := NewService(repo)
+	for i := int64(1); i <= 3; i++ {
+		postAt(t, repo, "alice", 1000, i, "Top-up")
+	}
+
+	repo.entries[2].Amount = 5000
+	report, _ := service.VerifyChain()
+	if report.Valid || report.FirstBreak.Sequence != 3 || report.FirstBreak.Reason != "content does not match the entry's hash" {
+		t.Errorf("Expected an edited entry at sequence 3, got %+v", report.FirstBreak)
+	}
+	repo.entries[2].Amount = 1000
+
+	repo.entries = append(repo.entries[:1], repo.entries[2:]...)
+	report, _ = service.VerifyChain()
+	if report.Valid || report.FirstBreak.Sequence != 2 || report.FirstBreak.Reason != "previous hash does not match the entry before it" {
+		t.Errorf("Expected a broken link at sequence 2 after a deletion, got %+v", report.FirstBreak)
+	}
+}
+
+// TestExportDailyRoot tests that the root commits to the last entry of the day and is signed
+func TestExportDailyRoot(t *testing.T) {
+	repo := newTestRepository(t)
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	service := NewService(repo, WithSigningKey(key))
+
+	day := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	postAt(t, repo, "alice", 1000, day.Add(9*time.Hour).Unix(), "Morning")
+	postAt(t, repo, "alice", 2000, day.Add(23*time.Hour).Unix(), "Evening")
+	postAt(t, repo, "alice", 3000, day.Add(25*time.Hour).Unix(), "Next day")
+
+	root, err := service.ExportDailyRoot(day.Add(12 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to export root: %v", err)
+	}
+	evening, _ := repo.GetEntriesByTransactionID(fmt.Sprintf("txn-alice-%d", day.Add(23*time.Hour).Unix()))
+	if root.Date != "2024-01-01" || root.RootHash != evening[len(evening)-1].Hash {
+		t.Errorf("Expected the root to be the evening posting's hash, got %+v", root)
+	}
+	signature, _ := base64.StdEncoding.DecodeString(root.Signature)
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), root.SignedMessage(), signature) {
+		t.Error("Expected a valid signature over the root")
+	}
+
+	if root, _ := service.ExportDailyRoot(day.AddDate(0, 0, -1)); root.RootHash != GenesisHash || root.Sequence != 0 {
+		t.Errorf("Expected the genesis hash before any posting, got %+v", root)
+	}
+	if _, err := service.ExportDailyRoot(time.Now()); err != ErrDayNotEnded {
+		t.Errorf("Expected ErrDayNotEnded, got %v", err)
+	}
+	if _, err := NewService(repo).ExportDailyRoot(day); err != ErrSigningNotConfigured {
+		t.Errorf("Expected ErrSigningNotConfigured, got %v", err)
+	}
+}
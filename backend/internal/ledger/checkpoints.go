@@ -0,0 +1,69 @@
+package ledger
+
+import (
+	"log"
+	"time"
+)
+
+// CheckpointLag keeps checkpoints behind the clock, so postings still in flight
+// (stamped just before the checkpoint is taken) are never left out of one
+const CheckpointLag = time.Minute
+
+// GetBalanceAt returns an account's ledger balance as of a point in time
+// It is computed from entries, starting at the nearest earlier balance checkpoint
+func (s *Service) GetBalanceAt(accountID string, at time.Time) (*HistoricalBalance, error) {
+	if accountID == "" {
+		return nil, ErrMissingAccountID
+	}
+
+	current, err := s.repo.GetBalance(accountID)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.repo.GetBalanceAt(accountID, at.Unix())
+	if err != nil {
+		return nil, err
+	}
+
+	return &HistoricalBalance{
+		AccountID:   accountID,
+		AccountType: current.AccountType,
+		Currency:    current.Currency,
+		Balance:     balance,
+		AsOf:        at.Unix(),
+	}, nil
+}
+
+// CheckpointBalances records every account's balance as of CheckpointLag before now
+// It returns how many checkpoints were created
+func (s *Service) CheckpointBalances(now time.Time) (int, error) {
+	return s.repo.CreateBalanceCheckpoints(now.Add(-CheckpointLag).Unix())
+}
+
+// StartBalanceCheckpointer checkpoints balances every interval until the returned stop function is called
+func (s *Service) StartBalanceCheckpointer(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				created, err := s.CheckpointBalances(now)
+				if err != nil {
+					log.Printf("Error checkpointing balances: %v", err)
+				}
+				if created > 0 {
+					log.Printf("Checkpointed %d account balances", created)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,44 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGetBalanceAt tests historical balances before, between and after checkpoints
+func TestGetBalanceAt(t *testing.T) {
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	postAt(t, repo, "alice", 10000, 100, "Salary")
+	postAt(t, repo, "alice", -2500, 200, "Groceries")
+
+	// Checkpoint as of 250, then keep posting
+	created, err := service.CheckpointBalances(time.Unix(250, 0).Add(CheckpointLag))
+	if err != nil {
+		t.Fatalf("Failed to checkpoint balances: %v", err)
+	}
+	if created != 2 {
+		t.Errorf("Expected checkpoints for alice and the external pool, got %d", created)
+	}
+	if created, _ := service.CheckpointBalances(time.Unix(250, 0).Add(CheckpointLag)); created != 0 {
+		t.Errorf("Expected a repeated checkpoint to be skipped, got %d", created)
+	}
+
+	postAt(t, repo, "alice", -1000, 300, "Coffee beans")
+	postAt(t, repo, "alice", 5000, 400, "Refund from shop")
+
+	for at, want := range map[int64]int64{50: 0, 100: 10000, 199: 10000, 250: 7500, 300: 6500, 1000: 11500} {
+		balance, err := service.GetBalanceAt("alice", time.Unix(at, 0))
+		if err != nil {
+			t.Fatalf("Failed to get balance at %d: %v", at, err)
+		}
+		if balance.Balance != want || balance.Currency != "USD" || balance.AsOf != at {
+			t.Errorf("At %d: expected %d USD, got %d %s as of %d", at, want, balance.Balance, balance.Currency, balance.AsOf)
+		}
+	}
+
+	if _, err := service.GetBalanceAt("nobody", time.Unix(1000, 0)); err != ErrAccountBalanceNotFound {
+		t.Errorf("Expected ErrAccountBalanceNotFound, got %v", err)
+	}
+}
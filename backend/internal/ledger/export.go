@@ -0,0 +1,338 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+var ErrUnsupportedExportFormat = errors.New("unsupported export format: expected csv, ofx or camt053")
+
+// Statement export formats
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatOFX     = "ofx"     // OFX 2.2, for personal finance tools
+	ExportFormatCamt053 = "camt053" // ISO 20022 bank-to-customer statement, camt.053.001.08
+)
+
+// StatementExport is every entry of an account for a period, ready to be rendered
+type StatementExport struct {
+	*Statement
+	From        int64 // Period start (Unix time), resolved from the query or the first entry
+	To          int64 // Period end (Unix time), resolved from the query or GeneratedAt
+	GeneratedAt int64
+}
+
+// ExportStatement collects the whole statement for a period by following every page
+func (s *Service) ExportStatement(accountID string, from, to int64, generatedAt time.Time) (*StatementExport, error) {
+	query := &StatementQuery{AccountID: accountID, From: from, To: to, Limit: MaxStatementLimit}
+
+	var full *Statement
+	for {
+		page, err := s.GetStatement(query)
+		if err != nil {
+			return nil, err
+		}
+		if full == nil {
+			full = page
+		} else {
+			full.Lines = append(full.Lines, page.Lines...)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	full.NextCursor = ""
+
+	export := &StatementExport{Statement: full, From: from, To: to, GeneratedAt: generatedAt.Unix()}
+	if export.From == 0 && len(full.Lines) > 0 {
+		export.From = full.Lines[0].Entry.CreatedAt
+	}
+	if export.To == 0 {
+		export.To = export.GeneratedAt
+	}
+	return export, nil
+}
+
+// ExportContentType returns the MIME type and file extension for an export format
+func ExportContentType(format string) (contentType, extension string, err error) {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case ExportFormatOFX:
+		return "application/x-ofx", "ofx", nil
+	case ExportFormatCamt053:
+		return "application/xml", "xml", nil
+	}
+	return "", "", ErrUnsupportedExportFormat
+}
+
+// RenderStatement writes the statement in the given format
+func RenderStatement(w io.Writer, format string, export *StatementExport) error {
+	switch format {
+	case ExportFormatCSV:
+		return renderCSV(w, export)
+	case ExportFormatOFX:
+		return renderOFX(w, export)
+	case ExportFormatCamt053:
+		return renderCamt053(w, export)
+	}
+	return ErrUnsupportedExportFormat
+}
+
+// renderCSV writes one row per entry, framed by opening and closing balance rows
+func renderCSV(w io.Writer, export *StatementExport) error {
+	money := func(amount int64) string { return currency.New(amount, export.Currency).String() }
+	date := func(unix int64) string { return time.Unix(unix, 0).UTC().Format(time.RFC3339) }
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "transaction_id", "transaction_type", "description", "amount", "balance", "currency"})
+	out.Write([]string{date(export.From), "", "OPENING_BALANCE", "Opening balance", "", money(export.OpeningBalance), export.Currency})
+	for _, line := range export.Lines {
+		entry := line.Entry
+		out.Write([]string{
+			date(entry.CreatedAt), entry.TransactionID, entry.TransactionType, entry.Description,
+			money(entry.Amount), money(line.RunningBalance), export.Currency,
+		})
+	}
+	out.Write([]string{date(export.To), "", "CLOSING_BALANCE", "Closing balance", "", money(export.ClosingBalance), export.Currency})
+	out.Flush()
+	return out.Error()
+}
+
+// OFX 2.2 documents; field order follows the OFX specification
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response struct {
+			Status   ofxStatus `xml:"STATUS"`
+			DTServer string    `xml:"DTSERVER"`
+			Language string    `xml:"LANGUAGE"`
+		} `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Transaction struct {
+			TrnUID    string           `xml:"TRNUID"`
+			Status    ofxStatus        `xml:"STATUS"`
+			Statement ofxStatementBody `xml:"STMTRS"`
+		} `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxStatementBody struct {
+	CurDef  string `xml:"CURDEF"`
+	Account struct {
+		BankID   string `xml:"BANKID"`
+		AcctID   string `xml:"ACCTID"`
+		AcctType string `xml:"ACCTTYPE"`
+	} `xml:"BANKACCTFROM"`
+	TranList struct {
+		DTStart      string           `xml:"DTSTART"`
+		DTEnd        string           `xml:"DTEND"`
+		Transactions []ofxTransaction `xml:"STMTTRN"`
+	} `xml:"BANKTRANLIST"`
+	LedgerBal ofxBalance `xml:"LEDGERBAL"`
+	// OFX has no opening balance element; it is carried in the optional balance list
+	BalList []ofxListBalance `xml:"BALLIST>BAL"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	Name     string `xml:"NAME"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	BalAmt string `xml:"BALAMT"`
+	DTAsOf string `xml:"DTASOF"`
+}
+
+type ofxListBalance struct {
+	Name    string `xml:"NAME"`
+	Desc    string `xml:"DESC"`
+	BalType string `xml:"BALTYPE"`
+	Value   string `xml:"VALUE"`
+	DTAsOf  string `xml:"DTASOF"`
+}
+
+// renderOFX writes an OFX 2.2 bank statement response
+func renderOFX(w io.Writer, export *StatementExport) error {
+	money := func(amount int64) string { return currency.New(amount, export.Currency).String() }
+	date := func(unix int64) string { return time.Unix(unix, 0).UTC().Format("20060102150405.000") + "[0:UTC]" }
+
+	doc := &ofxDocument{}
+	doc.SignOn.Response.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.SignOn.Response.DTServer = date(export.GeneratedAt)
+	doc.SignOn.Response.Language = "ENG"
+
+	doc.Bank.Transaction.TrnUID = strconv.FormatInt(export.GeneratedAt, 10)
+	doc.Bank.Transaction.Status = ofxStatus{Code: 0, Severity: "INFO"}
+
+	body := &doc.Bank.Transaction.Statement
+	body.CurDef = export.Currency
+	body.Account.BankID = "DIGITALWALLET"
+	body.Account.AcctID = export.AccountID
+	body.Account.AcctType = "CHECKING"
+	body.TranList.DTStart = date(export.From)
+	body.TranList.DTEnd = date(export.To)
+	for _, line := range export.Lines {
+		entry := line.Entry
+		trnType := "CREDIT"
+		if entry.Amount < 0 {
+			trnType = "DEBIT"
+		}
+		body.TranList.Transactions = append(body.TranList.Transactions, ofxTransaction{
+			TrnType:  trnType,
+			DTPosted: date(entry.CreatedAt),
+			TrnAmt:   money(entry.Amount),
+			FITID:    entry.ID,
+			Name:     truncate(entry.TransactionType, 32),
+			Memo:     truncate(entry.Description, 255),
+		})
+	}
+	body.LedgerBal = ofxBalance{BalAmt: money(export.ClosingBalance), DTAsOf: date(export.To)}
+	body.BalList = []ofxListBalance{{
+		Name: "Opening balance", Desc: "Balance at the start of the period", BalType: "DOLLAR",
+		Value: money(export.OpeningBalance), DTAsOf: date(export.From),
+	}}
+
+	header := xml.Header + `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	return writeXML(w, header, doc)
+}
+
+// camt.053.001.08 documents (only the elements we fill in)
+type camtDocument struct {
+	XMLName   xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:camt.053.001.08 Document"`
+	Statement struct {
+		GroupHeader struct {
+			MsgID   string `xml:"MsgId"`
+			CreDtTm string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+		Stmt camtStatement `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camtStatement struct {
+	ID      string `xml:"Id"`
+	CreDtTm string `xml:"CreDtTm"`
+	FromTo  struct {
+		From string `xml:"FrDtTm"`
+		To   string `xml:"ToDtTm"`
+	} `xml:"FrToDt"`
+	Account struct {
+		ID       string `xml:"Id>Othr>Id"`
+		Currency string `xml:"Ccy"`
+	} `xml:"Acct"`
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"` // OPBD (opening booked) or CLBD (closing booked)
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"` // CRDT or DBIT
+	DateTime  string     `xml:"Dt>DtTm"`
+}
+
+type camtEntry struct {
+	Reference      string     `xml:"NtryRef"`
+	Amount         camtAmount `xml:"Amt"`
+	Indicator      string     `xml:"CdtDbtInd"`
+	Status         string     `xml:"Sts>Cd"`
+	BookingDate    string     `xml:"BookgDt>DtTm"`
+	ServicerRef    string     `xml:"AcctSvcrRef"`
+	BankTxCode     string     `xml:"BkTxCd>Prtry>Cd"`
+	AdditionalInfo string     `xml:"AddtlNtryInf,omitempty"`
+}
+
+// renderCamt053 writes an ISO 20022 camt.053 bank-to-customer statement
+// Amounts are unsigned; the sign goes in the credit/debit indicator
+func renderCamt053(w io.Writer, export *StatementExport) error {
+	amount := func(value int64) (camtAmount, string) {
+		indicator := "CRDT"
+		if value < 0 {
+			value, indicator = -value, "DBIT"
+		}
+		return camtAmount{Currency: export.Currency, Value: currency.New(value, export.Currency).String()}, indicator
+	}
+	date := func(unix int64) string { return time.Unix(unix, 0).UTC().Format("2006-01-02T15:04:05Z") }
+	statementID := fmt.Sprintf("%s-%d-%d", export.AccountID, export.From, export.To)
+
+	doc := &camtDocument{}
+	doc.Statement.GroupHeader.MsgID = fmt.Sprintf("%s-%d", statementID, export.GeneratedAt)
+	doc.Statement.GroupHeader.CreDtTm = date(export.GeneratedAt)
+
+	stmt := &doc.Statement.Stmt
+	stmt.ID = statementID
+	stmt.CreDtTm = date(export.GeneratedAt)
+	stmt.FromTo.From = date(export.From)
+	stmt.FromTo.To = date(export.To)
+	stmt.Account.ID = export.AccountID
+	stmt.Account.Currency = export.Currency
+
+	opening, openingIndicator := amount(export.OpeningBalance)
+	closing, closingIndicator := amount(export.ClosingBalance)
+	stmt.Balances = []camtBalance{
+		{Type: "OPBD", Amount: opening, Indicator: openingIndicator, DateTime: date(export.From)},
+		{Type: "CLBD", Amount: closing, Indicator: closingIndicator, DateTime: date(export.To)},
+	}
+
+	for _, line := range export.Lines {
+		entry := line.Entry
+		entryAmount, indicator := amount(entry.Amount)
+		stmt.Entries = append(stmt.Entries, camtEntry{
+			Reference:      entry.ID,
+			Amount:         entryAmount,
+			Indicator:      indicator,
+			Status:         "BOOK",
+			BookingDate:    date(entry.CreatedAt),
+			ServicerRef:    entry.TransactionID,
+			BankTxCode:     entry.TransactionType,
+			AdditionalInfo: truncate(entry.Description, 500),
+		})
+	}
+
+	return writeXML(w, xml.Header, doc)
+}
+
+// writeXML writes the header followed by the indented document and a trailing newline
+func writeXML(w io.Writer, header string, doc any) error {
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// truncate shortens s to at most n runes, as the formats limit free-text field lengths
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
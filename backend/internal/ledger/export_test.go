@@ -0,0 +1,96 @@
+package ledger
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenExport is a fixed statement whose descriptions need escaping in every format
+func goldenExport() *StatementExport {
+	entry := func(id, transactionID, transactionType, description string, amount, createdAt int64) *LedgerEntry {
+		return &LedgerEntry{
+			ID: id, AccountID: "wallet-alice", AccountType: AccountTypeUserWallet, Amount: amount, Currency: "USD",
+			TransactionID: transactionID, TransactionType: transactionType, CreatedAt: createdAt, Description: description,
+		}
+	}
+	return &StatementExport{
+		Statement: &Statement{
+			AccountID:      "wallet-alice",
+			Currency:       "USD",
+			OpeningBalance: 12050,
+			ClosingBalance: 9525,
+			Lines: []*StatementLine{
+				{Entry: entry("entry-1", "txn-1", TransactionTypeDeposit, "Salary, October", 50000, 1696150800), RunningBalance: 62050},
+				{Entry: entry("entry-2", "txn-2", TransactionTypeTransfer, `Rent to "Bob & Co" <flat 2>`, -52500, 1696237200), RunningBalance: 9550},
+				{Entry: entry("entry-3", "txn-2", TransactionTypeFee, "Transfer fee", -25, 1696237200), RunningBalance: 9525},
+			},
+		},
+		From:        1696118400, // 2023-10-01T00:00:00Z
+		To:          1698796799, // 2023-10-31T23:59:59Z
+		GeneratedAt: 1698800400,
+	}
+}
+
+// TestRenderStatementGolden compares each export format against its golden file
+// Run with -update to regenerate the files after an intended format change
+func TestRenderStatementGolden(t *testing.T) {
+	for format, golden := range map[string]string{
+		ExportFormatCSV:     "statement.csv",
+		ExportFormatOFX:     "statement.ofx",
+		ExportFormatCamt053: "statement.camt053.xml",
+	} {
+		t.Run(format, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := RenderStatement(&got, format, goldenExport()); err != nil {
+				t.Fatalf("Failed to render %s: %v", format, err)
+			}
+
+			path := filepath.Join("testdata", golden)
+			if *updateGolden {
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatalf("Failed to update %s: %v", path, err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", path, err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s output differs from %s:\n%s", format, path, got.String())
+			}
+		})
+	}
+
+	if err := RenderStatement(&bytes.Buffer{}, "pdf", goldenExport()); err != ErrUnsupportedExportFormat {
+		t.Errorf("Expected ErrUnsupportedExportFormat, got %v", err)
+	}
+}
+
+// TestExportStatementCollectsAllPages tests that an export isn't cut off at the statement page limit
+func TestExportStatementCollectsAllPages(t *testing.T) {
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	count := int64(MaxStatementLimit + 20)
+	for i := int64(1); i <= count; i++ {
+		postAt(t, repo, "alice", 100, i, "Top-up")
+	}
+
+	export, err := service.ExportStatement("alice", 0, 0, time.Unix(10000, 0))
+	if err != nil {
+		t.Fatalf("Failed to export statement: %v", err)
+	}
+	if int64(len(export.Lines)) != count || export.ClosingBalance != count*100 {
+		t.Errorf("Expected %d lines closing at %d, got %d lines closing at %d",
+			count, count*100, len(export.Lines), export.ClosingBalance)
+	}
+	if export.From != 1 || export.To != 10000 {
+		t.Errorf("Expected the period to resolve to [1, 10000], got [%d, %d]", export.From, export.To)
+	}
+}
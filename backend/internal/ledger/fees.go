@@ -0,0 +1,360 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidFeeRule   = errors.New("invalid fee rule")
+	ErrFeeExceedsAmount = errors.New("fee exceeds the deposited amount")
+	ErrNotFeeable       = errors.New("fees only apply to transfers, withdrawals and deposits")
+)
+
+// Fee rule kinds
+const (
+	FeeKindFixed      = "FIXED"      // A flat fee per transaction
+	FeeKindPercentage = "PERCENTAGE" // A share of the amount, optionally clamped to a minimum and maximum fee
+)
+
+// FeeRuleConfig is a fee rule as written in the fee schedule file
+// Amounts are decimal strings in the rule's currency, e.g. "0.50"
+type FeeRuleConfig struct {
+	ID              string `json:"id"`
+	TransactionType string `json:"transaction_type"` // TRANSFER, WITHDRAWAL or DEPOSIT
+	Currency        string `json:"currency"`
+	MinAmount       string `json:"min_amount,omitempty"`     // Lower bound of the amount band, inclusive (default 0)
+	MaxAmount       string `json:"max_amount,omitempty"`     // Upper bound of the amount band, inclusive (default unbounded)
+	Kind            string `json:"kind"`                     // FIXED or PERCENTAGE
+	FixedFee        string `json:"fixed_fee,omitempty"`      // FIXED only
+	RateBps         int64  `json:"rate_bps,omitempty"`       // PERCENTAGE only, in basis points (150 = 1.5%)
+	MinFee          string `json:"min_fee,omitempty"`        // PERCENTAGE only: optional floor
+	MaxFee          string `json:"max_fee,omitempty"`        // PERCENTAGE only: optional cap
+	FreePerMonth    int    `json:"free_per_month,omitempty"` // An account's first N matching transactions each UTC month are free
+}
+
+// FeeRule is a validated fee rule with amounts in minor units
+type FeeRule struct {
+	ID              string
+	TransactionType string
+	Currency        string
+	MinAmount       int64
+	MaxAmount       int64 // 0 means unbounded
+	Kind            string
+	FixedFee        int64
+	RateBps         int64
+	MinFee          int64 // 0 means no floor
+	MaxFee          int64 // 0 means no cap
+	FreePerMonth    int
+}
+
+// FeeSchedule picks the fee rule for a transaction by type, currency and amount band
+// Transactions no rule matches are free
+type FeeSchedule struct {
+	rules   []*FeeRule
+	configs []FeeRuleConfig
+}
+
+// NewFeeSchedule validates the rules; bands for the same transaction type and currency must not overlap
+func NewFeeSchedule(configs []FeeRuleConfig) (*FeeSchedule, error) {
+	schedule := &FeeSchedule{configs: append([]FeeRuleConfig(nil), configs...)}
+	ids := make(map[string]bool, len(configs))
+	for i := range configs {
+		rule, err := parseFeeRule(&configs[i])
+		if err != nil {
+			return nil, err
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("%w: duplicate rule id %s", ErrInvalidFeeRule, rule.ID)
+		}
+		ids[rule.ID] = true
+		schedule.rules = append(schedule.rules, rule)
+	}
+
+	sorted := append([]*FeeRule(nil), schedule.rules...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinAmount < sorted[j].MinAmount })
+	last := make(map[string]*FeeRule)
+	for _, rule := range sorted {
+		key := rule.TransactionType + "/" + rule.Currency
+		if prev := last[key]; prev != nil && (prev.MaxAmount == 0 || prev.MaxAmount >= rule.MinAmount) {
+			return nil, fmt.Errorf("%w: %s overlaps %s", ErrInvalidFeeRule, rule.ID, prev.ID)
+		}
+		last[key] = rule
+	}
+	return schedule, nil
+}
+
+// LoadFeeSchedule loads a fee schedule from a JSON file holding a list of rules
+func LoadFeeSchedule(path string) (*FeeSchedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fee schedule file: %w", err)
+	}
+
+	var configs []FeeRuleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("error parsing fee schedule file: %w", err)
+	}
+	return NewFeeSchedule(configs)
+}
+
+// Rules returns the schedule as configured
+func (fs *FeeSchedule) Rules() []FeeRuleConfig {
+	return append([]FeeRuleConfig(nil), fs.configs...)
+}
+
+// match returns the rule whose band contains amount, or nil
+func (fs *FeeSchedule) match(transactionType, cur string, amount int64) *FeeRule {
+	for _, rule := range fs.rules {
+		if rule.TransactionType == transactionType && rule.Currency == cur &&
+			amount >= rule.MinAmount && (rule.MaxAmount == 0 || amount <= rule.MaxAmount) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// parseFeeRule validates a configured rule and converts its amounts to minor units
+func parseFeeRule(config *FeeRuleConfig) (*FeeRule, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidFeeRule, config.ID, fmt.Sprintf(format, args...))
+	}
+
+	if config.ID == "" {
+		return nil, fmt.Errorf("%w: missing id", ErrInvalidFeeRule)
+	}
+	switch config.TransactionType {
+	case TransactionTypeTransfer, TransactionTypeWithdrawal, TransactionTypeDeposit:
+	default:
+		return nil, invalid("unsupported transaction type %q", config.TransactionType)
+	}
+	if !currency.IsSupported(config.Currency) {
+		return nil, invalid("unsupported currency %q", config.Currency)
+	}
+	if config.FreePerMonth < 0 {
+		return nil, invalid("free_per_month must not be negative")
+	}
+
+	amounts := make(map[string]int64, 5)
+	for field, value := range map[string]string{
+		"min_amount": config.MinAmount,
+		"max_amount": config.MaxAmount,
+		"fixed_fee":  config.FixedFee,
+		"min_fee":    config.MinFee,
+		"max_fee":    config.MaxFee,
+	} {
+		if value == "" {
+			continue
+		}
+		money, err := currency.Parse(value, config.Currency)
+		if err != nil {
+			return nil, invalid("%s: %v", field, err)
+		}
+		if money.IsNegative() {
+			return nil, invalid("%s must not be negative", field)
+		}
+		amounts[field] = money.Amount()
+	}
+
+	rule := &FeeRule{
+		ID:              config.ID,
+		TransactionType: config.TransactionType,
+		Currency:        config.Currency,
+		MinAmount:       amounts["min_amount"],
+		MaxAmount:       amounts["max_amount"],
+		Kind:            config.Kind,
+		FixedFee:        amounts["fixed_fee"],
+		RateBps:         config.RateBps,
+		MinFee:          amounts["min_fee"],
+		MaxFee:          amounts["max_fee"],
+		FreePerMonth:    config.FreePerMonth,
+	}
+	if rule.MaxAmount != 0 && rule.MaxAmount < rule.MinAmount {
+		return nil, invalid("max_amount is below min_amount")
+	}
+
+	switch rule.Kind {
+	case FeeKindFixed:
+		if rule.RateBps != 0 || config.MinFee != "" || config.MaxFee != "" {
+			return nil, invalid("FIXED rules take only fixed_fee")
+		}
+	case FeeKindPercentage:
+		if config.FixedFee != "" {
+			return nil, invalid("PERCENTAGE rules don't take fixed_fee")
+		}
+		if rule.RateBps <= 0 || rule.RateBps > 10000 {
+			return nil, invalid("rate_bps must be between 1 and 10000")
+		}
+		if rule.MaxFee != 0 && rule.MinFee > rule.MaxFee {
+			return nil, invalid("min_fee is above max_fee")
+		}
+	default:
+		return nil, invalid("unknown kind %q", config.Kind)
+	}
+	return rule, nil
+}
+
+// fee prices a transaction of amount under the rule, ignoring the free tier
+// Percentages round half up to the minor unit before the floor and cap apply
+func (r *FeeRule) fee(amount int64) (int64, error) {
+	if r.Kind == FeeKindFixed {
+		return r.FixedFee, nil
+	}
+
+	fee, err := currency.Round(new(big.Rat).Mul(big.NewRat(amount, 1), big.NewRat(r.RateBps, 10000)), currency.RoundHalfUp)
+	if err != nil {
+		return 0, err
+	}
+	if fee < r.MinFee {
+		fee = r.MinFee
+	}
+	if r.MaxFee != 0 && fee > r.MaxFee {
+		fee = r.MaxFee
+	}
+	return fee, nil
+}
+
+// WithFees charges fees on transfers, withdrawals and deposits according to the schedule
+func WithFees(schedule *FeeSchedule) Option {
+	return func(s *Service) {
+		s.fees = schedule
+	}
+}
+
+// FeeSchedule returns the configured fee rules, or nil when fees are not configured
+func (s *Service) FeeSchedule() []FeeRuleConfig {
+	if s.fees == nil {
+		return nil
+	}
+	return s.fees.Rules()
+}
+
+// QuoteFee prices the fee on a transaction before it is posted
+// The quote is indicative: the fee is priced again when the transaction posts, so a free-tier
+// allowance used up in between is charged
+func (s *Service) QuoteFee(transactionType, accountID, cur string, amount int64) (*FeeQuote, error) {
+	if accountID == "" {
+		return nil, ErrMissingAccountID
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	cur, err := resolveCurrency(cur)
+	if err != nil {
+		return nil, err
+	}
+	switch transactionType {
+	case TransactionTypeTransfer, TransactionTypeWithdrawal, TransactionTypeDeposit:
+	default:
+		return nil, ErrNotFeeable
+	}
+	if err := s.checkCurrency(accountID, cur); err != nil {
+		return nil, err
+	}
+
+	return s.quoteFee(transactionType, accountID, cur, amount, time.Now().Unix())
+}
+
+// quoteFee prices a transaction's fee; callers posting it hold the account's lock so a free-tier
+// allowance can't be used twice
+func (s *Service) quoteFee(transactionType, accountID, cur string, amount, now int64) (*FeeQuote, error) {
+	quotes, err := s.quoteFees(transactionType, accountID, cur, []int64{amount}, now)
+	if err != nil {
+		return nil, err
+	}
+	return quotes[0], nil
+}
+
+// quoteFees prices several postings of one account made together, such as the items of a batch
+// Each posting uses up a free-tier allowance in turn, as separate transactions would
+func (s *Service) quoteFees(transactionType, accountID, cur string, amounts []int64, now int64) ([]*FeeQuote, error) {
+	used := -1 // Counted when the first rule with a free tier matches
+	quotes := make([]*FeeQuote, 0, len(amounts))
+	for _, amount := range amounts {
+		quote := &FeeQuote{
+			TransactionType: transactionType,
+			AccountID:       accountID,
+			Currency:        cur,
+			Amount:          amount,
+		}
+		var rule *FeeRule
+		if s.fees != nil {
+			rule = s.fees.match(transactionType, cur, amount)
+		}
+
+		if rule != nil {
+			quote.RuleID = rule.ID
+			if rule.FreePerMonth > 0 && used < 0 {
+				// The free tier counts the account's postings of this type on the paying side this UTC month
+				month := time.Unix(now, 0).UTC()
+				from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC).Unix()
+				activity, err := s.repo.GetActivity(accountID, transactionType, payerEntryType(transactionType), from, now)
+				if err != nil {
+					return nil, fmt.Errorf("error counting transactions: %w", err)
+				}
+				used = activity.Transactions
+			}
+			if rule.FreePerMonth > 0 && used < rule.FreePerMonth {
+				quote.FreeRemaining = rule.FreePerMonth - used - 1
+				used++
+			} else {
+				fee, err := rule.fee(amount)
+				if err != nil {
+					return nil, err
+				}
+				quote.Fee = fee
+			}
+		}
+
+		quote.Total = amount + quote.Fee
+		if transactionType == TransactionTypeDeposit {
+			// Deposit fees come out of the deposited amount
+			if quote.Fee >= amount {
+				return nil, ErrFeeExceedsAmount
+			}
+			quote.Total = amount - quote.Fee
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, nil
+}
+
+// payerEntryType is the side of the paying account's entry: deposits credit it, the rest debit it
+func payerEntryType(transactionType string) string {
+	if transactionType == TransactionTypeDeposit {
+		return EntryTypeCredit
+	}
+	return EntryTypeDebit
+}
+
+// withFeeEntry adds the entry crediting a charged fee to the currency's fee account
+func (s *Service) withFeeEntry(entries []*LedgerEntry, quote *FeeQuote, transactionID string) ([]*LedgerEntry, error) {
+	if quote.Fee == 0 {
+		return entries, nil
+	}
+	feeAccountID, err := s.systemAccount(AccountTypeSystemFee, quote.Currency, "")
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, &LedgerEntry{
+		ID:              uuid.New().String(),
+		AccountID:       feeAccountID, // The currency's fee account in the chart
+		AccountType:     AccountTypeSystemFee,
+		Amount:          quote.Fee, // Positive for credit
+		Currency:        quote.Currency,
+		EntryType:       EntryTypeCredit,
+		TransactionID:   transactionID,
+		TransactionType: TransactionTypeFee,
+		CreatedAt:       entries[0].CreatedAt,
+		CreatedBy:       "ledger-service",
+		Description:     fmt.Sprintf("%s fee from %s (rule %s)", quote.TransactionType, quote.AccountID, quote.RuleID),
+	}), nil
+}
@@ -0,0 +1,245 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// flatTransferFee charges a fixed fee on every USD transfer
+func flatTransferFee(t *testing.T, fee string) Option {
+	t.Helper()
+	schedule, err := NewFeeSchedule([]FeeRuleConfig{
+		{ID: "usd-transfer", TransactionType: TransactionTypeTransfer, Currency: "USD", Kind: FeeKindFixed, FixedFee: fee},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build fee schedule: %v", err)
+	}
+	return WithFees(schedule)
+}
+
+// TestNewFeeScheduleValidation tests that malformed and overlapping rules are rejected
+func TestNewFeeScheduleValidation(t *testing.T) {
+	fixed := FeeRuleConfig{ID: "fixed", TransactionType: TransactionTypeTransfer, Currency: "USD", Kind: FeeKindFixed, FixedFee: "0.50"}
+	percentage := FeeRuleConfig{ID: "pct", TransactionType: TransactionTypeWithdrawal, Currency: "USD", Kind: FeeKindPercentage, RateBps: 100}
+
+	invalid := map[string][]FeeRuleConfig{
+		"missing id":           {func() FeeRuleConfig { r := fixed; r.ID = ""; return r }()},
+		"duplicate id":         {fixed, func() FeeRuleConfig { r := percentage; r.ID = "fixed"; return r }()},
+		"reversal type":        {func() FeeRuleConfig { r := fixed; r.TransactionType = TransactionTypeReversal; return r }()},
+		"unsupported currency": {func() FeeRuleConfig { r := fixed; r.Currency = "JPY"; return r }()},
+		"unknown kind":         {func() FeeRuleConfig { r := fixed; r.Kind = "TIERED"; return r }()},
+		"negative fee":         {func() FeeRuleConfig { r := fixed; r.FixedFee = "-0.50"; return r }()},
+		"sub-cent fee":         {func() FeeRuleConfig { r := fixed; r.FixedFee = "0.005"; return r }()},
+		"fixed with rate":      {func() FeeRuleConfig { r := fixed; r.RateBps = 100; return r }()},
+		"zero rate":            {func() FeeRuleConfig { r := percentage; r.RateBps = 0; return r }()},
+		"rate above 100%":      {func() FeeRuleConfig { r := percentage; r.RateBps = 10001; return r }()},
+		"min fee above max":    {func() FeeRuleConfig { r := percentage; r.MinFee, r.MaxFee = "5.00", "1.00"; return r }()},
+		"inverted band":        {func() FeeRuleConfig { r := fixed; r.MinAmount, r.MaxAmount = "100.00", "10.00"; return r }()},
+		"negative free tier":   {func() FeeRuleConfig { r := fixed; r.FreePerMonth = -1; return r }()},
+		"overlapping bands": {
+			func() FeeRuleConfig { r := fixed; r.MaxAmount = "100.00"; return r }(),
+			func() FeeRuleConfig { r := fixed; r.ID = "large"; r.MinAmount = "100.00"; return r }(),
+		},
+		"unbounded band overlaps": {
+			fixed,
+			func() FeeRuleConfig { r := fixed; r.ID = "large"; r.MinAmount = "1000.00"; return r }(),
+		},
+	}
+	for name, rules := range invalid {
+		if _, err := NewFeeSchedule(rules); !errors.Is(err, ErrInvalidFeeRule) {
+			t.Errorf("%s: expected ErrInvalidFeeRule, got %v", name, err)
+		}
+	}
+
+	// Adjacent bands, and the same band in another currency or for another type, are fine
+	valid := []FeeRuleConfig{
+		func() FeeRuleConfig { r := fixed; r.MaxAmount = "100.00"; return r }(),
+		func() FeeRuleConfig { r := fixed; r.ID = "large"; r.MinAmount = "100.01"; return r }(),
+		func() FeeRuleConfig { r := fixed; r.ID = "eur"; r.Currency = "EUR"; return r }(),
+		percentage,
+	}
+	if _, err := NewFeeSchedule(valid); err != nil {
+		t.Errorf("Expected a valid schedule, got %v", err)
+	}
+}
+
+// TestFeeCalculation tests percentage rounding, fee caps and inclusive band edges
+func TestFeeCalculation(t *testing.T) {
+	schedule, err := NewFeeSchedule([]FeeRuleConfig{
+		{ID: "small", TransactionType: TransactionTypeTransfer, Currency: "USD", MaxAmount: "100.00", Kind: FeeKindFixed, FixedFee: "0.25"},
+		{ID: "large", TransactionType: TransactionTypeTransfer, Currency: "USD", MinAmount: "100.01", Kind: FeeKindPercentage, RateBps: 150, MinFee: "2.00", MaxFee: "20.00"},
+		{ID: "withdrawal", TransactionType: TransactionTypeWithdrawal, Currency: "USD", Kind: FeeKindPercentage, RateBps: 150},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build fee schedule: %v", err)
+	}
+
+	tests := []struct {
+		transactionType string
+		amount          int64
+		rule            string
+		fee             int64
+	}{
+		{TransactionTypeTransfer, 1, "small", 25},
+		{TransactionTypeTransfer, 10000, "small", 25},     // Upper edge is inclusive
+		{TransactionTypeTransfer, 10001, "large", 200},    // 1.50 raised to the 2.00 floor
+		{TransactionTypeTransfer, 50000, "large", 750},    // 1.5% of 500.00
+		{TransactionTypeTransfer, 500000, "large", 2000},  // 75.00 capped at 20.00
+		{TransactionTypeWithdrawal, 333, "withdrawal", 5}, // 4.995 rounds half up
+		{TransactionTypeWithdrawal, 100, "withdrawal", 2}, // 1.5 rounds half up
+		{TransactionTypeWithdrawal, 33, "withdrawal", 0},  // 0.495 rounds down to nothing
+		{TransactionTypeDeposit, 10000, "", 0},            // No rule: free
+	}
+	for _, tt := range tests {
+		rule := schedule.match(tt.transactionType, "USD", tt.amount)
+		if tt.rule == "" {
+			if rule != nil {
+				t.Errorf("%s %d: expected no rule, got %s", tt.transactionType, tt.amount, rule.ID)
+			}
+			continue
+		}
+		if rule == nil || rule.ID != tt.rule {
+			t.Errorf("%s %d: expected rule %s, got %+v", tt.transactionType, tt.amount, tt.rule, rule)
+			continue
+		}
+		if fee, err := rule.fee(tt.amount); err != nil || fee != tt.fee {
+			t.Errorf("%s %d: expected fee %d, got %d (%v)", tt.transactionType, tt.amount, tt.fee, fee, err)
+		}
+	}
+	if rule := schedule.match(TransactionTypeTransfer, "EUR", 10000); rule != nil {
+		t.Errorf("Expected no rule for EUR, got %s", rule.ID)
+	}
+}
+
+// TestFeeLegs tests that postings charge the scheduled fee to the fee account
+func TestFeeLegs(t *testing.T) {
+	schedule, err := NewFeeSchedule([]FeeRuleConfig{
+		{ID: "transfer", TransactionType: TransactionTypeTransfer, Currency: "USD", Kind: FeeKindFixed, FixedFee: "0.30"},
+		{ID: "withdrawal", TransactionType: TransactionTypeWithdrawal, Currency: "USD", Kind: FeeKindPercentage, RateBps: 100, MinFee: "1.00"},
+		{ID: "deposit", TransactionType: TransactionTypeDeposit, Currency: "USD", Kind: FeeKindFixed, FixedFee: "2.00"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build fee schedule: %v", err)
+	}
+	service := NewService(newTestRepository(t), WithFees(schedule))
+	openWallets(t, service, "USD", "alice", "bob")
+
+	quote, err := service.QuoteFee(TransactionTypeDeposit, "alice", "USD", 10000)
+	if err != nil || quote.Fee != 200 || quote.Total != 9800 || quote.RuleID != "deposit" {
+		t.Fatalf("Expected a 2.00 deposit fee netting 98.00, got %+v (%v)", quote, err)
+	}
+	if _, err := service.QuoteFee(TransactionTypeReversal, "alice", "USD", 10000); err != ErrNotFeeable {
+		t.Errorf("Expected ErrNotFeeable, got %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 200, Source: "bank"}); err != ErrFeeExceedsAmount {
+		t.Errorf("Expected a deposit no larger than its fee to fail, got %v", err)
+	}
+
+	depositID, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"})
+	if err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	transferID, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 2000})
+	if err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+	withdrawalID, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 5000})
+	if err != nil {
+		t.Fatalf("Failed to record withdrawal: %v", err)
+	}
+
+	// The fee can't be covered: 98.00 - 20.30 - 51.00 leaves 26.70, short of 26.70 + 1.00
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 2670}); err != ErrInsufficientBalance {
+		t.Errorf("Expected ErrInsufficientBalance when only the fee is short, got %v", err)
+	}
+
+	for _, transactionID := range []string{depositID, transferID, withdrawalID} {
+		entries, _ := service.GetTransactionDetails(transactionID)
+		if len(entries) != 3 || entries[2].TransactionType != TransactionTypeFee || entries[2].AccountID != FeeAccountID("USD") {
+			t.Errorf("%s: expected a third entry crediting the fee account, got %+v", transactionID, entries)
+		}
+		if err := service.VerifyTransaction(transactionID); err != nil {
+			t.Errorf("%s: expected a balanced transaction, got %v", transactionID, err)
+		}
+	}
+	for accountID, want := range map[string]int64{"alice": 2670, "bob": 2000, FeeAccountID("USD"): 330} {
+		if balance, _ := service.GetBalance(accountID); balance.Balance != want {
+			t.Errorf("%s: expected %d, got %d", accountID, want, balance.Balance)
+		}
+	}
+}
+
+// TestFreeTier tests that the first transactions each month are free and quotes don't use the allowance
+func TestFreeTier(t *testing.T) {
+	schedule, err := NewFeeSchedule([]FeeRuleConfig{
+		{ID: "transfer", TransactionType: TransactionTypeTransfer, Currency: "USD", Kind: FeeKindFixed, FixedFee: "0.50", FreePerMonth: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to build fee schedule: %v", err)
+	}
+	service := NewService(newTestRepository(t), WithFees(schedule))
+	openWallets(t, service, "USD", "alice", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	for i, wantRemaining := range []int{1, 0} {
+		quote, err := service.QuoteFee(TransactionTypeTransfer, "alice", "USD", 1000)
+		if err != nil || quote.Fee != 0 || quote.FreeRemaining != wantRemaining {
+			t.Fatalf("Transfer %d: expected a free transfer with %d left, got %+v (%v)", i+1, wantRemaining, quote, err)
+		}
+		if _, err := service.QuoteFee(TransactionTypeTransfer, "alice", "USD", 1000); err != nil {
+			t.Fatalf("Failed to quote again: %v", err)
+		}
+		if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 1000}); err != nil {
+			t.Fatalf("Failed to record transfer: %v", err)
+		}
+	}
+
+	// Incoming transfers don't use bob's allowance
+	if quote, _ := service.QuoteFee(TransactionTypeTransfer, "bob", "USD", 1000); quote.Fee != 0 || quote.FreeRemaining != 1 {
+		t.Errorf("Expected bob's whole allowance to be left, got %+v", quote)
+	}
+
+	quote, err := service.QuoteFee(TransactionTypeTransfer, "alice", "USD", 1000)
+	if err != nil || quote.Fee != 50 || quote.Total != 1050 || quote.FreeRemaining != 0 {
+		t.Fatalf("Expected the third transfer to be charged, got %+v (%v)", quote, err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 1000}); err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+	if alice, _ := service.GetBalance("alice"); alice.Balance != 6950 {
+		t.Errorf("Expected alice to pay one fee, got balance %d", alice.Balance)
+	}
+}
+
+// TestLoadFeeSchedule tests loading rules from a JSON file
+func TestLoadFeeSchedule(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "fees.json")
+	os.WriteFile(valid, []byte(`[
+		{"id": "transfer-usd", "transaction_type": "TRANSFER", "currency": "USD", "kind": "PERCENTAGE", "rate_bps": 150, "min_fee": "0.25", "max_fee": "5.00", "free_per_month": 3},
+		{"id": "withdrawal-eur", "transaction_type": "WITHDRAWAL", "currency": "EUR", "max_amount": "1000.00", "kind": "FIXED", "fixed_fee": "1.50"}
+	]`), 0o600)
+	schedule, err := LoadFeeSchedule(valid)
+	if err != nil {
+		t.Fatalf("Failed to load fee schedule: %v", err)
+	}
+	if rules := schedule.Rules(); len(rules) != 2 || rules[0].FreePerMonth != 3 {
+		t.Errorf("Expected two rules as configured, got %+v", rules)
+	}
+	if rule := schedule.match(TransactionTypeWithdrawal, "EUR", 100000); rule == nil || rule.FixedFee != 150 {
+		t.Errorf("Expected the EUR withdrawal rule in cents, got %+v", rule)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"id": "transfer-usd", "transaction_type": "TRANSFER", "currency": "USD", "kind": "PERCENTAGE"}]`), 0o600)
+	if _, err := LoadFeeSchedule(invalid); !errors.Is(err, ErrInvalidFeeRule) {
+		t.Errorf("Expected a rule without a rate to be rejected, got %v", err)
+	}
+	if _, err := LoadFeeSchedule(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,362 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrFXNotConfigured = errors.New("currency conversion is not configured")
+	ErrRateUnavailable = errors.New("no exchange rate available for this currency pair")
+	ErrSameCurrency    = errors.New("conversion requires two different currencies")
+	ErrQuoteNotFound   = errors.New("conversion quote not found")
+	ErrQuoteExpired    = errors.New("conversion quote has expired")
+	ErrQuoteUsed       = errors.New("conversion quote has already been executed")
+)
+
+// RateProvider supplies mid-market exchange rates
+type RateProvider interface {
+	// Rate returns how many units of quote currency one unit of base currency buys
+	Rate(base, quote string) (*big.Rat, error)
+}
+
+// DefaultRates are indicative rates for local development
+var DefaultRates = map[string]string{
+	"EUR/USD": "1.08",
+	"GBP/USD": "1.27",
+	"EUR/GBP": "0.85",
+}
+
+// StaticRateProvider serves a fixed table of rates keyed by "BASE/QUOTE"
+// Inverse pairs are derived, so "EUR/GBP" also prices GBP to EUR
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider parses a table of decimal rates such as {"EUR/GBP": "0.85"}
+func NewStaticRateProvider(rates map[string]string) (*StaticRateProvider, error) {
+	parsed := make(map[string]*big.Rat, len(rates))
+	for pair, value := range rates {
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, pair)
+		}
+		parsed[pair] = rate
+	}
+	return &StaticRateProvider{rates: parsed}, nil
+}
+
+// NewFileRateProvider loads a rate table from a JSON file such as {"EUR/GBP": "0.85"}
+func NewFileRateProvider(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rates file: %w", err)
+	}
+
+	var rates map[string]string
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("error parsing rates file: %w", err)
+	}
+	return NewStaticRateProvider(rates)
+}
+
+// Rate implements RateProvider
+func (p *StaticRateProvider) Rate(base, quote string) (*big.Rat, error) {
+	if rate, ok := p.rates[base+"/"+quote]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := p.rates[quote+"/"+base]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, ErrRateUnavailable
+}
+
+// FXConfig configures currency conversions
+type FXConfig struct {
+	SpreadBps int64         // Spread taken from the mid rate, in basis points (50 = 0.5%)
+	QuoteTTL  time.Duration // How long a quote can be executed after it is issued
+}
+
+// fxDesk holds conversion pricing and the quotes issued but not yet expired
+type fxDesk struct {
+	rates  RateProvider
+	config FXConfig
+
+	mu     sync.Mutex
+	quotes map[string]*FXQuote
+}
+
+// WithFX enables currency conversions priced by the given rate provider
+func WithFX(rates RateProvider, config FXConfig) Option {
+	return func(s *Service) {
+		s.fx = &fxDesk{rates: rates, config: config, quotes: make(map[string]*FXQuote)}
+	}
+}
+
+// ConversionRequest represents a request to convert money between accounts in different currencies
+type ConversionRequest struct {
+	FromAccountID string
+	ToAccountID   string
+	FromCurrency  string
+	ToCurrency    string
+	Amount        int64 // Amount debited from the sender, in FromCurrency cents
+	Description   string
+}
+
+// QuoteConversion prices a conversion; the quote can be executed with ExecuteConversion until it expires
+func (s *Service) QuoteConversion(req *ConversionRequest) (*FXQuote, error) {
+	if s.fx == nil {
+		return nil, ErrFXNotConfigured
+	}
+	if req.FromAccountID == "" || req.ToAccountID == "" {
+		return nil, ErrMissingAccountID
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return nil, ErrSameAccountTransfer
+	}
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if !currency.IsSupported(req.FromCurrency) || !currency.IsSupported(req.ToCurrency) {
+		return nil, ErrUnsupportedCurrency
+	}
+	if req.FromCurrency == req.ToCurrency {
+		return nil, ErrSameCurrency
+	}
+
+	mid, err := s.fx.rates.Rate(req.FromCurrency, req.ToCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	// Customer rate = mid * (1 - spread); both legs round down to whole cents
+	customerRate := new(big.Rat).Mul(mid, big.NewRat(10000-s.fx.config.SpreadBps, 10000))
+	grossTarget, err := convertMinorUnits(req.Amount, req.FromCurrency, req.ToCurrency, mid)
+	if err != nil {
+		return nil, err
+	}
+	target, err := convertMinorUnits(req.Amount, req.FromCurrency, req.ToCurrency, customerRate)
+	if err != nil {
+		return nil, err
+	}
+	if target <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	now := time.Now()
+	quote := &FXQuote{
+		ID:            uuid.New().String(),
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		FromCurrency:  req.FromCurrency,
+		ToCurrency:    req.ToCurrency,
+		SourceAmount:  req.Amount,
+		TargetAmount:  target,
+		SpreadAmount:  grossTarget - target,
+		MidRate:       mid.FloatString(8),
+		CustomerRate:  customerRate.FloatString(8),
+		Description:   req.Description,
+		CreatedAt:     now.Unix(),
+		ExpiresAt:     now.Add(s.fx.config.QuoteTTL).Unix(),
+	}
+
+	s.fx.mu.Lock()
+	defer s.fx.mu.Unlock()
+	for id, q := range s.fx.quotes {
+		if q.ExpiresAt <= now.Unix() {
+			delete(s.fx.quotes, id)
+		}
+	}
+	stored := *quote
+	s.fx.quotes[quote.ID] = &stored
+
+	log.Printf("Conversion quoted: %s %d %s -> %d %s (rate %s, spread %d), expires %d",
+		quote.ID, quote.SourceAmount, quote.FromCurrency, quote.TargetAmount, quote.ToCurrency,
+		quote.CustomerRate, quote.SpreadAmount, quote.ExpiresAt)
+	return quote, nil
+}
+
+// GetConversionQuote retrieves an issued quote that has not expired yet
+func (s *Service) GetConversionQuote(quoteID string) (*FXQuote, error) {
+	if s.fx == nil {
+		return nil, ErrFXNotConfigured
+	}
+
+	s.fx.mu.Lock()
+	defer s.fx.mu.Unlock()
+	quote, exists := s.fx.quotes[quoteID]
+	if !exists {
+		return nil, ErrQuoteNotFound
+	}
+	result := *quote
+	return &result, nil
+}
+
+// ExecuteConversion posts a quoted conversion at the quoted amounts
+// The sender's leg goes into the FX position account of the source currency, and the recipient
+// is paid out of the FX position account of the target currency, with the spread kept as revenue
+func (s *Service) ExecuteConversion(quoteID string) (string, error) {
+	quote, err := s.GetConversionQuote(quoteID)
+	if err != nil {
+		return "", err
+	}
+	if quote.ExecutedTransactionID != "" {
+		return "", ErrQuoteUsed
+	}
+	if quote.ExpiresAt <= time.Now().Unix() {
+		return "", ErrQuoteExpired
+	}
+
+	unlock := s.locks.Lock(quote.FromAccountID, quote.ToAccountID)
+	defer unlock()
+
+	if err := s.checkFunds(quote.FromAccountID, quote.FromCurrency, quote.SourceAmount); err != nil {
+		return "", err
+	}
+	if err := s.checkCurrency(quote.ToAccountID, quote.ToCurrency); err != nil {
+		return "", err
+	}
+	// A conversion pays another wallet, so the source leg counts against the sender's transfer limits
+	now := time.Now().Unix()
+	if err := s.checkLimits(TransactionTypeTransfer, quote.FromAccountID, quote.FromCurrency, quote.SourceAmount, now); err != nil {
+		return "", err
+	}
+
+	sourcePosition, err := s.systemAccount(AccountTypeFXPosition, quote.FromCurrency, "")
+	if err != nil {
+		return "", err
+	}
+	targetPosition, err := s.systemAccount(AccountTypeFXPosition, quote.ToCurrency, "")
+	if err != nil {
+		return "", err
+	}
+	revenueAccountID, err := s.systemAccount(AccountTypeFXRevenue, quote.ToCurrency, "")
+	if err != nil {
+		return "", err
+	}
+
+	// The quote ID is the transaction ID, so a quote can only ever be posted once
+	transactionID := quote.ID
+	grossTarget := quote.TargetAmount + quote.SpreadAmount
+
+	entries := []*LedgerEntry{
+		// Source currency leg: sender -> FX position
+		{
+			ID:              uuid.New().String(),
+			AccountID:       quote.FromAccountID,
+			AccountType:     AccountTypeUserWallet,
+			Amount:          -quote.SourceAmount,
+			Currency:        quote.FromCurrency,
+			EntryType:       EntryTypeDebit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeConversion,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Conversion to %s at %s: %s", quote.ToAccountID, quote.CustomerRate, quote.Description),
+		},
+		{
+			ID:              uuid.New().String(),
+			AccountID:       sourcePosition,
+			AccountType:     AccountTypeFXPosition,
+			Amount:          quote.SourceAmount,
+			Currency:        quote.FromCurrency,
+			EntryType:       EntryTypeCredit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeConversion,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Conversion %s bought from %s", quote.FromCurrency, quote.FromAccountID),
+		},
+		// Target currency leg: FX position -> recipient (+ spread to revenue)
+		{
+			ID:              uuid.New().String(),
+			AccountID:       targetPosition,
+			AccountType:     AccountTypeFXPosition,
+			Amount:          -grossTarget,
+			Currency:        quote.ToCurrency,
+			EntryType:       EntryTypeDebit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeConversion,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Conversion %s sold to %s", quote.ToCurrency, quote.ToAccountID),
+		},
+		{
+			ID:              uuid.New().String(),
+			AccountID:       quote.ToAccountID,
+			AccountType:     AccountTypeUserWallet,
+			Amount:          quote.TargetAmount,
+			Currency:        quote.ToCurrency,
+			EntryType:       EntryTypeCredit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeConversion,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Conversion from %s at %s: %s", quote.FromAccountID, quote.CustomerRate, quote.Description),
+		},
+	}
+	if quote.SpreadAmount > 0 {
+		entries = append(entries, &LedgerEntry{
+			ID:              uuid.New().String(),
+			AccountID:       revenueAccountID,
+			AccountType:     AccountTypeFXRevenue,
+			Amount:          quote.SpreadAmount,
+			Currency:        quote.ToCurrency,
+			EntryType:       EntryTypeCredit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeConversion,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("FX spread on conversion from %s", quote.FromAccountID),
+		})
+	}
+
+	if err := s.repo.CreateEntries(entries); err != nil {
+		if err == ErrDuplicateTransaction {
+			return "", ErrQuoteUsed
+		}
+		log.Printf("Error creating conversion entries: %v", err)
+		return "", err
+	}
+
+	s.fx.mu.Lock()
+	if stored, exists := s.fx.quotes[quote.ID]; exists {
+		stored.ExecutedTransactionID = transactionID
+	}
+	s.fx.mu.Unlock()
+
+	log.Printf("Conversion recorded: %s -> %s, %d %s -> %d %s, txn: %s",
+		quote.FromAccountID, quote.ToAccountID, quote.SourceAmount, quote.FromCurrency,
+		quote.TargetAmount, quote.ToCurrency, transactionID)
+
+	return transactionID, nil
+}
+
+// convertMinorUnits converts an amount between currencies whose minor units may differ in size
+// The result is rounded down so the customer is never credited more than the rate gives
+func convertMinorUnits(amount int64, from, to string, rate *big.Rat) (int64, error) {
+	fromExponent, err := currency.Exponent(from)
+	if err != nil {
+		return 0, err
+	}
+	toExponent, err := currency.Exponent(to)
+	if err != nil {
+		return 0, err
+	}
+
+	scale := new(big.Rat).SetFrac(
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(toExponent)), nil),
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(fromExponent)), nil),
+	)
+	converted := new(big.Rat).Mul(big.NewRat(amount, 1), rate)
+	return currency.Round(converted.Mul(converted, scale), currency.RoundDown)
+}
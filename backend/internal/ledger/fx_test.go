@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"testing"
+	"time"
+)
+
+func newFXTestService(t *testing.T, ttl time.Duration) *Service {
+	t.Helper()
+	rates, err := NewStaticRateProvider(map[string]string{"EUR/GBP": "0.85"})
+	if err != nil {
+		t.Fatalf("Failed to create rate provider: %v", err)
+	}
+	service := NewService(newTestRepository(t), WithFX(rates, FXConfig{SpreadBps: 100, QuoteTTL: ttl}))
+	openWallets(t, service, currency.CurrencyEUR, "alice-eur", "dave-eur", "erin-eur")
+	openWallets(t, service, currency.CurrencyGBP, "bob-gbp")
+	openWallets(t, service, currency.CurrencyUSD, "carol-usd")
+	return service
+}
+
+// TestConversionQuoteAndExecute converts EUR to GBP and checks every leg lands in the right currency
+func TestConversionQuoteAndExecute(t *testing.T) {
+	service := newFXTestService(t, time.Minute)
+
+	if _, err := service.RecordDeposit(&DepositRequest{
+		AccountID: "alice-eur", Amount: 10000, Currency: currency.CurrencyEUR, Source: "external_bank",
+	}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	quote, err := service.QuoteConversion(&ConversionRequest{
+		FromAccountID: "alice-eur",
+		ToAccountID:   "bob-gbp",
+		FromCurrency:  currency.CurrencyEUR,
+		ToCurrency:    currency.CurrencyGBP,
+		Amount:        10000,
+	})
+	if err != nil {
+		t.Fatalf("Failed to quote conversion: %v", err)
+	}
+	// 100.00 EUR at 0.85 = 85.00 GBP, less 1% spread = 84.15 GBP
+	if quote.TargetAmount != 8415 || quote.SpreadAmount != 85 {
+		t.Fatalf("Expected target 8415 and spread 85, got %d and %d", quote.TargetAmount, quote.SpreadAmount)
+	}
+
+	transactionID, err := service.ExecuteConversion(quote.ID)
+	if err != nil {
+		t.Fatalf("Failed to execute conversion: %v", err)
+	}
+	if err := service.VerifyTransaction(transactionID); err != nil {
+		t.Errorf("Conversion does not balance: %v", err)
+	}
+
+	expected := map[string]struct {
+		balance  int64
+		currency string
+	}{
+		"alice-eur": {0, currency.CurrencyEUR},
+		"bob-gbp":   {8415, currency.CurrencyGBP},
+		FXPositionAccountID(currency.CurrencyEUR): {10000, currency.CurrencyEUR},
+		FXPositionAccountID(currency.CurrencyGBP): {-8500, currency.CurrencyGBP},
+		FXRevenueAccountID(currency.CurrencyGBP):  {85, currency.CurrencyGBP},
+	}
+	for accountID, want := range expected {
+		balance, err := service.GetBalance(accountID)
+		if err != nil {
+			t.Fatalf("Failed to get balance for %s: %v", accountID, err)
+		}
+		if balance.Balance != want.balance || balance.Currency != want.currency {
+			t.Errorf("%s: expected %d %s, got %d %s", accountID, want.balance, want.currency, balance.Balance, balance.Currency)
+		}
+	}
+
+	if _, err := service.ExecuteConversion(quote.ID); err != ErrQuoteUsed {
+		t.Errorf("Expected ErrQuoteUsed on second execution, got %v", err)
+	}
+}
+
+// TestConversionQuoteErrors covers expiry, unknown pairs and insufficient funds
+func TestConversionQuoteErrors(t *testing.T) {
+	service := newFXTestService(t, -time.Second)
+
+	if _, err := service.RecordDeposit(&DepositRequest{
+		AccountID: "alice-eur", Amount: 1000, Currency: currency.CurrencyEUR, Source: "external_bank",
+	}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	quote, err := service.QuoteConversion(&ConversionRequest{
+		FromAccountID: "alice-eur", ToAccountID: "bob-gbp",
+		FromCurrency: currency.CurrencyEUR, ToCurrency: currency.CurrencyGBP, Amount: 1000,
+	})
+	if err != nil {
+		t.Fatalf("Failed to quote conversion: %v", err)
+	}
+	if _, err := service.ExecuteConversion(quote.ID); err != ErrQuoteExpired {
+		t.Errorf("Expected ErrQuoteExpired, got %v", err)
+	}
+
+	if _, err := service.QuoteConversion(&ConversionRequest{
+		FromAccountID: "alice-eur", ToAccountID: "carol-usd",
+		FromCurrency: currency.CurrencyEUR, ToCurrency: currency.CurrencyUSD, Amount: 1000,
+	}); err != ErrRateUnavailable {
+		t.Errorf("Expected ErrRateUnavailable, got %v", err)
+	}
+
+	if _, err := service.QuoteConversion(&ConversionRequest{
+		FromAccountID: "alice-eur", ToAccountID: "dave-eur",
+		FromCurrency: currency.CurrencyEUR, ToCurrency: currency.CurrencyEUR, Amount: 1000,
+	}); err != ErrSameCurrency {
+		t.Errorf("Expected ErrSameCurrency, got %v", err)
+	}
+
+	service = newFXTestService(t, time.Minute)
+	quote, err = service.QuoteConversion(&ConversionRequest{
+		FromAccountID: "erin-eur", ToAccountID: "bob-gbp",
+		FromCurrency: currency.CurrencyEUR, ToCurrency: currency.CurrencyGBP, Amount: 1000,
+	})
+	if err != nil {
+		t.Fatalf("Failed to quote conversion: %v", err)
+	}
+	if _, err := service.ExecuteConversion(quote.ID); err != ErrInsufficientBalance {
+		t.Errorf("Expected ErrInsufficientBalance, got %v", err)
+	}
+}
+
+// TestStaticRateProviderInverse derives the reverse pair from the configured one
+func TestStaticRateProviderInverse(t *testing.T) {
+	rates, err := NewStaticRateProvider(map[string]string{"EUR/GBP": "0.8"})
+	if err != nil {
+		t.Fatalf("Failed to create rate provider: %v", err)
+	}
+	rate, err := rates.Rate(currency.CurrencyGBP, currency.CurrencyEUR)
+	if err != nil {
+		t.Fatalf("Failed to get inverse rate: %v", err)
+	}
+	if rate.FloatString(2) != "1.25" {
+		t.Errorf("Expected inverse rate 1.25, got %s", rate.FloatString(2))
+	}
+
+	if _, err := NewStaticRateProvider(map[string]string{"EUR/GBP": "-1"}); err == nil {
+		t.Error("Expected an error for a negative rate")
+	}
+}
@@ -1,22 +1,656 @@
 package ledger
 
 import (
+	"bytes"
+	"digitalwallet/backend/internal/wallet"
+	"digitalwallet/backend/pkg"
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// WalletService resolves wallets so money can only be moved out of the caller's own wallet
+type WalletService interface {
+	GetWalletByID(walletID string) (*wallet.Wallet, error)
+	GetWalletByUserID(userID string) (*wallet.Wallet, error)
+}
+
+// ApprovalQueue holds customer withdrawals above the approval threshold until an admin approves them
+type ApprovalQueue interface {
+	QueueWithdrawal(withdrawal WithdrawalRequest, requestedBy string) (string, error)
+}
+
 type Handler struct {
-	service *Service
+	service       *Service
+	walletService WalletService
+	approvals     ApprovalQueue // Optional: without it withdrawals needing approval are refused
+}
+
+func NewHandler(service *Service, walletService WalletService, approvals ApprovalQueue) *Handler {
+	return &Handler{service: service, walletService: walletService, approvals: approvals}
+}
+
+// Deposit credits the caller's wallet from an external source
+// POST /api/ledger/deposits
+func (h *Handler) Deposit(c *gin.Context) {
+	var req DepositRequestDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to DepositRequestDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c, req.Currency)
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency so its minor unit precision applies
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transactionID, err := h.service.RecordDeposit(&DepositRequest{
+		AccountID:   callerWallet.ID,
+		Amount:      amount.Amount(),
+		Currency:    callerWallet.Currency,
+		Source:      req.Source,
+		Description: req.Description,
+	})
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	h.writePostingResult(c, "Deposit recorded successfully", transactionID, callerWallet.ID)
+}
+
+// Withdraw debits the caller's wallet to an external destination
+// POST /api/ledger/withdrawals
+func (h *Handler) Withdraw(c *gin.Context) {
+	var req WithdrawalRequestDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to WithdrawalRequestDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c, req.Currency)
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency so its minor unit precision applies
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	withdrawal := WithdrawalRequest{
+		AccountID:   callerWallet.ID,
+		Amount:      amount.Amount(),
+		Currency:    callerWallet.Currency,
+		Destination: req.Destination,
+		Description: req.Description,
+	}
+	// Large withdrawals wait for an admin instead of being refused; the approval posts them
+	if h.approvals != nil && h.service.NeedsApproval(withdrawal.Amount, withdrawal.Currency) {
+		approvalID, err := h.approvals.QueueWithdrawal(withdrawal, c.GetString("userId"))
+		if err != nil {
+			log.Printf("Error queueing withdrawal from %s for approval: %v", callerWallet.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":     "Withdrawal is waiting for approval",
+			"approval_id": approvalID,
+		})
+		return
+	}
+
+	transactionID, err := h.service.RecordWithdrawal(&withdrawal)
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	h.writePostingResult(c, "Withdrawal recorded successfully", transactionID, callerWallet.ID)
+}
+
+// Transfer moves money from the caller's wallet to another wallet
+// POST /api/ledger/transfers
+func (h *Handler) Transfer(c *gin.Context) {
+	var req TransferRequestDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to TransferRequestDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if req.ToWalletID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: to_wallet_id"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c, req.Currency)
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency so its minor unit precision applies
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recipientWallet, err := h.walletService.GetWalletByID(req.ToWalletID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient wallet not found"})
+			return
+		}
+		log.Printf("Error getting recipient wallet %s: %v", req.ToWalletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if recipientWallet.Currency != callerWallet.Currency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Recipient wallet holds a different currency"})
+		return
+	}
+
+	transactionID, err := h.service.RecordTransfer(&TransferRequest{
+		FromAccountID: callerWallet.ID,
+		ToAccountID:   req.ToWalletID,
+		Amount:        amount.Amount(),
+		Currency:      callerWallet.Currency,
+		Description:   req.Description,
+	})
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	h.writePostingResult(c, "Transfer recorded successfully", transactionID, callerWallet.ID)
+}
+
+// QuoteConversion prices a conversion from the caller's wallet into a wallet holding another currency
+// POST /api/ledger/conversions/quotes
+func (h *Handler) QuoteConversion(c *gin.Context) {
+	var req ConversionQuoteRequestDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to ConversionQuoteRequestDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if req.ToWalletID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: to_wallet_id"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c, "")
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency so its minor unit precision applies
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recipientWallet, err := h.walletService.GetWalletByID(req.ToWalletID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient wallet not found"})
+			return
+		}
+		log.Printf("Error getting recipient wallet %s: %v", req.ToWalletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	quote, err := h.service.QuoteConversion(&ConversionRequest{
+		FromAccountID: callerWallet.ID,
+		ToAccountID:   recipientWallet.ID,
+		FromCurrency:  callerWallet.Currency,
+		ToCurrency:    recipientWallet.Currency,
+		Amount:        amount.Amount(),
+		Description:   req.Description,
+	})
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"quote": quote.ToDTO()})
+}
+
+// ExecuteConversion posts a previously quoted conversion
+// POST /api/ledger/conversions
+func (h *Handler) ExecuteConversion(c *gin.Context) {
+	var req ConversionRequestDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to ConversionRequestDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if req.QuoteID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required field: quote_id"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c, "")
+	if !ok {
+		return
+	}
+
+	// Quotes issued to other users are reported as missing
+	quote, err := h.service.GetConversionQuote(req.QuoteID)
+	if err == nil && quote.FromAccountID != callerWallet.ID {
+		err = ErrQuoteNotFound
+	}
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	transactionID, err := h.service.ExecuteConversion(quote.ID)
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	h.writePostingResult(c, "Conversion recorded successfully", transactionID, callerWallet.ID)
+}
+
+// CreateBatch validates a batch of transfers from the caller's wallet and posts it in the background
+// POST /api/ledger/batches: a JSON body, or a multipart upload with mode, currency and description
+// fields and a CSV file with to_wallet_id, amount and optional description columns
+// Poll GET /api/ledger/batches/:batchId for the outcome
+func (h *Handler) CreateBatch(c *gin.Context) {
+	req, ok := bindBatchRequest(c)
+	if !ok {
+		return
+	}
+	callerWallet, ok := h.callerWallet(c, req.Currency)
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency; items that don't parse are reported with the rest
+	items := make([]BatchItemRequest, len(req.Items))
+	parseErrors := make(map[int]string)
+	for i, item := range req.Items {
+		amount, err := currency.Parse(item.Amount, callerWallet.Currency)
+		if err != nil {
+			parseErrors[i] = err.Error()
+		}
+		items[i] = BatchItemRequest{ToAccountID: item.ToWalletID, Amount: amount.Amount(), Description: item.Description}
+	}
+
+	batch, err := h.service.CreateBatch(&BatchRequest{
+		FromAccountID: callerWallet.ID,
+		Currency:      callerWallet.Currency,
+		Mode:          req.Mode,
+		Description:   req.Description,
+		Items:         items,
+		CreatedBy:     c.GetString("userId"),
+	})
+	var invalid *BatchValidationError
+	if errors.As(err, &invalid) {
+		for i, item := range invalid.Items {
+			if message, unparsed := parseErrors[item.Index]; unparsed {
+				invalid.Items[i].Error = message
+			}
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidBatch.Error(), "items": invalid.Items})
+		return
+	}
+	if errors.Is(err, ErrInvalidBatch) || err == ErrInvalidBatchMode {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	go func() {
+		if _, err := h.service.ProcessBatch(batch.ID); err != nil {
+			log.Printf("Error processing batch %s: %v", batch.ID, err)
+		}
+	}()
+	c.JSON(http.StatusAccepted, batch.ToDTO())
+}
+
+// GetBatch returns the status of one of the caller's batches and of each of its items
+// GET /api/ledger/batches/:batchId
+func (h *Handler) GetBatch(c *gin.Context) {
+	callerWallet, ok := h.callerWallet(c, "")
+	if !ok {
+		return
+	}
+
+	batch, err := h.service.GetBatch(c.Param("batchId"))
+	// Other wallets' batches are reported as missing rather than forbidden
+	if err == ErrBatchNotFound || (err == nil && batch.FromAccountID != callerWallet.ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting batch %s: %v", c.Param("batchId"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, batch.ToDTO())
+}
+
+// bindBatchRequest reads a batch from a JSON body or a multipart CSV upload, writing the error response if it can't
+func bindBatchRequest(c *gin.Context) (*BatchRequestDTO, bool) {
+	if c.ContentType() != "multipart/form-data" {
+		var req BatchRequestDTO
+		if err := c.BindJSON(&req); err != nil {
+			log.Println("Error: binding the request payload to BatchRequestDTO:", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return nil, false
+		}
+		return &req, true
+	}
+
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required CSV file: file"})
+		return nil, false
+	}
+	file, err := upload.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded batch file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	defer file.Close()
+
+	items, err := parseBatchCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &BatchRequestDTO{
+		Mode:        c.PostForm("mode"),
+		Currency:    c.PostForm("currency"),
+		Description: c.PostForm("description"),
+		Items:       items,
+	}, true
+}
+
+// QuoteFee prices the fee on a transfer, withdrawal or deposit from the caller's wallet before it is posted
+// POST /api/ledger/fees/quote
+func (h *Handler) QuoteFee(c *gin.Context) {
+	var req FeeQuoteRequestDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to FeeQuoteRequestDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c, "")
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency so its minor unit precision applies
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quote, err := h.service.QuoteFee(req.TransactionType, callerWallet.ID, callerWallet.Currency, amount.Amount())
+	if err != nil {
+		h.writePostingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote.ToDTO()})
+}
+
+// GetFeeSchedule lists the configured fee rules
+// GET /api/ledger/fees/schedule
+func (h *Handler) GetFeeSchedule(c *gin.Context) {
+	rules := h.service.FeeSchedule()
+	if rules == nil {
+		rules = []FeeRuleConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules, "count": len(rules)})
+}
+
+// GetMyLimits reports the limits on the caller's wallet and how much of each is left
+// GET /api/ledger/limits
+func (h *Handler) GetMyLimits(c *gin.Context) {
+	callerWallet, ok := h.callerWallet(c, "")
+	if !ok {
+		return
+	}
+	h.writeLimits(c, callerWallet.ID)
+}
+
+// GetAccountLimits reports an account's KYC level, overrides and remaining allowances (admin, or the wallet's owner)
+// GET /api/ledger/accounts/:accountId/limits
+func (h *Handler) GetAccountLimits(c *gin.Context) {
+	if !h.canReadAccount(c, c.Param("accountId")) {
+		return
+	}
+	h.writeLimits(c, c.Param("accountId"))
+}
+
+// SetAccountLimits sets an account's KYC level and replaces its limit overrides (admin)
+// PUT /api/ledger/accounts/:accountId/limits
+func (h *Handler) SetAccountLimits(c *gin.Context) {
+	accountID := c.Param("accountId")
+
+	var req AccountLimitsRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to the AccountLimitsRequestDTO struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	account, err := h.service.GetAccount(accountID)
+	if err != nil {
+		h.writeLimitsError(c, accountID, err)
+		return
+	}
+
+	// Amount limits are parsed in the account's currency so its minor unit precision applies
+	overrides := make([]Limit, 0, len(req.Overrides))
+	for _, override := range req.Overrides {
+		limit := Limit{Kind: override.Kind, TransactionType: override.TransactionType}
+		if override.Kind == LimitHourlyCount {
+			limit.Value, err = strconv.ParseInt(override.Value, 10, 64)
+		} else {
+			var amount currency.Money
+			amount, err = currency.Parse(override.Value, account.Currency)
+			limit.Value = amount.Amount()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid value %q for %s %s", override.Value, override.TransactionType, override.Kind)})
+			return
+		}
+		overrides = append(overrides, limit)
+	}
+
+	if _, err := h.service.SetAccountLimits(accountID, req.KYCLevel, overrides, c.GetString("userId")); err != nil {
+		h.writeLimitsError(c, accountID, err)
+		return
+	}
+	h.writeLimits(c, accountID)
+}
+
+// writeLimits responds with the account's KYC level and every limit that applies to it
+func (h *Handler) writeLimits(c *gin.Context, accountID string) {
+	limits, err := h.service.GetAccountLimits(accountID)
+	if err != nil {
+		h.writeLimitsError(c, accountID, err)
+		return
+	}
+	usages, err := h.service.GetLimitUsage(accountID)
+	if err != nil {
+		h.writeLimitsError(c, accountID, err)
+		return
+	}
+
+	usageDTOs := make([]*LimitUsageDTO, len(usages))
+	for i, usage := range usages {
+		usageDTOs[i] = usage.ToDTO()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"account_id": accountID,
+		"kyc_level":  limits.KYCLevel,
+		"limits":     usageDTOs,
+		"updated_by": limits.UpdatedBy,
+		"updated_at": limits.UpdatedAt,
+	})
+}
+
+// writeLimitsError maps limit configuration errors to HTTP status codes
+func (h *Handler) writeLimitsError(c *gin.Context, accountID string, err error) {
+	switch {
+	case err == ErrAccountNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err == ErrUnknownKYCLevel, errors.Is(err, ErrInvalidLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err == ErrLimitsNotConfigured:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error handling limits for %s: %v", accountID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+// canReadAccount lets admins read any account and other users only their own wallet, writing 403 otherwise
+func (h *Handler) canReadAccount(c *gin.Context, accountID string) bool {
+	if c.GetBool("isAdmin") {
+		return true
+	}
+	callerWallet, ok := h.callerWallet(c, "")
+	if !ok {
+		return false
+	}
+	if callerWallet.ID != accountID {
+		log.Printf("Error: User %s may not read account %s", c.GetString("userId"), accountID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		return false
+	}
+	return true
+}
+
+// touchesAccount reports whether any of the entries posts to accountID
+func touchesAccount(entries []*LedgerEntry, accountID string) bool {
+	for _, entry := range entries {
+		if entry.AccountID == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// callerWallet resolves the authenticated user's wallet, writing the error response if it can't
+// A non-empty requestCurrency must match the wallet's currency
+func (h *Handler) callerWallet(c *gin.Context, requestCurrency string) (*wallet.Wallet, bool) {
+	userID := c.GetString("userId")
+	if userID == "" {
+		log.Println("Error: User is not set in the user context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	callerWallet, err := h.walletService.GetWalletByUserID(userID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+			return nil, false
+		}
+		log.Printf("Error getting wallet for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+
+	if requestCurrency != "" && requestCurrency != callerWallet.Currency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Currency does not match wallet currency " + callerWallet.Currency})
+		return nil, false
+	}
+	return callerWallet, true
 }
 
-func NewHandler(service *Service) *Handler {
-	return &Handler{service: service}
+// writePostingError maps ledger posting errors to HTTP status codes
+// Limit errors name the limit and the remaining allowance
+func (h *Handler) writePostingError(c *gin.Context, err error) {
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": ErrLimitExceeded.Error(), "limit": limitErr.ToDTO()})
+		return
+	}
+
+	switch err {
+	case ErrInsufficientBalance:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Insufficient balance"})
+	case ErrInvalidAmount, ErrMissingAccountID, ErrSameAccountTransfer, ErrUnsupportedCurrency, ErrCurrencyMismatch:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrFeeExceedsAmount, ErrNotFeeable:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrSameCurrency, ErrRateUnavailable:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrQuoteNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrDuplicateTransaction, ErrQuoteUsed:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case ErrQuoteExpired:
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case ErrFXNotConfigured, ErrNoSystemAccount:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case ErrAccountNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case ErrAccountClosed, ErrAccountFrozen:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case ErrAccountNotPostable, ErrAccountTypeMismatch:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case ErrApprovalRequired, ErrSelfApproval, ErrApprovalMismatch:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Println("Error recording posting:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
+
+// writePostingResult responds with the transaction ID and the caller's new balance
+func (h *Handler) writePostingResult(c *gin.Context, message, transactionID, walletID string) {
+	balance, err := h.service.GetBalance(walletID)
+	if err != nil {
+		log.Printf("Error getting balance for wallet %s: %v", walletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        message,
+		"transaction_id": transactionID,
+		"balance":        balance.ToDTO(),
+	})
 }
 
-// GetBalance retrieves the current balance for an account
-// GET /api/ledger/balance/:accountId
+// GetBalance retrieves the current balance for an account, or its balance at a past time with as_of
+// GET /api/ledger/balance/:accountId?as_of= (Unix seconds or an RFC 3339 time)
 func (h *Handler) GetBalance(c *gin.Context) {
 	accountID := c.Param("accountId")
 	if accountID == "" {
@@ -25,6 +659,15 @@ func (h *Handler) GetBalance(c *gin.Context) {
 		return
 	}
 
+	if !h.canReadAccount(c, accountID) {
+		return
+	}
+
+	if asOf := c.Query("as_of"); asOf != "" {
+		h.getBalanceAt(c, accountID, asOf)
+		return
+	}
+
 	balance, err := h.service.GetBalance(accountID)
 	if err != nil {
 		if err == ErrAccountBalanceNotFound {
@@ -43,8 +686,35 @@ func (h *Handler) GetBalance(c *gin.Context) {
 	})
 }
 
-// GetStatement retrieves all ledger entries for an account (account statement)
-// GET /api/ledger/statement/:accountId
+// getBalanceAt answers GetBalance with ?as_of=, the balance at a past point in time
+func (h *Handler) getBalanceAt(c *gin.Context, accountID, asOf string) {
+	at, err := parseStatementTime(asOf)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid as_of: expected Unix seconds or an RFC 3339 time"})
+		return
+	}
+
+	balance, err := h.service.GetBalanceAt(accountID, time.Unix(at, 0))
+	if err != nil {
+		if err == ErrAccountBalanceNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		log.Printf("Error getting balance for account %s as of %d: %v", accountID, at, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account_id": balance.AccountID,
+		"balance":    balance.ToDTO(),
+	})
+}
+
+// GetStatement retrieves a page of an account's entries with running balances
+// GET /api/ledger/statement/:accountId?from=&to=&type=&min_amount=&max_amount=&q=&cursor=&limit=
+// from/to are Unix seconds or RFC 3339 times; amounts are decimal strings in the account currency
+// Users can only read their own wallet's statement; admins can read any account's
 func (h *Handler) GetStatement(c *gin.Context) {
 	accountID := c.Param("accountId")
 	if accountID == "" {
@@ -52,29 +722,140 @@ func (h *Handler) GetStatement(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameter: accountId"})
 		return
 	}
+	if !h.canReadAccount(c, accountID) {
+		return
+	}
 
-	entries, err := h.service.GetAccountStatement(accountID)
+	balance, err := h.service.GetBalance(accountID)
 	if err != nil {
-		log.Printf("Error getting statement for account %s: %v", accountID, err)
+		if err == ErrAccountBalanceNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		log.Printf("Error getting balance for account %s: %v", accountID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
 
-	// Convert entries to DTOs for user-friendly response
-	entryDTOs := make([]*LedgerEntryDTO, len(entries))
-	for i, entry := range entries {
-		entryDTOs[i] = entry.ToDTO()
+	query := &StatementQuery{
+		AccountID:       accountID,
+		TransactionType: strings.ToUpper(c.Query("type")),
+		Search:          c.Query("q"),
+		Cursor:          c.Query("cursor"),
+	}
+	if query.From, err = parseStatementTime(c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: expected Unix seconds or an RFC 3339 time"})
+		return
+	}
+	if query.To, err = parseStatementTime(c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: expected Unix seconds or an RFC 3339 time"})
+		return
+	}
+	for param, target := range map[string]*int64{"min_amount": &query.MinAmount, "max_amount": &query.MaxAmount} {
+		if value := c.Query(param); value != "" {
+			amount, err := currency.Parse(value, balance.Currency)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ": " + err.Error()})
+				return
+			}
+			*target = amount.Amount()
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: expected a positive integer"})
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"account_id": accountID,
-		"entries":    entryDTOs,
-		"count":      len(entryDTOs),
-	})
+	statement, err := h.service.GetStatement(query)
+	if err != nil {
+		switch err {
+		case ErrInvalidCursor, ErrInvalidAmount:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error getting statement for account %s: %v", accountID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, statement.ToDTO())
+}
+
+// ExportStatement downloads an account's statement for a period as CSV, OFX or camt.053 XML
+// Users can only export their own wallet; admins can export any account
+// GET /api/ledger/statement/:accountId/export?format=csv|ofx|camt053&from=&to=
+func (h *Handler) ExportStatement(c *gin.Context) {
+	accountID := c.Param("accountId")
+	if accountID == "" {
+		log.Println("Error: Missing required parameter (accountId)")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameter: accountId"})
+		return
+	}
+	if !h.canReadAccount(c, accountID) {
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", ExportFormatCSV))
+	contentType, extension, err := ExportContentType(format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	from, err := parseStatementTime(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: expected Unix seconds or an RFC 3339 time"})
+		return
+	}
+	to, err := parseStatementTime(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: expected Unix seconds or an RFC 3339 time"})
+		return
+	}
+
+	export, err := h.service.ExportStatement(accountID, from, to, time.Now())
+	if err != nil {
+		if err == ErrAccountBalanceNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		log.Printf("Error exporting statement for account %s: %v", accountID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Render fully before writing so a failure can still be reported as an error response
+	var body bytes.Buffer
+	if err := RenderStatement(&body, format, export); err != nil {
+		log.Printf("Error rendering %s statement for account %s: %v", format, accountID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%d-%d.%s", accountID, export.From, export.To, extension)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// parseStatementTime accepts Unix seconds or an RFC 3339 time; empty means unbounded
+func parseStatementTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
 }
 
 // GetTransactionDetails retrieves all ledger entries for a specific transaction
 // GET /api/ledger/transaction/:transactionId
+// Admins can read any transaction; users only those with an entry on their own wallet
 func (h *Handler) GetTransactionDetails(c *gin.Context) {
 	transactionID := c.Param("transactionId")
 	if transactionID == "" {
@@ -94,6 +875,26 @@ func (h *Handler) GetTransactionDetails(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
 		return
 	}
+	// Users only see transactions that touch their own wallet; others look like they don't exist
+	if !c.GetBool("isAdmin") {
+		callerWallet, ok := h.callerWallet(c, "")
+		if !ok {
+			return
+		}
+		if !touchesAccount(entries, callerWallet.ID) {
+			log.Printf("Error: User %s may not read transaction %s", c.GetString("userId"), transactionID)
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+	}
+
+	// Reversals and refunds of this transaction
+	linkedIDs, err := h.service.GetLinkedTransactionIDs(transactionID)
+	if err != nil {
+		log.Printf("Error getting linked transactions for %s: %v", transactionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
 
 	// Convert entries to DTOs
 	entryDTOs := make([]*LedgerEntryDTO, len(entries))
@@ -101,11 +902,16 @@ func (h *Handler) GetTransactionDetails(c *gin.Context) {
 		entryDTOs[i] = entry.ToDTO()
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"transaction_id": transactionID,
-		"entries":        entryDTOs,
-		"count":          len(entryDTOs),
-	})
+	response := gin.H{
+		"transaction_id":         transactionID,
+		"entries":                entryDTOs,
+		"count":                  len(entryDTOs),
+		"linked_transaction_ids": linkedIDs,
+	}
+	if reference := entries[0].ReferenceTransactionID; reference != "" {
+		response["reference_transaction_id"] = reference
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // VerifyAccountBalance verifies that cached balance matches calculated balance
@@ -176,3 +982,284 @@ func (h *Handler) VerifyTransaction(c *gin.Context) {
 		"message":        "Transaction verified successfully",
 	})
 }
+
+// VerifyChain walks the hash chain over every entry and reports the first break
+// POST /api/ledger/verify/chain
+func (h *Handler) VerifyChain(c *gin.Context) {
+	report, err := h.service.VerifyChain()
+	if err != nil {
+		log.Printf("Error verifying the ledger hash chain: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if !report.Valid {
+		log.Printf("WARNING: Ledger hash chain broken at entry %s (seq %d): %s",
+			report.FirstBreak.EntryID, report.FirstBreak.Sequence, report.FirstBreak.Reason)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportDailyRoot returns the signed chain head at the end of a day, for anchoring outside the ledger
+// GET /api/ledger/verify/chain/root?date=YYYY-MM-DD
+func (h *Handler) ExportDailyRoot(c *gin.Context) {
+	day, err := time.Parse(time.DateOnly, c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date: expected YYYY-MM-DD"})
+		return
+	}
+
+	root, err := h.service.ExportDailyRoot(day)
+	if err != nil {
+		switch err {
+		case ErrDayNotEnded:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case ErrSigningNotConfigured:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error exporting root hash for %s: %v", c.Query("date"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, root)
+}
+
+// RunAudit audits the whole ledger now and returns the report, including the trial balance
+// POST /api/ledger/audit
+func (h *Handler) RunAudit(c *gin.Context) {
+	report, err := h.service.RunAudit(time.Now())
+	if err != nil {
+		log.Printf("Error auditing the ledger: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if !report.Passed {
+		log.Printf("WARNING: Ledger audit %s raised %d alerts", report.ID, len(report.Alerts))
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetLatestAuditReport returns the report of the most recent audit run
+// GET /api/ledger/audit/latest
+func (h *Handler) GetLatestAuditReport(c *gin.Context) {
+	report, err := h.service.GetLatestAuditReport()
+	if err != nil {
+		if err == ErrAuditReportNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting the latest audit report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAuditAlerts lists the alerts raised by audit runs, newest first
+// GET /api/ledger/audit/alerts?limit=
+func (h *Handler) GetAuditAlerts(c *gin.Context) {
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: expected a positive integer"})
+			return
+		}
+	}
+
+	alerts, err := h.service.GetAuditAlerts(limit)
+	if err != nil {
+		log.Printf("Error getting audit alerts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// GetTrialBalance totals the current cached balances by account type and currency
+// GET /api/ledger/trial-balance
+func (h *Handler) GetTrialBalance(c *gin.Context) {
+	trial, err := h.service.TrialBalance(time.Now())
+	if err != nil {
+		log.Printf("Error building the trial balance: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, trial)
+}
+
+// RebuildBalances recomputes every cached balance from the entry log
+// POST /api/ledger/balances/rebuild?mode=dry-run|repair
+// Dry runs (the default) only report the differences; repairs overwrite them and keep an audit record
+func (h *Handler) RebuildBalances(c *gin.Context) {
+	var dryRun bool
+	switch c.DefaultQuery("mode", "dry-run") {
+	case "dry-run":
+		dryRun = true
+	case "repair":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mode: expected dry-run or repair"})
+		return
+	}
+
+	rebuild, err := h.service.RebuildBalances(c.GetString("userId"), dryRun, time.Now())
+	if err != nil {
+		log.Printf("Error rebuilding balances: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, rebuild)
+}
+
+// GetBalanceRebuilds lists past balance repairs and what they changed, newest first
+// GET /api/ledger/balances/rebuilds?limit=
+func (h *Handler) GetBalanceRebuilds(c *gin.Context) {
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: expected a positive integer"})
+			return
+		}
+	}
+
+	rebuilds, err := h.service.GetBalanceRebuilds(limit)
+	if err != nil {
+		log.Printf("Error getting balance rebuilds: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rebuilds": rebuilds})
+}
+
+// ListAccounts returns the chart of accounts
+// GET /api/ledger/accounts
+func (h *Handler) ListAccounts(c *gin.Context) {
+	accounts, err := h.service.ListAccounts()
+	if err != nil {
+		log.Printf("Error listing ledger accounts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// GetAccount returns one account of the chart
+// GET /api/ledger/accounts/:accountId
+func (h *Handler) GetAccount(c *gin.Context) {
+	account, err := h.service.GetAccount(c.Param("accountId"))
+	if err != nil {
+		if err == ErrAccountNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting ledger account %s: %v", c.Param("accountId"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// OpenAccount registers a system or group account, e.g. a funding account for a new deposit source
+// POST /api/ledger/accounts
+// Wallet accounts are opened together with their wallet
+func (h *Handler) OpenAccount(c *gin.Context) {
+	var account Account
+	if err := c.ShouldBindJSON(&account); err != nil {
+		log.Println("Error: binding the request payload to the Account struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if account.Type == AccountTypeUserWallet && !account.Group {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Wallet accounts are opened by creating a wallet"})
+		return
+	}
+
+	if err := h.service.OpenAccount(&account); err != nil {
+		switch {
+		case errors.Is(err, ErrAccountExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidAccount), err == ErrMissingAccountID, err == ErrUnsupportedCurrency:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error opening ledger account %s: %v", account.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, account)
+}
+
+// ChangeAccountStatus freezes, unfreezes or closes a system account
+// POST /api/ledger/accounts/:accountId/status
+// Wallet accounts change status through the wallet endpoints, which keep the wallet in step
+func (h *Handler) ChangeAccountStatus(c *gin.Context) {
+	accountID := c.Param("accountId")
+
+	var req AccountStatusRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to the AccountStatusRequestDTO struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	account, err := h.service.GetAccount(accountID)
+	if err != nil {
+		if err == ErrAccountNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting ledger account %s: %v", accountID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if account.Type == AccountTypeUserWallet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Wallet accounts change status through /wallets/:walletID"})
+		return
+	}
+
+	changedBy := c.GetString("userId")
+	var change *AccountStatusChange
+	switch req.Status {
+	case AccountStatusFrozen:
+		change, err = h.service.FreezeAccount(accountID, req.Reason, changedBy, req.AllowCredits)
+	case AccountStatusOpen:
+		change, err = h.service.UnfreezeAccount(accountID, req.Reason, changedBy)
+	case AccountStatusClosed:
+		change, err = h.service.CloseAccount(accountID, req.Reason, changedBy, req.SweepToAccountID)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be OPEN, FROZEN or CLOSED"})
+		return
+	}
+	if err != nil {
+		switch {
+		case err == ErrMissingStatusReason, errors.Is(err, ErrInvalidSweepAccount), err == ErrSameAccountTransfer:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidStatusTransition), err == ErrAccountClosed, err == ErrAccountNotEmpty, err == ErrStatusChanged:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Error changing status of ledger account %s: %v", accountID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, change)
+}
+
+// GetAccountStatusChanges returns an account's freezes, unfreezes and closure, oldest first
+// GET /api/ledger/accounts/:accountId/status-history
+func (h *Handler) GetAccountStatusChanges(c *gin.Context) {
+	changes, err := h.service.GetAccountStatusChanges(c.Param("accountId"))
+	if err != nil {
+		if err == ErrAccountNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error getting status history of %s: %v", c.Param("accountId"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
@@ -0,0 +1,374 @@
+package ledger
+
+import (
+	"bytes"
+	"digitalwallet/backend/internal/wallet"
+	"digitalwallet/backend/pkg"
+	"digitalwallet/backend/pkg/currency"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestHandler wires a handler to fresh in-memory services and a router whose
+// authentication is replaced by an X-Test-User header (and X-Test-Admin: true for admins)
+func newTestHandler(t *testing.T, opts ...Option) (*gin.Engine, *Service, *wallet.Service) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	service := NewService(newTestRepository(t), opts...)
+	walletService := wallet.NewService(wallet.NewRepository(), service)
+	handler := NewHandler(service, walletService, nil)
+
+	authenticate := func(c *gin.Context) {
+		c.Set("userId", c.GetHeader("X-Test-User"))
+		c.Set("isAdmin", c.GetHeader("X-Test-Admin") == "true")
+		c.Next()
+	}
+	r := gin.New()
+	r.POST("/api/ledger/deposits", authenticate, handler.Deposit)
+	r.POST("/api/ledger/withdrawals", authenticate, handler.Withdraw)
+	r.POST("/api/ledger/transfers", authenticate, handler.Transfer)
+	r.GET("/api/ledger/balance/:accountId", authenticate, handler.GetBalance)
+	r.GET("/api/ledger/transaction/:transactionId", authenticate, handler.GetTransactionDetails)
+	r.GET("/api/ledger/statement/:accountId", authenticate, handler.GetStatement)
+	r.GET("/api/ledger/statement/:accountId/export", authenticate, handler.ExportStatement)
+	r.GET("/api/ledger/limits", authenticate, handler.GetMyLimits)
+	r.GET("/api/ledger/accounts/:accountId/limits", authenticate, handler.GetAccountLimits)
+	r.PUT("/api/ledger/accounts/:accountId/limits", authenticate, handler.SetAccountLimits)
+	r.POST("/api/ledger/batches", authenticate, handler.CreateBatch)
+	r.GET("/api/ledger/batches/:batchId", authenticate, handler.GetBatch)
+	return r, service, walletService
+}
+
+func doPost(r *gin.Engine, path, user, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("X-Test-User", user)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestPostingEndpoints tests deposits, withdrawals and transfers through HTTP
+func TestPostingEndpoints(t *testing.T) {
+	r, service, walletService := newTestHandler(t)
+
+	aliceWalletID, _ := walletService.CreateWallet("alice", "")
+	bobWalletID, _ := walletService.CreateWallet("bob", "")
+
+	// Deposit $100.29 into Alice's wallet: the decimal string must be parsed exactly
+	w := doPost(r, "/api/ledger/deposits", "alice", `{"amount": "100.29", "source": "bank"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var result struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.TransactionID == "" {
+		t.Errorf("Expected a transaction ID in %s", w.Body)
+	}
+
+	// Transfer $50 to Bob
+	w = doPost(r, "/api/ledger/transfers", "alice", `{"to_wallet_id": "`+bobWalletID+`", "amount": "50.00"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	// Withdraw $10 to an external bank
+	w = doPost(r, "/api/ledger/withdrawals", "alice", `{"amount": "10", "destination": "bank"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	// Amounts are serialized as exact decimal strings
+	var withdrawal struct {
+		Balance AccountBalanceDTO `json:"balance"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &withdrawal); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", w.Body, err)
+	}
+	if withdrawal.Balance.Balance != "40.29" {
+		t.Errorf("Expected balance \"40.29\" in the response, got %q", withdrawal.Balance.Balance)
+	}
+
+	aliceBalance, _ := service.GetBalance(aliceWalletID)
+	if aliceBalance.Balance != 4029 {
+		t.Errorf("Expected Alice's balance to be 4029, got %d", aliceBalance.Balance)
+	}
+	bobBalance, _ := service.GetBalance(bobWalletID)
+	if bobBalance.Balance != 5000 {
+		t.Errorf("Expected Bob's balance to be 5000, got %d", bobBalance.Balance)
+	}
+	t.Logf("✓ Alice: %d, Bob: %d", aliceBalance.Balance, bobBalance.Balance)
+}
+
+// TestPostingEndpointErrors tests that posting failures map to the right status codes
+func TestPostingEndpointErrors(t *testing.T) {
+	r, _, walletService := newTestHandler(t)
+
+	aliceWalletID, _ := walletService.CreateWallet("alice", "")
+	eurWalletID, _ := walletService.CreateWallet("joao", currency.CurrencyEUR)
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		body     string
+		expected int
+	}{
+		{"insufficient balance", "/api/ledger/withdrawals", "alice", `{"amount": "10.00"}`, http.StatusUnprocessableEntity},
+		{"float amount", "/api/ledger/deposits", "alice", `{"amount": 10.5}`, http.StatusBadRequest},
+		{"too many decimals", "/api/ledger/deposits", "alice", `{"amount": "10.505"}`, http.StatusBadRequest},
+		{"negative amount", "/api/ledger/deposits", "alice", `{"amount": "-10.00"}`, http.StatusBadRequest},
+		{"zero amount", "/api/ledger/withdrawals", "alice", `{"amount": "0"}`, http.StatusBadRequest},
+		{"no wallet", "/api/ledger/deposits", "carol", `{"amount": "10.00"}`, http.StatusNotFound},
+		{"unknown recipient", "/api/ledger/transfers", "alice", `{"to_wallet_id": "nope", "amount": "1.00"}`, http.StatusNotFound},
+		{"transfer to self", "/api/ledger/transfers", "alice", `{"to_wallet_id": "` + aliceWalletID + `", "amount": "1.00"}`, http.StatusBadRequest},
+		{"missing recipient", "/api/ledger/transfers", "alice", `{"amount": "1.00"}`, http.StatusBadRequest},
+		{"currency mismatch", "/api/ledger/deposits", "alice", `{"amount": "1.00", "currency": "EUR"}`, http.StatusBadRequest},
+		{"recipient in other currency", "/api/ledger/transfers", "alice", `{"to_wallet_id": "` + eurWalletID + `", "amount": "1.00"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doPost(r, tt.path, tt.user, tt.body)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d, got %d: %s", tt.expected, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+// TestWalletStatusWorkflow tests that wallet status changes reach the ledger and show on the wallet
+func TestWalletStatusWorkflow(t *testing.T) {
+	r, service, walletService := newTestHandler(t)
+
+	aliceWalletID, _ := walletService.CreateWallet("alice", "")
+	bobWalletID, _ := walletService.CreateWallet("bob", "")
+	if w := doPost(r, "/api/ledger/deposits", "alice", `{"amount": "25.00", "source": "bank"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	if _, err := walletService.FreezeWallet(aliceWalletID, "", "compliance", false); err != pkg.ErrMissingStatusReason {
+		t.Errorf("Expected ErrMissingStatusReason, got %v", err)
+	}
+	frozen, err := walletService.FreezeWallet(aliceWalletID, "Chargeback investigation", "compliance", true)
+	if err != nil {
+		t.Fatalf("Failed to freeze wallet: %v", err)
+	}
+	if frozen.Status != wallet.StatusFrozen || !frozen.AllowCredits || frozen.StatusReason != "Chargeback investigation" {
+		t.Errorf("Expected the wallet to show the freeze, got %+v", frozen)
+	}
+
+	if w := doPost(r, "/api/ledger/transfers", "alice", `{"to_wallet_id": "`+bobWalletID+`", "amount": "5.00"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a transfer out of a frozen wallet, got %d: %s", w.Code, w.Body)
+	}
+	if w := doPost(r, "/api/ledger/deposits", "alice", `{"amount": "5.00", "source": "bank"}`); w.Code != http.StatusCreated {
+		t.Errorf("Expected 201 for a deposit into a wallet frozen with credits allowed, got %d: %s", w.Code, w.Body)
+	}
+
+	if _, _, err := walletService.CloseWallet(aliceWalletID, "Account terminated", "compliance", ""); err != pkg.ErrWalletNotEmpty {
+		t.Errorf("Expected ErrWalletNotEmpty, got %v", err)
+	}
+	if _, _, err := walletService.CloseWallet(aliceWalletID, "Account terminated", "compliance", bobWalletID); err != pkg.ErrInvalidSweepAccount {
+		t.Errorf("Expected sweeping into another user's wallet to fail, got %v", err)
+	}
+	closed, sweepID, err := walletService.CloseWallet(aliceWalletID, "Account terminated", "compliance", ExternalBankAccountID("USD"))
+	if err != nil {
+		t.Fatalf("Failed to close wallet: %v", err)
+	}
+	if closed.Status != wallet.StatusClosed || closed.AllowCredits || sweepID == "" {
+		t.Errorf("Expected a closed wallet and a sweep, got %+v (%q)", closed, sweepID)
+	}
+	if balance, _ := service.GetBalance(aliceWalletID); balance.Balance != 0 {
+		t.Errorf("Expected the 3000 left to be paid out, got %d", balance.Balance)
+	}
+	if _, err := walletService.UnfreezeWallet(aliceWalletID, "Reopen", "compliance"); !errors.Is(err, pkg.ErrInvalidWalletStatus) {
+		t.Errorf("Expected ErrInvalidWalletStatus, got %v", err)
+	}
+}
+
+// TestLimitEndpoints tests that a limit breach names the limit, and that admins can raise it
+func TestLimitEndpoints(t *testing.T) {
+	r, _, walletService := newTestHandler(t, WithLimits(DefaultLimitTiers()))
+	aliceWalletID, _ := walletService.CreateWallet("alice", "")
+
+	if w := doPost(r, "/api/ledger/deposits", "alice", `{"amount": "1000.00", "source": "bank"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	w := doPost(r, "/api/ledger/withdrawals", "alice", `{"amount": "250.00", "destination": "bank"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422, got %d: %s", w.Code, w.Body)
+	}
+	var breach struct {
+		Limit LimitUsageDTO `json:"limit"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &breach)
+	if breach.Limit.Kind != LimitPerTransaction || breach.Limit.TransactionType != TransactionTypeWithdrawal || breach.Limit.Remaining != "200.00" {
+		t.Errorf("Expected the withdrawal limit with 200.00 remaining, got %+v", breach.Limit)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/ledger/accounts/"+aliceWalletID+"/limits", strings.NewReader(
+		`{"kyc_level": "BASIC", "overrides": [{"kind": "DAILY", "transaction_type": "WITHDRAWAL", "value": "300.00"}]}`))
+	req.Header.Set("X-Test-User", "compliance")
+	req.Header.Set("X-Test-Admin", "true")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	// Only admins and the wallet's owner can read its limit settings
+	walletService.CreateWallet("bob", "")
+	for user, want := range map[string]int{"alice": http.StatusOK, "bob": http.StatusForbidden, "compliance": http.StatusOK} {
+		req = httptest.NewRequest(http.MethodGet, "/api/ledger/accounts/"+aliceWalletID+"/limits", nil)
+		req.Header.Set("X-Test-User", user)
+		req.Header.Set("X-Test-Admin", strconv.FormatBool(user == "compliance"))
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d reading alice's limits, got %d: %s", user, want, w.Code, w.Body)
+		}
+	}
+
+	if w := doPost(r, "/api/ledger/withdrawals", "alice", `{"amount": "250.00", "destination": "bank"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected the raised limit to allow the withdrawal, got %d: %s", w.Code, w.Body)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/ledger/limits", nil)
+	req.Header.Set("X-Test-User", "alice")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var limits struct {
+		KYCLevel string           `json:"kyc_level"`
+		Limits   []*LimitUsageDTO `json:"limits"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &limits)
+	if limits.KYCLevel != KYCLevelBasic {
+		t.Errorf("Expected KYC level BASIC, got %s", w.Body)
+	}
+	for _, limit := range limits.Limits {
+		if limit.Kind == LimitDaily && limit.TransactionType == TransactionTypeWithdrawal {
+			if !limit.Overridden || limit.Used != "250.00" || limit.Remaining != "50.00" {
+				t.Errorf("Expected the overridden daily withdrawal limit to have 50.00 left, got %+v", limit)
+			}
+		}
+	}
+}
+
+// TestAccountHistoryAccess tests that balances, statements, their exports and transactions are only shown to the owner and admins
+func TestAccountHistoryAccess(t *testing.T) {
+	r, _, walletService := newTestHandler(t)
+	aliceWalletID, _ := walletService.CreateWallet("alice", "")
+	walletService.CreateWallet("bob", "")
+	w := doPost(r, "/api/ledger/deposits", "alice", `{"amount": "10.00", "source": "bank"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var deposit struct {
+		TransactionID string `json:"transaction_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &deposit); err != nil || deposit.TransactionID == "" {
+		t.Fatalf("Expected a transaction ID, got %s", w.Body)
+	}
+
+	paths := map[string]int{
+		"/balance/" + aliceWalletID: http.StatusForbidden,
+		"/balance/" + aliceWalletID + "?as_of=" + strconv.FormatInt(time.Now().Unix(), 10): http.StatusForbidden,
+		"/statement/" + aliceWalletID:                        http.StatusForbidden,
+		"/statement/" + aliceWalletID + "/export?format=csv": http.StatusForbidden,
+		"/transaction/" + deposit.TransactionID:              http.StatusNotFound,
+	}
+	for path, denied := range paths {
+		for user, want := range map[string]int{"alice": http.StatusOK, "bob": denied, "compliance": http.StatusOK} {
+			req := httptest.NewRequest(http.MethodGet, "/api/ledger"+path, nil)
+			req.Header.Set("X-Test-User", user)
+			req.Header.Set("X-Test-Admin", strconv.FormatBool(user == "compliance"))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s %s: expected %d, got %d: %s", user, path, want, w.Code, w.Body)
+			}
+		}
+	}
+}
+
+// TestBatchEndpoints tests per-item validation errors, a CSV upload and polling the batch until it is done
+func TestBatchEndpoints(t *testing.T) {
+	r, _, walletService := newTestHandler(t)
+	walletService.CreateWallet("employer", "")
+	aliceWalletID, _ := walletService.CreateWallet("alice", "")
+	bobWalletID, _ := walletService.CreateWallet("bob", "")
+	if w := doPost(r, "/api/ledger/deposits", "employer", `{"amount": "1000.00", "source": "bank"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	// Every bad item is reported at once
+	w := doPost(r, "/api/ledger/batches", "employer", `{"mode": "BEST_EFFORT", "items": [
+		{"to_wallet_id": "`+aliceWalletID+`", "amount": "1.001"},
+		{"to_wallet_id": "`+bobWalletID+`", "amount": "10.00"},
+		{"to_wallet_id": "nobody", "amount": "10.00"}]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %s", w.Code, w.Body)
+	}
+	var invalid struct {
+		Items []BatchItemError `json:"items"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &invalid)
+	if len(invalid.Items) != 2 || invalid.Items[0].Index != 0 || invalid.Items[0].Error == ErrInvalidAmount.Error() || invalid.Items[1].Index != 2 {
+		t.Errorf("Expected the unparseable amount and the unknown wallet to be reported, got %s", w.Body)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("mode", BatchModeAllOrNothing)
+	form.WriteField("description", "March payroll")
+	file, _ := form.CreateFormFile("file", "payroll.csv")
+	file.Write([]byte("to_wallet_id,amount\n" + aliceWalletID + ",600.00\n" + bobWalletID + ",250.50\n"))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/ledger/batches", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Test-User", "employer")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var batch BatchDTO
+	json.Unmarshal(w.Body.Bytes(), &batch)
+	if batch.TotalAmount != "850.50" || len(batch.Items) != 2 {
+		t.Fatalf("Expected a batch of 850.50 over 2 items, got %s", w.Body)
+	}
+
+	getBatch := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/ledger/batches/"+batch.ID, nil)
+		req.Header.Set("X-Test-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for deadline := time.Now().Add(5 * time.Second); batch.Status == BatchStatusPending || batch.Status == BatchStatusProcessing; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Batch still %s after 5s", batch.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		json.Unmarshal(getBatch("employer").Body.Bytes(), &batch)
+	}
+	if batch.Status != BatchStatusCompleted || batch.Items[1].Description != "March payroll" {
+		t.Errorf("Expected the batch to complete, got %+v", batch)
+	}
+
+	if w := getBatch("alice"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected other users' batches to be hidden, got %d", w.Code)
+	}
+}
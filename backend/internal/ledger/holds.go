@@ -0,0 +1,268 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrHoldExpired        = errors.New("hold has expired")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+)
+
+// DefaultHoldTTL is how long a hold reserves funds when the request sets no expiry
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+// HoldRequest represents a request to reserve funds on an account
+type HoldRequest struct {
+	AccountID   string
+	Amount      int64  // Amount in cents
+	Currency    string // ISO 4217 code, must match the account (defaults to USD)
+	Destination string // e.g., merchant name, where captured funds go
+	Description string
+	ExpiresAt   int64 // Optional Unix time; defaults to now + DefaultHoldTTL
+}
+
+// PlaceHold reserves funds on an account without posting entries
+// The reserved amount no longer counts towards the available balance until it is captured or released
+func (s *Service) PlaceHold(req *HoldRequest) (*Hold, error) {
+	if req.AccountID == "" {
+		return nil, ErrMissingAccountID
+	}
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	cur, err := resolveCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := req.ExpiresAt
+	if expiresAt == 0 {
+		expiresAt = now.Add(DefaultHoldTTL).Unix()
+	}
+	if expiresAt <= now.Unix() {
+		return nil, ErrHoldExpired
+	}
+
+	unlock := s.locks.Lock(req.AccountID)
+	defer unlock()
+
+	if err := s.checkFunds(req.AccountID, cur, req.Amount); err != nil {
+		return nil, err
+	}
+
+	hold := &Hold{
+		ID:          uuid.New().String(),
+		AccountID:   req.AccountID,
+		Amount:      req.Amount,
+		Currency:    cur,
+		Destination: req.Destination,
+		Description: req.Description,
+		Status:      HoldStatusActive,
+		CreatedAt:   now.Unix(),
+		ExpiresAt:   expiresAt,
+		UpdatedAt:   now.Unix(),
+	}
+	if err := s.repo.CreateHold(hold); err != nil {
+		log.Printf("Error placing hold: %v", err)
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CaptureHold posts amount (at most the held amount) of an active hold and releases the rest
+// The hold ID is used as the transaction ID, so a hold can only be captured once
+func (s *Service) CaptureHold(holdID string, amount int64) (string, error) {
+	if amount <= 0 {
+		return "", ErrInvalidAmount
+	}
+
+	hold, unlock, err := s.lockHold(holdID)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	now := time.Now().Unix()
+	if hold.ExpiresAt <= now {
+		return "", ErrHoldExpired
+	}
+	if amount > hold.Amount {
+		return "", ErrCaptureExceedsHold
+	}
+	// A capture pays out of the platform, so it counts against the account's withdrawal limits
+	if err := s.checkLimits(TransactionTypeWithdrawal, hold.AccountID, hold.Currency, amount, now); err != nil {
+		return "", err
+	}
+
+	poolAccountID, err := s.systemAccount(AccountTypeExternalBank, hold.Currency, "")
+	if err != nil {
+		return "", err
+	}
+
+	transactionID := hold.ID
+
+	entries := []*LedgerEntry{
+		// Debit the account the funds were reserved on
+		{
+			ID:              uuid.New().String(),
+			AccountID:       hold.AccountID,
+			AccountType:     AccountTypeUserWallet,
+			Amount:          -amount,
+			Currency:        hold.Currency,
+			EntryType:       EntryTypeDebit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeCapture,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Payment to %s: %s", hold.Destination, hold.Description),
+		},
+		// Credit the external pool the captured funds settle through
+		{
+			ID:              uuid.New().String(),
+			AccountID:       poolAccountID,
+			AccountType:     AccountTypeExternalBank,
+			Amount:          amount,
+			Currency:        hold.Currency,
+			EntryType:       EntryTypeCredit,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeCapture,
+			CreatedAt:       now,
+			CreatedBy:       "ledger-service",
+			Description:     fmt.Sprintf("Captured hold on %s", hold.AccountID),
+		},
+	}
+
+	hold.Status = HoldStatusCaptured
+	hold.CapturedAmount = amount
+	hold.CaptureTransactionID = transactionID
+	hold.UpdatedAt = now
+	if err := s.repo.CloseHold(hold, entries); err != nil {
+		log.Printf("Error capturing hold %s: %v", holdID, err)
+		return "", err
+	}
+
+	log.Printf("Hold captured: %s, amount: %d of %d cents, txn: %s", hold.ID, amount, hold.Amount, transactionID)
+	return transactionID, nil
+}
+
+// ReleaseHold releases an active hold without posting anything
+func (s *Service) ReleaseHold(holdID string) error {
+	hold, unlock, err := s.lockHold(holdID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.closeHold(hold, HoldStatusReleased)
+}
+
+// GetHold retrieves a hold by ID
+func (s *Service) GetHold(holdID string) (*Hold, error) {
+	return s.repo.GetHold(holdID)
+}
+
+// GetHolds retrieves all holds placed on an account
+func (s *Service) GetHolds(accountID string) ([]*Hold, error) {
+	return s.repo.GetHoldsByAccountID(accountID)
+}
+
+// ReleaseExpiredHolds releases every active hold whose expiry is at or before now
+// It returns how many holds were released
+func (s *Service) ReleaseExpiredHolds(now time.Time) (int, error) {
+	holds, err := s.repo.GetExpiredHolds(now.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	released := 0
+	for _, expired := range holds {
+		hold, unlock, err := s.lockHold(expired.ID)
+		if err != nil {
+			// Captured or released since it was listed
+			if err == ErrHoldNotActive {
+				continue
+			}
+			return released, err
+		}
+
+		err = s.closeHold(hold, HoldStatusExpired)
+		unlock()
+		if err != nil && err != ErrHoldNotActive {
+			return released, err
+		}
+		if err == nil {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// StartHoldSweeper releases expired holds every interval until the returned stop function is called
+func (s *Service) StartHoldSweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				released, err := s.ReleaseExpiredHolds(now)
+				if err != nil {
+					log.Printf("Error releasing expired holds: %v", err)
+				}
+				if released > 0 {
+					log.Printf("Released %d expired holds", released)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// lockHold locks the hold's account and returns the hold as stored once the lock is held
+// It fails with ErrHoldNotActive if the hold is no longer active
+func (s *Service) lockHold(holdID string) (*Hold, func(), error) {
+	hold, err := s.repo.GetHold(holdID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unlock := s.locks.Lock(hold.AccountID)
+
+	// Re-read under the lock: a concurrent capture or release may have closed it
+	hold, err = s.repo.GetHold(holdID)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	if hold.Status != HoldStatusActive {
+		unlock()
+		return nil, nil, ErrHoldNotActive
+	}
+	return hold, unlock, nil
+}
+
+// closeHold releases a hold without posting; callers must hold the account's lock
+func (s *Service) closeHold(hold *Hold, status string) error {
+	hold.Status = status
+	hold.UpdatedAt = time.Now().Unix()
+	if err := s.repo.CloseHold(hold, nil); err != nil {
+		log.Printf("Error closing hold %s: %v", hold.ID, err)
+		return err
+	}
+	log.Printf("Hold %s: %s, %d cents returned to %s", status, hold.ID, hold.Amount, hold.AccountID)
+	return nil
+}
@@ -0,0 +1,115 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+// TestHoldLifecycle tests that holds reduce the available balance and that capture posts only what is captured
+func TestHoldLifecycle(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "alice")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	hold, err := service.PlaceHold(&HoldRequest{AccountID: "alice", Amount: 6000, Destination: "coffee-shop"})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+
+	balance, _ := service.GetBalance("alice")
+	if balance.Balance != 10000 || balance.Available() != 4000 {
+		t.Errorf("Expected ledger 10000 and available 4000, got %d and %d", balance.Balance, balance.Available())
+	}
+
+	// Held funds can't be withdrawn or reserved again
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 5000}); err != ErrInsufficientBalance {
+		t.Errorf("Expected ErrInsufficientBalance for withdrawal, got %v", err)
+	}
+	if _, err := service.PlaceHold(&HoldRequest{AccountID: "alice", Amount: 5000}); err != ErrInsufficientBalance {
+		t.Errorf("Expected ErrInsufficientBalance for second hold, got %v", err)
+	}
+
+	if _, err := service.CaptureHold(hold.ID, 7000); err != ErrCaptureExceedsHold {
+		t.Errorf("Expected ErrCaptureExceedsHold, got %v", err)
+	}
+
+	// Partial capture posts 45.00 and returns the other 15.00
+	transactionID, err := service.CaptureHold(hold.ID, 4500)
+	if err != nil {
+		t.Fatalf("Failed to capture hold: %v", err)
+	}
+	if err := service.VerifyTransaction(transactionID); err != nil {
+		t.Errorf("Capture does not balance: %v", err)
+	}
+
+	balance, _ = service.GetBalance("alice")
+	if balance.Balance != 5500 || balance.Held != 0 || balance.Available() != 5500 {
+		t.Errorf("Expected ledger 5500 with nothing held, got %d (held %d)", balance.Balance, balance.Held)
+	}
+
+	captured, _ := service.GetHold(hold.ID)
+	if captured.Status != HoldStatusCaptured || captured.CapturedAmount != 4500 {
+		t.Errorf("Expected CAPTURED with 4500, got %s with %d", captured.Status, captured.CapturedAmount)
+	}
+
+	if _, err := service.CaptureHold(hold.ID, 100); err != ErrHoldNotActive {
+		t.Errorf("Expected ErrHoldNotActive on second capture, got %v", err)
+	}
+	if err := service.ReleaseHold(hold.ID); err != ErrHoldNotActive {
+		t.Errorf("Expected ErrHoldNotActive on release after capture, got %v", err)
+	}
+}
+
+// TestHoldReleaseAndExpiry tests that released and expired holds return funds without posting
+func TestHoldReleaseAndExpiry(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "bob")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "bob", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	released, err := service.PlaceHold(&HoldRequest{AccountID: "bob", Amount: 3000})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+	expiring, err := service.PlaceHold(&HoldRequest{AccountID: "bob", Amount: 2000, ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+
+	if err := service.ReleaseHold(released.ID); err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+	balance, _ := service.GetBalance("bob")
+	if balance.Available() != 8000 {
+		t.Errorf("Expected available 8000 after release, got %d", balance.Available())
+	}
+
+	// Nothing has expired yet
+	if count, err := service.ReleaseExpiredHolds(time.Now()); err != nil || count != 0 {
+		t.Errorf("Expected no expired holds, got %d (%v)", count, err)
+	}
+
+	count, err := service.ReleaseExpiredHolds(time.Now().Add(time.Hour))
+	if err != nil || count != 1 {
+		t.Fatalf("Expected one expired hold, got %d (%v)", count, err)
+	}
+
+	balance, _ = service.GetBalance("bob")
+	if balance.Balance != 10000 || balance.Available() != 10000 {
+		t.Errorf("Expected all funds available again, got ledger %d available %d", balance.Balance, balance.Available())
+	}
+	hold, _ := service.GetHold(expiring.ID)
+	if hold.Status != HoldStatusExpired {
+		t.Errorf("Expected EXPIRED, got %s", hold.Status)
+	}
+
+	entries, _ := service.GetAccountStatement("bob")
+	if len(entries) != 1 {
+		t.Errorf("Expected only the deposit entry, got %d entries", len(entries))
+	}
+}
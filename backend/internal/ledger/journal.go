@@ -0,0 +1,174 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidJournal    = errors.New("a journal needs at least two legs")
+	ErrInvalidReasonCode = errors.New("reason code must be GOODWILL, WRITE_OFF or CORRECTION")
+	ErrMissingOperator   = errors.New("operator user ID is required")
+)
+
+// Reason codes for manual journals
+const (
+	JournalReasonGoodwill   = "GOODWILL"   // Credit given to a customer as a gesture of goodwill
+	JournalReasonWriteOff   = "WRITE_OFF"  // Balance that won't be recovered
+	JournalReasonCorrection = "CORRECTION" // Fix for an earlier mis-posting, e.g. between system accounts
+)
+
+var journalReasons = map[string]bool{
+	JournalReasonGoodwill:   true,
+	JournalReasonWriteOff:   true,
+	JournalReasonCorrection: true,
+}
+
+// JournalRequest represents a manual journal posted by an operator and approved by a different one
+type JournalRequest struct {
+	Legs          []JournalLeg
+	Currency      string // ISO 4217 code shared by every leg (defaults to USD)
+	ReasonCode    string // GOODWILL, WRITE_OFF or CORRECTION
+	Description   string
+	CreatedBy     string // The operator's user ID
+	ApprovalID    string // Request in the approval queue a different admin approved
+	TransactionID string // Optional; the approval's ID when ApprovalID is set
+}
+
+// JournalLeg is one side of a manual journal
+type JournalLeg struct {
+	AccountID   string
+	EntryType   string // DEBIT or CREDIT
+	Amount      int64  // Amount in cents (negative for debit, positive for credit)
+	Description string // Optional, defaults to the journal's description
+}
+
+// Validate checks a journal without posting it: a known reason code and at least two valid legs that balance
+// Whether the accounts accept their legs is only known when the journal is posted
+func (r *JournalRequest) Validate() error {
+	_, err := r.entries(time.Now().Unix())
+	return err
+}
+
+// entries builds the journal's ledger entries and runs the same checks CreateEntries runs before storing them
+func (r *JournalRequest) entries(now int64) ([]*LedgerEntry, error) {
+	if r.CreatedBy == "" {
+		return nil, ErrMissingOperator
+	}
+	if !journalReasons[r.ReasonCode] {
+		return nil, ErrInvalidReasonCode
+	}
+	if len(r.Legs) < 2 {
+		return nil, ErrInvalidJournal
+	}
+	cur, err := resolveCurrency(r.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionID := r.TransactionID
+	if transactionID == "" {
+		transactionID = uuid.New().String()
+	}
+
+	entries := make([]*LedgerEntry, len(r.Legs))
+	for i, leg := range r.Legs {
+		if leg.AccountID == "" {
+			return nil, fmt.Errorf("leg %d: %w", i, ErrMissingAccountID)
+		}
+		description := leg.Description
+		if description == "" {
+			description = r.Description
+		}
+		metadata := map[string]interface{}{"reason_code": r.ReasonCode}
+		entries[i] = &LedgerEntry{
+			ID:              uuid.New().String(),
+			AccountID:       leg.AccountID,
+			Amount:          leg.Amount,
+			Currency:        cur,
+			EntryType:       leg.EntryType,
+			TransactionID:   transactionID,
+			TransactionType: TransactionTypeAdjustment,
+			CreatedAt:       now,
+			CreatedBy:       r.CreatedBy,
+			Description:     fmt.Sprintf("%s: %s", r.ReasonCode, description),
+			Metadata:        metadata,
+		}
+		if err := entries[i].Validate(); err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i, err)
+		}
+	}
+	if err := checkBalancedPerCurrency(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// PostJournal posts arbitrary balanced legs as one ADJUSTMENT transaction
+// The journal must have been approved in the queue by an admin other than the operator; a wallet can't be debited
+// below its available balance
+func (s *Service) PostJournal(req *JournalRequest) (string, error) {
+	entries, err := req.entries(time.Now().Unix())
+	if err != nil {
+		return "", err
+	}
+	approval, err := s.approved(req.ApprovalID)
+	if err != nil {
+		return "", err
+	}
+	if !approval.matchesJournal(req) {
+		log.Printf("Error: Journal by %s does not match approval %s", req.CreatedBy, req.ApprovalID)
+		return "", ErrApprovalMismatch
+	}
+	for _, entry := range entries {
+		entry.Metadata["approved_by"] = approval.ApprovedBy
+		entry.Metadata["approval_id"] = approval.ID
+	}
+
+	accountIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		accountIDs[i] = entry.AccountID
+	}
+	unlock := s.locks.Lock(accountIDs...)
+	defer unlock()
+
+	// Wallets that end up debited must have the money; system accounts may run negative as they do for any posting
+	debited := make(map[string]int64)
+	for _, entry := range entries {
+		debited[entry.AccountID] -= entry.Amount
+	}
+	for _, entry := range entries {
+		amount := debited[entry.AccountID]
+		if amount <= 0 {
+			continue
+		}
+		delete(debited, entry.AccountID)
+		account, err := s.repo.GetAccount(entry.AccountID)
+		if err == ErrAccountNotFound {
+			log.Printf("Error: Unknown ledger account %s", entry.AccountID)
+			return "", err
+		}
+		if err != nil {
+			return "", fmt.Errorf("error checking account: %w", err)
+		}
+		if account.Type == AccountTypeUserWallet {
+			if err := s.checkFunds(entry.AccountID, entry.Currency, amount); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// The repository checks again that the legs sum to zero, and that every account accepts its leg
+	if err := s.repo.CreateEntries(entries); err != nil {
+		log.Printf("Error creating journal entries: %v", err)
+		return "", err
+	}
+
+	log.Printf("Journal posted: %d legs, reason: %s, operator: %s, approver: %s, txn: %s",
+		len(entries), req.ReasonCode, req.CreatedBy, approval.ApprovedBy, entries[0].TransactionID)
+	return entries[0].TransactionID, nil
+}
@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPostJournal tests a goodwill credit and a correction between system accounts, recorded against the operator
+func TestPostJournal(t *testing.T) {
+	approvals := stubApprovals{}
+	service := NewService(newTestRepository(t), WithApprovals(approvals))
+	openWallets(t, service, "USD", "alice")
+
+	goodwill := &JournalRequest{
+		ReasonCode:  JournalReasonGoodwill,
+		Description: "Apology for the outage",
+		CreatedBy:   "admin-1",
+		Legs: []JournalLeg{
+			{AccountID: FeeAccountID("USD"), EntryType: EntryTypeDebit, Amount: -1500},
+			{AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1500},
+		},
+	}
+	approvals.approveJournal(goodwill, "approval-1", "admin-2")
+	transactionID, err := service.PostJournal(goodwill)
+	if err != nil {
+		t.Fatalf("Failed to post journal: %v", err)
+	}
+	entries, _ := service.GetTransactionDetails(transactionID)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	for _, entry := range entries {
+		if entry.CreatedBy != "admin-1" || entry.TransactionType != TransactionTypeAdjustment ||
+			entry.Metadata["reason_code"] != JournalReasonGoodwill || entry.Metadata["approved_by"] != "admin-2" ||
+			entry.Metadata["approval_id"] != "approval-1" {
+			t.Errorf("Expected an ADJUSTMENT by admin-1 with its reason code and approver, got %+v", entry)
+		}
+	}
+	if balance, _ := service.GetBalance("alice"); balance.Balance != 1500 {
+		t.Errorf("Expected alice to have 15.00, got %d", balance.Balance)
+	}
+
+	correction := &JournalRequest{
+		ReasonCode: JournalReasonCorrection,
+		CreatedBy:  "admin-1",
+		Legs: []JournalLeg{
+			{AccountID: ExternalBankAccountID("USD"), EntryType: EntryTypeDebit, Amount: -700},
+			{AccountID: FeeAccountID("USD"), EntryType: EntryTypeCredit, Amount: 300},
+			{AccountID: FXRevenueAccountID("USD"), EntryType: EntryTypeCredit, Amount: 400},
+		},
+	}
+	approvals.approveJournal(correction, "approval-2", "admin-2")
+	if _, err := service.PostJournal(correction); err != nil {
+		t.Errorf("Failed to post a three-leg correction: %v", err)
+	}
+}
+
+// TestPostJournalValidation tests that invalid journals are rejected and post nothing
+func TestPostJournalValidation(t *testing.T) {
+	approvals := stubApprovals{}
+	service := NewService(newTestRepository(t), WithApprovals(approvals))
+	openWallets(t, service, "USD", "alice")
+	openWallets(t, service, "EUR", "euro")
+	fees := FeeAccountID("USD")
+
+	invalid := map[string]struct {
+		req        JournalRequest
+		approvedBy string // Empty leaves the journal out of the queue
+		want       error
+	}{
+		"no operator": {JournalRequest{ReasonCode: JournalReasonWriteOff, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrMissingOperator},
+		"no reason code": {JournalRequest{CreatedBy: "admin-1", Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrInvalidReasonCode},
+		"one leg": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonWriteOff, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}}}, "admin-2", ErrInvalidJournal},
+		"debit with a positive amount": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonWriteOff, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: 1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrInvalidDebitAmount},
+		"unknown entry type": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonWriteOff, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: "MOVE", Amount: -1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrInvalidEntryType},
+		"unbalanced": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonWriteOff, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -2}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrTransactionNotBalanced},
+		"overdrawn wallet": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonWriteOff, Legs: []JournalLeg{
+			{AccountID: "alice", EntryType: EntryTypeDebit, Amount: -1}, {AccountID: fees, EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrInsufficientBalance},
+		"unapproved": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonGoodwill, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "", ErrApprovalRequired},
+		"self-approved": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonGoodwill, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-1", ErrSelfApproval},
+		"wrong currency": {JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonGoodwill, Legs: []JournalLeg{
+			{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}, {AccountID: "euro", EntryType: EntryTypeCredit, Amount: 1}}}, "admin-2", ErrCurrencyMismatch},
+	}
+	for name, tt := range invalid {
+		if tt.approvedBy != "" {
+			approvals.approveJournal(&tt.req, name, tt.approvedBy)
+		}
+		if _, err := service.PostJournal(&tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", name, tt.want, err)
+		}
+	}
+	changed := JournalRequest{CreatedBy: "admin-1", ReasonCode: JournalReasonGoodwill, Legs: []JournalLeg{
+		{AccountID: fees, EntryType: EntryTypeDebit, Amount: -1}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 1}}}
+	approvals.approveJournal(&changed, "changed", "admin-2")
+	changed.Legs = []JournalLeg{
+		{AccountID: fees, EntryType: EntryTypeDebit, Amount: -100}, {AccountID: "alice", EntryType: EntryTypeCredit, Amount: 100}}
+	if _, err := service.PostJournal(&changed); err != ErrApprovalMismatch {
+		t.Errorf("Expected a journal changed after approval to be refused, got %v", err)
+	}
+
+	if statement, _ := service.GetAccountStatement(fees); len(statement) != 0 {
+		t.Errorf("Expected nothing to be posted, got %d entries", len(statement))
+	}
+}
@@ -0,0 +1,318 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	ErrLimitExceeded       = errors.New("transaction limit exceeded")
+	ErrLimitsNotConfigured = errors.New("transaction limits are not configured")
+	ErrLimitsNotFound      = errors.New("account limits not found")
+	ErrInvalidLimit        = errors.New("invalid limit")
+	ErrUnknownKYCLevel     = errors.New("unknown KYC level")
+)
+
+// Limit kinds
+const (
+	LimitPerTransaction = "PER_TRANSACTION" // Largest single transaction
+	LimitDaily          = "DAILY"           // Total over the last 24 hours
+	LimitMonthly        = "MONTHLY"         // Total over the last 30 days
+	LimitHourlyCount    = "HOURLY_COUNT"    // Number of transactions over the last hour
+)
+
+// KYC levels
+const (
+	KYCLevelNone     = "NONE"     // Default for accounts nobody has verified
+	KYCLevelBasic    = "BASIC"    // Identity verified
+	KYCLevelEnhanced = "ENHANCED" // Identity, address and source of funds verified
+)
+
+// limitWindows are how far back the rolling limits look
+var limitWindows = map[string]time.Duration{
+	LimitDaily:       24 * time.Hour,
+	LimitMonthly:     30 * 24 * time.Hour,
+	LimitHourlyCount: time.Hour,
+}
+
+// limitedTypes are the transaction types each limit counts: conversions pay another wallet like transfers,
+// and captured holds pay out of the platform like withdrawals
+var limitedTypes = map[string][]string{
+	TransactionTypeTransfer:   {TransactionTypeTransfer, TransactionTypeConversion},
+	TransactionTypeWithdrawal: {TransactionTypeWithdrawal, TransactionTypeCapture},
+	TransactionTypeDeposit:    {TransactionTypeDeposit},
+}
+
+// Limit caps one kind of activity for one transaction type
+// Amount limits are in minor units of the account's currency and count amounts as posted: fees included for
+// transfers and withdrawals, net of fees for deposits. HOURLY_COUNT limits are a number of transactions
+// TRANSFER limits also cover conversions (source leg), and WITHDRAWAL limits also cover hold captures
+type Limit struct {
+	Kind            string `json:"kind"`
+	TransactionType string `json:"transaction_type"` // TRANSFER, WITHDRAWAL or DEPOSIT
+	Value           int64  `json:"value"`            // 0 blocks the transaction type entirely
+}
+
+// key identifies the limit an override replaces
+func (l Limit) key() string {
+	return l.Kind + "/" + l.TransactionType
+}
+
+// LimitTiers are the limits that apply to each KYC level
+type LimitTiers map[string][]Limit
+
+// DefaultLimitTiers are the built-in limits per KYC level, in minor units
+func DefaultLimitTiers() LimitTiers {
+	tier := func(transferMax, withdrawalMax, daily, monthly int64, transfersPerHour int64) []Limit {
+		return []Limit{
+			{Kind: LimitPerTransaction, TransactionType: TransactionTypeTransfer, Value: transferMax},
+			{Kind: LimitPerTransaction, TransactionType: TransactionTypeWithdrawal, Value: withdrawalMax},
+			{Kind: LimitDaily, TransactionType: TransactionTypeTransfer, Value: daily},
+			{Kind: LimitDaily, TransactionType: TransactionTypeWithdrawal, Value: daily},
+			{Kind: LimitMonthly, TransactionType: TransactionTypeTransfer, Value: monthly},
+			{Kind: LimitMonthly, TransactionType: TransactionTypeWithdrawal, Value: monthly},
+			{Kind: LimitHourlyCount, TransactionType: TransactionTypeTransfer, Value: transfersPerHour},
+		}
+	}
+	return LimitTiers{
+		KYCLevelNone:     tier(50000, 20000, 100000, 500000, 5),
+		KYCLevelBasic:    tier(200000, 100000, 500000, 2000000, 20),
+		KYCLevelEnhanced: tier(1000000, 1000000, 2500000, 10000000, 60),
+	}
+}
+
+// AccountLimits is an account's KYC level and the limits admins have overridden for it
+type AccountLimits struct {
+	AccountID string  `json:"account_id"`
+	KYCLevel  string  `json:"kyc_level"`
+	Overrides []Limit `json:"overrides"` // Replace the tier's limit of the same kind and transaction type
+	UpdatedBy string  `json:"updated_by,omitempty"`
+	UpdatedAt int64   `json:"updated_at,omitempty"`
+}
+
+// LimitUsage is how much of a limit an account has used
+type LimitUsage struct {
+	Limit
+	Currency   string `json:"currency"`
+	Used       int64  `json:"used"`
+	Remaining  int64  `json:"remaining"`
+	Overridden bool   `json:"overridden"` // Set by an admin rather than the KYC tier
+}
+
+// LimitError reports the limit a transaction would exceed and what is left of it
+type LimitError struct {
+	LimitUsage
+	Requested int64 // The transaction's amount, or the number of transactions for HOURLY_COUNT
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s %s limit of %d, %d remaining", ErrLimitExceeded, e.TransactionType, e.Kind, e.Value, e.Remaining)
+}
+
+// Is makes errors.Is(err, ErrLimitExceeded) match
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// WithLimits enforces per-account limits on transfers, withdrawals and deposits, tiered by KYC level
+func WithLimits(tiers LimitTiers) Option {
+	return func(s *Service) {
+		s.limits = tiers
+	}
+}
+
+// GetAccountLimits returns an account's KYC level and overrides; accounts never configured are at KYCLevelNone
+func (s *Service) GetAccountLimits(accountID string) (*AccountLimits, error) {
+	if _, err := s.repo.GetAccount(accountID); err != nil {
+		return nil, err
+	}
+	return s.accountLimits(accountID)
+}
+
+// SetAccountLimits sets an account's KYC level and replaces its overrides
+func (s *Service) SetAccountLimits(accountID, kycLevel string, overrides []Limit, updatedBy string) (*AccountLimits, error) {
+	if s.limits == nil {
+		return nil, ErrLimitsNotConfigured
+	}
+	if _, err := s.repo.GetAccount(accountID); err != nil {
+		return nil, err
+	}
+	if _, known := s.limits[kycLevel]; !known {
+		return nil, ErrUnknownKYCLevel
+	}
+	seen := make(map[string]bool, len(overrides))
+	for _, limit := range overrides {
+		if err := validateLimit(limit); err != nil {
+			return nil, err
+		}
+		if seen[limit.key()] {
+			return nil, fmt.Errorf("%w: duplicate %s override", ErrInvalidLimit, limit.key())
+		}
+		seen[limit.key()] = true
+	}
+
+	limits := &AccountLimits{
+		AccountID: accountID,
+		KYCLevel:  kycLevel,
+		Overrides: append([]Limit{}, overrides...),
+		UpdatedBy: updatedBy,
+		UpdatedAt: time.Now().Unix(),
+	}
+	if err := s.repo.SaveAccountLimits(limits); err != nil {
+		log.Printf("Error saving limits for %s: %v", accountID, err)
+		return nil, err
+	}
+	log.Printf("Limits for %s set to %s with %d overrides by %s", accountID, kycLevel, len(overrides), updatedBy)
+	return limits, nil
+}
+
+// GetLimitUsage reports every limit that applies to an account and how much of each is left
+func (s *Service) GetLimitUsage(accountID string) ([]*LimitUsage, error) {
+	if s.limits == nil {
+		return nil, ErrLimitsNotConfigured
+	}
+	account, err := s.repo.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.accountLimits(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	usages := []*LimitUsage{}
+	for _, usage := range s.effectiveLimits(limits) {
+		usage.Currency = account.Currency
+		if err := s.measureLimit(usage, accountID, now); err != nil {
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+// checkLimits fails with a *LimitError if posting amount would exceed one of the account's limits
+// Callers hold the account's lock so concurrent postings can't both fit under the same allowance
+func (s *Service) checkLimits(transactionType, accountID, cur string, amount, now int64) error {
+	return s.checkLimitsEach(transactionType, accountID, cur, []int64{amount}, now)
+}
+
+// checkLimitsEach checks several postings made at once, e.g. the items of a batch: each one must fit
+// PER_TRANSACTION, each counts towards HOURLY_COUNT, and their total towards the rolling amount limits
+func (s *Service) checkLimitsEach(transactionType, accountID, cur string, amounts []int64, now int64) error {
+	if s.limits == nil {
+		return nil
+	}
+	limits, err := s.accountLimits(accountID)
+	if err != nil {
+		return err
+	}
+
+	for _, usage := range s.effectiveLimits(limits) {
+		if usage.TransactionType != transactionType {
+			continue
+		}
+		usage.Currency = cur
+		if err := s.measureLimit(usage, accountID, now); err != nil {
+			return err
+		}
+
+		var requested int64
+		for _, amount := range amounts {
+			switch {
+			case usage.Kind == LimitHourlyCount:
+				requested++
+			case usage.Kind == LimitPerTransaction:
+				requested = max(requested, amount)
+			default:
+				requested += amount
+			}
+		}
+		if requested > usage.Remaining {
+			log.Printf("Error: %s of %d on %s exceeds its %s %s limit (%d remaining)",
+				transactionType, requested, accountID, usage.TransactionType, usage.Kind, usage.Remaining)
+			return &LimitError{LimitUsage: *usage, Requested: requested}
+		}
+	}
+	return nil
+}
+
+// measureLimit fills in how much of the limit the account has used in its window
+func (s *Service) measureLimit(usage *LimitUsage, accountID string, now int64) error {
+	if window, rolling := limitWindows[usage.Kind]; rolling {
+		from := now - int64(window/time.Second) + 1
+		usage.Used = 0
+		for _, transactionType := range limitedTypes[usage.TransactionType] {
+			activity, err := s.repo.GetActivity(accountID, transactionType, payerEntryType(transactionType), from, now)
+			if err != nil {
+				return fmt.Errorf("error measuring %s limit: %w", usage.Kind, err)
+			}
+			if usage.Kind == LimitHourlyCount {
+				usage.Used += int64(activity.Transactions)
+			} else {
+				usage.Used += activity.Amount
+			}
+		}
+	}
+
+	usage.Remaining = usage.Value - usage.Used
+	if usage.Remaining < 0 {
+		usage.Remaining = 0
+	}
+	return nil
+}
+
+// accountLimits loads the account's limit settings, defaulting to KYCLevelNone without overrides
+func (s *Service) accountLimits(accountID string) (*AccountLimits, error) {
+	limits, err := s.repo.GetAccountLimits(accountID)
+	if err == ErrLimitsNotFound {
+		return &AccountLimits{AccountID: accountID, KYCLevel: KYCLevelNone, Overrides: []Limit{}}, nil
+	}
+	return limits, err
+}
+
+// effectiveLimits merges the account's overrides into its tier's limits
+func (s *Service) effectiveLimits(limits *AccountLimits) []*LimitUsage {
+	overrides := make(map[string]Limit, len(limits.Overrides))
+	for _, limit := range limits.Overrides {
+		overrides[limit.key()] = limit
+	}
+
+	var usages []*LimitUsage
+	for _, limit := range s.limits[limits.KYCLevel] {
+		if override, exists := overrides[limit.key()]; exists {
+			delete(overrides, limit.key())
+			usages = append(usages, &LimitUsage{Limit: override, Overridden: true})
+			continue
+		}
+		usages = append(usages, &LimitUsage{Limit: limit})
+	}
+	// Overrides can also add limits the tier doesn't have
+	for _, limit := range limits.Overrides {
+		if _, extra := overrides[limit.key()]; extra {
+			usages = append(usages, &LimitUsage{Limit: limit, Overridden: true})
+		}
+	}
+	return usages
+}
+
+// validateLimit checks an override's kind, transaction type and value
+func validateLimit(limit Limit) error {
+	switch limit.Kind {
+	case LimitPerTransaction, LimitDaily, LimitMonthly, LimitHourlyCount:
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidLimit, limit.Kind)
+	}
+	switch limit.TransactionType {
+	case TransactionTypeTransfer, TransactionTypeWithdrawal, TransactionTypeDeposit:
+	default:
+		return fmt.Errorf("%w: limits apply to TRANSFER, WITHDRAWAL and DEPOSIT, not %q", ErrInvalidLimit, limit.TransactionType)
+	}
+	if limit.Value < 0 {
+		return fmt.Errorf("%w: %s must not be negative", ErrInvalidLimit, limit.key())
+	}
+	return nil
+}
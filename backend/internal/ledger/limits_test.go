@@ -0,0 +1,182 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestTransactionLimits tests per-transaction, rolling and hourly count limits and the structured error
+func TestTransactionLimits(t *testing.T) {
+	tiers := LimitTiers{
+		KYCLevelNone: {
+			{Kind: LimitPerTransaction, TransactionType: TransactionTypeWithdrawal, Value: 5000},
+			{Kind: LimitDaily, TransactionType: TransactionTypeWithdrawal, Value: 8000},
+			{Kind: LimitHourlyCount, TransactionType: TransactionTypeTransfer, Value: 2},
+		},
+		KYCLevelBasic: {
+			{Kind: LimitPerTransaction, TransactionType: TransactionTypeWithdrawal, Value: 50000},
+		},
+	}
+	service := NewService(newTestRepository(t), WithLimits(tiers))
+	openWallets(t, service, "USD", "alice", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 100000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	_, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 5001})
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("Expected a LimitError, got %v", err)
+	}
+	if limitErr.Kind != LimitPerTransaction || limitErr.Remaining != 5000 || limitErr.Requested != 5001 {
+		t.Errorf("Unexpected limit error: %+v", limitErr)
+	}
+
+	// 50.00 fits; the daily cap then leaves 30.00
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 5000}); err != nil {
+		t.Fatalf("Failed to record withdrawal: %v", err)
+	}
+	_, err = service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 3001})
+	if !errors.As(err, &limitErr) || limitErr.Kind != LimitDaily || limitErr.Used != 5000 || limitErr.Remaining != 3000 {
+		t.Fatalf("Expected the daily limit with 3000 remaining, got %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 3000}); err != nil {
+		t.Errorf("Expected a withdrawal of exactly the remaining allowance to succeed, got %v", err)
+	}
+
+	// The third transfer within the hour is refused
+	for i := 0; i < 2; i++ {
+		if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 100}); err != nil {
+			t.Fatalf("Failed to record transfer %d: %v", i+1, err)
+		}
+	}
+	_, err = service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 100})
+	if !errors.As(err, &limitErr) || limitErr.Kind != LimitHourlyCount || limitErr.Used != 2 || limitErr.Remaining != 0 {
+		t.Fatalf("Expected the hourly transfer count limit, got %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "bob", ToAccountID: "alice", Amount: 100}); err != nil {
+		t.Errorf("Expected incoming transfers not to count against bob, got %v", err)
+	}
+
+	// Upgrading the KYC level switches tiers; an override replaces the tier's limit
+	if _, err := service.SetAccountLimits("alice", KYCLevelBasic, []Limit{
+		{Kind: LimitHourlyCount, TransactionType: TransactionTypeTransfer, Value: 10},
+	}, "compliance"); err != nil {
+		t.Fatalf("Failed to set limits: %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 20000}); err != nil {
+		t.Errorf("Expected the BASIC tier to allow a 200.00 withdrawal, got %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 100}); err != nil {
+		t.Errorf("Expected the override to allow another transfer, got %v", err)
+	}
+
+	usages, err := service.GetLimitUsage("alice")
+	if err != nil || len(usages) != 2 {
+		t.Fatalf("Expected the BASIC limit and the override, got %+v (%v)", usages, err)
+	}
+	if count := usages[1]; !count.Overridden || count.Used != 3 || count.Remaining != 7 {
+		t.Errorf("Expected 7 transfers left this hour, got %+v", count)
+	}
+}
+
+// TestConversionAndCaptureLimits tests that conversions share transfer limits and captures share withdrawal limits
+func TestConversionAndCaptureLimits(t *testing.T) {
+	rates, err := NewStaticRateProvider(map[string]string{"EUR/GBP": "0.85"})
+	if err != nil {
+		t.Fatalf("Failed to create rate provider: %v", err)
+	}
+	service := NewService(newTestRepository(t), WithFX(rates, FXConfig{SpreadBps: 100, QuoteTTL: time.Minute}), WithLimits(LimitTiers{
+		KYCLevelNone: {
+			{Kind: LimitDaily, TransactionType: TransactionTypeTransfer, Value: 5000},
+			{Kind: LimitDaily, TransactionType: TransactionTypeWithdrawal, Value: 3000},
+		},
+	}))
+	openWallets(t, service, "EUR", "alice", "carol")
+	openWallets(t, service, "GBP", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 100000, Currency: "EUR", Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	convert := func(amount int64) error {
+		quote, err := service.QuoteConversion(&ConversionRequest{FromAccountID: "alice", ToAccountID: "bob", FromCurrency: "EUR", ToCurrency: "GBP", Amount: amount})
+		if err != nil {
+			t.Fatalf("Failed to quote conversion: %v", err)
+		}
+		_, err = service.ExecuteConversion(quote.ID)
+		return err
+	}
+	if err := convert(4000); err != nil {
+		t.Fatalf("Failed to execute conversion: %v", err)
+	}
+	var limitErr *LimitError
+	if err := convert(1001); !errors.As(err, &limitErr) || limitErr.Used != 4000 {
+		t.Errorf("Expected the conversion to exceed the daily transfer limit, got %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "carol", Amount: 1001, Currency: "EUR"}); !errors.As(err, &limitErr) {
+		t.Errorf("Expected conversions to count towards transfers, got %v", err)
+	}
+
+	hold, err := service.PlaceHold(&HoldRequest{AccountID: "alice", Amount: 5000, Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+	if _, err := service.CaptureHold(hold.ID, 3001); !errors.As(err, &limitErr) || limitErr.TransactionType != TransactionTypeWithdrawal {
+		t.Errorf("Expected the capture to exceed the daily withdrawal limit, got %v", err)
+	}
+	if _, err := service.CaptureHold(hold.ID, 3000); err != nil {
+		t.Fatalf("Failed to capture hold: %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 1, Currency: "EUR"}); !errors.As(err, &limitErr) || limitErr.Used != 3000 {
+		t.Errorf("Expected captures to count towards withdrawals, got %v", err)
+	}
+}
+
+// TestSetAccountLimitsValidation tests that unknown levels and malformed overrides are rejected
+func TestSetAccountLimitsValidation(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "alice")
+	if _, err := service.SetAccountLimits("alice", KYCLevelBasic, nil, "compliance"); err != ErrLimitsNotConfigured {
+		t.Errorf("Expected ErrLimitsNotConfigured, got %v", err)
+	}
+
+	service = NewService(newTestRepository(t), WithLimits(DefaultLimitTiers()))
+	openWallets(t, service, "USD", "alice")
+	daily := Limit{Kind: LimitDaily, TransactionType: TransactionTypeTransfer, Value: 1000}
+
+	invalid := map[string]struct {
+		level     string
+		overrides []Limit
+		want      error
+	}{
+		"unknown level":    {"GOLD", nil, ErrUnknownKYCLevel},
+		"unknown kind":     {KYCLevelNone, []Limit{{Kind: "WEEKLY", TransactionType: TransactionTypeTransfer}}, ErrInvalidLimit},
+		"reversal type":    {KYCLevelNone, []Limit{{Kind: LimitDaily, TransactionType: TransactionTypeReversal}}, ErrInvalidLimit},
+		"negative value":   {KYCLevelNone, []Limit{{Kind: LimitDaily, TransactionType: TransactionTypeTransfer, Value: -1}}, ErrInvalidLimit},
+		"duplicate limits": {KYCLevelNone, []Limit{daily, daily}, ErrInvalidLimit},
+	}
+	for name, tt := range invalid {
+		if _, err := service.SetAccountLimits("alice", tt.level, tt.overrides, "compliance"); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", name, tt.want, err)
+		}
+	}
+	if _, err := service.SetAccountLimits("missing", KYCLevelNone, nil, "compliance"); err != ErrAccountNotFound {
+		t.Errorf("Expected ErrAccountNotFound, got %v", err)
+	}
+
+	limits, err := service.GetAccountLimits("alice")
+	if err != nil || limits.KYCLevel != KYCLevelNone || len(limits.Overrides) != 0 {
+		t.Errorf("Expected an unconfigured account at NONE, got %+v (%v)", limits, err)
+	}
+
+	// An override of 0 blocks the transaction type
+	if _, err := service.SetAccountLimits("alice", KYCLevelNone, []Limit{
+		{Kind: LimitPerTransaction, TransactionType: TransactionTypeDeposit, Value: 0},
+	}, "compliance"); err != nil {
+		t.Fatalf("Failed to set limits: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 1, Source: "bank"}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected deposits to be blocked, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package ledger
+
+import (
+	"sort"
+	"sync"
+)
+
+// accountLocker serializes operations per account so a balance check and the
+// entry write that depends on it happen as one atomic step
+// Locks are process-local, so they only cover postings made through the same Service instance;
+// the Postgres repository re-checks wallet funds under a row lock, which holds across replicas
+type accountLocker struct {
+	mu    sync.Mutex
+	locks map[string]*accountLock
+}
+
+// accountLock is a per-account mutex with a count of goroutines holding or waiting for it
+type accountLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newAccountLocker() *accountLocker {
+	return &accountLocker{locks: make(map[string]*accountLock)}
+}
+
+// Lock acquires the locks for all given accounts and returns a function that releases them
+// Accounts are always locked in sorted order, so two transfers between the same pair of
+// accounts in opposite directions cannot deadlock
+func (l *accountLocker) Lock(accountIDs ...string) (unlock func()) {
+	ids := make([]string, 0, len(accountIDs))
+	seen := make(map[string]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	held := make([]*accountLock, len(ids))
+	for i, id := range ids {
+		held[i] = l.acquire(id)
+		held[i].mu.Lock()
+	}
+
+	return func() {
+		for i := len(ids) - 1; i >= 0; i-- {
+			held[i].mu.Unlock()
+			l.release(ids[i])
+		}
+	}
+}
+
+// acquire returns the lock for an account, creating it on first use
+func (l *accountLocker) acquire(accountID string) *accountLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, exists := l.locks[accountID]
+	if !exists {
+		lock = &accountLock{}
+		l.locks[accountID] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// release drops a reference and forgets the lock once nobody holds or waits for it
+func (l *accountLocker) release(accountID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock := l.locks[accountID]
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, accountID)
+	}
+}
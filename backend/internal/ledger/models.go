@@ -3,6 +3,8 @@ package ledger
 import (
 	"digitalwallet/backend/pkg/currency"
 	"errors"
+	"strconv"
+	"strings"
 )
 
 // Account Types - What kind of account is this?
@@ -10,8 +12,39 @@ const (
 	AccountTypeUserWallet   = "USER_WALLET"   // Individual user's wallet
 	AccountTypeSystemFee    = "SYSTEM_FEE"    // Platform fees/revenue
 	AccountTypeExternalBank = "EXTERNAL_BANK" // External bank accounts (liability tracking)
+	AccountTypeFXPosition   = "FX_POSITION"   // Platform's position in each currency for conversions
+	AccountTypeFXRevenue    = "FX_REVENUE"    // Spread earned on conversions
 )
 
+// System account ID prefixes - every account holds a single currency,
+// so each system account exists once per currency (e.g., "system-fee-account-eur")
+const (
+	systemFeeAccountPrefix    = "system-fee-account"
+	externalBankAccountPrefix = "external-bank-pool"
+	fxPositionAccountPrefix   = "fx-position"
+	fxRevenueAccountPrefix    = "fx-revenue"
+)
+
+// FeeAccountID returns the platform fee account for a currency
+func FeeAccountID(cur string) string {
+	return systemFeeAccountPrefix + "-" + strings.ToLower(cur)
+}
+
+// ExternalBankAccountID returns the external funds pool account for a currency
+func ExternalBankAccountID(cur string) string {
+	return externalBankAccountPrefix + "-" + strings.ToLower(cur)
+}
+
+// FXPositionAccountID returns the account through which conversions move money in a currency
+func FXPositionAccountID(cur string) string {
+	return fxPositionAccountPrefix + "-" + strings.ToLower(cur)
+}
+
+// FXRevenueAccountID returns the account credited with conversion spread in a currency
+func FXRevenueAccountID(cur string) string {
+	return fxRevenueAccountPrefix + "-" + strings.ToLower(cur)
+}
+
 // Entry Types - Is money going in or out?
 const (
 	EntryTypeDebit  = "DEBIT"  // Money leaving the account (negative amount)
@@ -20,10 +53,16 @@ const (
 
 // Transaction Types - What kind of operation is this?
 const (
-	TransactionTypeTransfer   = "TRANSFER"   // User-to-user transfer
-	TransactionTypeDeposit    = "DEPOSIT"    // External funds coming in
-	TransactionTypeWithdrawal = "WITHDRAWAL" // Funds going out to external account
-	TransactionTypeFee        = "FEE"        // Platform fee charge
+	TransactionTypeTransfer     = "TRANSFER"      // User-to-user transfer
+	TransactionTypeDeposit      = "DEPOSIT"       // External funds coming in
+	TransactionTypeWithdrawal   = "WITHDRAWAL"    // Funds going out to external account
+	TransactionTypeFee          = "FEE"           // Platform fee charge
+	TransactionTypeConversion   = "CONVERSION"    // Currency exchange between two accounts
+	TransactionTypeCapture      = "CAPTURE"       // Settlement of a captured hold
+	TransactionTypeReversal     = "REVERSAL"      // Full undo of an earlier transaction
+	TransactionTypeRefund       = "REFUND"        // Partial or full return of an earlier payment
+	TransactionTypeClosureSweep = "CLOSURE_SWEEP" // Final sweep of a closing account's balance
+	TransactionTypeAdjustment   = "ADJUSTMENT"    // Manual journal posted by an operator
 )
 
 // Validation errors
@@ -31,6 +70,10 @@ var (
 	ErrInvalidDebitAmount  = errors.New("debit entries must have negative amounts")
 	ErrInvalidCreditAmount = errors.New("credit entries must have positive amounts")
 	ErrInvalidEntryType    = errors.New("entry type must be DEBIT or CREDIT")
+	ErrMissingAccountID    = errors.New("account ID is required")
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrSameAccountTransfer = errors.New("cannot transfer to the same account")
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
 )
 
 // LedgerEntry represents a single entry in the double-entry ledger
@@ -48,6 +91,13 @@ type LedgerEntry struct {
 	CreatedBy       string                 `json:"created_by"`         // Service or user that created this entry
 	Description     string                 `json:"description"`        // Human-readable description
 	Metadata        map[string]interface{} `json:"metadata,omitempty"` // Additional context (optional)
+
+	// ReferenceTransactionID links a compensating entry (reversal, refund) to the transaction it undoes
+	ReferenceTransactionID string `json:"reference_transaction_id,omitempty"`
+
+	// Hash chain: every entry commits to its own content and to the entry posted before it
+	PrevHash string `json:"prev_hash"` // Hash of the previous entry in posting order (GenesisHash for the first)
+	Hash     string `json:"hash"`      // SHA-256 of the canonical content, see ComputeHash
 }
 
 // Validate ensures the ledger entry follows double-entry bookkeeping rules
@@ -67,35 +117,113 @@ func (e *LedgerEntry) Validate() error {
 		return ErrInvalidCreditAmount
 	}
 
+	if !currency.IsSupported(e.Currency) {
+		return ErrUnsupportedCurrency
+	}
+
 	return nil
 }
 
-// ToDTO converts the entry to a user-friendly format with dollar amounts
+// ToDTO converts the entry to a user-friendly format with decimal string amounts
 func (e *LedgerEntry) ToDTO() *LedgerEntryDTO {
 	return &LedgerEntryDTO{
 		ID:              e.ID,
 		AccountID:       e.AccountID,
-		Amount:          currency.CentsToStandardCurrencyFormat(e.Amount),
+		Amount:          currency.New(e.Amount, e.Currency).String(),
 		Currency:        e.Currency,
 		EntryType:       e.EntryType,
 		TransactionID:   e.TransactionID,
 		TransactionType: e.TransactionType,
 		CreatedAt:       e.CreatedAt,
 		Description:     e.Description,
+
+		ReferenceTransactionID: e.ReferenceTransactionID,
+		Hash:                   e.Hash,
 	}
 }
 
-// LedgerEntryDTO is the API response format with dollar amounts instead of cents
+// LedgerEntryDTO is the API response format with decimal string amounts instead of minor units
 type LedgerEntryDTO struct {
-	ID              string  `json:"id"`
-	AccountID       string  `json:"account_id"`
-	Amount          float64 `json:"amount"` // In dollars (e.g., 50.00)
-	Currency        string  `json:"currency"`
-	EntryType       string  `json:"entry_type"`
-	TransactionID   string  `json:"transaction_id"`
-	TransactionType string  `json:"transaction_type"`
-	CreatedAt       int64   `json:"created_at"`
-	Description     string  `json:"description"`
+	ID              string `json:"id"`
+	AccountID       string `json:"account_id"`
+	Amount          string `json:"amount"` // Decimal string (e.g., "50.00")
+	Currency        string `json:"currency"`
+	EntryType       string `json:"entry_type"`
+	TransactionID   string `json:"transaction_id"`
+	TransactionType string `json:"transaction_type"`
+	CreatedAt       int64  `json:"created_at"`
+	Description     string `json:"description"`
+
+	ReferenceTransactionID string `json:"reference_transaction_id,omitempty"`
+	Hash                   string `json:"hash,omitempty"`
+}
+
+// StatementQuery selects a page of an account's entries
+// Zero values mean "no filter"
+type StatementQuery struct {
+	AccountID       string
+	From            int64  // Unix time, inclusive
+	To              int64  // Unix time, inclusive
+	TransactionType string // e.g., TRANSFER
+	MinAmount       int64  // Cents, compared against the absolute entry amount
+	MaxAmount       int64  // Cents, compared against the absolute entry amount
+	Search          string // Case-insensitive substring of the description
+	Cursor          string // NextCursor of the previous page
+	Limit           int
+}
+
+// StatementLine is one entry of a statement with the account balance right after it
+type StatementLine struct {
+	Entry          *LedgerEntry
+	RunningBalance int64
+}
+
+// Statement is one page of an account's entries for a period
+// Opening and closing balances cover the whole period, regardless of the other filters
+type Statement struct {
+	AccountID      string
+	Currency       string
+	OpeningBalance int64 // Balance before From
+	ClosingBalance int64 // Balance after To
+	Lines          []*StatementLine
+	NextCursor     string // Empty on the last page
+}
+
+// ToDTO converts the statement to a user-friendly format with decimal string amounts
+func (s *Statement) ToDTO() *StatementDTO {
+	lines := make([]*StatementLineDTO, len(s.Lines))
+	for i, line := range s.Lines {
+		lines[i] = &StatementLineDTO{
+			LedgerEntryDTO: line.Entry.ToDTO(),
+			RunningBalance: currency.New(line.RunningBalance, s.Currency).String(),
+		}
+	}
+	return &StatementDTO{
+		AccountID:      s.AccountID,
+		Currency:       s.Currency,
+		OpeningBalance: currency.New(s.OpeningBalance, s.Currency).String(),
+		ClosingBalance: currency.New(s.ClosingBalance, s.Currency).String(),
+		Entries:        lines,
+		Count:          len(lines),
+		NextCursor:     s.NextCursor,
+	}
+}
+
+// StatementDTO is the API response format for a statement page
+type StatementDTO struct {
+	AccountID      string              `json:"account_id"`
+	Currency       string              `json:"currency"`
+	OpeningBalance string              `json:"opening_balance"`
+	ClosingBalance string              `json:"closing_balance"`
+	Entries        []*StatementLineDTO `json:"entries"`
+	Count          int                 `json:"count"`
+	NextCursor     string              `json:"next_cursor,omitempty"`
+}
+
+// StatementLineDTO is a statement entry with the running balance after it
+type StatementLineDTO struct {
+	*LedgerEntryDTO
+	RunningBalance string `json:"running_balance"`
 }
 
 // AccountBalance stores the cached balance for an account
@@ -103,26 +231,356 @@ type LedgerEntryDTO struct {
 type AccountBalance struct {
 	AccountID   string `json:"account_id"`
 	AccountType string `json:"account_type"`
-	Balance     int64  `json:"balance"` // Balance in cents
-	Currency    string `json:"currency"`
+	Balance     int64  `json:"balance"`  // Ledger balance in cents: the sum of all posted entries
+	Held        int64  `json:"held"`     // Cents reserved by active holds, not yet posted
+	Currency    string `json:"currency"` // Fixed when the account receives its first posting
 	UpdatedAt   int64  `json:"updated_at"`
 	LastEntryID string `json:"last_entry_id"` // Last ledger entry applied to this balance
 }
 
-// ToDTO converts the balance to a user-friendly format with dollar amounts
+// Available returns the balance that can still be spent: the ledger balance minus active holds
+func (b *AccountBalance) Available() int64 {
+	return b.Balance - b.Held
+}
+
+// ToDTO converts the balance to a user-friendly format with decimal string amounts
 func (b *AccountBalance) ToDTO() *AccountBalanceDTO {
 	return &AccountBalanceDTO{
-		AccountID: b.AccountID,
-		Balance:   currency.CentsToStandardCurrencyFormat(b.Balance),
-		Currency:  b.Currency,
-		UpdatedAt: b.UpdatedAt,
+		AccountID:        b.AccountID,
+		Balance:          currency.New(b.Balance, b.Currency).String(),
+		AvailableBalance: currency.New(b.Available(), b.Currency).String(),
+		Held:             currency.New(b.Held, b.Currency).String(),
+		Currency:         b.Currency,
+		UpdatedAt:        b.UpdatedAt,
 	}
 }
 
 // AccountBalanceDTO is the API response format
 type AccountBalanceDTO struct {
-	AccountID string  `json:"account_id"`
-	Balance   float64 `json:"balance"` // In standard format (e.g., 50.00)
-	Currency  string  `json:"currency"`
-	UpdatedAt int64   `json:"updated_at"`
+	AccountID        string `json:"account_id"`
+	Balance          string `json:"balance"`           // Ledger balance as a decimal string (e.g., "50.00")
+	AvailableBalance string `json:"available_balance"` // Ledger balance minus active holds
+	Held             string `json:"held"`
+	Currency         string `json:"currency"`
+	UpdatedAt        int64  `json:"updated_at"`
+}
+
+// BalanceCheckpoint records an account's balance as of a point in time
+// Historical balances start from the nearest earlier checkpoint instead of summing the full history
+type BalanceCheckpoint struct {
+	AccountID string `json:"account_id"`
+	AsOf      int64  `json:"as_of"`   // Unix time; covers every entry created at or before it
+	Balance   int64  `json:"balance"` // Sum of the account's entries up to AsOf, in cents
+}
+
+// HistoricalBalance is an account's ledger balance at a past point in time
+// Holds are not tracked historically, so there is no held or available amount
+type HistoricalBalance struct {
+	AccountID   string
+	AccountType string
+	Currency    string
+	Balance     int64
+	AsOf        int64
+}
+
+// ToDTO converts the balance to the API format with a decimal string amount
+func (b *HistoricalBalance) ToDTO() *HistoricalBalanceDTO {
+	return &HistoricalBalanceDTO{
+		AccountID: b.AccountID,
+		Balance:   currency.New(b.Balance, b.Currency).String(),
+		Currency:  b.Currency,
+		AsOf:      b.AsOf,
+	}
+}
+
+// HistoricalBalanceDTO is the API response format for balance-as-of queries
+type HistoricalBalanceDTO struct {
+	AccountID string `json:"account_id"`
+	Balance   string `json:"balance"` // Ledger balance at AsOf as a decimal string
+	Currency  string `json:"currency"`
+	AsOf      int64  `json:"as_of"`
+}
+
+// DepositRequestDTO is the API payload for depositing into the caller's wallet
+type DepositRequestDTO struct {
+	Amount      string `json:"amount"`             // Decimal string (e.g., "50.00")
+	Currency    string `json:"currency,omitempty"` // Optional, must match the wallet currency
+	Source      string `json:"source"`
+	Description string `json:"description"`
+}
+
+// WithdrawalRequestDTO is the API payload for withdrawing from the caller's wallet
+type WithdrawalRequestDTO struct {
+	Amount      string `json:"amount"`             // Decimal string (e.g., "50.00")
+	Currency    string `json:"currency,omitempty"` // Optional, must match the wallet currency
+	Destination string `json:"destination"`
+	Description string `json:"description"`
+}
+
+// TransferRequestDTO is the API payload for transferring from the caller's wallet to another wallet
+type TransferRequestDTO struct {
+	ToWalletID  string `json:"to_wallet_id"`
+	Amount      string `json:"amount"`             // Decimal string (e.g., "50.00")
+	Currency    string `json:"currency,omitempty"` // Optional, must match both wallets' currency
+	Description string `json:"description"`
+}
+
+// AccountStatusRequestDTO is the payload for changing a system account's status
+type AccountStatusRequestDTO struct {
+	Status           string `json:"status"` // OPEN (unfreeze), FROZEN or CLOSED
+	Reason           string `json:"reason"` // Required
+	AllowCredits     bool   `json:"allow_credits"`
+	SweepToAccountID string `json:"sweep_to_account_id"` // CLOSED only: where a remaining balance goes
+}
+
+// AccountActivity summarises an account's postings of one transaction type and side over a time window
+type AccountActivity struct {
+	Transactions int   // Matching entries: one per posting, or per item of a batch
+	Amount       int64 // Total posted, in cents, fees included
+}
+
+// Hold statuses
+const (
+	HoldStatusActive   = "ACTIVE"   // Funds reserved, can be captured or released
+	HoldStatusCaptured = "CAPTURED" // Funds (all or part) posted; any remainder released
+	HoldStatusReleased = "RELEASED" // Released without posting
+	HoldStatusExpired  = "EXPIRED"  // Released by the sweeper after ExpiresAt
+)
+
+// Hold reserves funds on an account without moving them (card-style authorization)
+// Holds reduce the available balance but never the ledger balance; only a capture posts entries
+type Hold struct {
+	ID                   string `json:"id"`
+	AccountID            string `json:"account_id"`
+	Amount               int64  `json:"amount"` // Reserved amount in cents
+	Currency             string `json:"currency"`
+	Destination          string `json:"destination"` // e.g., merchant or card network, for descriptions
+	Description          string `json:"description"`
+	Status               string `json:"status"`
+	CapturedAmount       int64  `json:"captured_amount"`
+	CaptureTransactionID string `json:"capture_transaction_id,omitempty"`
+	CreatedAt            int64  `json:"created_at"`
+	ExpiresAt            int64  `json:"expires_at"`
+	UpdatedAt            int64  `json:"updated_at"`
+}
+
+// FXQuote is a priced currency conversion that can be executed until it expires
+type FXQuote struct {
+	ID                    string `json:"id"`
+	FromAccountID         string `json:"from_account_id"`
+	ToAccountID           string `json:"to_account_id"`
+	FromCurrency          string `json:"from_currency"`
+	ToCurrency            string `json:"to_currency"`
+	SourceAmount          int64  `json:"source_amount"` // Debited from the sender, in FromCurrency cents
+	TargetAmount          int64  `json:"target_amount"` // Credited to the recipient, in ToCurrency cents
+	SpreadAmount          int64  `json:"spread_amount"` // Credited to FX revenue, in ToCurrency cents
+	MidRate               string `json:"mid_rate"`      // Units of ToCurrency per unit of FromCurrency
+	CustomerRate          string `json:"customer_rate"` // Mid rate after the spread
+	Description           string `json:"description"`
+	CreatedAt             int64  `json:"created_at"`
+	ExpiresAt             int64  `json:"expires_at"`
+	ExecutedTransactionID string `json:"executed_transaction_id,omitempty"`
+}
+
+// ToDTO converts the quote to a user-friendly format with decimal string amounts
+func (q *FXQuote) ToDTO() *FXQuoteDTO {
+	return &FXQuoteDTO{
+		ID:           q.ID,
+		FromCurrency: q.FromCurrency,
+		ToCurrency:   q.ToCurrency,
+		SourceAmount: currency.New(q.SourceAmount, q.FromCurrency).String(),
+		TargetAmount: currency.New(q.TargetAmount, q.ToCurrency).String(),
+		Fee:          currency.New(q.SpreadAmount, q.ToCurrency).String(),
+		Rate:         q.CustomerRate,
+		ExpiresAt:    q.ExpiresAt,
+	}
+}
+
+// FXQuoteDTO is the API response format for a conversion quote
+type FXQuoteDTO struct {
+	ID           string `json:"id"`
+	FromCurrency string `json:"from_currency"`
+	ToCurrency   string `json:"to_currency"`
+	SourceAmount string `json:"source_amount"`
+	TargetAmount string `json:"target_amount"`
+	Fee          string `json:"fee"` // Spread, in ToCurrency
+	Rate         string `json:"rate"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+// ConversionQuoteRequestDTO is the API payload for pricing a conversion from the caller's wallet
+type ConversionQuoteRequestDTO struct {
+	ToWalletID  string `json:"to_wallet_id"`
+	Amount      string `json:"amount"` // Decimal string in the caller's wallet currency
+	Description string `json:"description"`
+}
+
+// ConversionRequestDTO is the API payload for executing a quoted conversion
+type ConversionRequestDTO struct {
+	QuoteID string `json:"quote_id"`
+}
+
+// FeeQuote is the fee a transaction would be charged if it were posted now
+type FeeQuote struct {
+	TransactionType string `json:"transaction_type"`
+	AccountID       string `json:"account_id"` // The account paying the fee
+	Currency        string `json:"currency"`
+	Amount          int64  `json:"amount"`
+	Fee             int64  `json:"fee"`
+	Total           int64  `json:"total"`             // Debited for transfers and withdrawals, credited for deposits
+	RuleID          string `json:"rule_id,omitempty"` // Empty when no rule matches
+	FreeRemaining   int    `json:"free_remaining"`    // Free transactions left this month after this one
+}
+
+// ToDTO converts the quote to a user-friendly format with decimal string amounts
+func (q *FeeQuote) ToDTO() *FeeQuoteDTO {
+	return &FeeQuoteDTO{
+		TransactionType: q.TransactionType,
+		Currency:        q.Currency,
+		Amount:          currency.New(q.Amount, q.Currency).String(),
+		Fee:             currency.New(q.Fee, q.Currency).String(),
+		Total:           currency.New(q.Total, q.Currency).String(),
+		RuleID:          q.RuleID,
+		FreeRemaining:   q.FreeRemaining,
+	}
+}
+
+// FeeQuoteDTO is the API response format for a fee quote
+type FeeQuoteDTO struct {
+	TransactionType string `json:"transaction_type"`
+	Currency        string `json:"currency"`
+	Amount          string `json:"amount"`
+	Fee             string `json:"fee"`
+	Total           string `json:"total"` // Debited for transfers and withdrawals, credited for deposits
+	RuleID          string `json:"rule_id,omitempty"`
+	FreeRemaining   int    `json:"free_remaining"`
+}
+
+// FeeQuoteRequestDTO is the API payload for pricing the fee on a transaction from the caller's wallet
+type FeeQuoteRequestDTO struct {
+	TransactionType string `json:"transaction_type"` // TRANSFER, WITHDRAWAL or DEPOSIT
+	Amount          string `json:"amount"`           // Decimal string in the caller's wallet currency
+}
+
+// ToDTO converts the usage to a user-friendly format: decimal strings for amount limits, counts for HOURLY_COUNT
+func (u *LimitUsage) ToDTO() *LimitUsageDTO {
+	format := func(value int64) string {
+		if u.Kind == LimitHourlyCount {
+			return strconv.FormatInt(value, 10)
+		}
+		return currency.New(value, u.Currency).String()
+	}
+	return &LimitUsageDTO{
+		Kind:            u.Kind,
+		TransactionType: u.TransactionType,
+		Currency:        u.Currency,
+		Limit:           format(u.Value),
+		Used:            format(u.Used),
+		Remaining:       format(u.Remaining),
+		Overridden:      u.Overridden,
+	}
+}
+
+// LimitUsageDTO is the API response format for a limit and how much of it is left
+type LimitUsageDTO struct {
+	Kind            string `json:"kind"`
+	TransactionType string `json:"transaction_type"`
+	Currency        string `json:"currency"`
+	Limit           string `json:"limit"`
+	Used            string `json:"used"`
+	Remaining       string `json:"remaining"`
+	Overridden      bool   `json:"overridden"`
+}
+
+// AccountLimitsRequestDTO is the payload for setting an account's KYC level and limit overrides
+type AccountLimitsRequestDTO struct {
+	KYCLevel  string     `json:"kyc_level"` // NONE, BASIC or ENHANCED
+	Overrides []LimitDTO `json:"overrides"` // Replaces every existing override; empty clears them
+}
+
+// LimitDTO is one limit override in an API payload
+type LimitDTO struct {
+	Kind            string `json:"kind"` // PER_TRANSACTION, DAILY, MONTHLY or HOURLY_COUNT
+	TransactionType string `json:"transaction_type"`
+	Value           string `json:"value"` // Decimal string in the account's currency, or a count for HOURLY_COUNT
+}
+
+// BatchRequestDTO is the JSON body of a batch transfer from the caller's wallet
+type BatchRequestDTO struct {
+	Mode        string                `json:"mode"`     // ALL_OR_NOTHING or BEST_EFFORT
+	Currency    string                `json:"currency"` // Optional, must match the wallet
+	Description string                `json:"description"`
+	Items       []BatchItemRequestDTO `json:"items"`
+}
+
+// BatchItemRequestDTO is one recipient of a batch; CSV uploads use the same column names
+type BatchItemRequestDTO struct {
+	ToWalletID  string `json:"to_wallet_id"`
+	Amount      string `json:"amount"` // Decimal string (e.g., "1250.00")
+	Description string `json:"description"`
+}
+
+// ToDTO converts the batch to the API response format with decimal string amounts
+func (b *Batch) ToDTO() *BatchDTO {
+	items := make([]*BatchItemDTO, len(b.Items))
+	for i, item := range b.Items {
+		items[i] = &BatchItemDTO{
+			Index:         item.Index,
+			ToAccountID:   item.ToAccountID,
+			Amount:        currency.New(item.Amount, b.Currency).String(),
+			Description:   item.Description,
+			Status:        item.Status,
+			Fee:           currency.New(item.Fee, b.Currency).String(),
+			TransactionID: item.TransactionID,
+			Error:         item.Error,
+		}
+	}
+	return &BatchDTO{
+		ID:            b.ID,
+		FromAccountID: b.FromAccountID,
+		Currency:      b.Currency,
+		Mode:          b.Mode,
+		Description:   b.Description,
+		Status:        b.Status,
+		Error:         b.Error,
+		TransactionID: b.TransactionID,
+		TotalAmount:   currency.New(b.TotalAmount, b.Currency).String(),
+		TotalFees:     currency.New(b.TotalFees, b.Currency).String(),
+		Posted:        b.Posted,
+		Failed:        b.Failed,
+		Items:         items,
+		CreatedAt:     b.CreatedAt,
+		CompletedAt:   b.CompletedAt,
+	}
+}
+
+// BatchDTO is the API response format of a batch
+type BatchDTO struct {
+	ID            string          `json:"id"`
+	FromAccountID string          `json:"from_account_id"`
+	Currency      string          `json:"currency"`
+	Mode          string          `json:"mode"`
+	Description   string          `json:"description,omitempty"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	TransactionID string          `json:"transaction_id,omitempty"`
+	TotalAmount   string          `json:"total_amount"`
+	TotalFees     string          `json:"total_fees"`
+	Posted        int             `json:"posted"`
+	Failed        int             `json:"failed"`
+	Items         []*BatchItemDTO `json:"items"`
+	CreatedAt     int64           `json:"created_at"`
+	CompletedAt   int64           `json:"completed_at,omitempty"`
+}
+
+// BatchItemDTO is the API response format of one batch item
+type BatchItemDTO struct {
+	Index         int    `json:"index"`
+	ToAccountID   string `json:"to_account_id"`
+	Amount        string `json:"amount"`
+	Description   string `json:"description,omitempty"`
+	Status        string `json:"status"`
+	Fee           string `json:"fee"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Error         string `json:"error,omitempty"`
 }
@@ -0,0 +1,1139 @@
+package ledger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// queryer is satisfied by both *sql.DB and *sql.Tx so helpers can run inside or outside a transaction
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// postgresRepository implements Repository using PostgreSQL
+// Entry inserts and balance updates for a transaction share one database transaction
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a ledger repository backed by PostgreSQL
+// The schema is expected to exist already (see database.Migrate)
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+const entryColumns = `id, account_id, account_type, amount, currency, entry_type,
+	transaction_id, transaction_type, created_at, created_by, description, metadata, reference_transaction_id,
+	prev_hash, hash`
+
+// CreateEntry creates a single ledger entry and updates the balance in one transaction
+func (r *postgresRepository) CreateEntry(entry *LedgerEntry) error {
+	if err := entry.Validate(); err != nil {
+		log.Printf("Error: Invalid ledger entry: %v", err)
+		return err
+	}
+
+	return r.withTx(func(tx *sql.Tx) error {
+		return r.createEntries(tx, []*LedgerEntry{entry})
+	})
+}
+
+// CreateEntries creates multiple ledger entries atomically
+// Either every entry and balance update is committed, or none of them are
+func (r *postgresRepository) CreateEntries(entries []*LedgerEntry) error {
+	if err := checkEntries(entries); err != nil {
+		return err
+	}
+
+	err := r.withTx(func(tx *sql.Tx) error {
+		return r.createEntries(tx, entries)
+	})
+	if err != nil {
+		log.Printf("Error: Failed to create entries, transaction rolled back: %v", err)
+		return err
+	}
+
+	log.Printf("Transaction created successfully with %d entries", len(entries))
+	return nil
+}
+
+// createEntries posts a checked set of entries inside tx
+func (r *postgresRepository) createEntries(tx *sql.Tx, entries []*LedgerEntry) error {
+	// The chain lock comes before any account or balance row lock, on every path that posts,
+	// so postings and status changes can't each hold a lock the other is waiting for
+	if err := lockChain(tx); err != nil {
+		return err
+	}
+
+	// A transaction ID can only be posted once; postings are serialized by the chain lock,
+	// so two concurrent postings of the same ID can't both pass the existence check
+	for _, transactionID := range transactionIDs(entries) {
+		var posted bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ledger_entries WHERE transaction_id = $1)`,
+			transactionID).Scan(&posted); err != nil {
+			return err
+		}
+		if posted {
+			log.Printf("Error: Transaction %s has already been posted", transactionID)
+			return ErrDuplicateTransaction
+		}
+	}
+
+	// Copies are inserted, so a posting that fails leaves the caller's entries as they were
+	staged := make([]*LedgerEntry, len(entries))
+	for i, entry := range entries {
+		staged[i] = copyEntry(entry)
+		if err := r.insertEntry(tx, staged[i]); err != nil {
+			return err
+		}
+	}
+	for i, entry := range staged {
+		*entries[i] = *entry
+	}
+	return nil
+}
+
+// GetEntryByID retrieves a single ledger entry by ID
+func (r *postgresRepository) GetEntryByID(id string) (*LedgerEntry, error) {
+	row := r.db.QueryRow(`SELECT `+entryColumns+` FROM ledger_entries WHERE id = $1`, id)
+	entry, err := scanEntry(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error: Ledger entry not found: %s", id)
+			return nil, ErrLedgerEntryNotFound
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// GetEntriesByAccountID retrieves all ledger entries for an account in posting order
+func (r *postgresRepository) GetEntriesByAccountID(accountID string) ([]*LedgerEntry, error) {
+	entries, err := r.queryEntries(r.db,
+		`SELECT `+entryColumns+` FROM ledger_entries WHERE account_id = $1 ORDER BY seq`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Found %d entries for account %s", len(entries), accountID)
+	return entries, nil
+}
+
+// GetActivity counts and totals the account's entries of the given transaction type and side,
+// created between from and to inclusive
+func (r *postgresRepository) GetActivity(accountID, transactionType, entryType string, from, to int64) (*AccountActivity, error) {
+	activity := &AccountActivity{}
+	err := r.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(ABS(amount)), 0) FROM ledger_entries
+		WHERE account_id = $1 AND transaction_type = $2 AND entry_type = $3 AND created_at BETWEEN $4 AND $5`,
+		accountID, transactionType, entryType, from, to).Scan(&activity.Transactions, &activity.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// GetEntriesByTransactionID retrieves all ledger entries for a transaction in posting order
+func (r *postgresRepository) GetEntriesByTransactionID(transactionID string) ([]*LedgerEntry, error) {
+	entries, err := r.queryEntries(r.db,
+		`SELECT `+entryColumns+` FROM ledger_entries WHERE transaction_id = $1 ORDER BY seq`, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Found %d entries for transaction %s", len(entries), transactionID)
+	return entries, nil
+}
+
+// GetEntriesByReferenceTransactionID retrieves the compensating entries that reference a transaction
+func (r *postgresRepository) GetEntriesByReferenceTransactionID(transactionID string) ([]*LedgerEntry, error) {
+	return r.queryEntries(r.db,
+		`SELECT `+entryColumns+` FROM ledger_entries WHERE reference_transaction_id = $1 ORDER BY seq`, transactionID)
+}
+
+// GetStatement returns one page of an account's entries
+// Running, opening and closing balances come from the balance_after column, so only the page is read
+func (r *postgresRepository) GetStatement(query *StatementQuery) (*Statement, error) {
+	after, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := r.GetBalance(query.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	statement := &Statement{AccountID: query.AccountID, Currency: balance.Currency}
+
+	to := query.To
+	if to <= 0 {
+		to = math.MaxInt64
+	}
+	if statement.OpeningBalance, err = r.balanceAt(query.AccountID, `created_at < $2`, query.From); err != nil {
+		return nil, err
+	}
+	if statement.ClosingBalance, err = r.balanceAt(query.AccountID, `created_at <= $2`, to); err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	rows, err := r.db.Query(`SELECT `+entryColumns+`, seq, balance_after FROM ledger_entries
+		WHERE account_id = $1 AND created_at >= $2 AND created_at <= $3 AND seq > $4
+			AND ($5 = '' OR transaction_type = $5)
+			AND ABS(amount) >= $6 AND ($7 = 0 OR ABS(amount) <= $7)
+			AND ($8 = '' OR description ILIKE '%' || $8 || '%')
+		ORDER BY seq
+		LIMIT $9`,
+		query.AccountID, query.From, to, after, query.TransactionType,
+		query.MinAmount, query.MaxAmount, escapeLike(query.Search), query.Limit+1,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lastSeq int64
+	for rows.Next() {
+		var seq int64
+		line := &StatementLine{}
+		line.Entry, err = scanEntry(rows, &seq, &line.RunningBalance)
+		if err != nil {
+			return nil, err
+		}
+		if len(statement.Lines) == query.Limit {
+			statement.NextCursor = encodeCursor(lastSeq)
+			break
+		}
+		statement.Lines = append(statement.Lines, line)
+		lastSeq = seq
+	}
+	return statement, rows.Err()
+}
+
+// balanceAt returns the running balance after the last entry matching condition ($2 is bound to bound)
+// created_at never goes back as seq grows, so the last entry by time is also the last one posted
+func (r *postgresRepository) balanceAt(accountID, condition string, bound int64) (int64, error) {
+	var balance int64
+	err := r.db.QueryRow(`SELECT balance_after FROM ledger_entries
+		WHERE account_id = $1 AND `+condition+`
+		ORDER BY created_at DESC, seq DESC LIMIT 1`, accountID, bound).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return balance, err
+}
+
+// escapeLike escapes LIKE wildcards so a search matches literally
+func escapeLike(search string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(search)
+}
+
+// GetChain returns up to limit entries after the given sequence number, in posting order
+func (r *postgresRepository) GetChain(afterSequence int64, limit int) ([]*ChainLink, error) {
+	rows, err := r.db.Query(`SELECT `+entryColumns+`, seq FROM ledger_entries WHERE seq > $1 ORDER BY seq LIMIT $2`,
+		afterSequence, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*ChainLink
+	for rows.Next() {
+		link := &ChainLink{}
+		if link.Entry, err = scanEntry(rows, &link.Sequence); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// GetChainHeadAt returns the last entry, in posting order, created at or before at
+func (r *postgresRepository) GetChainHeadAt(at int64) (*ChainLink, error) {
+	link := &ChainLink{}
+	var err error
+	row := r.db.QueryRow(`SELECT `+entryColumns+`, seq FROM ledger_entries WHERE created_at <= $1 ORDER BY seq DESC LIMIT 1`, at)
+	if link.Entry, err = scanEntry(row, &link.Sequence); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return link, nil
+}
+
+// GetBalance retrieves the cached balance for an account
+func (r *postgresRepository) GetBalance(accountID string) (*AccountBalance, error) {
+	balance := &AccountBalance{}
+	err := r.db.QueryRow(`SELECT account_id, account_type, balance, held, currency, updated_at, last_entry_id
+		FROM account_balances WHERE account_id = $1`, accountID).Scan(
+		&balance.AccountID, &balance.AccountType, &balance.Balance, &balance.Held,
+		&balance.Currency, &balance.UpdatedAt, &balance.LastEntryID,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error: Account balance not found: %s", accountID)
+			return nil, ErrAccountBalanceNotFound
+		}
+		return nil, err
+	}
+	return balance, nil
+}
+
+// CreateOrUpdateBalance updates the cached balance for an account
+func (r *postgresRepository) CreateOrUpdateBalance(accountID, accountType, currency string, amountChange int64, lastEntryID string) error {
+	_, err := upsertBalance(r.db, accountID, accountType, currency, amountChange, lastEntryID)
+	return err
+}
+
+// CalculateBalanceFromEntries recalculates an account's balance from all ledger entries
+func (r *postgresRepository) CalculateBalanceFromEntries(accountID string) (int64, error) {
+	var balance int64
+	err := r.db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account_id = $1`,
+		accountID).Scan(&balance)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Calculated balance for account %s: %d", accountID, balance)
+	return balance, nil
+}
+
+// balanceAtSQL selects each account's balance as of the time bound to param:
+// its latest checkpoint at or before then plus the entries created since
+func balanceAtSQL(param string) string {
+	return `SELECT b.account_id, COALESCE(cp.balance, 0) + COALESCE((
+			SELECT SUM(e.amount) FROM ledger_entries e
+			WHERE e.account_id = b.account_id
+				AND e.created_at > COALESCE(cp.as_of, -9223372036854775808) AND e.created_at <= ` + param + `
+		), 0)
+		FROM account_balances b
+		LEFT JOIN LATERAL (
+			SELECT as_of, balance FROM balance_checkpoints c
+			WHERE c.account_id = b.account_id AND c.as_of <= ` + param + `
+			ORDER BY as_of DESC LIMIT 1
+		) cp ON TRUE`
+}
+
+// GetBalanceAt returns an account's balance as of at, from its nearest checkpoint plus the entries since
+func (r *postgresRepository) GetBalanceAt(accountID string, at int64) (int64, error) {
+	var id string
+	var balance int64
+	err := r.db.QueryRow(balanceAtSQL("$2")+` WHERE b.account_id = $1`, accountID, at).Scan(&id, &balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrAccountBalanceNotFound
+	}
+	return balance, err
+}
+
+// CreateBalanceCheckpoints records every account's balance as of asOf
+func (r *postgresRepository) CreateBalanceCheckpoints(asOf int64) (int, error) {
+	result, err := r.db.Exec(`INSERT INTO balance_checkpoints (account_id, as_of, balance)
+		SELECT account_id, $1::BIGINT, balance FROM (`+balanceAtSQL("$1")+`) AS balances (account_id, balance)
+		ON CONFLICT (account_id, as_of) DO NOTHING`, asOf)
+	if err != nil {
+		return 0, err
+	}
+	created, err := result.RowsAffected()
+	return int(created), err
+}
+
+// GetAllBalances returns every cached balance, ordered by account ID
+func (r *postgresRepository) GetAllBalances() ([]*AccountBalance, error) {
+	rows, err := r.db.Query(`SELECT account_id, account_type, balance, held, currency, updated_at, last_entry_id
+		FROM account_balances ORDER BY account_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := []*AccountBalance{}
+	for rows.Next() {
+		balance := &AccountBalance{}
+		if err := rows.Scan(&balance.AccountID, &balance.AccountType, &balance.Balance, &balance.Held,
+			&balance.Currency, &balance.UpdatedAt, &balance.LastEntryID); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// RebuildBalances compares every cached balance with the sum of its entries and overwrites the ones
+// that differ unless this is a dry run; a repair locks account_balances so postings wait until it commits
+// Entries posted on a drifted balance carry the drift in balance_after, so repaired accounts get their
+// running balances recomputed too, keeping statements and as-of balances in line with the repair
+func (r *postgresRepository) RebuildBalances(rebuild *BalanceRebuild) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		if !rebuild.DryRun {
+			if _, err := tx.Exec(`LOCK TABLE account_balances IN EXCLUSIVE MODE`); err != nil {
+				return fmt.Errorf("error locking balances: %w", err)
+			}
+		}
+
+		rows, err := tx.Query(`SELECT COALESCE(b.account_id, e.account_id), COALESCE(b.account_type, e.account_type),
+				COALESCE(b.currency, e.currency), COALESCE(b.balance, 0), COALESCE(e.total, 0),
+				b.account_id IS NULL, COALESCE(e.last_entry_id, b.last_entry_id)
+			FROM account_balances b
+			FULL OUTER JOIN (
+				SELECT account_id, MIN(account_type) AS account_type, MIN(currency) AS currency, SUM(amount) AS total,
+					(ARRAY_AGG(id ORDER BY seq DESC))[1] AS last_entry_id
+				FROM ledger_entries GROUP BY account_id
+			) e ON e.account_id = b.account_id
+			ORDER BY 1`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			correction := &BalanceCorrection{}
+			if err := rows.Scan(&correction.AccountID, &correction.AccountType, &correction.Currency, &correction.Cached,
+				&correction.Rebuilt, &correction.Missing, &correction.LastEntryID); err != nil {
+				return err
+			}
+			rebuild.AccountsChecked++
+			if correction.Missing || correction.Cached != correction.Rebuilt {
+				rebuild.Corrections = append(rebuild.Corrections, correction)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if rebuild.DryRun {
+			return nil
+		}
+
+		for _, correction := range rebuild.Corrections {
+			_, err := tx.Exec(`INSERT INTO account_balances (account_id, account_type, balance, currency, updated_at, last_entry_id)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (account_id) DO UPDATE
+				SET balance = EXCLUDED.balance, updated_at = EXCLUDED.updated_at, last_entry_id = EXCLUDED.last_entry_id`,
+				correction.AccountID, correction.AccountType, correction.Rebuilt, correction.Currency, rebuild.RunAt, correction.LastEntryID)
+			if err != nil {
+				return fmt.Errorf("error repairing balance of %s: %w", correction.AccountID, err)
+			}
+			_, err = tx.Exec(`UPDATE ledger_entries e SET balance_after = r.running
+				FROM (
+					SELECT seq, SUM(amount) OVER (ORDER BY seq) AS running
+					FROM ledger_entries WHERE account_id = $1
+				) r
+				WHERE e.seq = r.seq AND e.balance_after <> r.running`, correction.AccountID)
+			if err != nil {
+				return fmt.Errorf("error repairing running balances of %s: %w", correction.AccountID, err)
+			}
+		}
+
+		corrections, err := json.Marshal(rebuild.Corrections)
+		if err != nil {
+			return fmt.Errorf("error encoding balance corrections: %w", err)
+		}
+		_, err = tx.Exec(`INSERT INTO balance_rebuilds (id, run_at, requested_by, accounts_checked, corrections)
+			VALUES ($1, $2, $3, $4, $5)`,
+			rebuild.ID, rebuild.RunAt, rebuild.RequestedBy, rebuild.AccountsChecked, corrections)
+		return err
+	})
+}
+
+// GetBalanceRebuilds returns up to limit stored repairs, newest first
+func (r *postgresRepository) GetBalanceRebuilds(limit int) ([]*BalanceRebuild, error) {
+	rows, err := r.db.Query(`SELECT id, run_at, requested_by, accounts_checked, corrections
+		FROM balance_rebuilds ORDER BY seq DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rebuilds := []*BalanceRebuild{}
+	for rows.Next() {
+		rebuild := &BalanceRebuild{}
+		var corrections []byte
+		if err := rows.Scan(&rebuild.ID, &rebuild.RunAt, &rebuild.RequestedBy, &rebuild.AccountsChecked, &corrections); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(corrections, &rebuild.Corrections); err != nil {
+			return nil, fmt.Errorf("error decoding balance corrections: %w", err)
+		}
+		rebuilds = append(rebuilds, rebuild)
+	}
+	return rebuilds, rows.Err()
+}
+
+// VerifyTransactionBalance verifies that all entries for a transaction sum to zero
+func (r *postgresRepository) VerifyTransactionBalance(transactionID string) error {
+	var sum int64
+	err := r.db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE transaction_id = $1`,
+		transactionID).Scan(&sum)
+	if err != nil {
+		return err
+	}
+
+	if sum != 0 {
+		log.Printf("Error: Transaction %s does not balance! Sum: %d", transactionID, sum)
+		return ErrTransactionNotBalanced
+	}
+
+	log.Printf("Transaction %s verified: balances to zero ✓", transactionID)
+	return nil
+}
+
+// GetUnbalancedTransactions sums every transaction's entries per currency and returns the non-zero sums
+func (r *postgresRepository) GetUnbalancedTransactions() ([]*TransactionImbalance, error) {
+	rows, err := r.db.Query(`SELECT transaction_id, currency, SUM(amount) FROM ledger_entries
+		GROUP BY transaction_id, currency HAVING SUM(amount) <> 0
+		ORDER BY transaction_id, currency`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imbalances []*TransactionImbalance
+	for rows.Next() {
+		imbalance := &TransactionImbalance{}
+		if err := rows.Scan(&imbalance.TransactionID, &imbalance.Currency, &imbalance.Sum); err != nil {
+			return nil, err
+		}
+		imbalances = append(imbalances, imbalance)
+	}
+	return imbalances, rows.Err()
+}
+
+// SaveAuditReport stores an audit report and its alerts in one transaction
+func (r *postgresRepository) SaveAuditReport(report *AuditReport) error {
+	document, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error encoding audit report: %w", err)
+	}
+
+	return r.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`INSERT INTO ledger_audit_reports (id, run_at, passed, report) VALUES ($1, $2, $3, $4)`,
+			report.ID, report.RunAt, report.Passed, document); err != nil {
+			return err
+		}
+		for _, alert := range report.Alerts {
+			if _, err := tx.Exec(`INSERT INTO ledger_audit_alerts (`+auditAlertColumns+`)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				alert.ID, alert.ReportID, alert.Kind, alert.AccountID, alert.TransactionID, alert.Currency,
+				alert.Expected, alert.Actual, alert.Message, alert.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLatestAuditReport returns the most recently stored audit report
+func (r *postgresRepository) GetLatestAuditReport() (*AuditReport, error) {
+	var document []byte
+	err := r.db.QueryRow(`SELECT report FROM ledger_audit_reports ORDER BY seq DESC LIMIT 1`).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAuditReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AuditReport{}
+	if err := json.Unmarshal(document, report); err != nil {
+		return nil, fmt.Errorf("error decoding audit report: %w", err)
+	}
+	return report, nil
+}
+
+const auditAlertColumns = `id, report_id, kind, account_id, transaction_id, currency, expected, actual, message, created_at`
+
+// GetAuditAlerts returns up to limit alerts, newest first
+func (r *postgresRepository) GetAuditAlerts(limit int) ([]*AuditAlert, error) {
+	rows, err := r.db.Query(`SELECT `+auditAlertColumns+` FROM ledger_audit_alerts ORDER BY seq DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []*AuditAlert{}
+	for rows.Next() {
+		alert := &AuditAlert{}
+		if err := rows.Scan(&alert.ID, &alert.ReportID, &alert.Kind, &alert.AccountID, &alert.TransactionID,
+			&alert.Currency, &alert.Expected, &alert.Actual, &alert.Message, &alert.CreatedAt); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+const accountColumns = `id, name, type, currency, normal_balance, status, status_reason, allow_credits,
+	COALESCE(parent_id, ''), is_group, funding_source, created_at, updated_at`
+
+// CreateAccount adds an account to the chart
+func (r *postgresRepository) CreateAccount(account *Account) error {
+	var parentID any
+	if account.ParentID != "" {
+		parentID = account.ParentID
+	}
+	result, err := r.db.Exec(`INSERT INTO ledger_accounts (id, name, type, currency, normal_balance, status, status_reason,
+			allow_credits, parent_id, is_group, funding_source, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (id) DO NOTHING`,
+		account.ID, account.Name, account.Type, account.Currency, account.NormalBalance, account.Status, account.StatusReason,
+		account.AllowCredits, parentID, account.Group, account.FundingSource, account.CreatedAt, account.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if created, err := result.RowsAffected(); err == nil && created == 0 {
+		return ErrAccountExists
+	}
+	return nil
+}
+
+// GetAccount returns a registered account
+func (r *postgresRepository) GetAccount(id string) (*Account, error) {
+	account, err := scanAccount(r.db.QueryRow(`SELECT `+accountColumns+` FROM ledger_accounts WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	return account, err
+}
+
+// ListAccounts returns every registered account, ordered by ID
+func (r *postgresRepository) ListAccounts() ([]*Account, error) {
+	rows, err := r.db.Query(`SELECT ` + accountColumns + ` FROM ledger_accounts ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// FindSystemAccount returns the posting account of a type and currency registered for source,
+// falling back to the one without a funding source
+func (r *postgresRepository) FindSystemAccount(accountType, currency, source string) (*Account, error) {
+	account, err := scanAccount(r.db.QueryRow(`SELECT `+accountColumns+` FROM ledger_accounts
+		WHERE type = $1 AND currency = $2 AND NOT is_group AND (funding_source = $3 OR funding_source = '')
+		ORDER BY funding_source = $3 DESC LIMIT 1`, accountType, currency, source))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoSystemAccount
+	}
+	return account, err
+}
+
+// ChangeAccountStatus posts the closure sweep (if any) and applies the status change in one transaction
+// The account must still have the change's FromStatus, and an account being closed must end up empty
+func (r *postgresRepository) ChangeAccountStatus(change *AccountStatusChange, sweep []*LedgerEntry) error {
+	if len(sweep) > 0 {
+		if err := checkEntries(sweep); err != nil {
+			return err
+		}
+	}
+
+	return r.withTx(func(tx *sql.Tx) error {
+		// A sweep posts entries, so like every posting it takes the chain lock before the account row
+		if len(sweep) > 0 {
+			if err := lockChain(tx); err != nil {
+				return err
+			}
+		}
+
+		// The row lock keeps postings (which take it FOR SHARE) out until the new status is committed
+		var status string
+		err := tx.QueryRow(`SELECT status FROM ledger_accounts WHERE id = $1 FOR UPDATE`, change.AccountID).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status != change.FromStatus {
+			log.Printf("Error: Account %s is %s, expected %s", change.AccountID, status, change.FromStatus)
+			return ErrStatusChanged
+		}
+
+		// Sweep first: the account still accepts it until the status is updated below
+		if len(sweep) > 0 {
+			if err := r.createEntries(tx, sweep); err != nil {
+				return err
+			}
+		}
+		if change.ToStatus == AccountStatusClosed {
+			var balance, held int64
+			err := tx.QueryRow(`SELECT balance, held FROM account_balances WHERE account_id = $1 FOR UPDATE`,
+				change.AccountID).Scan(&balance, &held)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if balance != 0 || held != 0 {
+				return ErrAccountNotEmpty
+			}
+		}
+
+		if _, err := tx.Exec(`UPDATE ledger_accounts SET status = $2, allow_credits = $3, status_reason = $4, updated_at = $5
+			WHERE id = $1`,
+			change.AccountID, change.ToStatus, change.ToStatus == AccountStatusFrozen && change.AllowCredits, change.Reason, change.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("error updating account %s: %w", change.AccountID, err)
+		}
+		_, err = tx.Exec(`INSERT INTO ledger_account_status_changes (`+statusChangeColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			change.ID, change.AccountID, change.FromStatus, change.ToStatus, change.AllowCredits, change.Reason,
+			change.ChangedBy, change.SweepAccountID, change.SweepTransactionID, change.CreatedAt,
+		)
+		return err
+	})
+}
+
+const statusChangeColumns = `id, account_id, from_status, to_status, allow_credits, reason, changed_by,
+	sweep_account_id, sweep_transaction_id, created_at`
+
+// GetAccountStatusChanges returns an account's status changes, oldest first
+func (r *postgresRepository) GetAccountStatusChanges(accountID string) ([]*AccountStatusChange, error) {
+	rows, err := r.db.Query(`SELECT `+statusChangeColumns+` FROM ledger_account_status_changes
+		WHERE account_id = $1 ORDER BY seq`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*AccountStatusChange{}
+	for rows.Next() {
+		change := &AccountStatusChange{}
+		err := rows.Scan(&change.ID, &change.AccountID, &change.FromStatus, &change.ToStatus, &change.AllowCredits,
+			&change.Reason, &change.ChangedBy, &change.SweepAccountID, &change.SweepTransactionID, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// GetAccountLimits retrieves an account's KYC level and limit overrides
+func (r *postgresRepository) GetAccountLimits(accountID string) (*AccountLimits, error) {
+	limits := &AccountLimits{}
+	var overrides []byte
+	err := r.db.QueryRow(`SELECT account_id, kyc_level, overrides, updated_by, updated_at
+		FROM ledger_account_limits WHERE account_id = $1`, accountID).
+		Scan(&limits.AccountID, &limits.KYCLevel, &overrides, &limits.UpdatedBy, &limits.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLimitsNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(overrides, &limits.Overrides); err != nil {
+		return nil, fmt.Errorf("error decoding limit overrides: %w", err)
+	}
+	return limits, nil
+}
+
+// SaveAccountLimits replaces an account's KYC level and limit overrides
+func (r *postgresRepository) SaveAccountLimits(limits *AccountLimits) error {
+	overrides, err := json.Marshal(limits.Overrides)
+	if err != nil {
+		return fmt.Errorf("error encoding limit overrides: %w", err)
+	}
+	_, err = r.db.Exec(`INSERT INTO ledger_account_limits (account_id, kyc_level, overrides, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (account_id) DO UPDATE
+		SET kyc_level = EXCLUDED.kyc_level, overrides = EXCLUDED.overrides,
+			updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at`,
+		limits.AccountID, limits.KYCLevel, overrides, limits.UpdatedBy, limits.UpdatedAt)
+	return err
+}
+
+// SaveBatch creates or replaces a batch; its items are stored with it as JSON
+func (r *postgresRepository) SaveBatch(batch *Batch) error {
+	document, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("error encoding batch: %w", err)
+	}
+	_, err = r.db.Exec(`INSERT INTO ledger_batches (id, from_account_id, status, created_at, batch)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, batch = EXCLUDED.batch`,
+		batch.ID, batch.FromAccountID, batch.Status, batch.CreatedAt, document)
+	return err
+}
+
+// GetBatch retrieves a batch and its items
+func (r *postgresRepository) GetBatch(id string) (*Batch, error) {
+	var document []byte
+	err := r.db.QueryRow(`SELECT batch FROM ledger_batches WHERE id = $1`, id).Scan(&document)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &Batch{}
+	if err := json.Unmarshal(document, batch); err != nil {
+		return nil, fmt.Errorf("error decoding batch: %w", err)
+	}
+	return batch, nil
+}
+
+// scanAccount reads one account from a row selected with accountColumns
+func scanAccount(row interface{ Scan(dest ...any) error }) (*Account, error) {
+	account := &Account{}
+	err := row.Scan(&account.ID, &account.Name, &account.Type, &account.Currency, &account.NormalBalance, &account.Status,
+		&account.StatusReason, &account.AllowCredits, &account.ParentID, &account.Group, &account.FundingSource,
+		&account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+const holdColumns = `id, account_id, amount, currency, destination, description, status,
+	captured_amount, capture_transaction_id, created_at, expires_at, updated_at`
+
+// CreateHold stores a hold and reserves its amount on the account in one transaction
+func (r *postgresRepository) CreateHold(hold *Hold) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		var cur string
+		var balance, held int64
+		err := tx.QueryRow(`SELECT currency, balance, held FROM account_balances WHERE account_id = $1 FOR UPDATE`,
+			hold.AccountID).Scan(&cur, &balance, &held)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrAccountBalanceNotFound
+			}
+			return err
+		}
+		if cur != hold.Currency {
+			log.Printf("Error: Account %s holds %s, hold is in %s", hold.AccountID, cur, hold.Currency)
+			return ErrCurrencyMismatch
+		}
+		// Checked under the row lock, so holds placed through different replicas can't reserve the same funds
+		if balance-held < hold.Amount {
+			log.Printf("Error: Insufficient balance. Account %s has %d available (%d held), hold needs %d",
+				hold.AccountID, balance-held, held, hold.Amount)
+			return ErrInsufficientBalance
+		}
+
+		_, err = tx.Exec(`INSERT INTO ledger_holds (`+holdColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			hold.ID, hold.AccountID, hold.Amount, hold.Currency, hold.Destination, hold.Description, hold.Status,
+			hold.CapturedAmount, hold.CaptureTransactionID, hold.CreatedAt, hold.ExpiresAt, hold.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("error inserting hold %s: %w", hold.ID, err)
+		}
+
+		if _, err := tx.Exec(`UPDATE account_balances SET held = held + $2, updated_at = $3 WHERE account_id = $1`,
+			hold.AccountID, hold.Amount, time.Now().Unix()); err != nil {
+			return fmt.Errorf("error reserving hold %s: %w", hold.ID, err)
+		}
+		log.Printf("Hold placed: %s (account: %s, amount: %d, expires: %d)", hold.ID, hold.AccountID, hold.Amount, hold.ExpiresAt)
+		return nil
+	})
+}
+
+// GetHold retrieves a hold by ID
+func (r *postgresRepository) GetHold(id string) (*Hold, error) {
+	hold, err := scanHold(r.db.QueryRow(`SELECT `+holdColumns+` FROM ledger_holds WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error: Hold not found: %s", id)
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+// GetHoldsByAccountID retrieves all holds placed on an account, oldest first
+func (r *postgresRepository) GetHoldsByAccountID(accountID string) ([]*Hold, error) {
+	return r.queryHolds(`SELECT `+holdColumns+` FROM ledger_holds WHERE account_id = $1 ORDER BY created_at, id`, accountID)
+}
+
+// GetExpiredHolds retrieves active holds that expired at or before now
+func (r *postgresRepository) GetExpiredHolds(now int64) ([]*Hold, error) {
+	return r.queryHolds(`SELECT `+holdColumns+` FROM ledger_holds WHERE status = $1 AND expires_at <= $2`,
+		HoldStatusActive, now)
+}
+
+// CloseHold closes an active hold, releases its reserved amount and posts any capture entries in one transaction
+func (r *postgresRepository) CloseHold(hold *Hold, entries []*LedgerEntry) error {
+	if len(entries) > 0 {
+		if err := checkEntries(entries); err != nil {
+			return err
+		}
+	}
+
+	return r.withTx(func(tx *sql.Tx) error {
+		// Only an active hold can be closed; the status condition makes this a compare-and-set
+		var accountID string
+		var amount int64
+		err := tx.QueryRow(`UPDATE ledger_holds
+			SET status = $2, captured_amount = $3, capture_transaction_id = $4, updated_at = $5
+			WHERE id = $1 AND status = $6
+			RETURNING account_id, amount`,
+			hold.ID, hold.Status, hold.CapturedAmount, hold.CaptureTransactionID, hold.UpdatedAt, HoldStatusActive,
+		).Scan(&accountID, &amount)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				if _, getErr := r.GetHold(hold.ID); getErr != nil {
+					return getErr
+				}
+				return ErrHoldNotActive
+			}
+			return err
+		}
+
+		// Release the reservation before posting the capture, whose funds check would otherwise count it
+		// Like every posting, a capture takes the chain lock before the balance row
+		if len(entries) > 0 {
+			if err := lockChain(tx); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec(`UPDATE account_balances SET held = held - $2, updated_at = $3 WHERE account_id = $1`,
+			accountID, amount, time.Now().Unix()); err != nil {
+			return fmt.Errorf("error releasing hold %s: %w", hold.ID, err)
+		}
+
+		if len(entries) > 0 {
+			if err := r.createEntries(tx, entries); err != nil {
+				return err
+			}
+		}
+		log.Printf("Hold closed: %s (status: %s, captured: %d)", hold.ID, hold.Status, hold.CapturedAmount)
+		return nil
+	})
+}
+
+// queryHolds runs a query returning hold rows
+func (r *postgresRepository) queryHolds(query string, args ...any) ([]*Hold, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holds []*Hold
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, hold)
+	}
+	return holds, rows.Err()
+}
+
+// scanHold reads one hold from a row selected with holdColumns
+func scanHold(row interface{ Scan(dest ...any) error }) (*Hold, error) {
+	hold := &Hold{}
+	err := row.Scan(
+		&hold.ID, &hold.AccountID, &hold.Amount, &hold.Currency, &hold.Destination, &hold.Description, &hold.Status,
+		&hold.CapturedAmount, &hold.CaptureTransactionID, &hold.CreatedAt, &hold.ExpiresAt, &hold.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// withTx runs fn inside a database transaction, rolling back if fn returns an error
+func (r *postgresRepository) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("Error rolling back transaction: %v", rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// insertEntry writes one entry and applies it to the cached balance; callers must hold the chain lock
+func (r *postgresRepository) insertEntry(q queryer, entry *LedgerEntry) error {
+	// Generate ID if not provided
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+
+	// Set creation time if not provided
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().Unix()
+	}
+
+	// The account must be registered and accept the entry; FOR SHARE keeps it from being closed until commit
+	account, err := scanAccount(q.QueryRow(`SELECT `+accountColumns+` FROM ledger_accounts WHERE id = $1 FOR SHARE`, entry.AccountID))
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error: Posting to unknown ledger account %s", entry.AccountID)
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := account.checkPosting(entry); err != nil {
+		return err
+	}
+
+	var metadata []byte
+	if entry.Metadata != nil {
+		if metadata, err = json.Marshal(entry.Metadata); err != nil {
+			return fmt.Errorf("error encoding entry metadata: %w", err)
+		}
+	}
+
+	// Entries are chained in posting order, so appends are serialized: createEntries holds the chain lock
+	// until commit, which makes the last committed entry the one to chain to
+	var previous string
+	var latest int64
+	err = q.QueryRow(`SELECT hash, created_at FROM ledger_entries ORDER BY seq DESC LIMIT 1`).Scan(&previous, &latest)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// Callers stamp entries before they get here, so a posting that waited on the lock may carry an earlier
+	// time than the last entry; created_at must follow seq for statements and balances as of a time
+	entry.CreatedAt = max(entry.CreatedAt, latest)
+	if err := entry.seal(previous); err != nil {
+		return err
+	}
+
+	// Wallets can't be overdrawn whichever replica posts: the row lock makes the check and the update one step
+	if entry.AccountType == AccountTypeUserWallet && entry.Amount < 0 {
+		if err := checkAvailable(q, entry.AccountID, -entry.Amount); err != nil {
+			return err
+		}
+	}
+
+	// Apply the entry to the balance first: the new balance is stored on the entry as its running balance
+	balanceAfter, err := upsertBalance(q, entry.AccountID, entry.AccountType, entry.Currency, entry.Amount, entry.ID)
+	if err != nil {
+		log.Printf("Error updating balance for account %s: %v", entry.AccountID, err)
+		return err
+	}
+
+	_, err = q.Exec(`INSERT INTO ledger_entries (`+entryColumns+`, balance_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		entry.ID, entry.AccountID, entry.AccountType, entry.Amount, entry.Currency, entry.EntryType,
+		entry.TransactionID, entry.TransactionType, entry.CreatedAt, entry.CreatedBy, entry.Description, metadata,
+		entry.ReferenceTransactionID, entry.PrevHash, entry.Hash, balanceAfter,
+	)
+	if err != nil {
+		return fmt.Errorf("error inserting ledger entry %s: %w", entry.ID, err)
+	}
+	log.Printf("Ledger entry created: %s (account: %s, amount: %d, type: %s, txn: %s)",
+		entry.ID, entry.AccountID, entry.Amount, entry.EntryType, entry.TransactionID)
+	return nil
+}
+
+// lockChain takes the transaction-scoped lock that serializes appends to the entry chain
+// Taking it again in the same transaction returns at once
+func lockChain(q queryer) error {
+	_, err := q.Exec(`SELECT pg_advisory_xact_lock(hashtext('ledger_entries_chain'), 0)`)
+	return err
+}
+
+// checkAvailable locks the account's balance row until commit and fails with ErrInsufficientBalance
+// unless amount is available on top of what its holds reserve
+func checkAvailable(q queryer, accountID string, amount int64) error {
+	var balance, held int64
+	err := q.QueryRow(`SELECT balance, held FROM account_balances WHERE account_id = $1 FOR UPDATE`,
+		accountID).Scan(&balance, &held)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error: Insufficient balance. Account %s has no balance, needs %d", accountID, amount)
+		return ErrInsufficientBalance
+	}
+	if err != nil {
+		return err
+	}
+	if balance-held < amount {
+		log.Printf("Error: Insufficient balance. Account %s has %d available (%d held), needs %d",
+			accountID, balance-held, held, amount)
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// upsertBalance creates the balance row or adds amountChange to it, returning the new balance
+// The account's currency is fixed by the first insert; a change in another currency updates no row
+func upsertBalance(q queryer, accountID, accountType, currency string, amountChange int64, lastEntryID string) (int64, error) {
+	var balance int64
+	err := q.QueryRow(`INSERT INTO account_balances (account_id, account_type, balance, currency, updated_at, last_entry_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (account_id) DO UPDATE SET
+			balance       = account_balances.balance + EXCLUDED.balance,
+			updated_at    = EXCLUDED.updated_at,
+			last_entry_id = EXCLUDED.last_entry_id
+		WHERE account_balances.currency = EXCLUDED.currency
+		RETURNING balance`,
+		accountID, accountType, amountChange, currency, time.Now().Unix(), lastEntryID,
+	).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error: Account %s does not hold %s", accountID, currency)
+		return 0, ErrCurrencyMismatch
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error updating balance for account %s: %w", accountID, err)
+	}
+	return balance, nil
+}
+
+// queryEntries runs a query returning ledger entry rows
+func (r *postgresRepository) queryEntries(q queryer, query string, args ...any) ([]*LedgerEntry, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*LedgerEntry
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// scanEntry reads one ledger entry from a row selected with entryColumns
+// Any extra destinations receive columns selected after entryColumns
+func scanEntry(row interface{ Scan(dest ...any) error }, extra ...any) (*LedgerEntry, error) {
+	entry := &LedgerEntry{}
+	var metadata []byte
+	dest := []any{
+		&entry.ID, &entry.AccountID, &entry.AccountType, &entry.Amount, &entry.Currency, &entry.EntryType,
+		&entry.TransactionID, &entry.TransactionType, &entry.CreatedAt, &entry.CreatedBy, &entry.Description, &metadata,
+		&entry.ReferenceTransactionID, &entry.PrevHash, &entry.Hash,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &entry.Metadata); err != nil {
+			return nil, fmt.Errorf("error decoding entry metadata: %w", err)
+		}
+	}
+	return entry, nil
+}
@@ -0,0 +1,188 @@
+package ledger
+
+import (
+	"database/sql"
+	"digitalwallet/backend/internal/database"
+	"digitalwallet/backend/pkg"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestRepository returns a PostgreSQL-backed repository when LEDGER_TEST_DATABASE_URL
+// is set, so the whole suite can run against a real database, and the in-memory one otherwise
+// In CI the database is required, so the PostgreSQL repository can't go untested unnoticed
+func newTestRepository(t *testing.T) Repository {
+	t.Helper()
+	if testDatabaseURL(t) == "" {
+		return NewRepository()
+	}
+	return NewPostgresRepository(openTestDB(t))
+}
+
+// testDatabaseURL returns LEDGER_TEST_DATABASE_URL, failing the test when it is unset in CI
+func testDatabaseURL(t *testing.T) string {
+	t.Helper()
+	url := os.Getenv("LEDGER_TEST_DATABASE_URL")
+	if url == "" && os.Getenv("CI") != "" {
+		t.Fatal("LEDGER_TEST_DATABASE_URL must be set in CI; without it the PostgreSQL repository is never tested")
+	}
+	return url
+}
+
+// openTestDB connects to the test database, applies migrations, empties the ledger tables and registers the default chart
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := testDatabaseURL(t)
+	if url == "" {
+		t.Skip("LEDGER_TEST_DATABASE_URL not set; skipping PostgreSQL test")
+	}
+
+	db, err := database.Open(url)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if _, err := db.Exec(`TRUNCATE ledger_entries, account_balances, ledger_holds, balance_checkpoints, ledger_audit_alerts, ledger_audit_reports, balance_rebuilds, ledger_account_status_changes, ledger_account_limits, ledger_batches`); err != nil {
+		t.Fatalf("Failed to reset test database: %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM ledger_accounts WHERE NOT is_group`); err != nil {
+		t.Fatalf("Failed to reset test accounts: %v", err)
+	}
+	if _, err := NewService(NewPostgresRepository(db)).RegisterAccounts(DefaultChart()); err != nil {
+		t.Fatalf("Failed to register the default chart: %v", err)
+	}
+	return db
+}
+
+// TestPostgresCreateEntriesRollback tests that a failing entry rolls back the whole transaction
+func TestPostgresCreateEntriesRollback(t *testing.T) {
+	repo := NewPostgresRepository(openTestDB(t))
+	service := NewService(repo)
+
+	aliceWalletID := "alice-wallet-atomic"
+	bobWalletID := "bob-wallet-atomic"
+	openWallets(t, service, "USD", aliceWalletID, bobWalletID)
+
+	txnID, err := service.RecordDeposit(&DepositRequest{
+		AccountID:   aliceWalletID,
+		Amount:      10000,
+		Source:      "bank",
+		Description: "Initial deposit",
+	})
+	if err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	deposit, err := service.GetTransactionDetails(txnID)
+	if err != nil {
+		t.Fatalf("Failed to get deposit entries: %v", err)
+	}
+
+	// The second entry reuses an existing entry ID, so its insert violates the unique constraint
+	entries := []*LedgerEntry{
+		{
+			AccountID: aliceWalletID, AccountType: AccountTypeUserWallet, Amount: -5000, Currency: "USD",
+			EntryType: EntryTypeDebit, TransactionID: "txn-atomic", TransactionType: TransactionTypeTransfer,
+		},
+		{
+			ID: deposit[0].ID, AccountID: bobWalletID, AccountType: AccountTypeUserWallet, Amount: 5000, Currency: "USD",
+			EntryType: EntryTypeCredit, TransactionID: "txn-atomic", TransactionType: TransactionTypeTransfer,
+		},
+	}
+	if err := repo.CreateEntries(entries); err == nil {
+		t.Fatal("Expected CreateEntries to fail on duplicate entry ID")
+	}
+
+	// Alice's debit must have been rolled back together with Bob's failed credit
+	balance, err := service.GetBalance(aliceWalletID)
+	if err != nil {
+		t.Fatalf("Failed to get Alice's balance: %v", err)
+	}
+	if balance.Balance != 10000 {
+		t.Errorf("Expected Alice's balance to remain 10000, got %d", balance.Balance)
+	}
+	if _, err := service.GetBalance(bobWalletID); err != ErrAccountBalanceNotFound {
+		t.Errorf("Expected Bob to have no balance, got: %v", err)
+	}
+	partial, _ := service.GetTransactionDetails("txn-atomic")
+	if len(partial) != 0 {
+		t.Errorf("Expected no entries for the failed transaction, got %d", len(partial))
+	}
+	t.Logf("✓ Failed transaction left no partial entries or balances")
+}
+
+// TestPostgresRebuildRunningBalances tests that a repair also fixes the running balances posted on a drifted balance
+func TestPostgresRebuildRunningBalances(t *testing.T) {
+	db := openTestDB(t)
+	service := NewService(NewPostgresRepository(db))
+	openWallets(t, service, "USD", "alice-wallet-drift")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice-wallet-drift", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE account_balances SET balance = balance + 1000 WHERE account_id = 'alice-wallet-drift'`); err != nil {
+		t.Fatalf("Failed to corrupt the balance: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice-wallet-drift", Amount: 500, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if balance, _ := service.GetBalanceAt("alice-wallet-drift", time.Now()); balance.Balance != 11500 {
+		t.Fatalf("Expected the drift to reach the running balance, got %d", balance.Balance)
+	}
+
+	if _, err := service.RebuildBalances("tester", false, time.Now()); err != nil {
+		t.Fatalf("Failed to repair balances: %v", err)
+	}
+	if balance, _ := service.GetBalanceAt("alice-wallet-drift", time.Now()); balance.Balance != 10500 {
+		t.Errorf("Expected the repaired running balance to be 10500, got %d", balance.Balance)
+	}
+	statement, err := service.GetStatement(&StatementQuery{AccountID: "alice-wallet-drift"})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if statement.ClosingBalance != 10500 {
+		t.Errorf("Expected the statement to close at 10500, got %d", statement.ClosingBalance)
+	}
+}
+
+// TestPostgresStatusChangeDuringPostings tests that closing an account with a sweep while another replica posts
+// to it never deadlocks: both paths take the chain lock before any account or balance row
+func TestPostgresStatusChangeDuringPostings(t *testing.T) {
+	repo := NewPostgresRepository(openTestDB(t))
+	poster, closer := NewService(repo), NewService(repo)
+	openWallets(t, poster, "USD", "alice-wallet-order", "bob-wallet-order")
+	if _, err := poster.RecordDeposit(&DepositRequest{AccountID: "alice-wallet-order", Amount: 100000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_, err := poster.RecordTransfer(&TransferRequest{FromAccountID: "alice-wallet-order", ToAccountID: "bob-wallet-order", Amount: 100})
+			if err != nil && err != ErrAccountClosed {
+				t.Errorf("Unexpected transfer error: %v", err)
+				return
+			}
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	// A transfer landing between the sweep being drafted and posted leaves the account not empty; try again
+	for {
+		_, err := closer.CloseWalletAccount("bob-wallet-order", "Customer request", "admin-1", ExternalBankAccountID("USD"))
+		if err == nil {
+			break
+		}
+		if err != pkg.ErrWalletNotEmpty {
+			t.Errorf("Failed to close the account while it was being posted to: %v", err)
+			break
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,73 @@
+package ledger
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BalanceCorrection is an account whose cached balance differs from the sum of its entries
+type BalanceCorrection struct {
+	AccountID   string `json:"account_id"`
+	AccountType string `json:"account_type"`
+	Currency    string `json:"currency"`
+	Cached      int64  `json:"cached"`        // Cached balance before the rebuild, in cents
+	Rebuilt     int64  `json:"rebuilt"`       // Sum of the account's entries, in cents
+	Missing     bool   `json:"missing"`       // The account has entries but no cached balance
+	LastEntryID string `json:"last_entry_id"` // The account's last entry, recorded on the rebuilt balance
+}
+
+// Delta is what the rebuild adds to the cached balance
+func (c *BalanceCorrection) Delta() int64 {
+	return c.Rebuilt - c.Cached
+}
+
+// BalanceRebuild is one pass recomputing every cached balance from the entry log
+// Repairs are stored as an audit record of what changed; dry runs are not
+type BalanceRebuild struct {
+	ID              string               `json:"id"`
+	RunAt           int64                `json:"run_at"`
+	RequestedBy     string               `json:"requested_by"`
+	DryRun          bool                 `json:"dry_run"`
+	AccountsChecked int                  `json:"accounts_checked"`
+	Corrections     []*BalanceCorrection `json:"corrections"`
+}
+
+// RebuildBalances recomputes every account balance from its entries and reports the ones that differ
+// Unless dryRun is set, the differing balances are overwritten and the rebuild is stored for audit
+// Postings wait while a repair runs, so no entry lands between summing and writing a balance
+func (s *Service) RebuildBalances(requestedBy string, dryRun bool, now time.Time) (*BalanceRebuild, error) {
+	rebuild := &BalanceRebuild{
+		ID:          uuid.New().String(),
+		RunAt:       now.Unix(),
+		RequestedBy: requestedBy,
+		DryRun:      dryRun,
+		Corrections: []*BalanceCorrection{},
+	}
+	if err := s.repo.RebuildBalances(rebuild); err != nil {
+		return nil, err
+	}
+
+	for _, correction := range rebuild.Corrections {
+		action := "would change"
+		if !dryRun {
+			action = "changed"
+		}
+		log.Printf("Balance rebuild %s: %s %s from %d to %d", rebuild.ID, action, correction.AccountID, correction.Cached, correction.Rebuilt)
+	}
+	log.Printf("Balance rebuild %s checked %d accounts, %d differ (dry run: %t)",
+		rebuild.ID, rebuild.AccountsChecked, len(rebuild.Corrections), dryRun)
+	return rebuild, nil
+}
+
+// GetBalanceRebuilds returns the most recent balance repairs, newest first
+func (s *Service) GetBalanceRebuilds(limit int) ([]*BalanceRebuild, error) {
+	if limit <= 0 {
+		limit = DefaultAuditListLimit
+	}
+	if limit > MaxAuditListLimit {
+		limit = MaxAuditListLimit
+	}
+	return s.repo.GetBalanceRebuilds(limit)
+}
@@ -0,0 +1,65 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRebuildBalances tests that a dry run only reports drift and a repair fixes it and is recorded
+func TestRebuildBalances(t *testing.T) {
+	repo := NewRepository().(*inMemoryRepository)
+	service := NewService(repo)
+	openWallets(t, service, "USD", "alice", "bob")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 2500}); err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+	repo.balances["alice"].Balance = 9000
+	delete(repo.balances, "bob")
+
+	dryRun, err := service.RebuildBalances("tester", true, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to run dry rebuild: %v", err)
+	}
+	if dryRun.AccountsChecked != 3 || len(dryRun.Corrections) != 2 {
+		t.Fatalf("Expected 2 of 3 accounts to differ, got %+v", dryRun)
+	}
+	alice, bob := dryRun.Corrections[0], dryRun.Corrections[1]
+	if alice.AccountID != "alice" || alice.Cached != 9000 || alice.Rebuilt != 7500 || alice.Delta() != -1500 {
+		t.Errorf("Expected alice to be rebuilt from 90.00 to 75.00, got %+v", alice)
+	}
+	if bob.AccountID != "bob" || !bob.Missing || bob.Rebuilt != 2500 || bob.AccountType != AccountTypeUserWallet || bob.Currency != "USD" {
+		t.Errorf("Expected bob's missing balance to be rebuilt to 25.00, got %+v", bob)
+	}
+	if balance, _ := service.GetBalance("alice"); balance.Balance != 9000 {
+		t.Error("Expected a dry run to leave balances alone")
+	}
+	if rebuilds, _ := service.GetBalanceRebuilds(0); len(rebuilds) != 0 {
+		t.Error("Expected dry runs not to be recorded")
+	}
+
+	repair, err := service.RebuildBalances("tester", false, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to repair balances: %v", err)
+	}
+	for accountID, expected := range map[string]int64{"alice": 7500, "bob": 2500} {
+		balance, err := service.GetBalance(accountID)
+		if err != nil || balance.Balance != expected {
+			t.Errorf("Expected %s to be repaired to %d, got %v (%v)", accountID, expected, balance, err)
+		}
+	}
+	if ok, _ := service.VerifyAccountBalance("bob"); !ok {
+		t.Error("Expected bob's repaired balance to verify")
+	}
+
+	rebuilds, _ := service.GetBalanceRebuilds(0)
+	if len(rebuilds) != 1 || rebuilds[0].ID != repair.ID || rebuilds[0].RequestedBy != "tester" || len(rebuilds[0].Corrections) != 2 {
+		t.Errorf("Expected the repair to be recorded, got %v", rebuilds)
+	}
+	if again, _ := service.RebuildBalances("tester", true, time.Now()); len(again.Corrections) != 0 {
+		t.Errorf("Expected nothing left to repair, got %+v", again.Corrections)
+	}
+}
@@ -1,9 +1,11 @@
 package ledger
 
 import (
-	"digitalwallet/backend/pkg/currency"
 	"errors"
 	"log"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,42 +16,181 @@ var (
 	ErrAccountBalanceNotFound = errors.New("account balance not found")
 	ErrTransactionNotBalanced = errors.New("transaction entries do not sum to zero")
 	ErrInsufficientBalance    = errors.New("insufficient balance for this operation")
+	ErrDuplicateTransaction   = errors.New("transaction ID has already been posted")
+	ErrCurrencyMismatch       = errors.New("posting currency does not match the account currency")
+	ErrHoldNotFound           = errors.New("hold not found")
+	ErrHoldNotActive          = errors.New("hold has already been captured, released or expired")
 )
 
 // Repository defines the interface for ledger data operations
+// Entries can only be posted to registered accounts; see Account.checkPosting
 type Repository interface {
 	// Ledger Entry operations
 	CreateEntry(entry *LedgerEntry) error
-	CreateEntries(entries []*LedgerEntry) error // Atomic: all or nothing
+	CreateEntries(entries []*LedgerEntry) error // Atomic: all or nothing, rejects already-posted transaction IDs, currency mismatches and wallet overdrafts
 	GetEntryByID(id string) (*LedgerEntry, error)
 	GetEntriesByAccountID(accountID string) ([]*LedgerEntry, error)
 	GetEntriesByTransactionID(transactionID string) ([]*LedgerEntry, error)
+	GetEntriesByReferenceTransactionID(transactionID string) ([]*LedgerEntry, error)                    // Reversal and refund entries linked to a transaction
+	GetStatement(query *StatementQuery) (*Statement, error)                                             // One filtered page of an account's entries with running balances
+	GetChain(afterSequence int64, limit int) ([]*ChainLink, error)                                      // Entries in posting order, for walking the hash chain
+	GetChainHeadAt(at int64) (*ChainLink, error)                                                        // Last entry created at or before at, nil if none
+	GetActivity(accountID, transactionType, entryType string, from, to int64) (*AccountActivity, error) // The account's matching entries created in [from, to]
 
 	// Balance operations
 	GetBalance(accountID string) (*AccountBalance, error)
-	CreateOrUpdateBalance(accountID, accountType string, amountChange int64, lastEntryID string) error
+	CreateOrUpdateBalance(accountID, accountType, currency string, amountChange int64, lastEntryID string) error
 	CalculateBalanceFromEntries(accountID string) (int64, error)
+	GetBalanceAt(accountID string, at int64) (int64, error)  // Sum of entries created at or before at, starting from the nearest checkpoint
+	CreateBalanceCheckpoints(asOf int64) (int, error)        // Checkpoints every account as of asOf; accounts already checkpointed then are skipped
+	GetAllBalances() ([]*AccountBalance, error)              // Every cached balance, read as one consistent snapshot
+	RebuildBalances(rebuild *BalanceRebuild) error           // Fills in the corrections and, unless DryRun, applies and stores them atomically
+	GetBalanceRebuilds(limit int) ([]*BalanceRebuild, error) // Stored repairs, newest first
+
+	// Chart of accounts
+	CreateAccount(account *Account) error
+	GetAccount(id string) (*Account, error)
+	ListAccounts() ([]*Account, error)
+	FindSystemAccount(accountType, currency, source string) (*Account, error)    // The account for source, else the currency's default (no source)
+	ChangeAccountStatus(change *AccountStatusChange, sweep []*LedgerEntry) error // Atomic: posts the sweep, leaves a closed account empty, compare-and-sets the status
+	GetAccountStatusChanges(accountID string) ([]*AccountStatusChange, error)    // Oldest first
+	GetAccountLimits(accountID string) (*AccountLimits, error)                   // ErrLimitsNotFound if never set
+	SaveAccountLimits(limits *AccountLimits) error                               // Replaces the account's KYC level and overrides
+
+	// Hold operations
+	CreateHold(hold *Hold) error // Stores the hold and adds its amount to the account's held balance
+	GetHold(id string) (*Hold, error)
+	GetHoldsByAccountID(accountID string) ([]*Hold, error)
+	GetExpiredHolds(now int64) ([]*Hold, error)         // Active holds whose expiry has passed
+	CloseHold(hold *Hold, entries []*LedgerEntry) error // Atomic: closes an active hold, releases its amount and posts entries (if any)
+
+	// Batch operations
+	SaveBatch(batch *Batch) error // Creates or replaces the batch with its items
+	GetBatch(id string) (*Batch, error)
 
 	// Validation
 	VerifyTransactionBalance(transactionID string) error
+	GetUnbalancedTransactions() ([]*TransactionImbalance, error) // Transactions whose entries don't sum to zero in a currency
+
+	// Audit operations
+	SaveAuditReport(report *AuditReport) error // Stores the report together with its alerts
+	GetLatestAuditReport() (*AuditReport, error)
+	GetAuditAlerts(limit int) ([]*AuditAlert, error) // Newest first
 }
 
 // inMemoryRepository implements Repository using in-memory storage
+// All access goes through mu so concurrent HTTP handlers cannot corrupt entries or balances
 type inMemoryRepository struct {
-	entries  []*LedgerEntry
-	balances map[string]*AccountBalance // key: accountID
+	mu            sync.RWMutex
+	entries       []*LedgerEntry                 // Posting order; an entry's sequence number is its index + 1
+	byAccount     map[string]*accountEntries     // key: accountID
+	byTransaction map[string][]int               // key: transactionID, value: indexes into entries
+	balances      map[string]*AccountBalance     // key: accountID
+	accounts      map[string]*Account            // key: accountID; the chart of accounts
+	statusChanges []*AccountStatusChange         // Change order
+	limits        map[string]*AccountLimits      // key: accountID
+	holds         map[string]*Hold               // key: holdID
+	batches       map[string]*Batch              // key: batchID
+	checkpoints   map[string][]BalanceCheckpoint // key: accountID, ordered by AsOf
+	auditReports  []*AuditReport                 // Run order
+	auditAlerts   []*AuditAlert                  // Creation order
+	rebuilds      []*BalanceRebuild              // Stored repairs, in run order
+}
+
+// accountEntries indexes one account's entries in posting order, which is also time order
+// because createEntries never lets an entry's creation time go back past the last posted entry
+type accountEntries struct {
+	positions []int   // Indexes into entries
+	createdAt []int64 // CreatedAt of each entry, for binary search by time
+	running   []int64 // Account balance right after each entry
 }
 
-// NewRepository creates a new in-memory ledger repository
+// NewRepository creates a new in-memory ledger repository, seeded with the default chart of accounts
 func NewRepository() Repository {
-	return &inMemoryRepository{
-		entries:  []*LedgerEntry{},
-		balances: make(map[string]*AccountBalance),
+	r := &inMemoryRepository{
+		entries:       []*LedgerEntry{},
+		byAccount:     make(map[string]*accountEntries),
+		byTransaction: make(map[string][]int),
+		balances:      make(map[string]*AccountBalance),
+		accounts:      make(map[string]*Account),
+		limits:        make(map[string]*AccountLimits),
+		holds:         make(map[string]*Hold),
+		batches:       make(map[string]*Batch),
+		checkpoints:   make(map[string][]BalanceCheckpoint),
 	}
+	now := time.Now().Unix()
+	for _, account := range DefaultChart() {
+		account.CreatedAt, account.UpdatedAt = now, now
+		r.accounts[account.ID] = account
+	}
+	return r
 }
 
 // CreateEntry creates a single ledger entry
 func (r *inMemoryRepository) CreateEntry(entry *LedgerEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.createEntries([]*LedgerEntry{entry})
+}
+
+// CreateEntries creates multiple ledger entries atomically
+// This is the primary method for creating transactions (which need multiple entries)
+func (r *inMemoryRepository) CreateEntries(entries []*LedgerEntry) error {
+	if err := checkEntries(entries); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.createEntries(entries)
+}
+
+// createEntries posts a set of entries; callers must hold mu
+// Copies of the entries are checked and sealed before the first one is stored, so a failure leaves both the
+// ledger and the caller's entries untouched; on success the caller's entries get their IDs, times and hashes
+func (r *inMemoryRepository) createEntries(entries []*LedgerEntry) error {
+	// A transaction ID can only be posted once, otherwise a retried request would post twice
+	for _, transactionID := range transactionIDs(entries) {
+		if len(r.getEntriesByTransactionID(transactionID)) > 0 {
+			log.Printf("Error: Transaction %s has already been posted", transactionID)
+			return ErrDuplicateTransaction
+		}
+	}
+
+	previous, latest := "", int64(0)
+	if n := len(r.entries); n > 0 {
+		previous, latest = r.entries[n-1].Hash, r.entries[n-1].CreatedAt
+	}
+	staged := make([]*LedgerEntry, len(entries))
+	spent := make(map[string]int64) // Wallet debits of this set so far, per account
+	for i, entry := range entries {
+		entry = copyEntry(entry)
+		staged[i] = entry
+		if err := r.checkEntry(entry, spent); err != nil {
+			return err
+		}
+		// Callers stamp entries before taking the lock, so a posting that lost the race for it may carry
+		// an earlier time than the last entry; time order must follow posting order for searches by time
+		entry.CreatedAt = max(entry.CreatedAt, latest)
+		latest = entry.CreatedAt
+		// Chain each entry to the one before it, including earlier entries of this set
+		if err := entry.seal(previous); err != nil {
+			return err
+		}
+		previous = entry.Hash
+	}
+
+	for i, entry := range staged {
+		r.storeEntry(entry)
+		*entries[i] = *entry
+	}
+	log.Printf("Transaction created successfully with %d entries", len(entries))
+	return nil
+}
+
+// checkEntry fills in the entry's ID and creation time and checks it can be posted; callers must hold mu
+// spent carries the wallet debits of the entries checked before it in the same set
+func (r *inMemoryRepository) checkEntry(entry *LedgerEntry, spent map[string]int64) error {
 	// Validate the entry follows double-entry rules
 	if err := entry.Validate(); err != nil {
 		log.Printf("Error: Invalid ledger entry: %v", err)
@@ -66,59 +207,61 @@ func (r *inMemoryRepository) CreateEntry(entry *LedgerEntry) error {
 		entry.CreatedAt = time.Now().Unix()
 	}
 
-	// Store the entry
-	r.entries = append(r.entries, entry)
-	log.Printf("Ledger entry created: %s (account: %s, amount: %d, type: %s, txn: %s)",
-		entry.ID, entry.AccountID, entry.Amount, entry.EntryType, entry.TransactionID)
-
-	// Update the account balance
-	if err := r.CreateOrUpdateBalance(entry.AccountID, entry.AccountType, entry.Amount, entry.ID); err != nil {
-		log.Printf("Error updating balance for account %s: %v", entry.AccountID, err)
+	// The account must be registered and accept the entry
+	if err := r.checkAccount(entry); err != nil {
 		return err
 	}
+	balance, exists := r.balances[entry.AccountID]
+	if exists && balance.Currency != entry.Currency {
+		log.Printf("Error: Account %s holds %s, entry is in %s", entry.AccountID, balance.Currency, entry.Currency)
+		return ErrCurrencyMismatch
+	}
 
+	// Wallets can't be overdrawn, nor spend what their holds reserve
+	if entry.AccountType == AccountTypeUserWallet && entry.Amount < 0 {
+		spent[entry.AccountID] -= entry.Amount
+		var available int64
+		if exists {
+			available = balance.Available()
+		}
+		if available < spent[entry.AccountID] {
+			log.Printf("Error: Insufficient balance. Account %s has %d available, needs %d", entry.AccountID, available, spent[entry.AccountID])
+			return ErrInsufficientBalance
+		}
+	}
 	return nil
 }
 
-// CreateEntries creates multiple ledger entries atomically
-// This is the primary method for creating transactions (which need multiple entries)
-func (r *inMemoryRepository) CreateEntries(entries []*LedgerEntry) error {
-	// Validate all entries first
-	for i, entry := range entries {
-		if err := entry.Validate(); err != nil {
-			log.Printf("Error: Entry %d invalid: %v", i, err)
-			return err
-		}
-	}
+// storeEntry appends a checked, sealed entry and applies it to the account balance; callers must hold mu
+// A copy is stored so callers can't alter the ledger through their pointer
+func (r *inMemoryRepository) storeEntry(entry *LedgerEntry) {
+	r.entries = append(r.entries, copyEntry(entry))
+	r.indexEntry(len(r.entries) - 1)
+	log.Printf("Ledger entry created: %s (account: %s, amount: %d, type: %s, txn: %s)",
+		entry.ID, entry.AccountID, entry.Amount, entry.EntryType, entry.TransactionID)
 
-	// Verify the transaction balances to zero
-	var sum int64
-	for _, entry := range entries {
-		sum += entry.Amount
-	}
-	if sum != 0 {
-		log.Printf("Error: Transaction does not balance. Sum: %d", sum)
-		return ErrTransactionNotBalanced
-	}
+	// checkEntry has matched the entry's currency to the balance's, so this can't fail
+	r.createOrUpdateBalance(entry.AccountID, entry.AccountType, entry.Currency, entry.Amount, entry.ID)
+}
 
-	// Create all entries
-	for _, entry := range entries {
-		if err := r.CreateEntry(entry); err != nil {
-			// In a real database, this would be a transaction rollback
-			log.Printf("Error: Failed to create entry in transaction: %v", err)
-			return err
-		}
+// checkAccount verifies the entry's account is registered and can receive it; callers must hold mu
+func (r *inMemoryRepository) checkAccount(entry *LedgerEntry) error {
+	account, exists := r.accounts[entry.AccountID]
+	if !exists {
+		log.Printf("Error: Posting to unknown ledger account %s", entry.AccountID)
+		return ErrAccountNotFound
 	}
-
-	log.Printf("Transaction created successfully with %d entries", len(entries))
-	return nil
+	return account.checkPosting(entry)
 }
 
 // GetEntryByID retrieves a single ledger entry by ID
 func (r *inMemoryRepository) GetEntryByID(id string) (*LedgerEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, entry := range r.entries {
 		if entry.ID == id {
-			return entry, nil
+			return copyEntry(entry), nil
 		}
 	}
 	log.Printf("Error: Ledger entry not found: %s", id)
@@ -128,56 +271,235 @@ func (r *inMemoryRepository) GetEntryByID(id string) (*LedgerEntry, error) {
 // GetEntriesByAccountID retrieves all ledger entries for an account
 // This is useful for generating account statements
 func (r *inMemoryRepository) GetEntriesByAccountID(accountID string) ([]*LedgerEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var accountEntries []*LedgerEntry
-	for _, entry := range r.entries {
-		if entry.AccountID == accountID {
-			accountEntries = append(accountEntries, entry)
+	if index, exists := r.byAccount[accountID]; exists {
+		accountEntries = make([]*LedgerEntry, len(index.positions))
+		for i, position := range index.positions {
+			accountEntries[i] = copyEntry(r.entries[position])
 		}
 	}
 	log.Printf("Found %d entries for account %s", len(accountEntries), accountID)
 	return accountEntries, nil
 }
 
+// GetActivity counts and totals the account's entries of the given transaction type and side,
+// created between from and to inclusive
+func (r *inMemoryRepository) GetActivity(accountID, transactionType, entryType string, from, to int64) (*AccountActivity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	activity := &AccountActivity{}
+	index, exists := r.byAccount[accountID]
+	if !exists {
+		return activity, nil
+	}
+	start := sort.Search(len(index.createdAt), func(i int) bool { return index.createdAt[i] >= from })
+	for i := start; i < len(index.positions) && index.createdAt[i] <= to; i++ {
+		entry := r.entries[index.positions[i]]
+		if entry.TransactionType == transactionType && entry.EntryType == entryType {
+			activity.Transactions++
+			if entry.Amount < 0 {
+				activity.Amount -= entry.Amount
+			} else {
+				activity.Amount += entry.Amount
+			}
+		}
+	}
+	return activity, nil
+}
+
 // GetEntriesByTransactionID retrieves all ledger entries for a transaction
 // This shows the complete double-entry for a transaction
 func (r *inMemoryRepository) GetEntriesByTransactionID(transactionID string) ([]*LedgerEntry, error) {
-	var txnEntries []*LedgerEntry
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.getEntriesByTransactionID(transactionID), nil
+}
+
+// GetEntriesByReferenceTransactionID retrieves the compensating entries that reference a transaction
+func (r *inMemoryRepository) GetEntriesByReferenceTransactionID(transactionID string) ([]*LedgerEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var linked []*LedgerEntry
 	for _, entry := range r.entries {
-		if entry.TransactionID == transactionID {
-			txnEntries = append(txnEntries, entry)
+		if entry.ReferenceTransactionID == transactionID {
+			linked = append(linked, copyEntry(entry))
 		}
 	}
+	return linked, nil
+}
+
+// getEntriesByTransactionID looks up a transaction's entries; callers must hold mu
+func (r *inMemoryRepository) getEntriesByTransactionID(transactionID string) []*LedgerEntry {
+	var txnEntries []*LedgerEntry
+	for _, position := range r.byTransaction[transactionID] {
+		txnEntries = append(txnEntries, copyEntry(r.entries[position]))
+	}
 	log.Printf("Found %d entries for transaction %s", len(txnEntries), transactionID)
-	return txnEntries, nil
+	return txnEntries
+}
+
+// copyEntry returns a copy of a stored entry, so the ledger can only change by appending
+func copyEntry(entry *LedgerEntry) *LedgerEntry {
+	c := *entry
+	if entry.Metadata != nil {
+		c.Metadata = make(map[string]interface{}, len(entry.Metadata))
+		for key, value := range entry.Metadata {
+			c.Metadata[key] = value
+		}
+	}
+	return &c
+}
+
+// GetChain returns up to limit entries after the given sequence number, in posting order
+func (r *inMemoryRepository) GetChain(afterSequence int64, limit int) ([]*ChainLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var links []*ChainLink
+	for position := int(max(afterSequence, 0)); position < len(r.entries) && len(links) < limit; position++ {
+		links = append(links, &ChainLink{Sequence: int64(position + 1), Entry: copyEntry(r.entries[position])})
+	}
+	return links, nil
+}
+
+// GetChainHeadAt returns the last entry, in posting order, created at or before at
+func (r *inMemoryRepository) GetChainHeadAt(at int64) (*ChainLink, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for position := len(r.entries) - 1; position >= 0; position-- {
+		if r.entries[position].CreatedAt <= at {
+			return &ChainLink{Sequence: int64(position + 1), Entry: copyEntry(r.entries[position])}, nil
+		}
+	}
+	return nil, nil
+}
+
+// indexEntry adds the entry at position to the account and transaction indexes; callers must hold mu
+func (r *inMemoryRepository) indexEntry(position int) {
+	entry := r.entries[position]
+	r.byTransaction[entry.TransactionID] = append(r.byTransaction[entry.TransactionID], position)
+
+	index, exists := r.byAccount[entry.AccountID]
+	if !exists {
+		index = &accountEntries{}
+		r.byAccount[entry.AccountID] = index
+	}
+	var previous int64
+	if n := len(index.running); n > 0 {
+		previous = index.running[n-1]
+	}
+	index.positions = append(index.positions, position)
+	index.createdAt = append(index.createdAt, entry.CreatedAt)
+	index.running = append(index.running, previous+entry.Amount)
+}
+
+// GetStatement returns one page of an account's entries using the account index
+// Opening and closing balances come from the running balances, so only the selected period is scanned
+func (r *inMemoryRepository) GetStatement(query *StatementQuery) (*Statement, error) {
+	after, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	balance, exists := r.balances[query.AccountID]
+	if !exists {
+		return nil, ErrAccountBalanceNotFound
+	}
+	statement := &Statement{AccountID: query.AccountID, Currency: balance.Currency}
+
+	index := r.byAccount[query.AccountID]
+	if index == nil {
+		return statement, nil
+	}
+
+	// [start, end) is the period; both ends are found by binary search on time
+	start := sort.Search(len(index.createdAt), func(i int) bool { return index.createdAt[i] >= query.From })
+	end := len(index.createdAt)
+	if query.To > 0 {
+		end = sort.Search(len(index.createdAt), func(i int) bool { return index.createdAt[i] > query.To })
+	}
+	if start > 0 {
+		statement.OpeningBalance = index.running[start-1]
+	}
+	statement.ClosingBalance = statement.OpeningBalance
+	if end > start {
+		statement.ClosingBalance = index.running[end-1]
+	}
+
+	// Resume after the cursor (a sequence number, i.e. entry index + 1)
+	if first := sort.SearchInts(index.positions, int(after)); first > start {
+		start = first
+	}
+
+	lastPosition := -1
+	for i := start; i < end; i++ {
+		entry := r.entries[index.positions[i]]
+		if !query.matches(entry) {
+			continue
+		}
+		if len(statement.Lines) == query.Limit {
+			statement.NextCursor = encodeCursor(int64(lastPosition) + 1)
+			break
+		}
+		statement.Lines = append(statement.Lines, &StatementLine{Entry: copyEntry(entry), RunningBalance: index.running[i]})
+		lastPosition = index.positions[i]
+	}
+	return statement, nil
 }
 
 // GetBalance retrieves the cached balance for an account
+// A copy is returned so callers never read a balance while it is being updated
 func (r *inMemoryRepository) GetBalance(accountID string) (*AccountBalance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	balance, exists := r.balances[accountID]
 	if !exists {
 		log.Printf("Error: Account balance not found: %s", accountID)
 		return nil, ErrAccountBalanceNotFound
 	}
-	return balance, nil
+	snapshot := *balance
+	return &snapshot, nil
 }
 
 // CreateOrUpdateBalance updates the cached balance for an account
-func (r *inMemoryRepository) CreateOrUpdateBalance(accountID, accountType string, amountChange int64, lastEntryID string) error {
+func (r *inMemoryRepository) CreateOrUpdateBalance(accountID, accountType, currency string, amountChange int64, lastEntryID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.createOrUpdateBalance(accountID, accountType, currency, amountChange, lastEntryID)
+}
+
+// createOrUpdateBalance applies a balance change; callers must hold mu
+func (r *inMemoryRepository) createOrUpdateBalance(accountID, accountType, currency string, amountChange int64, lastEntryID string) error {
 	balance, exists := r.balances[accountID]
 
 	if !exists {
-		// Create new balance
+		// Create new balance; the account's currency is fixed from now on
 		balance = &AccountBalance{
 			AccountID:   accountID,
 			AccountType: accountType,
 			Balance:     amountChange,
-			Currency:    currency.CurrencyUSD, // Default currency
+			Currency:    currency,
 			UpdatedAt:   time.Now().Unix(),
 			LastEntryID: lastEntryID,
 		}
 		r.balances[accountID] = balance
 		log.Printf("Account balance created: %s with balance %d", accountID, balance.Balance)
 	} else {
+		if balance.Currency != currency {
+			log.Printf("Error: Account %s holds %s, change is in %s", accountID, balance.Currency, currency)
+			return ErrCurrencyMismatch
+		}
+
 		// Update existing balance
 		balance.Balance += amountChange
 		balance.UpdatedAt = time.Now().Unix()
@@ -191,23 +513,461 @@ func (r *inMemoryRepository) CreateOrUpdateBalance(accountID, accountType string
 // CalculateBalanceFromEntries recalculates an account's balance from all ledger entries
 // This is the "source of truth" - the cached balance should always match this
 func (r *inMemoryRepository) CalculateBalanceFromEntries(accountID string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	var balance int64
-	for _, entry := range r.entries {
-		if entry.AccountID == accountID {
-			balance += entry.Amount
+	if index, exists := r.byAccount[accountID]; exists {
+		for _, position := range index.positions {
+			balance += r.entries[position].Amount
 		}
 	}
 	log.Printf("Calculated balance for account %s: %d", accountID, balance)
 	return balance, nil
 }
 
+// GetBalanceAt returns an account's balance as of at, from its nearest checkpoint plus the entries since
+func (r *inMemoryRepository) GetBalanceAt(accountID string, at int64) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.balances[accountID]; !exists {
+		return 0, ErrAccountBalanceNotFound
+	}
+	return r.balanceAt(accountID, at), nil
+}
+
+// balanceAt sums an account's entries up to at; callers must hold mu
+func (r *inMemoryRepository) balanceAt(accountID string, at int64) int64 {
+	var balance int64
+	after := int64(math.MinInt64)
+	checkpoints := r.checkpoints[accountID]
+	if i := sort.Search(len(checkpoints), func(i int) bool { return checkpoints[i].AsOf > at }); i > 0 {
+		balance, after = checkpoints[i-1].Balance, checkpoints[i-1].AsOf
+	}
+
+	index := r.byAccount[accountID]
+	if index == nil {
+		return balance
+	}
+	start := sort.Search(len(index.createdAt), func(i int) bool { return index.createdAt[i] > after })
+	for i := start; i < len(index.createdAt) && index.createdAt[i] <= at; i++ {
+		balance += r.entries[index.positions[i]].Amount
+	}
+	return balance
+}
+
+// CreateBalanceCheckpoints records every account's balance as of asOf
+func (r *inMemoryRepository) CreateBalanceCheckpoints(asOf int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := 0
+	for accountID := range r.balances {
+		checkpoints := r.checkpoints[accountID]
+		i := sort.Search(len(checkpoints), func(i int) bool { return checkpoints[i].AsOf >= asOf })
+		if i < len(checkpoints) && checkpoints[i].AsOf == asOf {
+			continue
+		}
+
+		checkpoint := BalanceCheckpoint{AccountID: accountID, AsOf: asOf, Balance: r.balanceAt(accountID, asOf)}
+		checkpoints = append(checkpoints, BalanceCheckpoint{})
+		copy(checkpoints[i+1:], checkpoints[i:])
+		checkpoints[i] = checkpoint
+		r.checkpoints[accountID] = checkpoints
+		created++
+	}
+	return created, nil
+}
+
+// GetAllBalances returns a copy of every cached balance, ordered by account ID
+func (r *inMemoryRepository) GetAllBalances() ([]*AccountBalance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	balances := make([]*AccountBalance, 0, len(r.balances))
+	for _, balance := range r.balances {
+		snapshot := *balance
+		balances = append(balances, &snapshot)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].AccountID < balances[j].AccountID })
+	return balances, nil
+}
+
+// RebuildBalances compares every cached balance with the sum of its entries, holding mu so
+// no posting lands in between, and overwrites the ones that differ unless this is a dry run
+func (r *inMemoryRepository) RebuildBalances(rebuild *BalanceRebuild) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	accounts := make(map[string]bool)
+	for accountID := range r.balances {
+		accounts[accountID] = true
+	}
+	for accountID := range r.byAccount {
+		accounts[accountID] = true
+	}
+	rebuild.AccountsChecked = len(accounts)
+
+	for accountID := range accounts {
+		correction := &BalanceCorrection{AccountID: accountID}
+		if balance, exists := r.balances[accountID]; exists {
+			correction.AccountType, correction.Currency = balance.AccountType, balance.Currency
+			correction.Cached, correction.LastEntryID = balance.Balance, balance.LastEntryID
+		} else {
+			correction.Missing = true
+		}
+		if index, exists := r.byAccount[accountID]; exists {
+			for _, position := range index.positions {
+				correction.Rebuilt += r.entries[position].Amount
+			}
+			last := r.entries[index.positions[len(index.positions)-1]]
+			correction.LastEntryID = last.ID
+			if correction.Missing {
+				correction.AccountType, correction.Currency = last.AccountType, last.Currency
+			}
+		}
+		if correction.Missing || correction.Cached != correction.Rebuilt {
+			rebuild.Corrections = append(rebuild.Corrections, correction)
+		}
+	}
+	sort.Slice(rebuild.Corrections, func(i, j int) bool {
+		return rebuild.Corrections[i].AccountID < rebuild.Corrections[j].AccountID
+	})
+
+	if rebuild.DryRun {
+		return nil
+	}
+	for _, correction := range rebuild.Corrections {
+		balance, exists := r.balances[correction.AccountID]
+		if !exists {
+			balance = &AccountBalance{AccountID: correction.AccountID, AccountType: correction.AccountType, Currency: correction.Currency}
+			r.balances[correction.AccountID] = balance
+		}
+		balance.Balance = correction.Rebuilt
+		balance.LastEntryID = correction.LastEntryID
+		balance.UpdatedAt = rebuild.RunAt
+	}
+	r.rebuilds = append(r.rebuilds, rebuild)
+	return nil
+}
+
+// GetBalanceRebuilds returns up to limit stored repairs, newest first
+func (r *inMemoryRepository) GetBalanceRebuilds(limit int) ([]*BalanceRebuild, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rebuilds := []*BalanceRebuild{}
+	for i := len(r.rebuilds) - 1; i >= 0 && len(rebuilds) < limit; i-- {
+		rebuilds = append(rebuilds, r.rebuilds[i])
+	}
+	return rebuilds, nil
+}
+
+// CreateAccount adds an account to the chart
+func (r *inMemoryRepository) CreateAccount(account *Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.accounts[account.ID]; exists {
+		return ErrAccountExists
+	}
+	stored := *account
+	r.accounts[account.ID] = &stored
+	return nil
+}
+
+// GetAccount returns a copy of a registered account
+func (r *inMemoryRepository) GetAccount(id string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, exists := r.accounts[id]
+	if !exists {
+		return nil, ErrAccountNotFound
+	}
+	snapshot := *account
+	return &snapshot, nil
+}
+
+// ListAccounts returns a copy of every registered account, ordered by ID
+func (r *inMemoryRepository) ListAccounts() ([]*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := make([]*Account, 0, len(r.accounts))
+	for _, account := range r.accounts {
+		snapshot := *account
+		accounts = append(accounts, &snapshot)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+	return accounts, nil
+}
+
+// FindSystemAccount returns the posting account of a type and currency registered for source,
+// falling back to the one without a funding source
+func (r *inMemoryRepository) FindSystemAccount(accountType, currency, source string) (*Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var fallback *Account
+	for _, account := range r.accounts {
+		if account.Group || account.Type != accountType || account.Currency != currency {
+			continue
+		}
+		if source != "" && account.FundingSource == source {
+			snapshot := *account
+			return &snapshot, nil
+		}
+		if account.FundingSource == "" {
+			fallback = account
+		}
+	}
+	if fallback == nil {
+		return nil, ErrNoSystemAccount
+	}
+	snapshot := *fallback
+	return &snapshot, nil
+}
+
+// ChangeAccountStatus posts the closure sweep (if any) and applies the status change in one step
+// The account must still have the change's FromStatus, and an account being closed must end up empty
+func (r *inMemoryRepository) ChangeAccountStatus(change *AccountStatusChange, sweep []*LedgerEntry) error {
+	if len(sweep) > 0 {
+		if err := checkEntries(sweep); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, exists := r.accounts[change.AccountID]
+	if !exists {
+		return ErrAccountNotFound
+	}
+	if account.Status != change.FromStatus {
+		log.Printf("Error: Account %s is %s, expected %s", change.AccountID, account.Status, change.FromStatus)
+		return ErrStatusChanged
+	}
+
+	// Checked before posting, since in-memory postings can't be rolled back
+	if change.ToStatus == AccountStatusClosed {
+		if balance, exists := r.balances[change.AccountID]; exists {
+			remaining := balance.Balance
+			for _, entry := range sweep {
+				if entry.AccountID == change.AccountID {
+					remaining += entry.Amount
+				}
+			}
+			if remaining != 0 || balance.Held != 0 {
+				return ErrAccountNotEmpty
+			}
+		}
+	}
+	if len(sweep) > 0 {
+		if err := r.createEntries(sweep); err != nil {
+			return err
+		}
+	}
+
+	account.Status = change.ToStatus
+	account.AllowCredits = change.ToStatus == AccountStatusFrozen && change.AllowCredits
+	account.StatusReason = change.Reason
+	account.UpdatedAt = change.CreatedAt
+	stored := *change
+	r.statusChanges = append(r.statusChanges, &stored)
+	return nil
+}
+
+// GetAccountStatusChanges returns copies of an account's status changes, oldest first
+func (r *inMemoryRepository) GetAccountStatusChanges(accountID string) ([]*AccountStatusChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := []*AccountStatusChange{}
+	for _, change := range r.statusChanges {
+		if change.AccountID == accountID {
+			snapshot := *change
+			changes = append(changes, &snapshot)
+		}
+	}
+	return changes, nil
+}
+
+// GetAccountLimits retrieves an account's KYC level and limit overrides
+func (r *inMemoryRepository) GetAccountLimits(accountID string) (*AccountLimits, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	limits, exists := r.limits[accountID]
+	if !exists {
+		return nil, ErrLimitsNotFound
+	}
+	result := *limits
+	result.Overrides = append([]Limit{}, limits.Overrides...)
+	return &result, nil
+}
+
+// SaveAccountLimits replaces an account's KYC level and limit overrides
+func (r *inMemoryRepository) SaveAccountLimits(limits *AccountLimits) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *limits
+	stored.Overrides = append([]Limit{}, limits.Overrides...)
+	r.limits[limits.AccountID] = &stored
+	return nil
+}
+
+// SaveBatch stores a copy of a batch and its items
+func (r *inMemoryRepository) SaveBatch(batch *Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches[batch.ID] = copyBatch(batch)
+	return nil
+}
+
+// GetBatch retrieves a batch and its items
+func (r *inMemoryRepository) GetBatch(id string) (*Batch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	batch, exists := r.batches[id]
+	if !exists {
+		return nil, ErrBatchNotFound
+	}
+	return copyBatch(batch), nil
+}
+
+// copyBatch copies a batch and its items so callers can't change the stored one
+func copyBatch(batch *Batch) *Batch {
+	result := *batch
+	result.Items = make([]*BatchItem, len(batch.Items))
+	for i, item := range batch.Items {
+		itemCopy := *item
+		result.Items[i] = &itemCopy
+	}
+	return &result
+}
+
+// CreateHold stores a hold and reserves its amount on the account
+func (r *inMemoryRepository) CreateHold(hold *Hold) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balance, exists := r.balances[hold.AccountID]
+	if !exists {
+		log.Printf("Error: Account balance not found: %s", hold.AccountID)
+		return ErrAccountBalanceNotFound
+	}
+	if balance.Currency != hold.Currency {
+		log.Printf("Error: Account %s holds %s, hold is in %s", hold.AccountID, balance.Currency, hold.Currency)
+		return ErrCurrencyMismatch
+	}
+
+	stored := *hold
+	r.holds[hold.ID] = &stored
+	balance.Held += hold.Amount
+	balance.UpdatedAt = time.Now().Unix()
+
+	log.Printf("Hold placed: %s (account: %s, amount: %d, expires: %d)", hold.ID, hold.AccountID, hold.Amount, hold.ExpiresAt)
+	return nil
+}
+
+// GetHold retrieves a hold by ID
+func (r *inMemoryRepository) GetHold(id string) (*Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hold, exists := r.holds[id]
+	if !exists {
+		log.Printf("Error: Hold not found: %s", id)
+		return nil, ErrHoldNotFound
+	}
+	snapshot := *hold
+	return &snapshot, nil
+}
+
+// GetHoldsByAccountID retrieves all holds placed on an account, oldest first
+func (r *inMemoryRepository) GetHoldsByAccountID(accountID string) ([]*Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var holds []*Hold
+	for _, hold := range r.holds {
+		if hold.AccountID == accountID {
+			snapshot := *hold
+			holds = append(holds, &snapshot)
+		}
+	}
+	sort.Slice(holds, func(i, j int) bool { return holds[i].CreatedAt < holds[j].CreatedAt })
+	return holds, nil
+}
+
+// GetExpiredHolds retrieves active holds that expired at or before now
+func (r *inMemoryRepository) GetExpiredHolds(now int64) ([]*Hold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var holds []*Hold
+	for _, hold := range r.holds {
+		if hold.Status == HoldStatusActive && hold.ExpiresAt <= now {
+			snapshot := *hold
+			holds = append(holds, &snapshot)
+		}
+	}
+	return holds, nil
+}
+
+// CloseHold closes an active hold, releases its reserved amount and posts any capture entries
+// The stored hold must still be active, so a hold can only be closed once
+func (r *inMemoryRepository) CloseHold(hold *Hold, entries []*LedgerEntry) error {
+	if len(entries) > 0 {
+		if err := checkEntries(entries); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, exists := r.holds[hold.ID]
+	if !exists {
+		return ErrHoldNotFound
+	}
+	if stored.Status != HoldStatusActive {
+		log.Printf("Error: Hold %s is already %s", hold.ID, stored.Status)
+		return ErrHoldNotActive
+	}
+
+	// Release the reservation before posting the capture, whose funds check would otherwise count it
+	balance := r.balances[stored.AccountID]
+	balance.Held -= stored.Amount
+	if len(entries) > 0 {
+		if err := r.createEntries(entries); err != nil {
+			balance.Held += stored.Amount
+			return err
+		}
+	}
+	balance.UpdatedAt = time.Now().Unix()
+
+	stored.Status = hold.Status
+	stored.CapturedAmount = hold.CapturedAmount
+	stored.CaptureTransactionID = hold.CaptureTransactionID
+	stored.UpdatedAt = hold.UpdatedAt
+
+	log.Printf("Hold closed: %s (status: %s, captured: %d)", stored.ID, stored.Status, stored.CapturedAmount)
+	return nil
+}
+
 // VerifyTransactionBalance verifies that all entries for a transaction sum to zero
 // This is a key integrity check for double-entry bookkeeping
 func (r *inMemoryRepository) VerifyTransactionBalance(transactionID string) error {
-	entries, err := r.GetEntriesByTransactionID(transactionID)
-	if err != nil {
-		return err
-	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := r.getEntriesByTransactionID(transactionID)
 
 	var sum int64
 	for _, entry := range entries {
@@ -222,3 +982,120 @@ func (r *inMemoryRepository) VerifyTransactionBalance(transactionID string) erro
 	log.Printf("Transaction %s verified: balances to zero ✓", transactionID)
 	return nil
 }
+
+// GetUnbalancedTransactions sums every transaction's entries per currency and returns the non-zero sums
+func (r *inMemoryRepository) GetUnbalancedTransactions() ([]*TransactionImbalance, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var imbalances []*TransactionImbalance
+	for transactionID, positions := range r.byTransaction {
+		sums := make(map[string]int64)
+		for _, position := range positions {
+			sums[r.entries[position].Currency] += r.entries[position].Amount
+		}
+		for cur, sum := range sums {
+			if sum != 0 {
+				imbalances = append(imbalances, &TransactionImbalance{TransactionID: transactionID, Currency: cur, Sum: sum})
+			}
+		}
+	}
+	sort.Slice(imbalances, func(i, j int) bool {
+		if imbalances[i].TransactionID != imbalances[j].TransactionID {
+			return imbalances[i].TransactionID < imbalances[j].TransactionID
+		}
+		return imbalances[i].Currency < imbalances[j].Currency
+	})
+	return imbalances, nil
+}
+
+// SaveAuditReport stores an audit report and its alerts
+func (r *inMemoryRepository) SaveAuditReport(report *AuditReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.auditReports = append(r.auditReports, report)
+	r.auditAlerts = append(r.auditAlerts, report.Alerts...)
+	return nil
+}
+
+// GetLatestAuditReport returns the most recently stored audit report
+func (r *inMemoryRepository) GetLatestAuditReport() (*AuditReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.auditReports) == 0 {
+		return nil, ErrAuditReportNotFound
+	}
+	return r.auditReports[len(r.auditReports)-1], nil
+}
+
+// GetAuditAlerts returns up to limit alerts, newest first
+func (r *inMemoryRepository) GetAuditAlerts(limit int) ([]*AuditAlert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alerts := []*AuditAlert{}
+	for i := len(r.auditAlerts) - 1; i >= 0 && len(alerts) < limit; i-- {
+		alerts = append(alerts, r.auditAlerts[i])
+	}
+	return alerts, nil
+}
+
+// transactionIDs returns the distinct transaction IDs referenced by a set of entries
+func transactionIDs(entries []*LedgerEntry) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.TransactionID] {
+			seen[entry.TransactionID] = true
+			ids = append(ids, entry.TransactionID)
+		}
+	}
+	return ids
+}
+
+// checkEntries runs the checks that don't need storage: every entry is valid, the set balances
+// in each currency, and no account receives entries in two currencies
+func checkEntries(entries []*LedgerEntry) error {
+	for i, entry := range entries {
+		if err := entry.Validate(); err != nil {
+			log.Printf("Error: Entry %d invalid: %v", i, err)
+			return err
+		}
+	}
+
+	// Verify the transaction balances to zero in every currency
+	if err := checkBalancedPerCurrency(entries); err != nil {
+		return err
+	}
+	return checkSingleCurrencyPerAccount(entries)
+}
+
+// checkBalancedPerCurrency verifies that entries sum to zero separately in each currency
+func checkBalancedPerCurrency(entries []*LedgerEntry) error {
+	sums := make(map[string]int64)
+	for _, entry := range entries {
+		sums[entry.Currency] += entry.Amount
+	}
+	for cur, sum := range sums {
+		if sum != 0 {
+			log.Printf("Error: Transaction does not balance in %s. Sum: %d", cur, sum)
+			return ErrTransactionNotBalanced
+		}
+	}
+	return nil
+}
+
+// checkSingleCurrencyPerAccount verifies that a batch doesn't post two currencies to the same account
+func checkSingleCurrencyPerAccount(entries []*LedgerEntry) error {
+	currencies := make(map[string]string)
+	for _, entry := range entries {
+		if cur, seen := currencies[entry.AccountID]; seen && cur != entry.Currency {
+			log.Printf("Error: Account %s receives entries in both %s and %s", entry.AccountID, cur, entry.Currency)
+			return ErrCurrencyMismatch
+		}
+		currencies[entry.AccountID] = entry.Currency
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrAlreadyReversed       = errors.New("transaction has already been reversed or refunded")
+	ErrNotRefundable         = errors.New("transaction cannot be refunded")
+	ErrNotReversible         = errors.New("reversals and refunds cannot themselves be reversed")
+	ErrRefundExceedsOriginal = errors.New("refund exceeds the amount left to refund on the original transaction")
+)
+
+// ReversalTransactionID returns the ID a transaction's reversal is posted under
+// The ID is deterministic, so a transaction can be reversed at most once
+func ReversalTransactionID(transactionID string) string {
+	return "reversal-" + transactionID
+}
+
+// ReverseTransaction undoes every entry of a posted transaction with a compensating transaction
+// The compensating entries reference the original, which is left untouched (entries are immutable)
+// A transaction that has already been partially refunded can't be reversed
+func (s *Service) ReverseTransaction(transactionID, reason string) (string, error) {
+	original, err := s.repo.GetEntriesByTransactionID(transactionID)
+	if err != nil {
+		return "", err
+	}
+	if len(original) == 0 {
+		return "", ErrTransactionNotFound
+	}
+	if original[0].ReferenceTransactionID != "" {
+		return "", ErrNotReversible
+	}
+
+	accountIDs := make([]string, len(original))
+	for i, entry := range original {
+		accountIDs[i] = entry.AccountID
+	}
+	unlock := s.locks.Lock(accountIDs...)
+	defer unlock()
+
+	linked, err := s.repo.GetEntriesByReferenceTransactionID(transactionID)
+	if err != nil {
+		return "", err
+	}
+	if len(linked) > 0 {
+		return "", ErrAlreadyReversed
+	}
+
+	// Wallets that were credited must still have the money to give back
+	credited := make(map[string]int64)
+	for _, entry := range original {
+		if entry.AccountType == AccountTypeUserWallet {
+			credited[entry.AccountID] += entry.Amount
+		}
+	}
+	for _, entry := range original {
+		if amount := credited[entry.AccountID]; amount > 0 {
+			if err := s.checkFunds(entry.AccountID, entry.Currency, amount); err != nil {
+				return "", err
+			}
+			delete(credited, entry.AccountID)
+		}
+	}
+
+	reversalID := ReversalTransactionID(transactionID)
+	now := time.Now().Unix()
+
+	entries := make([]*LedgerEntry, len(original))
+	for i, entry := range original {
+		entryType := EntryTypeCredit
+		if entry.EntryType == EntryTypeCredit {
+			entryType = EntryTypeDebit
+		}
+		entries[i] = &LedgerEntry{
+			ID:                     uuid.New().String(),
+			AccountID:              entry.AccountID,
+			AccountType:            entry.AccountType,
+			Amount:                 -entry.Amount,
+			Currency:               entry.Currency,
+			EntryType:              entryType,
+			TransactionID:          reversalID,
+			TransactionType:        TransactionTypeReversal,
+			CreatedAt:              now,
+			CreatedBy:              "ledger-service",
+			Description:            fmt.Sprintf("Reversal of %s: %s", transactionID, reason),
+			ReferenceTransactionID: transactionID,
+		}
+	}
+
+	if err := s.repo.CreateEntries(entries); err != nil {
+		if err == ErrDuplicateTransaction {
+			return "", ErrAlreadyReversed
+		}
+		log.Printf("Error creating reversal entries: %v", err)
+		return "", err
+	}
+
+	log.Printf("Transaction reversed: %s, reversal txn: %s, reason: %s", transactionID, reversalID, reason)
+	return reversalID, nil
+}
+
+// RefundTransaction returns amount of a payment from the account that received it to the account that paid
+// Refunds can be partial and repeated, but never add up to more than the payee received
+// Fees charged on the original are not refunded
+func (s *Service) RefundTransaction(transactionID string, amount int64) (string, error) {
+	if amount <= 0 {
+		return "", ErrInvalidAmount
+	}
+
+	original, err := s.repo.GetEntriesByTransactionID(transactionID)
+	if err != nil {
+		return "", err
+	}
+	if len(original) == 0 {
+		return "", ErrTransactionNotFound
+	}
+	payer, payee, err := refundParties(original)
+	if err != nil {
+		return "", err
+	}
+
+	unlock := s.locks.Lock(payer.AccountID, payee.AccountID)
+	defer unlock()
+
+	linked, err := s.repo.GetEntriesByReferenceTransactionID(transactionID)
+	if err != nil {
+		return "", err
+	}
+	var refunded int64
+	for _, entry := range linked {
+		if entry.TransactionType == TransactionTypeReversal {
+			return "", ErrAlreadyReversed
+		}
+		if entry.AccountID == payer.AccountID && entry.Amount > 0 {
+			refunded += entry.Amount
+		}
+	}
+	if refunded+amount > payee.Amount {
+		log.Printf("Error: Refund of %d on %s exceeds the %d left to refund", amount, transactionID, payee.Amount-refunded)
+		return "", ErrRefundExceedsOriginal
+	}
+
+	if payee.AccountType == AccountTypeUserWallet {
+		if err := s.checkFunds(payee.AccountID, payee.Currency, amount); err != nil {
+			return "", err
+		}
+	}
+
+	refundID := uuid.New().String()
+	now := time.Now().Unix()
+
+	entries := []*LedgerEntry{
+		// Debit the account that received the payment
+		{
+			ID:                     uuid.New().String(),
+			AccountID:              payee.AccountID,
+			AccountType:            payee.AccountType,
+			Amount:                 -amount,
+			Currency:               payee.Currency,
+			EntryType:              EntryTypeDebit,
+			TransactionID:          refundID,
+			TransactionType:        TransactionTypeRefund,
+			CreatedAt:              now,
+			CreatedBy:              "ledger-service",
+			Description:            fmt.Sprintf("Refund to %s of %s", payer.AccountID, transactionID),
+			ReferenceTransactionID: transactionID,
+		},
+		// Credit the account that paid
+		{
+			ID:                     uuid.New().String(),
+			AccountID:              payer.AccountID,
+			AccountType:            payer.AccountType,
+			Amount:                 amount,
+			Currency:               payer.Currency,
+			EntryType:              EntryTypeCredit,
+			TransactionID:          refundID,
+			TransactionType:        TransactionTypeRefund,
+			CreatedAt:              now,
+			CreatedBy:              "ledger-service",
+			Description:            fmt.Sprintf("Refund from %s of %s", payee.AccountID, transactionID),
+			ReferenceTransactionID: transactionID,
+		},
+	}
+
+	if err := s.repo.CreateEntries(entries); err != nil {
+		log.Printf("Error creating refund entries: %v", err)
+		return "", err
+	}
+
+	log.Printf("Transaction refunded: %s, amount: %d cents (%d refunded in total), refund txn: %s",
+		transactionID, amount, refunded+amount, refundID)
+	return refundID, nil
+}
+
+// GetLinkedTransactionIDs returns the reversals and refunds that reference a transaction
+func (s *Service) GetLinkedTransactionIDs(transactionID string) ([]string, error) {
+	linked, err := s.repo.GetEntriesByReferenceTransactionID(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return transactionIDs(linked), nil
+}
+
+// refundParties finds the paying and receiving entries of a refundable transaction
+// Fee entries are ignored; what remains must be a single debit and credit in one currency
+func refundParties(entries []*LedgerEntry) (payer, payee *LedgerEntry, err error) {
+	for _, entry := range entries {
+		if entry.ReferenceTransactionID != "" {
+			return nil, nil, ErrNotRefundable
+		}
+		if entry.TransactionType == TransactionTypeFee {
+			continue
+		}
+		switch {
+		case entry.EntryType == EntryTypeDebit && payer == nil:
+			payer = entry
+		case entry.EntryType == EntryTypeCredit && payee == nil:
+			payee = entry
+		default:
+			return nil, nil, ErrNotRefundable
+		}
+	}
+	if payer == nil || payee == nil || payer.Currency != payee.Currency {
+		return nil, nil, ErrNotRefundable
+	}
+	return payer, payee, nil
+}
@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"testing"
+)
+
+// TestReverseTransaction tests that a reversal undoes every entry and links back to the original
+func TestReverseTransaction(t *testing.T) {
+	service := NewService(newTestRepository(t), flatTransferFee(t, "1.00"))
+	openWallets(t, service, "USD", "alice", "bob", "shop")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	transferID, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 5000})
+	if err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+
+	reversalID, err := service.ReverseTransaction(transferID, "sent to the wrong wallet")
+	if err != nil {
+		t.Fatalf("Failed to reverse transfer: %v", err)
+	}
+	if err := service.VerifyTransaction(reversalID); err != nil {
+		t.Errorf("Reversal does not balance: %v", err)
+	}
+
+	for accountID, want := range map[string]int64{"alice": 10000, "bob": 0, FeeAccountID("USD"): 0} {
+		balance, _ := service.GetBalance(accountID)
+		if balance.Balance != want {
+			t.Errorf("%s: expected %d after reversal, got %d", accountID, want, balance.Balance)
+		}
+	}
+
+	entries, _ := service.GetTransactionDetails(reversalID)
+	for _, entry := range entries {
+		if entry.ReferenceTransactionID != transferID || entry.TransactionType != TransactionTypeReversal {
+			t.Errorf("Expected a REVERSAL entry referencing %s, got %s referencing %q",
+				transferID, entry.TransactionType, entry.ReferenceTransactionID)
+		}
+	}
+	linked, _ := service.GetLinkedTransactionIDs(transferID)
+	if len(linked) != 1 || linked[0] != reversalID {
+		t.Errorf("Expected %s to link to [%s], got %v", transferID, reversalID, linked)
+	}
+
+	if _, err := service.ReverseTransaction(transferID, "again"); err != ErrAlreadyReversed {
+		t.Errorf("Expected ErrAlreadyReversed, got %v", err)
+	}
+	if _, err := service.ReverseTransaction(reversalID, "undo the undo"); err != ErrNotReversible {
+		t.Errorf("Expected ErrNotReversible, got %v", err)
+	}
+	if _, err := service.RefundTransaction(transferID, 100); err != ErrAlreadyReversed {
+		t.Errorf("Expected ErrAlreadyReversed for refund after reversal, got %v", err)
+	}
+	if _, err := service.ReverseTransaction("missing", "nope"); err != ErrTransactionNotFound {
+		t.Errorf("Expected ErrTransactionNotFound, got %v", err)
+	}
+}
+
+// TestReverseRequiresFunds tests that a reversal can't overdraw a wallet that spent the money
+func TestReverseRequiresFunds(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "alice", "bob", "shop")
+
+	depositID, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"})
+	if err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 6000}); err != nil {
+		t.Fatalf("Failed to record withdrawal: %v", err)
+	}
+
+	if _, err := service.ReverseTransaction(depositID, "chargeback"); err != ErrInsufficientBalance {
+		t.Errorf("Expected ErrInsufficientBalance, got %v", err)
+	}
+}
+
+// TestPartialRefunds tests that refunds can be repeated but never exceed what the payee received
+func TestPartialRefunds(t *testing.T) {
+	service := NewService(newTestRepository(t), flatTransferFee(t, "1.00"))
+	openWallets(t, service, "USD", "alice", "bob", "shop")
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	transferID, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "shop", Amount: 5000})
+	if err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+
+	firstRefund, err := service.RefundTransaction(transferID, 2000)
+	if err != nil {
+		t.Fatalf("Failed to refund: %v", err)
+	}
+	if _, err := service.RefundTransaction(transferID, 3000); err != nil {
+		t.Fatalf("Failed to refund the rest: %v", err)
+	}
+	if _, err := service.RefundTransaction(transferID, 1); err != ErrRefundExceedsOriginal {
+		t.Errorf("Expected ErrRefundExceedsOriginal, got %v", err)
+	}
+
+	// Alice gets the 50.00 back but not the fee
+	alice, _ := service.GetBalance("alice")
+	if alice.Balance != 9900 {
+		t.Errorf("Expected Alice to have 9900 after refunds, got %d", alice.Balance)
+	}
+	shop, _ := service.GetBalance("shop")
+	if shop.Balance != 0 {
+		t.Errorf("Expected the shop to have 0 after refunds, got %d", shop.Balance)
+	}
+
+	linked, _ := service.GetLinkedTransactionIDs(transferID)
+	if len(linked) != 2 || linked[0] != firstRefund {
+		t.Errorf("Expected two linked refunds starting with %s, got %v", firstRefund, linked)
+	}
+	if _, err := service.ReverseTransaction(transferID, "too late"); err != ErrAlreadyReversed {
+		t.Errorf("Expected ErrAlreadyReversed for reversal after refunds, got %v", err)
+	}
+	if _, err := service.RefundTransaction(firstRefund, 100); err != ErrNotRefundable {
+		t.Errorf("Expected ErrNotRefundable for refunding a refund, got %v", err)
+	}
+}
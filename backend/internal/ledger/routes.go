@@ -2,22 +2,64 @@ package ledger
 
 import (
 	"digitalwallet/backend/internal/auth"
+	"digitalwallet/backend/internal/idempotency"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRoutes(router *gin.Engine, ledgerHandler *Handler, authMiddleware *auth.Middleware) {
-	// Public routes (read-only queries)
-	// These could be protected with auth middleware if needed
+func RegisterRoutes(router *gin.Engine, ledgerHandler *Handler, authMiddleware *auth.Middleware, idempotencyMiddleware *idempotency.Middleware) {
+	// Protected routes
 	ledger := router.Group("/api/ledger")
 	{
 		// Balance and statement queries
 		ledger.GET("/balance/:accountId", authMiddleware.Authenticate, ledgerHandler.GetBalance)
 		ledger.GET("/statement/:accountId", authMiddleware.Authenticate, ledgerHandler.GetStatement)
+		ledger.GET("/statement/:accountId/export", authMiddleware.Authenticate, ledgerHandler.ExportStatement)
 		ledger.GET("/transaction/:transactionId", authMiddleware.Authenticate, ledgerHandler.GetTransactionDetails)
 
-		// Verification endpoints (admin/debugging)
-		ledger.POST("/verify/account/:accountId", authMiddleware.Authenticate, ledgerHandler.VerifyAccountBalance)
-		ledger.POST("/verify/transaction/:transactionId", authMiddleware.Authenticate, ledgerHandler.VerifyTransaction)
+		// Money-moving endpoints (always on the caller's own wallet, retry-safe via Idempotency-Key)
+		ledger.POST("/deposits", authMiddleware.Authenticate, idempotencyMiddleware.Enforce, ledgerHandler.Deposit)
+		ledger.POST("/withdrawals", authMiddleware.Authenticate, idempotencyMiddleware.Enforce, ledgerHandler.Withdraw)
+		ledger.POST("/transfers", authMiddleware.Authenticate, idempotencyMiddleware.Enforce, ledgerHandler.Transfer)
+
+		// Batch transfers: validated up front, posted in the background, then polled for the outcome
+		ledger.POST("/batches", authMiddleware.Authenticate, idempotencyMiddleware.Enforce, ledgerHandler.CreateBatch)
+		ledger.GET("/batches/:batchId", authMiddleware.Authenticate, ledgerHandler.GetBatch)
+
+		// Currency conversions: quote first, then execute the quote before it expires
+		ledger.POST("/conversions/quotes", authMiddleware.Authenticate, ledgerHandler.QuoteConversion)
+		ledger.POST("/conversions", authMiddleware.Authenticate, idempotencyMiddleware.Enforce, ledgerHandler.ExecuteConversion)
+
+		// Limits on the caller's wallet and what is left of them
+		ledger.GET("/limits", authMiddleware.Authenticate, ledgerHandler.GetMyLimits)
+
+		// Fees: quote before posting; postings charge the scheduled fee automatically
+		ledger.POST("/fees/quote", authMiddleware.Authenticate, ledgerHandler.QuoteFee)
+		ledger.GET("/fees/schedule", authMiddleware.Authenticate, ledgerHandler.GetFeeSchedule)
+
+		// Chart of accounts (admin only)
+		ledger.GET("/accounts", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.ListAccounts)
+		ledger.GET("/accounts/:accountId", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.GetAccount)
+		ledger.POST("/accounts", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.OpenAccount)
+		ledger.POST("/accounts/:accountId/status", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.ChangeAccountStatus)
+		ledger.GET("/accounts/:accountId/status-history", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.GetAccountStatusChanges)
+		ledger.GET("/accounts/:accountId/limits", authMiddleware.Authenticate, ledgerHandler.GetAccountLimits)
+		ledger.PUT("/accounts/:accountId/limits", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.SetAccountLimits)
+
+		// Verification endpoints (admin only, for debugging)
+		ledger.POST("/verify/account/:accountId", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.VerifyAccountBalance)
+		ledger.POST("/verify/transaction/:transactionId", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.VerifyTransaction)
+		ledger.POST("/verify/chain", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.VerifyChain)
+		ledger.GET("/verify/chain/root", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.ExportDailyRoot)
+
+		// Full-ledger audit (also run on a schedule) and trial balance (admin only)
+		ledger.POST("/audit", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.RunAudit)
+		ledger.GET("/audit/latest", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.GetLatestAuditReport)
+		ledger.GET("/audit/alerts", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.GetAuditAlerts)
+		ledger.GET("/trial-balance", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.GetTrialBalance)
+
+		// Rebuild cached balances from the entry log (admin only; also available as cmd/ledgertool)
+		ledger.POST("/balances/rebuild", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.RebuildBalances)
+		ledger.GET("/balances/rebuilds", authMiddleware.Authenticate, authMiddleware.RequireAdmin, ledgerHandler.GetBalanceRebuilds)
 	}
 }
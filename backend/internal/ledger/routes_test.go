@@ -0,0 +1,60 @@
+package ledger
+
+import (
+	"digitalwallet/backend/internal/auth"
+	"digitalwallet/backend/internal/idempotency"
+	"digitalwallet/backend/internal/wallet"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAdminRoutes tests that admin endpoints refuse other users before the handler runs
+func TestAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := NewService(NewRepository())
+	authService := auth.NewService(auth.NewRepository(), nil, "access-secret", "refresh-secret")
+	r := gin.New()
+	RegisterRoutes(r, NewHandler(service, wallet.NewService(wallet.NewRepository(), service), nil),
+		auth.NewMiddleware(authService, []string{"admin-1"}),
+		idempotency.NewMiddleware(idempotency.NewService(idempotency.NewRepository(), time.Hour)))
+
+	routes := []struct{ method, path, body string }{
+		{http.MethodGet, "/api/ledger/accounts", ""},
+		{http.MethodGet, "/api/ledger/accounts/" + FeeAccountID("USD"), ""},
+		{http.MethodPost, "/api/ledger/accounts", `{"id": "mine", "type": "USER_WALLET", "currency": "USD"}`},
+		{http.MethodPost, "/api/ledger/accounts/" + FeeAccountID("USD") + "/status", `{"status": "CLOSED", "reason": "Mine now"}`},
+		{http.MethodGet, "/api/ledger/accounts/" + FeeAccountID("USD") + "/status-history", ""},
+		{http.MethodPut, "/api/ledger/accounts/" + FeeAccountID("USD") + "/limits", `{"kyc_level": "ENHANCED", "overrides": []}`},
+		{http.MethodPost, "/api/ledger/balances/rebuild?mode=repair", ""},
+		{http.MethodGet, "/api/ledger/balances/rebuilds", ""},
+		{http.MethodPost, "/api/ledger/verify/account/" + FeeAccountID("USD"), ""},
+		{http.MethodPost, "/api/ledger/verify/transaction/unknown", ""},
+		{http.MethodPost, "/api/ledger/verify/chain", ""},
+		{http.MethodGet, "/api/ledger/verify/chain/root", ""},
+		{http.MethodPost, "/api/ledger/audit", ""},
+		{http.MethodGet, "/api/ledger/audit/latest", ""},
+		{http.MethodGet, "/api/ledger/audit/alerts", ""},
+		{http.MethodGet, "/api/ledger/trial-balance", ""},
+	}
+	for _, user := range []string{"user-1", "admin-1"} {
+		tokens, err := authService.GenerateTokens(user, user+"@example.com")
+		if err != nil {
+			t.Fatalf("Failed to generate tokens: %v", err)
+		}
+		for _, route := range routes {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: tokens.AccessToken})
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if forbidden := w.Code == http.StatusForbidden; forbidden != (user != "admin-1") {
+				t.Errorf("%s %s %s: got %d: %s", user, route.method, route.path, w.Code, w.Body)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package ledger
 
 import (
+	"crypto/ed25519"
 	"digitalwallet/backend/pkg/currency"
 	"fmt"
 	"log"
@@ -11,19 +12,35 @@ import (
 
 // Service handles ledger business logic
 type Service struct {
-	repo Repository
+	repo       Repository
+	locks      *accountLocker
+	fx         *fxDesk
+	fees       *FeeSchedule       // Prices fees on transfers, withdrawals and deposits (optional)
+	limits     LimitTiers         // Per-account limits by KYC level (optional)
+	signingKey ed25519.PrivateKey // Signs daily root hashes (optional)
+
+	approvalThreshold int64     // Withdrawals above this need approval (optional, major units)
+	approvals         Approvals // Resolves the approvals postings name (optional)
 }
 
+// Option configures optional ledger capabilities
+type Option func(*Service)
+
 // NewService creates a new ledger service
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, locks: newAccountLocker()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // TransferRequest represents a request to transfer money between accounts
 type TransferRequest struct {
 	FromAccountID string
 	ToAccountID   string
-	Amount        int64 // Amount in cents
+	Amount        int64  // Amount in cents
+	Currency      string // ISO 4217 code, must match both accounts (defaults to USD)
 	Description   string
 	TransactionID string // Optional: can be generated if not provided
 }
@@ -32,6 +49,7 @@ type TransferRequest struct {
 type DepositRequest struct {
 	AccountID     string
 	Amount        int64  // Amount in cents
+	Currency      string // ISO 4217 code, must match the account (defaults to USD)
 	Source        string // e.g., "external_bank", "stripe"
 	Description   string
 	TransactionID string // Optional
@@ -41,37 +59,56 @@ type DepositRequest struct {
 type WithdrawalRequest struct {
 	AccountID     string
 	Amount        int64  // Amount in cents
+	Currency      string // ISO 4217 code, must match the account (defaults to USD)
 	Destination   string // e.g., "external_bank"
 	Description   string
-	TransactionID string // Optional
+	TransactionID string // Optional; the approval's ID when ApprovalID is set
+	ApprovalID    string // Approved request in the approval queue; required above the approval threshold
 }
 
 // RecordTransfer creates ledger entries for a transfer between two accounts
-// This is the core operation for user-to-user transfers
+// This is the core operation for user-to-user transfers; the sender also pays any scheduled fee
 func (s *Service) RecordTransfer(req *TransferRequest) (string, error) {
+	transactionID, _, err := s.transfer(req)
+	return transactionID, err
+}
+
+// transfer records a transfer and returns the fee charged on it
+func (s *Service) transfer(req *TransferRequest) (string, *FeeQuote, error) {
 	// Validate request
 	if req.FromAccountID == "" || req.ToAccountID == "" {
-		return "", fmt.Errorf("from and to account IDs are required")
+		return "", nil, ErrMissingAccountID
+	}
+	if req.FromAccountID == req.ToAccountID {
+		return "", nil, ErrSameAccountTransfer
 	}
 	if req.Amount <= 0 {
-		return "", fmt.Errorf("amount must be positive")
+		return "", nil, ErrInvalidAmount
 	}
+	cur, err := resolveCurrency(req.Currency)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Hold both accounts until the entries are written so the balance check stays valid
+	unlock := s.locks.Lock(req.FromAccountID, req.ToAccountID)
+	defer unlock()
 
-	// Check if sender has sufficient balance
-	fromBalance, err := s.repo.GetBalance(req.FromAccountID)
+	now := time.Now().Unix()
+	fee, err := s.quoteFee(TransactionTypeTransfer, req.FromAccountID, cur, req.Amount, now)
 	if err != nil {
-		// If balance doesn't exist yet, it means balance is 0
-		if err != ErrAccountBalanceNotFound {
-			return "", fmt.Errorf("error checking balance: %w", err)
-		}
-		// Balance is 0, cannot transfer
-		return "", ErrInsufficientBalance
+		return "", nil, err
 	}
 
-	if fromBalance.Balance < req.Amount {
-		log.Printf("Error: Insufficient balance. Account %s has %d, needs %d",
-			req.FromAccountID, fromBalance.Balance, req.Amount)
-		return "", ErrInsufficientBalance
+	// Check if sender has sufficient balance (for amount + fee) and both accounts hold this currency
+	if err := s.checkFunds(req.FromAccountID, cur, fee.Total); err != nil {
+		return "", nil, err
+	}
+	if err := s.checkCurrency(req.ToAccountID, cur); err != nil {
+		return "", nil, err
+	}
+	if err := s.checkLimits(TransactionTypeTransfer, req.FromAccountID, cur, fee.Total, now); err != nil {
+		return "", nil, err
 	}
 
 	// Generate transaction ID if not provided
@@ -80,17 +117,15 @@ func (s *Service) RecordTransfer(req *TransferRequest) (string, error) {
 		transactionID = uuid.New().String()
 	}
 
-	now := time.Now().Unix()
-
 	// Create ledger entries for the transfer
 	entries := []*LedgerEntry{
-		// Debit from sender
+		// Debit from sender (amount + fee)
 		{
 			ID:              uuid.New().String(),
 			AccountID:       req.FromAccountID,
 			AccountType:     AccountTypeUserWallet,
-			Amount:          -req.Amount, // Negative for debit
-			Currency:        currency.CurrencyUSD,
+			Amount:          -fee.Total, // Negative for debit
+			Currency:        cur,
 			EntryType:       EntryTypeDebit,
 			TransactionID:   transactionID,
 			TransactionType: TransactionTypeTransfer,
@@ -104,7 +139,7 @@ func (s *Service) RecordTransfer(req *TransferRequest) (string, error) {
 			AccountID:       req.ToAccountID,
 			AccountType:     AccountTypeUserWallet,
 			Amount:          req.Amount, // Positive for credit
-			Currency:        currency.CurrencyUSD,
+			Currency:        cur,
 			EntryType:       EntryTypeCredit,
 			TransactionID:   transactionID,
 			TransactionType: TransactionTypeTransfer,
@@ -113,124 +148,54 @@ func (s *Service) RecordTransfer(req *TransferRequest) (string, error) {
 			Description:     fmt.Sprintf("Transfer from %s: %s", req.FromAccountID, req.Description),
 		},
 	}
+	if entries, err = s.withFeeEntry(entries, fee, transactionID); err != nil {
+		return "", nil, err
+	}
 
 	// Create entries atomically
 	if err := s.repo.CreateEntries(entries); err != nil {
 		log.Printf("Error creating transfer entries: %v", err)
-		return "", err
+		return "", nil, err
 	}
 
-	log.Printf("Transfer recorded: %s -> %s, amount: %d cents, txn: %s",
-		req.FromAccountID, req.ToAccountID, req.Amount, transactionID)
+	log.Printf("Transfer recorded: %s -> %s, amount: %d cents, fee: %d, txn: %s",
+		req.FromAccountID, req.ToAccountID, req.Amount, fee.Fee, transactionID)
 
-	return transactionID, nil
+	return transactionID, fee, nil
 }
 
-// RecordTransferWithFee creates ledger entries for a transfer with a platform fee
-// This demonstrates how to handle multi-entry transactions (3+ entries)
-func (s *Service) RecordTransferWithFee(req *TransferRequest, feeAmount int64) (string, error) {
+// RecordDeposit creates ledger entries for depositing money from an external source
+// Any scheduled fee comes out of the deposit, so the account is credited the amount minus the fee
+func (s *Service) RecordDeposit(req *DepositRequest) (string, error) {
 	// Validate request
-	if req.FromAccountID == "" || req.ToAccountID == "" {
-		return "", fmt.Errorf("from and to account IDs are required")
+	if req.AccountID == "" {
+		return "", ErrMissingAccountID
 	}
-	if req.Amount <= 0 || feeAmount < 0 {
-		return "", fmt.Errorf("invalid amounts")
+	if req.Amount <= 0 {
+		return "", ErrInvalidAmount
 	}
-
-	totalDebit := req.Amount + feeAmount
-
-	// Check if sender has sufficient balance (for amount + fee)
-	fromBalance, err := s.repo.GetBalance(req.FromAccountID)
+	cur, err := resolveCurrency(req.Currency)
 	if err != nil {
-		if err != ErrAccountBalanceNotFound {
-			return "", fmt.Errorf("error checking balance: %w", err)
-		}
-		return "", ErrInsufficientBalance
-	}
-
-	if fromBalance.Balance < totalDebit {
-		log.Printf("Error: Insufficient balance for transfer + fee. Has %d, needs %d",
-			fromBalance.Balance, totalDebit)
-		return "", ErrInsufficientBalance
-	}
-
-	// Generate transaction ID if not provided
-	transactionID := req.TransactionID
-	if transactionID == "" {
-		transactionID = uuid.New().String()
+		return "", err
 	}
 
-	now := time.Now().Unix()
+	unlock := s.locks.Lock(req.AccountID)
+	defer unlock()
 
-	// Create ledger entries
-	entries := []*LedgerEntry{
-		// Debit from sender (amount + fee)
-		{
-			ID:              uuid.New().String(),
-			AccountID:       req.FromAccountID,
-			AccountType:     AccountTypeUserWallet,
-			Amount:          -totalDebit, // Negative for debit
-			Currency:        currency.CurrencyUSD,
-			EntryType:       EntryTypeDebit,
-			TransactionID:   transactionID,
-			TransactionType: TransactionTypeTransfer,
-			CreatedAt:       now,
-			CreatedBy:       "ledger-service",
-			Description:     fmt.Sprintf("Transfer to %s (incl. fee): %s", req.ToAccountID, req.Description),
-		},
-		// Credit to receiver (just the amount, no fee)
-		{
-			ID:              uuid.New().String(),
-			AccountID:       req.ToAccountID,
-			AccountType:     AccountTypeUserWallet,
-			Amount:          req.Amount, // Positive for credit
-			Currency:        currency.CurrencyUSD,
-			EntryType:       EntryTypeCredit,
-			TransactionID:   transactionID,
-			TransactionType: TransactionTypeTransfer,
-			CreatedAt:       now,
-			CreatedBy:       "ledger-service",
-			Description:     fmt.Sprintf("Transfer from %s: %s", req.FromAccountID, req.Description),
-		},
-		// Credit to system fee account
-		{
-			ID:              uuid.New().String(),
-			AccountID:       "system-fee-account", // System account ID
-			AccountType:     AccountTypeSystemFee,
-			Amount:          feeAmount, // Positive for credit
-			Currency:        currency.CurrencyUSD,
-			EntryType:       EntryTypeCredit,
-			TransactionID:   transactionID,
-			TransactionType: TransactionTypeFee,
-			CreatedAt:       now,
-			CreatedBy:       "ledger-service",
-			Description:     fmt.Sprintf("Transfer fee from %s", req.FromAccountID),
-		},
+	if err := s.checkCurrency(req.AccountID, cur); err != nil {
+		return "", err
 	}
-
-	// Verify entries balance before creating
-	// -totalDebit + amount + fee = 0 ✓
-
-	// Create entries atomically
-	if err := s.repo.CreateEntries(entries); err != nil {
-		log.Printf("Error creating transfer with fee entries: %v", err)
+	fundingAccountID, err := s.systemAccount(AccountTypeExternalBank, cur, req.Source)
+	if err != nil {
 		return "", err
 	}
-
-	log.Printf("Transfer with fee recorded: %s -> %s, amount: %d, fee: %d, txn: %s",
-		req.FromAccountID, req.ToAccountID, req.Amount, feeAmount, transactionID)
-
-	return transactionID, nil
-}
-
-// RecordDeposit creates ledger entries for depositing money from an external source
-func (s *Service) RecordDeposit(req *DepositRequest) (string, error) {
-	// Validate request
-	if req.AccountID == "" {
-		return "", fmt.Errorf("account ID is required")
+	now := time.Now().Unix()
+	fee, err := s.quoteFee(TransactionTypeDeposit, req.AccountID, cur, req.Amount, now)
+	if err != nil {
+		return "", err
 	}
-	if req.Amount <= 0 {
-		return "", fmt.Errorf("amount must be positive")
+	if err := s.checkLimits(TransactionTypeDeposit, req.AccountID, cur, fee.Total, now); err != nil {
+		return "", err
 	}
 
 	// Generate transaction ID if not provided
@@ -239,17 +204,15 @@ func (s *Service) RecordDeposit(req *DepositRequest) (string, error) {
 		transactionID = uuid.New().String()
 	}
 
-	now := time.Now().Unix()
-
 	// Create ledger entries
 	entries := []*LedgerEntry{
-		// Credit user's wallet
+		// Credit user's wallet (amount - fee)
 		{
 			ID:              uuid.New().String(),
 			AccountID:       req.AccountID,
 			AccountType:     AccountTypeUserWallet,
-			Amount:          req.Amount, // Positive for credit
-			Currency:        currency.CurrencyUSD,
+			Amount:          fee.Total, // Positive for credit
+			Currency:        cur,
 			EntryType:       EntryTypeCredit,
 			TransactionID:   transactionID,
 			TransactionType: TransactionTypeDeposit,
@@ -260,10 +223,10 @@ func (s *Service) RecordDeposit(req *DepositRequest) (string, error) {
 		// Debit external bank account (system tracking)
 		{
 			ID:              uuid.New().String(),
-			AccountID:       "external-bank-pool", // System account for external funds
+			AccountID:       fundingAccountID, // The source's funding account, or the currency's default pool
 			AccountType:     AccountTypeExternalBank,
 			Amount:          -req.Amount, // Negative for debit
-			Currency:        currency.CurrencyUSD,
+			Currency:        cur,
 			EntryType:       EntryTypeDebit,
 			TransactionID:   transactionID,
 			TransactionType: TransactionTypeDeposit,
@@ -272,6 +235,9 @@ func (s *Service) RecordDeposit(req *DepositRequest) (string, error) {
 			Description:     fmt.Sprintf("External deposit to %s", req.AccountID),
 		},
 	}
+	if entries, err = s.withFeeEntry(entries, fee, transactionID); err != nil {
+		return "", err
+	}
 
 	// Create entries atomically
 	if err := s.repo.CreateEntries(entries); err != nil {
@@ -279,35 +245,57 @@ func (s *Service) RecordDeposit(req *DepositRequest) (string, error) {
 		return "", err
 	}
 
-	log.Printf("Deposit recorded: %s, amount: %d cents, source: %s, txn: %s",
-		req.AccountID, req.Amount, req.Source, transactionID)
+	log.Printf("Deposit recorded: %s, amount: %d cents, fee: %d, source: %s, txn: %s",
+		req.AccountID, req.Amount, fee.Fee, req.Source, transactionID)
 
 	return transactionID, nil
 }
 
 // RecordWithdrawal creates ledger entries for withdrawing money to an external account
+// Any scheduled fee is debited on top of the amount
 func (s *Service) RecordWithdrawal(req *WithdrawalRequest) (string, error) {
 	// Validate request
 	if req.AccountID == "" {
-		return "", fmt.Errorf("account ID is required")
+		return "", ErrMissingAccountID
 	}
 	if req.Amount <= 0 {
-		return "", fmt.Errorf("amount must be positive")
+		return "", ErrInvalidAmount
 	}
 
-	// Check if user has sufficient balance
-	balance, err := s.repo.GetBalance(req.AccountID)
+	cur, err := resolveCurrency(req.Currency)
 	if err != nil {
-		if err != ErrAccountBalanceNotFound {
-			return "", fmt.Errorf("error checking balance: %w", err)
+		return "", err
+	}
+	if s.NeedsApproval(req.Amount, cur) {
+		approval, err := s.approved(req.ApprovalID)
+		if err != nil {
+			return "", err
+		}
+		if !approval.matchesWithdrawal(req, cur) {
+			log.Printf("Error: Withdrawal from %s does not match approval %s", req.AccountID, req.ApprovalID)
+			return "", ErrApprovalMismatch
 		}
-		return "", ErrInsufficientBalance
 	}
 
-	if balance.Balance < req.Amount {
-		log.Printf("Error: Insufficient balance for withdrawal. Has %d, needs %d",
-			balance.Balance, req.Amount)
-		return "", ErrInsufficientBalance
+	unlock := s.locks.Lock(req.AccountID)
+	defer unlock()
+
+	now := time.Now().Unix()
+	fee, err := s.quoteFee(TransactionTypeWithdrawal, req.AccountID, cur, req.Amount, now)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if user has sufficient balance (for amount + fee)
+	if err := s.checkFunds(req.AccountID, cur, fee.Total); err != nil {
+		return "", err
+	}
+	if err := s.checkLimits(TransactionTypeWithdrawal, req.AccountID, cur, fee.Total, now); err != nil {
+		return "", err
+	}
+	fundingAccountID, err := s.systemAccount(AccountTypeExternalBank, cur, req.Destination)
+	if err != nil {
+		return "", err
 	}
 
 	// Generate transaction ID if not provided
@@ -316,17 +304,15 @@ func (s *Service) RecordWithdrawal(req *WithdrawalRequest) (string, error) {
 		transactionID = uuid.New().String()
 	}
 
-	now := time.Now().Unix()
-
 	// Create ledger entries
 	entries := []*LedgerEntry{
-		// Debit user's wallet
+		// Debit user's wallet (amount + fee)
 		{
 			ID:              uuid.New().String(),
 			AccountID:       req.AccountID,
 			AccountType:     AccountTypeUserWallet,
-			Amount:          -req.Amount, // Negative for debit
-			Currency:        currency.CurrencyUSD,
+			Amount:          -fee.Total, // Negative for debit
+			Currency:        cur,
 			EntryType:       EntryTypeDebit,
 			TransactionID:   transactionID,
 			TransactionType: TransactionTypeWithdrawal,
@@ -337,10 +323,10 @@ func (s *Service) RecordWithdrawal(req *WithdrawalRequest) (string, error) {
 		// Credit external bank account (system tracking)
 		{
 			ID:              uuid.New().String(),
-			AccountID:       "external-bank-pool",
+			AccountID:       fundingAccountID,
 			AccountType:     AccountTypeExternalBank,
 			Amount:          req.Amount, // Positive for credit
-			Currency:        currency.CurrencyUSD,
+			Currency:        cur,
 			EntryType:       EntryTypeCredit,
 			TransactionID:   transactionID,
 			TransactionType: TransactionTypeWithdrawal,
@@ -349,6 +335,9 @@ func (s *Service) RecordWithdrawal(req *WithdrawalRequest) (string, error) {
 			Description:     fmt.Sprintf("External withdrawal from %s", req.AccountID),
 		},
 	}
+	if entries, err = s.withFeeEntry(entries, fee, transactionID); err != nil {
+		return "", err
+	}
 
 	// Create entries atomically
 	if err := s.repo.CreateEntries(entries); err != nil {
@@ -356,12 +345,87 @@ func (s *Service) RecordWithdrawal(req *WithdrawalRequest) (string, error) {
 		return "", err
 	}
 
-	log.Printf("Withdrawal recorded: %s, amount: %d cents, destination: %s, txn: %s",
-		req.AccountID, req.Amount, req.Destination, transactionID)
+	log.Printf("Withdrawal recorded: %s, amount: %d cents, fee: %d, destination: %s, txn: %s",
+		req.AccountID, req.Amount, fee.Fee, req.Destination, transactionID)
 
 	return transactionID, nil
 }
 
+// resolveCurrency defaults an empty currency to USD and rejects unsupported ones
+func resolveCurrency(code string) (string, error) {
+	if code == "" {
+		return currency.CurrencyUSD, nil
+	}
+	if !currency.IsSupported(code) {
+		return "", ErrUnsupportedCurrency
+	}
+	return code, nil
+}
+
+// checkFunds verifies that an account can be debited and holds the currency and at least amount of it available
+// Callers must hold the account's lock so the result stays valid until the entries are written
+func (s *Service) checkFunds(accountID, cur string, amount int64) error {
+	if err := s.checkStatus(accountID, EntryTypeDebit); err != nil {
+		return err
+	}
+
+	balance, err := s.repo.GetBalance(accountID)
+	if err != nil {
+		// If balance doesn't exist yet, it means balance is 0
+		if err != ErrAccountBalanceNotFound {
+			return fmt.Errorf("error checking balance: %w", err)
+		}
+		return ErrInsufficientBalance
+	}
+
+	if balance.Currency != cur {
+		log.Printf("Error: Account %s holds %s, posting is in %s", accountID, balance.Currency, cur)
+		return ErrCurrencyMismatch
+	}
+
+	// Funds reserved by holds can't be spent twice
+	if balance.Available() < amount {
+		log.Printf("Error: Insufficient balance. Account %s has %d available (%d held), needs %d",
+			accountID, balance.Available(), balance.Held, amount)
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// checkStatus verifies that an account is registered and its status allows a posting on the given side
+func (s *Service) checkStatus(accountID, entryType string) error {
+	account, err := s.repo.GetAccount(accountID)
+	if err == ErrAccountNotFound {
+		log.Printf("Error: Unknown ledger account %s", accountID)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error checking account: %w", err)
+	}
+	return account.checkStatus(entryType, "")
+}
+
+// checkCurrency verifies that an account is registered in the chart, can be credited and holds the currency
+func (s *Service) checkCurrency(accountID, cur string) error {
+	account, err := s.repo.GetAccount(accountID)
+	if err == ErrAccountNotFound {
+		log.Printf("Error: Unknown ledger account %s", accountID)
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error checking account: %w", err)
+	}
+
+	if err := account.checkStatus(EntryTypeCredit, ""); err != nil {
+		return err
+	}
+	if account.Currency != cur {
+		log.Printf("Error: Account %s holds %s, posting is in %s", accountID, account.Currency, cur)
+		return ErrCurrencyMismatch
+	}
+	return nil
+}
+
 // GetBalance retrieves the current balance for an account
 func (s *Service) GetBalance(accountID string) (*AccountBalance, error) {
 	balance, err := s.repo.GetBalance(accountID)
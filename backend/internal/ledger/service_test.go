@@ -2,17 +2,20 @@ package ledger
 
 import (
 	"digitalwallet/backend/pkg/currency"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
 // TestTransferBasic tests a simple transfer between two accounts
 func TestTransferBasic(t *testing.T) {
 	// Setup
-	repo := NewRepository()
+	repo := newTestRepository(t)
 	service := NewService(repo)
 
 	aliceWalletID := "alice-wallet-123"
 	bobWalletID := "bob-wallet-456"
+	openWallets(t, service, "USD", aliceWalletID, bobWalletID)
 
 	// Step 1: Alice deposits $100 (so she has funds to transfer)
 	t.Log("Step 1: Alice deposits $100")
@@ -103,11 +106,12 @@ func TestTransferBasic(t *testing.T) {
 // TestTransferWithFee tests a transfer with a platform fee
 func TestTransferWithFee(t *testing.T) {
 	// Setup
-	repo := NewRepository()
-	service := NewService(repo)
+	repo := newTestRepository(t)
+	service := NewService(repo, flatTransferFee(t, "1.00"))
 
 	aliceWalletID := "alice-wallet-789"
 	bobWalletID := "bob-wallet-012"
+	openWallets(t, service, "USD", aliceWalletID, bobWalletID)
 
 	// Step 1: Alice deposits $100
 	t.Log("Step 1: Alice deposits $100")
@@ -130,8 +134,7 @@ func TestTransferWithFee(t *testing.T) {
 		Amount:        5000, // $50.00
 		Description:   "Payment with fee",
 	}
-	feeAmount := int64(100) // $1.00 fee
-	txnID, err := service.RecordTransferWithFee(transferReq, feeAmount)
+	txnID, err := service.RecordTransfer(transferReq)
 	if err != nil {
 		t.Fatalf("Failed to record transfer with fee: %v", err)
 	}
@@ -154,7 +157,7 @@ func TestTransferWithFee(t *testing.T) {
 	}
 	t.Logf("✓ Bob: %s (received $50, no fee)", currency.FormatAmount(bobBalance.Balance, currency.CurrencyUSD))
 
-	systemBalance, _ := service.GetBalance("system-fee-account")
+	systemBalance, _ := service.GetBalance(FeeAccountID(currency.CurrencyUSD))
 	expectedSystem := int64(100) // $1 fee
 	if systemBalance.Balance != expectedSystem {
 		t.Errorf("Expected system balance to be %d, got %d", expectedSystem, systemBalance.Balance)
@@ -191,11 +194,12 @@ func TestTransferWithFee(t *testing.T) {
 // TestInsufficientBalance tests that transfers fail with insufficient funds
 func TestInsufficientBalance(t *testing.T) {
 	// Setup
-	repo := NewRepository()
+	repo := newTestRepository(t)
 	service := NewService(repo)
 
 	aliceWalletID := "alice-wallet-poor"
 	bobWalletID := "bob-wallet-lucky"
+	openWallets(t, service, "USD", aliceWalletID, bobWalletID)
 
 	// Try to transfer $50 when Alice has $0
 	t.Log("Attempting transfer with $0 balance...")
@@ -238,10 +242,11 @@ func TestInsufficientBalance(t *testing.T) {
 // TestAccountStatement tests retrieving transaction history
 func TestAccountStatement(t *testing.T) {
 	// Setup
-	repo := NewRepository()
+	repo := newTestRepository(t)
 	service := NewService(repo)
 
 	aliceWalletID := "alice-wallet-active"
+	openWallets(t, service, "USD", aliceWalletID, "bob-wallet")
 
 	// Create several transactions
 	t.Log("Creating multiple transactions...")
@@ -296,3 +301,293 @@ func TestAccountStatement(t *testing.T) {
 	}
 	t.Logf("✓ Final balance: %s", currency.FormatAmount(balance.Balance, currency.CurrencyUSD))
 }
+
+// TestConcurrentWithdrawalsNoOverdraft tests that racing withdrawals cannot overdraw a wallet
+// Run with -race to also check the repository for data races
+func TestConcurrentWithdrawalsNoOverdraft(t *testing.T) {
+	// Setup
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	aliceWalletID := "alice-wallet-concurrent"
+	openWallets(t, service, "USD", aliceWalletID)
+
+	// Alice has $100 and 50 goroutines each try to withdraw $10
+	if _, err := service.RecordDeposit(&DepositRequest{
+		AccountID:   aliceWalletID,
+		Amount:      10000,
+		Source:      "bank",
+		Description: "Initial deposit",
+	}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var succeeded, rejected atomic.Int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RecordWithdrawal(&WithdrawalRequest{
+				AccountID:   aliceWalletID,
+				Amount:      1000,
+				Destination: "external_bank",
+				Description: "Concurrent withdrawal",
+			})
+			switch err {
+			case nil:
+				succeeded.Add(1)
+			case ErrInsufficientBalance:
+				rejected.Add(1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded.Load() != 10 || rejected.Load() != 40 {
+		t.Errorf("Expected 10 withdrawals to succeed and 40 to be rejected, got %d and %d",
+			succeeded.Load(), rejected.Load())
+	}
+
+	balance, _ := service.GetBalance(aliceWalletID)
+	if balance.Balance != 0 {
+		t.Errorf("Expected final balance 0, got %d", balance.Balance)
+	}
+	balanced, err := service.VerifyAccountBalance(aliceWalletID)
+	if err != nil || !balanced {
+		t.Errorf("Cached balance does not match entries after concurrent withdrawals (err: %v)", err)
+	}
+	t.Logf("✓ %d withdrawals succeeded, %d rejected, no overdraft", succeeded.Load(), rejected.Load())
+}
+
+// TestConcurrentReplicasNoOverdraft tests that two services sharing a repository, like two replicas
+// sharing a database, can't overdraw a wallet even though they don't share account locks
+func TestConcurrentReplicasNoOverdraft(t *testing.T) {
+	repo := newTestRepository(t)
+	replicas := []*Service{NewService(repo), NewService(repo)}
+
+	aliceWalletID := "alice-wallet-replicas"
+	openWallets(t, replicas[0], "USD", aliceWalletID)
+	if _, err := replicas[0].RecordDeposit(&DepositRequest{AccountID: aliceWalletID, Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(service *Service) {
+			defer wg.Done()
+			_, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: aliceWalletID, Amount: 1000, Destination: "bank"})
+			switch err {
+			case nil:
+				succeeded.Add(1)
+			case ErrInsufficientBalance:
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(replicas[i%2])
+	}
+	wg.Wait()
+
+	balance, _ := replicas[1].GetBalance(aliceWalletID)
+	if succeeded.Load() != 10 || balance.Balance != 0 {
+		t.Errorf("Expected 10 withdrawals and an empty wallet, got %d and a balance of %d", succeeded.Load(), balance.Balance)
+	}
+}
+
+// TestConcurrentOpposingTransfers tests that transfers in both directions between
+// the same two wallets neither deadlock nor lose balance updates
+func TestConcurrentOpposingTransfers(t *testing.T) {
+	// Setup
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	aliceWalletID := "alice-wallet-pingpong"
+	bobWalletID := "bob-wallet-pingpong"
+	openWallets(t, service, "USD", aliceWalletID, bobWalletID)
+
+	for _, accountID := range []string{aliceWalletID, bobWalletID} {
+		if _, err := service.RecordDeposit(&DepositRequest{
+			AccountID:   accountID,
+			Amount:      10000,
+			Source:      "bank",
+			Description: "Initial deposit",
+		}); err != nil {
+			t.Fatalf("Failed to record deposit: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		from, to := aliceWalletID, bobWalletID
+		if i%2 == 1 {
+			from, to = bobWalletID, aliceWalletID
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RecordTransfer(&TransferRequest{
+				FromAccountID: from,
+				ToAccountID:   to,
+				Amount:        700,
+				Description:   "Ping-pong",
+			})
+			if err != nil && err != ErrInsufficientBalance {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	aliceBalance, _ := service.GetBalance(aliceWalletID)
+	bobBalance, _ := service.GetBalance(bobWalletID)
+	if aliceBalance.Balance < 0 || bobBalance.Balance < 0 {
+		t.Errorf("Expected no negative balances, got Alice %d and Bob %d", aliceBalance.Balance, bobBalance.Balance)
+	}
+	if total := aliceBalance.Balance + bobBalance.Balance; total != 20000 {
+		t.Errorf("Expected combined balance to stay 20000, got %d", total)
+	}
+	for _, accountID := range []string{aliceWalletID, bobWalletID} {
+		if balanced, err := service.VerifyAccountBalance(accountID); err != nil || !balanced {
+			t.Errorf("Cached balance for %s does not match entries (err: %v)", accountID, err)
+		}
+	}
+	t.Logf("✓ Alice %d + Bob %d = 20000 after 100 opposing transfers", aliceBalance.Balance, bobBalance.Balance)
+}
+
+// TestDuplicateTransactionID tests that the same transaction ID cannot be posted twice
+func TestDuplicateTransactionID(t *testing.T) {
+	// Setup
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	aliceWalletID := "alice-wallet-retry"
+	openWallets(t, service, "USD", aliceWalletID)
+
+	depositReq := &DepositRequest{
+		AccountID:     aliceWalletID,
+		Amount:        10000,
+		Source:        "bank",
+		Description:   "Deposit retried by a flaky client",
+		TransactionID: "txn-client-generated-1",
+	}
+	if _, err := service.RecordDeposit(depositReq); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	// The retry must be rejected instead of crediting Alice a second time
+	if _, err := service.RecordDeposit(depositReq); err != ErrDuplicateTransaction {
+		t.Errorf("Expected ErrDuplicateTransaction, got: %v", err)
+	}
+
+	balance, _ := service.GetBalance(aliceWalletID)
+	if balance.Balance != 10000 {
+		t.Errorf("Expected balance to remain 10000, got %d", balance.Balance)
+	}
+	t.Logf("✓ Duplicate transaction rejected, balance: %s", currency.FormatAmount(balance.Balance, currency.CurrencyUSD))
+}
+
+// TestCreateEntriesAtomic tests that an entry failing its checks leaves the entries before it unposted
+func TestCreateEntriesAtomic(t *testing.T) {
+	repo := NewRepository().(*inMemoryRepository)
+	service := NewService(repo)
+	openWallets(t, service, "USD", "alice", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "bob", Amount: 100, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+	// Only Bob's cached balance disagrees with his account, so only his entry fails, after Alice's was checked
+	repo.balances["bob"].Currency = "EUR"
+	entriesBefore := len(repo.entries)
+
+	entries := []*LedgerEntry{
+		{AccountID: "alice", Amount: -5000, Currency: "USD", EntryType: EntryTypeDebit, TransactionID: "txn-atomic", TransactionType: TransactionTypeTransfer},
+		{AccountID: "bob", Amount: 5000, Currency: "USD", EntryType: EntryTypeCredit, TransactionID: "txn-atomic", TransactionType: TransactionTypeTransfer},
+	}
+	if err := repo.CreateEntries(entries); err != ErrCurrencyMismatch {
+		t.Fatalf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+	for _, entry := range entries {
+		if entry.ID != "" || entry.CreatedAt != 0 || entry.Hash != "" {
+			t.Errorf("Expected the rejected entries to be left as they were, got %+v", entry)
+		}
+	}
+	if len(repo.entries) != entriesBefore {
+		t.Errorf("Expected no entries to be stored, got %d new", len(repo.entries)-entriesBefore)
+	}
+	if balance, _ := service.GetBalance("alice"); balance.Balance != 10000 {
+		t.Errorf("Expected Alice's balance to remain 10000, got %d", balance.Balance)
+	}
+	if report, _ := service.VerifyChain(); !report.Valid {
+		t.Errorf("Expected the hash chain to stay intact, got %+v", report)
+	}
+}
+
+// TestMultiCurrencyAccounts tests that accounts only take postings in their registered currency
+func TestMultiCurrencyAccounts(t *testing.T) {
+	// Setup
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	joaoWalletID := "joao-wallet-eur"
+	emmaWalletID := "emma-wallet-gbp"
+	openWallets(t, service, currency.CurrencyEUR, joaoWalletID)
+	openWallets(t, service, currency.CurrencyGBP, emmaWalletID)
+
+	// Step 1: João deposits €100 and Emma deposits £100
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: joaoWalletID, Amount: 10000, Currency: currency.CurrencyEUR}); err != nil {
+		t.Fatalf("Failed to record EUR deposit: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: emmaWalletID, Amount: 10000, Currency: currency.CurrencyGBP}); err != nil {
+		t.Fatalf("Failed to record GBP deposit: %v", err)
+	}
+
+	joaoBalance, _ := service.GetBalance(joaoWalletID)
+	if joaoBalance.Currency != currency.CurrencyEUR {
+		t.Errorf("Expected João's account to hold EUR, got %s", joaoBalance.Currency)
+	}
+	poolBalance, _ := service.GetBalance(ExternalBankAccountID(currency.CurrencyEUR))
+	if poolBalance.Balance != -10000 || poolBalance.Currency != currency.CurrencyEUR {
+		t.Errorf("Expected EUR pool at -10000 EUR, got %d %s", poolBalance.Balance, poolBalance.Currency)
+	}
+	t.Logf("✓ João: %s, Emma holds GBP", currency.FormatAmount(joaoBalance.Balance, joaoBalance.Currency))
+
+	// Step 2: Postings in another currency are rejected
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: joaoWalletID, Amount: 500, Currency: currency.CurrencyGBP}); err != ErrCurrencyMismatch {
+		t.Errorf("Expected ErrCurrencyMismatch for GBP deposit into EUR account, got: %v", err)
+	}
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: joaoWalletID, Amount: 500, Currency: currency.CurrencyUSD}); err != ErrCurrencyMismatch {
+		t.Errorf("Expected ErrCurrencyMismatch for USD withdrawal from EUR account, got: %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{
+		FromAccountID: joaoWalletID, ToAccountID: emmaWalletID, Amount: 500, Currency: currency.CurrencyEUR,
+	}); err != ErrCurrencyMismatch {
+		t.Errorf("Expected ErrCurrencyMismatch for EUR transfer into GBP account, got: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: joaoWalletID, Amount: 500, Currency: "JPY"}); err != ErrUnsupportedCurrency {
+		t.Errorf("Expected ErrUnsupportedCurrency, got: %v", err)
+	}
+
+	joaoBalance, _ = service.GetBalance(joaoWalletID)
+	if joaoBalance.Balance != 10000 {
+		t.Errorf("Expected João's balance to remain 10000, got %d", joaoBalance.Balance)
+	}
+	t.Logf("✓ Mismatched currencies rejected")
+
+	// Step 3: Entries must balance within each currency, not just overall
+	err := repo.CreateEntries([]*LedgerEntry{
+		{AccountID: joaoWalletID, AccountType: AccountTypeUserWallet, Amount: -1000, Currency: currency.CurrencyEUR,
+			EntryType: EntryTypeDebit, TransactionID: "txn-cross-currency", TransactionType: TransactionTypeTransfer},
+		{AccountID: emmaWalletID, AccountType: AccountTypeUserWallet, Amount: 1000, Currency: currency.CurrencyGBP,
+			EntryType: EntryTypeCredit, TransactionID: "txn-cross-currency", TransactionType: TransactionTypeTransfer},
+	})
+	if err != ErrTransactionNotBalanced {
+		t.Errorf("Expected ErrTransactionNotBalanced for EUR debit against GBP credit, got: %v", err)
+	}
+	t.Logf("✓ Cross-currency entries without per-currency balance rejected")
+}
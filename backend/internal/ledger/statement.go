@@ -0,0 +1,75 @@
+package ledger
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("invalid statement cursor")
+
+// Statement page sizes
+const (
+	DefaultStatementLimit = 50
+	MaxStatementLimit     = 500
+)
+
+// GetStatement returns one page of an account's entries with running, opening and closing balances
+func (s *Service) GetStatement(query *StatementQuery) (*Statement, error) {
+	if query.AccountID == "" {
+		return nil, ErrMissingAccountID
+	}
+	if query.MinAmount < 0 || query.MaxAmount < 0 || (query.MaxAmount > 0 && query.MinAmount > query.MaxAmount) {
+		return nil, ErrInvalidAmount
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultStatementLimit
+	}
+	if query.Limit > MaxStatementLimit {
+		query.Limit = MaxStatementLimit
+	}
+	return s.repo.GetStatement(query)
+}
+
+// matches reports whether an entry passes the query's type, amount and text filters
+// The period and cursor are applied by the repository's index lookups
+func (q *StatementQuery) matches(entry *LedgerEntry) bool {
+	if q.TransactionType != "" && entry.TransactionType != q.TransactionType {
+		return false
+	}
+
+	amount := entry.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+	if amount < q.MinAmount || (q.MaxAmount > 0 && amount > q.MaxAmount) {
+		return false
+	}
+
+	if q.Search != "" && !strings.Contains(strings.ToLower(entry.Description), strings.ToLower(q.Search)) {
+		return false
+	}
+	return true
+}
+
+// encodeCursor makes an opaque cursor from the sequence number of the last entry on a page
+func encodeCursor(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(seq, 10)))
+}
+
+// decodeCursor returns the sequence number to resume after; an empty cursor starts from the beginning
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	seq, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || seq < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return seq, nil
+}
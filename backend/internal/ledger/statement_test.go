@@ -0,0 +1,151 @@
+package ledger
+
+import (
+	"fmt"
+	"testing"
+)
+
+// postAt posts a two-legged transaction between an account and the external pool at a fixed time
+func postAt(t *testing.T, repo Repository, accountID string, amount, createdAt int64, description string) {
+	t.Helper()
+	transactionType, entryType, poolType := TransactionTypeDeposit, EntryTypeCredit, EntryTypeDebit
+	if amount < 0 {
+		transactionType, entryType, poolType = TransactionTypeWithdrawal, EntryTypeDebit, EntryTypeCredit
+	}
+	transactionID := fmt.Sprintf("txn-%s-%d", accountID, createdAt)
+	err := repo.CreateAccount(&Account{ID: accountID, Type: AccountTypeUserWallet, Currency: "USD", NormalBalance: EntryTypeCredit, Status: AccountStatusOpen})
+	if err != nil && err != ErrAccountExists {
+		t.Fatalf("Failed to open account %s: %v", accountID, err)
+	}
+	err = repo.CreateEntries([]*LedgerEntry{
+		{AccountID: accountID, AccountType: AccountTypeUserWallet, Amount: amount, Currency: "USD", EntryType: entryType,
+			TransactionID: transactionID, TransactionType: transactionType, CreatedAt: createdAt, Description: description},
+		{AccountID: ExternalBankAccountID("USD"), AccountType: AccountTypeExternalBank, Amount: -amount, Currency: "USD", EntryType: poolType,
+			TransactionID: transactionID, TransactionType: transactionType, CreatedAt: createdAt, Description: description},
+	})
+	if err != nil {
+		t.Fatalf("Failed to post at %d: %v", createdAt, err)
+	}
+}
+
+// TestStatementPeriodAndRunningBalances tests opening/closing balances and running balances for a period
+func TestStatementPeriodAndRunningBalances(t *testing.T) {
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	postAt(t, repo, "alice", 10000, 100, "Salary")
+	postAt(t, repo, "alice", -2500, 200, "Groceries")
+	postAt(t, repo, "alice", -1000, 300, "Coffee beans")
+	postAt(t, repo, "alice", 5000, 400, "Refund from shop")
+	postAt(t, repo, "alice", -500, 500, "Coffee")
+
+	statement, err := service.GetStatement(&StatementQuery{AccountID: "alice", From: 200, To: 400})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if statement.OpeningBalance != 10000 || statement.ClosingBalance != 11500 {
+		t.Errorf("Expected opening 10000 and closing 11500, got %d and %d", statement.OpeningBalance, statement.ClosingBalance)
+	}
+	wantRunning := []int64{7500, 6500, 11500}
+	if len(statement.Lines) != len(wantRunning) {
+		t.Fatalf("Expected %d lines, got %d", len(wantRunning), len(statement.Lines))
+	}
+	for i, line := range statement.Lines {
+		if line.RunningBalance != wantRunning[i] {
+			t.Errorf("Line %d: expected running balance %d, got %d", i, wantRunning[i], line.RunningBalance)
+		}
+	}
+
+	// Filters narrow the lines but keep the period's opening and closing balances
+	statement, err = service.GetStatement(&StatementQuery{
+		AccountID: "alice", TransactionType: TransactionTypeWithdrawal, Search: "COFFEE", MaxAmount: 600,
+	})
+	if err != nil {
+		t.Fatalf("Failed to get filtered statement: %v", err)
+	}
+	if len(statement.Lines) != 1 || statement.Lines[0].Entry.Description != "Coffee" || statement.Lines[0].RunningBalance != 11000 {
+		t.Errorf("Expected only the 5.00 coffee at running balance 11000, got %d lines", len(statement.Lines))
+	}
+	if statement.OpeningBalance != 0 || statement.ClosingBalance != 11000 {
+		t.Errorf("Expected opening 0 and closing 11000, got %d and %d", statement.OpeningBalance, statement.ClosingBalance)
+	}
+
+	if _, err := service.GetStatement(&StatementQuery{AccountID: "nobody"}); err != ErrAccountBalanceNotFound {
+		t.Errorf("Expected ErrAccountBalanceNotFound, got %v", err)
+	}
+	if _, err := service.GetStatement(&StatementQuery{AccountID: "alice", Cursor: "not-a-cursor"}); err != ErrInvalidCursor {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+// TestStatementPagination tests that following cursors visits every matching entry exactly once
+func TestStatementPagination(t *testing.T) {
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	for i := int64(1); i <= 25; i++ {
+		postAt(t, repo, "bob", i*100, i, fmt.Sprintf("Deposit %d", i))
+		// Interleave another account so positions in the account index aren't contiguous
+		postAt(t, repo, "carol", 100, i, "Noise")
+	}
+
+	var seen []int64
+	query := &StatementQuery{AccountID: "bob", Limit: 10}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("Pagination did not terminate")
+		}
+		statement, err := service.GetStatement(query)
+		if err != nil {
+			t.Fatalf("Failed to get page: %v", err)
+		}
+		for _, line := range statement.Lines {
+			seen = append(seen, line.Entry.Amount)
+		}
+		if statement.NextCursor == "" {
+			break
+		}
+		query.Cursor = statement.NextCursor
+	}
+
+	if len(seen) != 25 {
+		t.Fatalf("Expected 25 entries across pages, got %d", len(seen))
+	}
+	for i, amount := range seen {
+		if amount != int64(i+1)*100 {
+			t.Errorf("Entry %d: expected %d, got %d", i, (i+1)*100, amount)
+		}
+	}
+}
+
+// TestLateStampedEntriesKeepTimeOrder tests that an entry stamped before an entry posted ahead of it
+// takes that entry's time, so searches by time neither drop nor double-count it
+func TestLateStampedEntriesKeepTimeOrder(t *testing.T) {
+	repo := newTestRepository(t)
+	service := NewService(repo)
+
+	postAt(t, repo, "dave", 10000, 500, "Salary")
+	postAt(t, repo, "dave", -2500, 300, "Stamped before the salary, posted after it")
+
+	entries, _ := repo.GetEntriesByAccountID("dave")
+	if len(entries) != 2 || entries[1].CreatedAt != 500 {
+		t.Fatalf("Expected the late entry to be stamped 500, got %+v", entries)
+	}
+
+	statement, err := service.GetStatement(&StatementQuery{AccountID: "dave", From: 400})
+	if err != nil {
+		t.Fatalf("Failed to get statement: %v", err)
+	}
+	if len(statement.Lines) != 2 || statement.OpeningBalance != 0 || statement.ClosingBalance != 7500 {
+		t.Errorf("Expected 2 lines from 0 to 7500, got %d lines from %d to %d",
+			len(statement.Lines), statement.OpeningBalance, statement.ClosingBalance)
+	}
+
+	activity, err := repo.GetActivity("dave", TransactionTypeWithdrawal, EntryTypeDebit, 400, 600)
+	if err != nil {
+		t.Fatalf("Failed to get activity: %v", err)
+	}
+	if activity.Transactions != 1 || activity.Amount != 2500 {
+		t.Errorf("Expected 1 withdrawal of 2500, got %d totalling %d", activity.Transactions, activity.Amount)
+	}
+}
@@ -0,0 +1,237 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMissingStatusReason     = errors.New("a reason is required to change an account's status")
+	ErrInvalidStatusTransition = errors.New("account status cannot change this way")
+	ErrAccountNotEmpty         = errors.New("account must have a zero balance and no active holds to close")
+	ErrStatusChanged           = errors.New("account status changed concurrently")
+	ErrInvalidSweepAccount     = errors.New("invalid sweep account")
+)
+
+// AccountStatusChange records one freeze, unfreeze or closure of an account
+type AccountStatusChange struct {
+	ID                 string `json:"id"`
+	AccountID          string `json:"account_id"`
+	FromStatus         string `json:"from_status"`
+	ToStatus           string `json:"to_status"`
+	AllowCredits       bool   `json:"allow_credits,omitempty"` // Set on freezes that still accept incoming postings
+	Reason             string `json:"reason"`
+	ChangedBy          string `json:"changed_by"`
+	SweepAccountID     string `json:"sweep_account_id,omitempty"`     // Closures only: where the remaining balance went
+	SweepTransactionID string `json:"sweep_transaction_id,omitempty"` // Closures only: the sweep posting, if there was a balance
+	CreatedAt          int64  `json:"created_at"`
+}
+
+// FreezeAccount stops debits from an account, e.g. during a compliance investigation
+// With allowCredits the account keeps receiving incoming postings; freezing a frozen account updates allowCredits
+func (s *Service) FreezeAccount(accountID, reason, changedBy string, allowCredits bool) (*AccountStatusChange, error) {
+	change, account, err := s.newStatusChange(accountID, AccountStatusFrozen, reason, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	change.AllowCredits = allowCredits
+
+	return change, s.applyStatusChange(change, nil)
+}
+
+// UnfreezeAccount reopens a frozen account
+func (s *Service) UnfreezeAccount(accountID, reason, changedBy string) (*AccountStatusChange, error) {
+	change, account, err := s.newStatusChange(accountID, AccountStatusOpen, reason, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status != AccountStatusFrozen {
+		return nil, fmt.Errorf("%w: %s is %s, not FROZEN", ErrInvalidStatusTransition, accountID, account.Status)
+	}
+
+	return change, s.applyStatusChange(change, nil)
+}
+
+// CloseAccount closes an open or frozen account for good
+// The account must be empty: a positive balance is swept to sweepToAccountID (in the same currency) in
+// the same step, active holds must be released first
+func (s *Service) CloseAccount(accountID, reason, changedBy, sweepToAccountID string) (*AccountStatusChange, error) {
+	if sweepToAccountID == accountID {
+		return nil, ErrSameAccountTransfer
+	}
+
+	unlock := s.locks.Lock(accountID, sweepToAccountID)
+	defer unlock()
+
+	change, account, err := s.newStatusChange(accountID, AccountStatusClosed, reason, changedBy)
+	if err != nil {
+		return nil, err
+	}
+	if account.Status == AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+
+	var balance, held int64
+	current, err := s.repo.GetBalance(accountID)
+	if err != nil && err != ErrAccountBalanceNotFound {
+		return nil, fmt.Errorf("error checking balance: %w", err)
+	}
+	if current != nil {
+		balance, held = current.Balance, current.Held
+	}
+	if held > 0 || balance < 0 || (balance > 0 && sweepToAccountID == "") {
+		log.Printf("Error: Account %s can't close with balance %d and %d held", accountID, balance, held)
+		return nil, ErrAccountNotEmpty
+	}
+
+	var sweep []*LedgerEntry
+	if balance > 0 {
+		if err := s.checkCurrency(sweepToAccountID, account.Currency); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSweepAccount, sweepToAccountID, err)
+		}
+		change.SweepAccountID = sweepToAccountID
+		change.SweepTransactionID = uuid.New().String()
+		sweep = closureSweep(change, account.Currency, balance)
+	}
+
+	if err := s.applyStatusChange(change, sweep); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// FreezeWalletAccount freezes the ledger account behind a wallet
+func (s *Service) FreezeWalletAccount(walletID, reason, changedBy string, allowCredits bool) error {
+	_, err := s.FreezeAccount(walletID, reason, changedBy, allowCredits)
+	return walletStatusError(err)
+}
+
+// UnfreezeWalletAccount reopens the ledger account behind a wallet
+func (s *Service) UnfreezeWalletAccount(walletID, reason, changedBy string) error {
+	_, err := s.UnfreezeAccount(walletID, reason, changedBy)
+	return walletStatusError(err)
+}
+
+// CloseWalletAccount closes the ledger account behind a wallet and returns the sweep transaction ID, if any
+// A wallet's balance can only be swept to a wallet (the caller checks it has the same owner) or paid out
+// to an external bank pool, never into fee, revenue or FX accounts
+func (s *Service) CloseWalletAccount(walletID, reason, changedBy, sweepToAccountID string) (string, error) {
+	if sweepToAccountID != "" {
+		target, err := s.repo.GetAccount(sweepToAccountID)
+		if err != nil && err != ErrAccountNotFound {
+			return "", err
+		}
+		if target == nil || (target.Type != AccountTypeUserWallet && target.Type != AccountTypeExternalBank) {
+			return "", walletStatusError(fmt.Errorf("%w: %s is not a wallet or external bank account", ErrInvalidSweepAccount, sweepToAccountID))
+		}
+	}
+	change, err := s.CloseAccount(walletID, reason, changedBy, sweepToAccountID)
+	if err != nil {
+		return "", walletStatusError(err)
+	}
+	return change.SweepTransactionID, nil
+}
+
+// walletStatusError translates status errors into the wallet errors the wallet package reports
+func walletStatusError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == ErrMissingStatusReason:
+		return pkg.ErrMissingStatusReason
+	case err == ErrAccountNotEmpty:
+		return pkg.ErrWalletNotEmpty
+	case err == ErrAccountNotFound:
+		return pkg.ErrWalletNotFound
+	case errors.Is(err, ErrInvalidSweepAccount), err == ErrSameAccountTransfer:
+		return fmt.Errorf("%w: %v", pkg.ErrInvalidSweepAccount, err)
+	case errors.Is(err, ErrInvalidStatusTransition), err == ErrAccountClosed, err == ErrStatusChanged:
+		return fmt.Errorf("%w: %v", pkg.ErrInvalidWalletStatus, err)
+	}
+	return err
+}
+
+// GetAccountStatusChanges returns an account's status history, oldest first
+func (s *Service) GetAccountStatusChanges(accountID string) ([]*AccountStatusChange, error) {
+	if _, err := s.repo.GetAccount(accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAccountStatusChanges(accountID)
+}
+
+// newStatusChange validates the request and drafts the change from the account's current status
+func (s *Service) newStatusChange(accountID, toStatus, reason, changedBy string) (*AccountStatusChange, *Account, error) {
+	if accountID == "" {
+		return nil, nil, ErrMissingAccountID
+	}
+	if reason == "" {
+		return nil, nil, ErrMissingStatusReason
+	}
+
+	account, err := s.repo.GetAccount(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if account.Group {
+		return nil, nil, fmt.Errorf("%w: %s is a group account", ErrInvalidStatusTransition, accountID)
+	}
+
+	change := &AccountStatusChange{
+		ID:         uuid.New().String(),
+		AccountID:  accountID,
+		FromStatus: account.Status,
+		ToStatus:   toStatus,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+		CreatedAt:  time.Now().Unix(),
+	}
+	return change, account, nil
+}
+
+// applyStatusChange stores the change together with the closure sweep, if any
+func (s *Service) applyStatusChange(change *AccountStatusChange, sweep []*LedgerEntry) error {
+	if err := s.repo.ChangeAccountStatus(change, sweep); err != nil {
+		log.Printf("Error changing status of %s to %s: %v", change.AccountID, change.ToStatus, err)
+		return err
+	}
+	log.Printf("Account %s: %s -> %s by %s (%s)", change.AccountID, change.FromStatus, change.ToStatus, change.ChangedBy, change.Reason)
+	return nil
+}
+
+// closureSweep moves a closing account's whole balance to the sweep account
+func closureSweep(change *AccountStatusChange, cur string, balance int64) []*LedgerEntry {
+	return []*LedgerEntry{
+		{
+			ID:              uuid.New().String(),
+			AccountID:       change.AccountID,
+			Amount:          -balance,
+			Currency:        cur,
+			EntryType:       EntryTypeDebit,
+			TransactionID:   change.SweepTransactionID,
+			TransactionType: TransactionTypeClosureSweep,
+			CreatedAt:       change.CreatedAt,
+			CreatedBy:       change.ChangedBy,
+			Description:     fmt.Sprintf("Closure sweep to %s: %s", change.SweepAccountID, change.Reason),
+		},
+		{
+			ID:              uuid.New().String(),
+			AccountID:       change.SweepAccountID,
+			Amount:          balance,
+			Currency:        cur,
+			EntryType:       EntryTypeCredit,
+			TransactionID:   change.SweepTransactionID,
+			TransactionType: TransactionTypeClosureSweep,
+			CreatedAt:       change.CreatedAt,
+			CreatedBy:       change.ChangedBy,
+			Description:     fmt.Sprintf("Closure sweep from %s: %s", change.AccountID, change.Reason),
+		},
+	}
+}
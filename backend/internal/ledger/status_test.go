@@ -0,0 +1,148 @@
+package ledger
+
+import (
+	"digitalwallet/backend/pkg"
+	"errors"
+	"testing"
+)
+
+// TestFreezeAccount tests that a freeze blocks debits, optionally allows credits and is undone by an unfreeze
+func TestFreezeAccount(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "alice", "bob")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	if _, err := service.FreezeAccount("alice", "", "compliance", false); err != ErrMissingStatusReason {
+		t.Errorf("Expected ErrMissingStatusReason, got %v", err)
+	}
+	change, err := service.FreezeAccount("alice", "AML review", "compliance", false)
+	if err != nil {
+		t.Fatalf("Failed to freeze account: %v", err)
+	}
+	if change.FromStatus != AccountStatusOpen || change.ToStatus != AccountStatusFrozen || change.ChangedBy != "compliance" {
+		t.Errorf("Unexpected status change: %+v", change)
+	}
+
+	if _, err := service.RecordWithdrawal(&WithdrawalRequest{AccountID: "alice", Amount: 100}); err != ErrAccountFrozen {
+		t.Errorf("Expected a withdrawal from a frozen account to fail with ErrAccountFrozen, got %v", err)
+	}
+	if _, err := service.PlaceHold(&HoldRequest{AccountID: "alice", Amount: 100}); err != ErrAccountFrozen {
+		t.Errorf("Expected a hold on a frozen account to fail with ErrAccountFrozen, got %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 100, Source: "bank"}); err != ErrAccountFrozen {
+		t.Errorf("Expected a deposit into a frozen account to fail with ErrAccountFrozen, got %v", err)
+	}
+
+	// Freezing again updates whether credits are accepted
+	if _, err := service.FreezeAccount("alice", "Salary may still arrive", "compliance", true); err != nil {
+		t.Fatalf("Failed to update freeze: %v", err)
+	}
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 100, Source: "bank"}); err != nil {
+		t.Errorf("Expected a deposit into an account frozen with credits allowed to succeed, got %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 100}); err != ErrAccountFrozen {
+		t.Errorf("Expected a transfer out of a frozen account to fail with ErrAccountFrozen, got %v", err)
+	}
+
+	if _, err := service.UnfreezeAccount("alice", "Review cleared", "compliance"); err != nil {
+		t.Fatalf("Failed to unfreeze account: %v", err)
+	}
+	if _, err := service.UnfreezeAccount("alice", "Again", "compliance"); !errors.Is(err, ErrInvalidStatusTransition) {
+		t.Errorf("Expected unfreezing an open account to fail, got %v", err)
+	}
+	if _, err := service.RecordTransfer(&TransferRequest{FromAccountID: "alice", ToAccountID: "bob", Amount: 100}); err != nil {
+		t.Errorf("Expected a transfer after unfreezing to succeed, got %v", err)
+	}
+
+	account, _ := service.GetAccount("alice")
+	if account.Status != AccountStatusOpen || account.AllowCredits || account.StatusReason != "Review cleared" {
+		t.Errorf("Expected an open account with the last reason, got %+v", account)
+	}
+	changes, _ := service.GetAccountStatusChanges("alice")
+	if len(changes) != 3 || !changes[1].AllowCredits || changes[2].ToStatus != AccountStatusOpen {
+		t.Errorf("Expected three recorded changes, got %+v", changes)
+	}
+}
+
+// TestCloseAccount tests that closing requires an empty account or a final sweep, even when frozen
+func TestCloseAccount(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "alice", "bob", "carol")
+	openWallets(t, service, "EUR", "dave-eur")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	if _, err := service.CloseAccount("alice", "Customer request", "support", ""); err != ErrAccountNotEmpty {
+		t.Errorf("Expected closing a funded account without a sweep to fail, got %v", err)
+	}
+	if _, err := service.CloseAccount("alice", "Customer request", "support", "dave-eur"); !errors.Is(err, ErrInvalidSweepAccount) {
+		t.Errorf("Expected a sweep into another currency to fail, got %v", err)
+	}
+
+	hold, err := service.PlaceHold(&HoldRequest{AccountID: "alice", Amount: 1000})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+	if _, err := service.CloseAccount("alice", "Customer request", "support", "bob"); err != ErrAccountNotEmpty {
+		t.Errorf("Expected closing an account with an active hold to fail, got %v", err)
+	}
+	if err := service.ReleaseHold(hold.ID); err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+
+	// A frozen account can still be swept on closure
+	if _, err := service.FreezeAccount("alice", "Fraud confirmed", "compliance", false); err != nil {
+		t.Fatalf("Failed to freeze account: %v", err)
+	}
+	change, err := service.CloseAccount("alice", "Fraud confirmed", "compliance", "bob")
+	if err != nil {
+		t.Fatalf("Failed to close account: %v", err)
+	}
+	if change.FromStatus != AccountStatusFrozen || change.SweepAccountID != "bob" || change.SweepTransactionID == "" {
+		t.Errorf("Unexpected status change: %+v", change)
+	}
+	sweep, _ := service.GetTransactionDetails(change.SweepTransactionID)
+	if len(sweep) != 2 || sweep[0].TransactionType != TransactionTypeClosureSweep || sweep[0].CreatedBy != "compliance" {
+		t.Errorf("Expected a two-entry closure sweep, got %+v", sweep)
+	}
+	if alice, _ := service.GetBalance("alice"); alice.Balance != 0 {
+		t.Errorf("Expected the closed account to be empty, got %d", alice.Balance)
+	}
+	if bob, _ := service.GetBalance("bob"); bob.Balance != 10000 {
+		t.Errorf("Expected the sweep to credit 10000, got %d", bob.Balance)
+	}
+
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 100, Source: "bank"}); err != ErrAccountClosed {
+		t.Errorf("Expected a deposit into a closed account to fail, got %v", err)
+	}
+	if _, err := service.FreezeAccount("alice", "Too late", "compliance", false); err != ErrAccountClosed {
+		t.Errorf("Expected freezing a closed account to fail, got %v", err)
+	}
+
+	// An account that never received a posting closes without a sweep
+	change, err = service.CloseAccount("carol", "Duplicate signup", "support", "")
+	if err != nil || change.SweepTransactionID != "" {
+		t.Errorf("Expected an empty account to close without a sweep, got %+v (%v)", change, err)
+	}
+}
+
+// TestCloseWalletAccountSweep tests that a wallet's balance can't be swept into the platform's own accounts
+func TestCloseWalletAccountSweep(t *testing.T) {
+	service := NewService(newTestRepository(t))
+	openWallets(t, service, "USD", "alice")
+	if _, err := service.RecordDeposit(&DepositRequest{AccountID: "alice", Amount: 10000, Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+
+	for _, target := range []string{FeeAccountID("USD"), FXRevenueAccountID("USD"), "nobody"} {
+		if _, err := service.CloseWalletAccount("alice", "Customer request", "support", target); !errors.Is(err, pkg.ErrInvalidSweepAccount) {
+			t.Errorf("%s: expected ErrInvalidSweepAccount, got %v", target, err)
+		}
+	}
+	if _, err := service.CloseWalletAccount("alice", "Customer request", "support", ExternalBankAccountID("USD")); err != nil {
+		t.Fatalf("Expected a payout to the bank pool to close the wallet, got %v", err)
+	}
+}
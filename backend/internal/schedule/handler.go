@@ -0,0 +1,218 @@
+package schedule
+
+import (
+	"digitalwallet/backend/pkg"
+	"digitalwallet/backend/pkg/currency"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service       *Service
+	walletService WalletService
+}
+
+func NewHandler(service *Service, walletService WalletService) *Handler {
+	return &Handler{service: service, walletService: walletService}
+}
+
+// Create sets up a standing order from the caller's wallet
+// POST /schedules
+func (h *Handler) Create(c *gin.Context) {
+	walletID, ok := h.callerWalletID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateScheduleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	callerWallet, err := h.walletService.GetWalletByID(walletID)
+	if err != nil {
+		log.Printf("Error getting wallet %s: %v", walletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		return
+	}
+	startAt, err := parseTime(req.StartAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_at: expected RFC 3339"})
+		return
+	}
+	endAt, err := parseTime(req.EndAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_at: expected RFC 3339"})
+		return
+	}
+
+	schedule, err := h.service.Create(&CreateRequest{
+		UserID:       c.GetString("userId"),
+		FromWalletID: walletID,
+		ToWalletID:   req.ToWalletID,
+		Amount:       amount.Amount(),
+		Description:  req.Description,
+		Frequency:    req.Frequency,
+		StartAt:      startAt,
+		EndAt:        endAt,
+		MaxRuns:      req.MaxRuns,
+	})
+	if err != nil {
+		switch err {
+		case ErrInvalidAmount, ErrInvalidFrequency, ErrStartInPast, ErrInvalidEnd, ErrInvalidMaxRuns, ErrSameWallet, ErrCurrencyMismatch:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case pkg.ErrWalletNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient wallet not found"})
+		default:
+			log.Printf("Error creating schedule for wallet %s: %v", walletID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, schedule.ToDTO())
+}
+
+// List returns the caller's schedules, newest first
+// GET /schedules
+func (h *Handler) List(c *gin.Context) {
+	walletID, ok := h.callerWalletID(c)
+	if !ok {
+		return
+	}
+
+	schedules, err := h.service.ListSchedules(walletID)
+	if err != nil {
+		log.Printf("Error listing schedules for wallet %s: %v", walletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	dtos := make([]*ScheduleDTO, 0, len(schedules))
+	for _, schedule := range schedules {
+		dtos = append(dtos, schedule.ToDTO())
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": dtos, "count": len(dtos)})
+}
+
+// Get returns one of the caller's schedules
+// GET /schedules/:id
+func (h *Handler) Get(c *gin.Context) {
+	schedule, ok := h.callerSchedule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, schedule.ToDTO())
+}
+
+// ListRuns returns the run history of one of the caller's schedules, oldest first
+// GET /schedules/:id/runs
+func (h *Handler) ListRuns(c *gin.Context) {
+	schedule, ok := h.callerSchedule(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.service.ListRuns(schedule.ID)
+	if err != nil {
+		log.Printf("Error listing runs of schedule %s: %v", schedule.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs, "count": len(runs)})
+}
+
+// Pause stops one of the caller's schedules until it is resumed
+// POST /schedules/:id/pause
+func (h *Handler) Pause(c *gin.Context) {
+	h.transition(c, h.service.Pause)
+}
+
+// Resume reactivates a paused schedule, skipping occurrences missed while paused
+// POST /schedules/:id/resume
+func (h *Handler) Resume(c *gin.Context) {
+	h.transition(c, h.service.Resume)
+}
+
+// Cancel ends one of the caller's schedules for good
+// DELETE /schedules/:id
+func (h *Handler) Cancel(c *gin.Context) {
+	h.transition(c, h.service.Cancel)
+}
+
+// transition applies a status change to one of the caller's schedules
+func (h *Handler) transition(c *gin.Context, change func(id string) (*Schedule, error)) {
+	schedule, ok := h.callerSchedule(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := change(schedule.ID)
+	if err != nil {
+		if err == ErrInvalidTransition {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error updating schedule %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, schedule.ToDTO())
+}
+
+// callerSchedule loads the schedule in the URL if the caller's wallet pays it, writing the error response if not
+func (h *Handler) callerSchedule(c *gin.Context) (*Schedule, bool) {
+	walletID, ok := h.callerWalletID(c)
+	if !ok {
+		return nil, false
+	}
+
+	schedule, err := h.service.GetSchedule(c.Param("id"))
+	// Other wallets' schedules are reported as missing rather than forbidden
+	if err == ErrScheduleNotFound || (err == nil && schedule.WalletID != walletID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting schedule %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return schedule, true
+}
+
+// callerWalletID resolves the authenticated user's wallet, writing the error response if it can't
+func (h *Handler) callerWalletID(c *gin.Context) (string, bool) {
+	userID := c.GetString("userId")
+	if userID == "" {
+		log.Println("Error: User is not set in the user context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", false
+	}
+
+	callerWallet, err := h.walletService.GetWalletByUserID(userID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+			return "", false
+		}
+		log.Printf("Error getting wallet for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return "", false
+	}
+	return callerWallet.ID, true
+}
+
+// parseTime parses an optional RFC 3339 timestamp; an empty value is the zero time
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,132 @@
+package schedule
+
+import (
+	"digitalwallet/backend/pkg/currency"
+	"time"
+)
+
+// Frequencies
+const (
+	FrequencyOnce    = "ONCE" // A single transfer at StartAt
+	FrequencyDaily   = "DAILY"
+	FrequencyWeekly  = "WEEKLY"
+	FrequencyMonthly = "MONTHLY" // Same day of month as StartAt, or the month's last day when it is shorter
+)
+
+// Schedule statuses
+const (
+	StatusActive    = "ACTIVE"
+	StatusPaused    = "PAUSED"
+	StatusCompleted = "COMPLETED" // Every occurrence has run
+	StatusCancelled = "CANCELLED"
+)
+
+// Run statuses
+const (
+	RunSucceeded = "SUCCEEDED"
+	RunRetrying  = "RETRYING" // Failed for lack of funds; the occurrence will be attempted again
+	RunFailed    = "FAILED"   // Failed for good; the schedule moves on to its next occurrence
+)
+
+// Schedule is a standing order moving a fixed amount out of a wallet once or on a recurring basis
+// Occurrence times are derived from StartAt (UTC), so a monthly order on the 31st returns to the 31st after February
+type Schedule struct {
+	ID            string `json:"id"`
+	WalletID      string `json:"wallet_id"` // Paying wallet
+	UserID        string `json:"user_id"`
+	ToWalletID    string `json:"to_wallet_id"`
+	Amount        int64  `json:"amount"` // Minor units
+	Currency      string `json:"currency"`
+	Description   string `json:"description,omitempty"`
+	Frequency     string `json:"frequency"`
+	StartAt       int64  `json:"start_at"`           // First occurrence, Unix time
+	EndAt         int64  `json:"end_at,omitempty"`   // No occurrences after this time; 0 for none
+	MaxRuns       int    `json:"max_runs,omitempty"` // Occurrences in total; 0 for unlimited
+	Status        string `json:"status"`
+	Occurrence    int    `json:"occurrence"`            // Zero-based index of the next occurrence
+	Attempts      int    `json:"attempts"`              // Attempts made at the next occurrence so far
+	NextRunAt     int64  `json:"next_run_at,omitempty"` // When the next attempt is due; 0 once the schedule is over
+	LastRunAt     int64  `json:"last_run_at,omitempty"`
+	LastRunStatus string `json:"last_run_status,omitempty"`
+	CreatedAt     int64  `json:"created_at"`
+	UpdatedAt     int64  `json:"updated_at"`
+}
+
+// Run is one attempt at one occurrence of a schedule
+type Run struct {
+	ID            string `json:"id"`
+	ScheduleID    string `json:"schedule_id"`
+	Occurrence    int    `json:"occurrence"` // One-based
+	DueAt         int64  `json:"due_at"`
+	Attempt       int    `json:"attempt"` // One-based
+	Status        string `json:"status"`
+	TransactionID string `json:"transaction_id"` // Same for every attempt at an occurrence
+	Error         string `json:"error,omitempty"`
+	RanAt         int64  `json:"ran_at"`
+}
+
+// CreateRequest describes a new schedule
+type CreateRequest struct {
+	UserID       string
+	FromWalletID string
+	ToWalletID   string
+	Amount       int64
+	Description  string
+	Frequency    string
+	StartAt      time.Time // Zero for now
+	EndAt        time.Time // Zero for no end date
+	MaxRuns      int
+}
+
+// CreateScheduleDTO is the body of POST /schedules
+type CreateScheduleDTO struct {
+	ToWalletID  string `json:"to_wallet_id" binding:"required"`
+	Amount      string `json:"amount" binding:"required"` // Decimal string in the wallet's currency
+	Description string `json:"description"`
+	Frequency   string `json:"frequency" binding:"required"`
+	StartAt     string `json:"start_at"` // RFC 3339; defaults to now
+	EndAt       string `json:"end_at"`   // RFC 3339
+	MaxRuns     int    `json:"max_runs"`
+}
+
+// ScheduleDTO is a schedule as returned by the API, with the amount as a decimal string
+type ScheduleDTO struct {
+	ID            string `json:"id"`
+	WalletID      string `json:"wallet_id"`
+	ToWalletID    string `json:"to_wallet_id"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	Description   string `json:"description,omitempty"`
+	Frequency     string `json:"frequency"`
+	StartAt       int64  `json:"start_at"`
+	EndAt         int64  `json:"end_at,omitempty"`
+	MaxRuns       int    `json:"max_runs,omitempty"`
+	Status        string `json:"status"`
+	Occurrences   int    `json:"occurrences"` // Occurrences past, including any skipped while paused
+	NextRunAt     int64  `json:"next_run_at,omitempty"`
+	LastRunAt     int64  `json:"last_run_at,omitempty"`
+	LastRunStatus string `json:"last_run_status,omitempty"`
+	CreatedAt     int64  `json:"created_at"`
+}
+
+// ToDTO converts the schedule to the API response format
+func (s *Schedule) ToDTO() *ScheduleDTO {
+	return &ScheduleDTO{
+		ID:            s.ID,
+		WalletID:      s.WalletID,
+		ToWalletID:    s.ToWalletID,
+		Amount:        currency.New(s.Amount, s.Currency).String(),
+		Currency:      s.Currency,
+		Description:   s.Description,
+		Frequency:     s.Frequency,
+		StartAt:       s.StartAt,
+		EndAt:         s.EndAt,
+		MaxRuns:       s.MaxRuns,
+		Status:        s.Status,
+		Occurrences:   s.Occurrence,
+		NextRunAt:     s.NextRunAt,
+		LastRunAt:     s.LastRunAt,
+		LastRunStatus: s.LastRunStatus,
+		CreatedAt:     s.CreatedAt,
+	}
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// Repository stores schedules and the history of their runs
+type Repository interface {
+	Save(schedule *Schedule) error // Creates or replaces the schedule
+	GetByID(id string) (*Schedule, error)
+	ListByWalletID(walletID string) ([]*Schedule, error)
+	ListDue(now int64) ([]*Schedule, error) // Active schedules with an attempt due at or before now
+	SaveRun(run *Run) error
+	ListRuns(scheduleID string) ([]*Run, error) // Oldest first
+}
+
+// inMemoryRepository implements Repository using in-memory storage
+type inMemoryRepository struct {
+	mu        sync.RWMutex
+	schedules map[string]*Schedule
+	runs      map[string][]*Run // schedule ID -> runs in the order they were saved
+}
+
+// NewRepository creates a new in-memory schedule repository
+func NewRepository() Repository {
+	return &inMemoryRepository{
+		schedules: make(map[string]*Schedule),
+		runs:      make(map[string][]*Run),
+	}
+}
+
+// Save stores a copy of the schedule
+func (r *inMemoryRepository) Save(schedule *Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *schedule
+	r.schedules[schedule.ID] = &stored
+	return nil
+}
+
+// GetByID retrieves a schedule by ID
+func (r *inMemoryRepository) GetByID(id string) (*Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedule, exists := r.schedules[id]
+	if !exists {
+		return nil, ErrScheduleNotFound
+	}
+	result := *schedule
+	return &result, nil
+}
+
+// ListByWalletID retrieves every schedule paid from a wallet, newest first
+func (r *inMemoryRepository) ListByWalletID(walletID string) ([]*Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var schedules []*Schedule
+	for _, schedule := range r.schedules {
+		if schedule.WalletID == walletID {
+			result := *schedule
+			schedules = append(schedules, &result)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].CreatedAt != schedules[j].CreatedAt {
+			return schedules[i].CreatedAt > schedules[j].CreatedAt
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+// ListDue retrieves the active schedules due at or before now, most overdue first
+func (r *inMemoryRepository) ListDue(now int64) ([]*Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var schedules []*Schedule
+	for _, schedule := range r.schedules {
+		if schedule.Status == StatusActive && schedule.NextRunAt <= now {
+			result := *schedule
+			schedules = append(schedules, &result)
+		}
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].NextRunAt != schedules[j].NextRunAt {
+			return schedules[i].NextRunAt < schedules[j].NextRunAt
+		}
+		return schedules[i].ID < schedules[j].ID
+	})
+	return schedules, nil
+}
+
+// SaveRun appends a run to its schedule's history
+func (r *inMemoryRepository) SaveRun(run *Run) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *run
+	r.runs[run.ScheduleID] = append(r.runs[run.ScheduleID], &stored)
+	return nil
+}
+
+// ListRuns retrieves a schedule's runs, oldest first
+func (r *inMemoryRepository) ListRuns(scheduleID string) ([]*Run, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runs := make([]*Run, 0, len(r.runs[scheduleID]))
+	for _, run := range r.runs[scheduleID] {
+		result := *run
+		runs = append(runs, &result)
+	}
+	return runs, nil
+}
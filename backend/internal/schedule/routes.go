@@ -0,0 +1,19 @@
+package schedule
+
+import (
+	"digitalwallet/backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes sets up the standing order routes; every route acts on schedules paid from the caller's wallet
+func RegisterRoutes(router *gin.Engine, scheduleHandler *Handler, authMiddleware *auth.Middleware) {
+	// Protected routes
+	router.POST("/schedules", authMiddleware.Authenticate, scheduleHandler.Create)
+	router.GET("/schedules", authMiddleware.Authenticate, scheduleHandler.List)
+	router.GET("/schedules/:id", authMiddleware.Authenticate, scheduleHandler.Get)
+	router.GET("/schedules/:id/runs", authMiddleware.Authenticate, scheduleHandler.ListRuns)
+	router.POST("/schedules/:id/pause", authMiddleware.Authenticate, scheduleHandler.Pause)
+	router.POST("/schedules/:id/resume", authMiddleware.Authenticate, scheduleHandler.Resume)
+	router.DELETE("/schedules/:id", authMiddleware.Authenticate, scheduleHandler.Cancel)
+}
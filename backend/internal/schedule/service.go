@@ -0,0 +1,390 @@
+package schedule
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidFrequency  = errors.New("invalid frequency: expected ONCE, DAILY, WEEKLY or MONTHLY")
+	ErrInvalidAmount     = errors.New("amount must be positive")
+	ErrStartInPast       = errors.New("start must not be in the past")
+	ErrInvalidEnd        = errors.New("end must not be before start")
+	ErrInvalidMaxRuns    = errors.New("max runs must not be negative")
+	ErrSameWallet        = errors.New("can't schedule transfers to the paying wallet")
+	ErrCurrencyMismatch  = errors.New("both wallets must have the same currency")
+	ErrInvalidTransition = errors.New("schedule can't be changed from its current status")
+)
+
+// transactionNamespace is the UUID namespace scheduled transfers' transaction IDs are derived in
+var transactionNamespace = uuid.MustParse("6f1c2a9e-3b7d-4d5e-9a8f-0c4b2e7d1a53")
+
+// LedgerService is the subset of ledger.Service used to post scheduled transfers
+type LedgerService interface {
+	RecordTransfer(req *ledger.TransferRequest) (string, error)
+	GetTransactionDetails(transactionID string) ([]*ledger.LedgerEntry, error)
+}
+
+// WalletService resolves the wallets a schedule pays from and to
+type WalletService interface {
+	GetWalletByID(walletID string) (*wallet.Wallet, error)
+	GetWalletByUserID(userID string) (*wallet.Wallet, error)
+}
+
+// Clock tells the scheduler what time it is; tests substitute one they can move forward
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock reads the wall clock
+var SystemClock Clock = systemClock{}
+
+// RetryPolicy decides how often an occurrence that failed for lack of funds is attempted again
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts per occurrence, including the first
+	Backoff     time.Duration // Wait between attempts
+}
+
+// DefaultRetryPolicy attempts an occurrence three times, an hour apart
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, Backoff: time.Hour}
+
+// Service stores standing orders and executes them when they fall due
+type Service struct {
+	mu      sync.Mutex // Serializes runs with pause, resume and cancel
+	repo    Repository
+	ledger  LedgerService
+	wallets WalletService
+	retry   RetryPolicy
+	clock   Clock
+}
+
+// NewService creates a new schedule service
+func NewService(repo Repository, ledgerService LedgerService, walletService WalletService, retry RetryPolicy, clock Clock) *Service {
+	if retry.MaxAttempts < 1 {
+		retry.MaxAttempts = 1
+	}
+	return &Service{repo: repo, ledger: ledgerService, wallets: walletService, retry: retry, clock: clock}
+}
+
+// Create validates and stores a new schedule; its first occurrence runs once the job next finds it due
+func (s *Service) Create(req *CreateRequest) (*Schedule, error) {
+	now := s.clock.Now()
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	switch req.Frequency {
+	case FrequencyOnce, FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	default:
+		return nil, ErrInvalidFrequency
+	}
+	start := req.StartAt
+	if start.IsZero() {
+		start = now
+	}
+	if start.Unix() < now.Unix() {
+		return nil, ErrStartInPast
+	}
+	if !req.EndAt.IsZero() && req.EndAt.Before(start) {
+		return nil, ErrInvalidEnd
+	}
+	if req.MaxRuns < 0 {
+		return nil, ErrInvalidMaxRuns
+	}
+	if req.FromWalletID == req.ToWalletID {
+		return nil, ErrSameWallet
+	}
+
+	from, err := s.wallets.GetWalletByID(req.FromWalletID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.wallets.GetWalletByID(req.ToWalletID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Currency != to.Currency {
+		return nil, ErrCurrencyMismatch
+	}
+
+	schedule := &Schedule{
+		ID:          uuid.New().String(),
+		WalletID:    from.ID,
+		UserID:      req.UserID,
+		ToWalletID:  to.ID,
+		Amount:      req.Amount,
+		Currency:    from.Currency,
+		Description: req.Description,
+		Frequency:   req.Frequency,
+		StartAt:     start.Unix(),
+		MaxRuns:     req.MaxRuns,
+		Status:      StatusActive,
+		NextRunAt:   start.Unix(),
+		CreatedAt:   now.Unix(),
+		UpdatedAt:   now.Unix(),
+	}
+	if !req.EndAt.IsZero() {
+		schedule.EndAt = req.EndAt.Unix()
+	}
+	if err := s.repo.Save(schedule); err != nil {
+		log.Printf("Error saving schedule for wallet %s: %v", from.ID, err)
+		return nil, err
+	}
+	log.Printf("Schedule created: %s (%s %d %s from %s to %s)",
+		schedule.ID, schedule.Frequency, schedule.Amount, schedule.Currency, schedule.WalletID, schedule.ToWalletID)
+	return schedule, nil
+}
+
+// GetSchedule retrieves a schedule
+func (s *Service) GetSchedule(id string) (*Schedule, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListSchedules retrieves the schedules paid from a wallet, newest first
+func (s *Service) ListSchedules(walletID string) ([]*Schedule, error) {
+	return s.repo.ListByWalletID(walletID)
+}
+
+// ListRuns retrieves a schedule's run history, oldest first
+func (s *Service) ListRuns(id string) ([]*Run, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRuns(id)
+}
+
+// Pause stops an active schedule from running until it is resumed
+func (s *Service) Pause(id string) (*Schedule, error) {
+	return s.transition(id, func(schedule *Schedule, now int64) error {
+		if schedule.Status != StatusActive {
+			return ErrInvalidTransition
+		}
+		schedule.Status = StatusPaused
+		return nil
+	})
+}
+
+// Resume reactivates a paused schedule
+// Occurrences that fell due while it was paused are skipped rather than paid in a burst
+func (s *Service) Resume(id string) (*Schedule, error) {
+	return s.transition(id, func(schedule *Schedule, now int64) error {
+		if schedule.Status != StatusPaused {
+			return ErrInvalidTransition
+		}
+		schedule.Status = StatusActive
+		schedule.Attempts = 0
+		for schedule.hasOccurrence(schedule.Occurrence) && schedule.occurrenceAt(schedule.Occurrence) < now {
+			schedule.Occurrence++
+		}
+		schedule.schedule(schedule.Occurrence)
+		return nil
+	})
+}
+
+// Cancel ends an active or paused schedule for good
+func (s *Service) Cancel(id string) (*Schedule, error) {
+	return s.transition(id, func(schedule *Schedule, now int64) error {
+		if schedule.Status != StatusActive && schedule.Status != StatusPaused {
+			return ErrInvalidTransition
+		}
+		schedule.Status = StatusCancelled
+		schedule.NextRunAt = 0
+		return nil
+	})
+}
+
+// transition applies a status change to a schedule while no run is in progress
+func (s *Service) transition(id string, change func(schedule *Schedule, now int64) error) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	from := schedule.Status
+	now := s.clock.Now().Unix()
+	if err := change(schedule, now); err != nil {
+		return nil, err
+	}
+	schedule.UpdatedAt = now
+	if err := s.repo.Save(schedule); err != nil {
+		log.Printf("Error saving schedule %s: %v", id, err)
+		return nil, err
+	}
+	log.Printf("Schedule %s: %s -> %s", id, from, schedule.Status)
+	return schedule, nil
+}
+
+// RunDue attempts every occurrence that is due, catching up on any that fell due while the job wasn't running
+// It returns how many runs were made
+func (s *Service) RunDue() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now().Unix()
+	due, err := s.repo.ListDue(now)
+	if err != nil {
+		return 0, err
+	}
+
+	runs := 0
+	var failures []error
+	for _, schedule := range due {
+		for schedule.Status == StatusActive && schedule.NextRunAt <= now {
+			if err := s.execute(schedule, now); err != nil {
+				log.Printf("Error running schedule %s: %v", schedule.ID, err)
+				failures = append(failures, err)
+				break
+			}
+			runs++
+		}
+	}
+	return runs, errors.Join(failures...)
+}
+
+// StartRunner runs due schedules every interval until the returned stop function is called
+func (s *Service) StartRunner(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				runs, err := s.RunDue()
+				if err != nil {
+					log.Printf("Error running scheduled transfers: %v", err)
+				}
+				if runs > 0 {
+					log.Printf("Made %d scheduled transfer runs", runs)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// execute attempts the schedule's current occurrence and records the run
+// Every attempt at an occurrence posts under the same transaction ID, so it is paid at most once
+func (s *Service) execute(schedule *Schedule, now int64) error {
+	schedule.Attempts++
+	run := &Run{
+		ID:            uuid.New().String(),
+		ScheduleID:    schedule.ID,
+		Occurrence:    schedule.Occurrence + 1,
+		DueAt:         schedule.occurrenceAt(schedule.Occurrence),
+		Attempt:       schedule.Attempts,
+		TransactionID: transactionID(schedule.ID, schedule.Occurrence),
+		RanAt:         now,
+	}
+
+	// An earlier attempt may have posted without its run being recorded; the balance check would
+	// otherwise fail the occurrence before the ledger noticed the duplicate transaction ID
+	posted, err := s.ledger.GetTransactionDetails(run.TransactionID)
+	if err != nil {
+		return err
+	}
+	if len(posted) == 0 {
+		description := schedule.Description
+		if description == "" {
+			description = "Scheduled transfer"
+		}
+		_, err = s.ledger.RecordTransfer(&ledger.TransferRequest{
+			FromAccountID: schedule.WalletID,
+			ToAccountID:   schedule.ToWalletID,
+			Amount:        schedule.Amount,
+			Currency:      schedule.Currency,
+			Description:   description,
+			TransactionID: run.TransactionID,
+		})
+	}
+	switch {
+	case err == nil || errors.Is(err, ledger.ErrDuplicateTransaction):
+		run.Status = RunSucceeded
+		schedule.schedule(schedule.Occurrence + 1)
+	case errors.Is(err, ledger.ErrInsufficientBalance) && schedule.Attempts < s.retry.MaxAttempts:
+		run.Status = RunRetrying
+		run.Error = err.Error()
+		schedule.NextRunAt = now + int64(s.retry.Backoff/time.Second)
+	default:
+		run.Status = RunFailed
+		run.Error = err.Error()
+		schedule.schedule(schedule.Occurrence + 1)
+	}
+	schedule.LastRunAt = now
+	schedule.LastRunStatus = run.Status
+	schedule.UpdatedAt = now
+
+	if err := s.repo.SaveRun(run); err != nil {
+		return err
+	}
+	log.Printf("Schedule %s occurrence %d attempt %d: %s %s", schedule.ID, run.Occurrence, run.Attempt, run.Status, run.Error)
+	return s.repo.Save(schedule)
+}
+
+// schedule makes the nth (zero-based) occurrence the next one, completing the schedule if it has none
+func (s *Schedule) schedule(n int) {
+	s.Occurrence = n
+	s.Attempts = 0
+	if !s.hasOccurrence(n) {
+		s.Status = StatusCompleted
+		s.NextRunAt = 0
+		return
+	}
+	s.NextRunAt = s.occurrenceAt(n)
+}
+
+// hasOccurrence reports whether the schedule's end conditions allow an nth (zero-based) occurrence
+func (s *Schedule) hasOccurrence(n int) bool {
+	if s.Frequency == FrequencyOnce {
+		return n == 0
+	}
+	if s.MaxRuns > 0 && n >= s.MaxRuns {
+		return false
+	}
+	return s.EndAt == 0 || s.occurrenceAt(n) <= s.EndAt
+}
+
+// occurrenceAt returns when the nth (zero-based) occurrence is due
+// Monthly occurrences keep StartAt's day of month, falling back to the last day of shorter months
+func (s *Schedule) occurrenceAt(n int) int64 {
+	start := time.Unix(s.StartAt, 0).UTC()
+	switch s.Frequency {
+	case FrequencyDaily:
+		return start.AddDate(0, 0, n).Unix()
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n).Unix()
+	case FrequencyMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n), 1, start.Hour(), start.Minute(), start.Second(), 0, time.UTC)
+		day := start.Day()
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return first.AddDate(0, 0, day-1).Unix()
+	}
+	return s.StartAt
+}
+
+// transactionID derives the ledger transaction ID of a schedule's nth occurrence
+func transactionID(scheduleID string, n int) string {
+	return uuid.NewSHA1(transactionNamespace, []byte(fmt.Sprintf("%s/%d", scheduleID, n))).String()
+}
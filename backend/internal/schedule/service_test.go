@@ -0,0 +1,244 @@
+package schedule
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock the tests move forward by hand
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestService(t *testing.T, retry RetryPolicy) (*Service, *ledger.Service, *fakeClock, string, string) {
+	t.Helper()
+	ledgerService := ledger.NewService(ledger.NewRepository())
+	walletService := wallet.NewService(wallet.NewRepository(), ledgerService)
+	payer, err := walletService.CreateWallet("payer", "EUR")
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	landlord, err := walletService.CreateWallet("landlord", "EUR")
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	clock := &fakeClock{now: time.Date(2026, time.January, 15, 9, 0, 0, 0, time.UTC)}
+	return NewService(NewRepository(), ledgerService, walletService, retry, clock), ledgerService, clock, payer, landlord
+}
+
+func deposit(t *testing.T, ledgerService *ledger.Service, walletID string, amount int64) {
+	t.Helper()
+	if _, err := ledgerService.RecordDeposit(&ledger.DepositRequest{AccountID: walletID, Amount: amount, Currency: "EUR", Source: "bank"}); err != nil {
+		t.Fatalf("Failed to record deposit: %v", err)
+	}
+}
+
+func runDue(t *testing.T, service *Service) int {
+	t.Helper()
+	runs, err := service.RunDue()
+	if err != nil {
+		t.Fatalf("Failed to run due schedules: %v", err)
+	}
+	return runs
+}
+
+// TestMonthlySchedule tests month-end clamping, catching up, idempotent transaction IDs and MaxRuns
+func TestMonthlySchedule(t *testing.T) {
+	service, ledgerService, clock, payer, landlord := newTestService(t, DefaultRetryPolicy)
+	deposit(t, ledgerService, payer, 500000)
+
+	schedule, err := service.Create(&CreateRequest{
+		UserID: "payer", FromWalletID: payer, ToWalletID: landlord, Amount: 50000, Description: "Rent",
+		Frequency: FrequencyMonthly, StartAt: time.Date(2026, time.January, 31, 10, 0, 0, 0, time.UTC), MaxRuns: 3,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	if runs := runDue(t, service); runs != 0 {
+		t.Errorf("Expected nothing due before the start, got %d runs", runs)
+	}
+
+	// Two occurrences fall due while the job isn't running: January 31st and February 28th
+	clock.now = time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if runs := runDue(t, service); runs != 2 {
+		t.Fatalf("Expected to catch up on 2 occurrences, got %d", runs)
+	}
+	schedule, _ = service.GetSchedule(schedule.ID)
+	if want := time.Date(2026, time.March, 31, 10, 0, 0, 0, time.UTC).Unix(); schedule.NextRunAt != want {
+		t.Errorf("Expected the next run on March 31st, got %s", time.Unix(schedule.NextRunAt, 0).UTC())
+	}
+	if runs := runDue(t, service); runs != 0 {
+		t.Errorf("Expected a rerun to find nothing due, got %d runs", runs)
+	}
+
+	clock.now = time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)
+	runDue(t, service)
+	schedule, _ = service.GetSchedule(schedule.ID)
+	if schedule.Status != StatusCompleted || schedule.NextRunAt != 0 {
+		t.Errorf("Expected the schedule to complete after 3 runs, got %+v", schedule)
+	}
+
+	runs, err := service.ListRuns(schedule.ID)
+	if err != nil || len(runs) != 3 {
+		t.Fatalf("Expected 3 runs, got %d (%v)", len(runs), err)
+	}
+	wantDue := []time.Time{
+		time.Date(2026, time.January, 31, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, time.February, 28, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, time.March, 31, 10, 0, 0, 0, time.UTC),
+	}
+	for i, run := range runs {
+		if run.Status != RunSucceeded || run.DueAt != wantDue[i].Unix() || run.TransactionID != transactionID(schedule.ID, i) {
+			t.Errorf("Unexpected run %d: %+v", i+1, run)
+		}
+	}
+
+	balance, err := ledgerService.GetBalance(landlord)
+	if err != nil || balance.Balance != 150000 {
+		t.Errorf("Expected the landlord to have received 1500.00, got %+v (%v)", balance, err)
+	}
+}
+
+// TestRetryOnInsufficientFunds tests that a short wallet is retried under the policy and then given up on
+func TestRetryOnInsufficientFunds(t *testing.T) {
+	service, ledgerService, clock, payer, landlord := newTestService(t, RetryPolicy{MaxAttempts: 2, Backoff: time.Hour})
+	schedule, err := service.Create(&CreateRequest{
+		FromWalletID: payer, ToWalletID: landlord, Amount: 10000, Frequency: FrequencyDaily,
+		EndAt: clock.now.Add(36 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+
+	// The first occurrence fails twice and is given up on
+	runDue(t, service)
+	schedule, _ = service.GetSchedule(schedule.ID)
+	if schedule.Status != StatusActive || schedule.NextRunAt != clock.now.Add(time.Hour).Unix() {
+		t.Fatalf("Expected a retry in an hour, got %+v", schedule)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	runDue(t, service)
+
+	// The second occurrence succeeds on its retry once the wallet is topped up
+	clock.now = clock.now.Add(23 * time.Hour)
+	runDue(t, service)
+	deposit(t, ledgerService, payer, 10000)
+	clock.now = clock.now.Add(time.Hour)
+	runDue(t, service)
+
+	runs, _ := service.ListRuns(schedule.ID)
+	want := []struct {
+		occurrence, attempt int
+		status              string
+	}{{1, 1, RunRetrying}, {1, 2, RunFailed}, {2, 1, RunRetrying}, {2, 2, RunSucceeded}}
+	if len(runs) != len(want) {
+		t.Fatalf("Expected %d runs, got %d", len(want), len(runs))
+	}
+	for i, run := range runs {
+		if run.Occurrence != want[i].occurrence || run.Attempt != want[i].attempt || run.Status != want[i].status {
+			t.Errorf("Run %d: expected %+v, got %+v", i+1, want[i], run)
+		}
+	}
+	if runs[2].TransactionID != runs[3].TransactionID {
+		t.Error("Expected retries of an occurrence to reuse its transaction ID")
+	}
+
+	schedule, _ = service.GetSchedule(schedule.ID)
+	if schedule.Status != StatusCompleted {
+		t.Errorf("Expected the end date to complete the schedule, got %s", schedule.Status)
+	}
+}
+
+// TestAlreadyPostedOccurrence tests that an occurrence posted without its run being recorded isn't paid twice
+func TestAlreadyPostedOccurrence(t *testing.T) {
+	service, ledgerService, _, payer, landlord := newTestService(t, RetryPolicy{MaxAttempts: 1})
+	deposit(t, ledgerService, payer, 10000)
+	schedule, err := service.Create(&CreateRequest{FromWalletID: payer, ToWalletID: landlord, Amount: 10000, Frequency: FrequencyOnce})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	if _, err := ledgerService.RecordTransfer(&ledger.TransferRequest{
+		FromAccountID: payer, ToAccountID: landlord, Amount: 10000, Currency: "EUR", TransactionID: transactionID(schedule.ID, 0),
+	}); err != nil {
+		t.Fatalf("Failed to record transfer: %v", err)
+	}
+
+	runDue(t, service)
+	runs, _ := service.ListRuns(schedule.ID)
+	if len(runs) != 1 || runs[0].Status != RunSucceeded {
+		t.Errorf("Expected the posted occurrence to count as succeeded, got %+v", runs)
+	}
+	balance, _ := ledgerService.GetBalance(landlord)
+	if balance.Balance != 10000 {
+		t.Errorf("Expected the landlord to be paid once, got %d", balance.Balance)
+	}
+}
+
+// TestPauseResumeCancel tests status changes and that occurrences missed while paused are skipped
+func TestPauseResumeCancel(t *testing.T) {
+	service, ledgerService, clock, payer, landlord := newTestService(t, DefaultRetryPolicy)
+	deposit(t, ledgerService, payer, 100000)
+	schedule, err := service.Create(&CreateRequest{FromWalletID: payer, ToWalletID: landlord, Amount: 1000, Frequency: FrequencyWeekly})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	start := clock.now
+
+	if _, err := service.Pause(schedule.ID); err != nil {
+		t.Fatalf("Failed to pause schedule: %v", err)
+	}
+	if _, err := service.Pause(schedule.ID); err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition pausing twice, got %v", err)
+	}
+	clock.now = start.Add(15 * 24 * time.Hour)
+	if runs := runDue(t, service); runs != 0 {
+		t.Errorf("Expected a paused schedule not to run, got %d runs", runs)
+	}
+
+	schedule, err = service.Resume(schedule.ID)
+	if err != nil {
+		t.Fatalf("Failed to resume schedule: %v", err)
+	}
+	if schedule.Occurrence != 3 || schedule.NextRunAt != start.Add(21*24*time.Hour).Unix() {
+		t.Errorf("Expected the three missed occurrences to be skipped, got %+v", schedule)
+	}
+
+	if _, err := service.Cancel(schedule.ID); err != nil {
+		t.Fatalf("Failed to cancel schedule: %v", err)
+	}
+	clock.now = start.Add(30 * 24 * time.Hour)
+	if runs := runDue(t, service); runs != 0 {
+		t.Errorf("Expected a cancelled schedule not to run, got %d runs", runs)
+	}
+	if _, err := service.Resume(schedule.ID); err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition resuming a cancelled schedule, got %v", err)
+	}
+}
+
+// TestCreateValidation tests that invalid schedules are rejected
+func TestCreateValidation(t *testing.T) {
+	service, _, clock, payer, landlord := newTestService(t, DefaultRetryPolicy)
+
+	invalid := map[string]struct {
+		req  CreateRequest
+		want error
+	}{
+		"zero amount":       {CreateRequest{FromWalletID: payer, ToWalletID: landlord, Frequency: FrequencyOnce}, ErrInvalidAmount},
+		"unknown frequency": {CreateRequest{FromWalletID: payer, ToWalletID: landlord, Amount: 1, Frequency: "YEARLY"}, ErrInvalidFrequency},
+		"start in the past": {CreateRequest{FromWalletID: payer, ToWalletID: landlord, Amount: 1, Frequency: FrequencyOnce, StartAt: clock.now.Add(-time.Hour)}, ErrStartInPast},
+		"end before start":  {CreateRequest{FromWalletID: payer, ToWalletID: landlord, Amount: 1, Frequency: FrequencyDaily, EndAt: clock.now.Add(-time.Hour)}, ErrInvalidEnd},
+		"negative max runs": {CreateRequest{FromWalletID: payer, ToWalletID: landlord, Amount: 1, Frequency: FrequencyDaily, MaxRuns: -1}, ErrInvalidMaxRuns},
+		"same wallet":       {CreateRequest{FromWalletID: payer, ToWalletID: payer, Amount: 1, Frequency: FrequencyOnce}, ErrSameWallet},
+	}
+	for name, tt := range invalid {
+		if _, err := service.Create(&tt.req); err != tt.want {
+			t.Errorf("%s: expected %v, got %v", name, tt.want, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package statement
+
+import (
+	"digitalwallet/backend/pkg"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service       *Service
+	walletService WalletService
+}
+
+func NewHandler(service *Service, walletService WalletService) *Handler {
+	return &Handler{service: service, walletService: walletService}
+}
+
+// Generate renders the caller's statement for a month on demand
+// GET /statements/monthly/:month (month is YYYY-MM)
+func (h *Handler) Generate(c *gin.Context) {
+	walletID, ok := h.callerWalletID(c)
+	if !ok {
+		return
+	}
+
+	month, err := ParseMonth(c.Param("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	statement, err := h.service.Generate(walletID, month, time.Now())
+	if err != nil {
+		if err == ErrFutureMonth {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Error generating statement for wallet %s: %v", walletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	writePDF(c, statement)
+}
+
+// List returns the caller's stored month-end statements, newest first
+// GET /statements
+func (h *Handler) List(c *gin.Context) {
+	walletID, ok := h.callerWalletID(c)
+	if !ok {
+		return
+	}
+
+	statements, err := h.service.ListStatements(walletID)
+	if err != nil {
+		log.Printf("Error listing statements for wallet %s: %v", walletID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if statements == nil {
+		statements = []*MonthlyStatement{}
+	}
+	c.JSON(http.StatusOK, gin.H{"statements": statements, "count": len(statements)})
+}
+
+// Download returns a stored statement's PDF
+// GET /statements/:id
+func (h *Handler) Download(c *gin.Context) {
+	walletID, ok := h.callerWalletID(c)
+	if !ok {
+		return
+	}
+
+	statement, err := h.service.GetStatement(c.Param("id"))
+	// Other wallets' statements are reported as missing rather than forbidden
+	if err == ErrStatementNotFound || (err == nil && statement.WalletID != walletID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Statement not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting statement %s: %v", c.Param("id"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	writePDF(c, statement)
+}
+
+// callerWalletID resolves the authenticated user's wallet, writing the error response if it can't
+func (h *Handler) callerWalletID(c *gin.Context) (string, bool) {
+	userID := c.GetString("userId")
+	if userID == "" {
+		log.Println("Error: User is not set in the user context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return "", false
+	}
+
+	callerWallet, err := h.walletService.GetWalletByUserID(userID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+			return "", false
+		}
+		log.Printf("Error getting wallet for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return "", false
+	}
+	return callerWallet.ID, true
+}
+
+func writePDF(c *gin.Context, statement *MonthlyStatement) {
+	filename := fmt.Sprintf("statement-%s-%s.pdf", statement.WalletID, statement.Month)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", statement.PDF)
+}
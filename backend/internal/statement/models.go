@@ -0,0 +1,17 @@
+package statement
+
+// MonthFormat is how statement months are written in URLs and responses
+const MonthFormat = "2006-01"
+
+// MonthlyStatement is a PDF statement of a wallet for one calendar month (UTC)
+type MonthlyStatement struct {
+	ID          string `json:"id"`
+	WalletID    string `json:"wallet_id"`
+	UserID      string `json:"user_id"`
+	Month       string `json:"month"`        // YYYY-MM
+	PeriodStart int64  `json:"period_start"` // Unix time, inclusive
+	PeriodEnd   int64  `json:"period_end"`   // Unix time, inclusive
+	GeneratedAt int64  `json:"generated_at"`
+	Size        int    `json:"size"` // PDF size in bytes
+	PDF         []byte `json:"-"`
+}
@@ -0,0 +1,108 @@
+package statement
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/pkg/currency"
+	"digitalwallet/backend/pkg/pdf"
+	"fmt"
+	"time"
+)
+
+// Page layout in points
+const (
+	marginLeft   = 50.0
+	marginRight  = pdf.PageWidth - 50
+	tableTop     = 630.0 // First row on the first page, below the account details
+	continuedTop = 740.0 // First row on later pages
+	tableBottom  = 70.0
+	rowHeight    = 16.0
+	fontSize     = 9.0
+)
+
+// Table columns: left edges for text, right edges for amounts
+const (
+	colDate        = marginLeft
+	colDescription = 115.0
+	colType        = 320.0
+	colAmountEnd   = 465.0
+	colBalanceEnd  = marginRight
+)
+
+// renderPDF lays out a statement as a paginated A4 document
+func renderPDF(holder string, statement *MonthlyStatement, export *ledger.StatementExport) []byte {
+	money := func(amount int64) string { return currency.FormatAmount(amount, export.Currency) }
+	date := func(unix int64) string { return time.Unix(unix, 0).UTC().Format("02 Jan 2006") }
+
+	doc := pdf.New(fmt.Sprintf("Statement %s %s", statement.WalletID, statement.Month))
+	page := doc.AddPage()
+	heading(page)
+
+	details := []struct{ label, value string }{
+		{"Account holder", holder},
+		{"Wallet ID", statement.WalletID},
+		{"Period", date(statement.PeriodStart) + " - " + date(statement.PeriodEnd)},
+		{"Currency", export.Currency},
+		{"Opening balance", money(export.OpeningBalance)},
+		{"Closing balance", money(export.ClosingBalance)},
+	}
+	y := 750.0
+	for _, detail := range details {
+		page.Text(marginLeft, y, pdf.HelveticaBold, 10, detail.label)
+		page.Text(160, y, pdf.Helvetica, 10, detail.value)
+		y -= 15
+	}
+
+	y = tableTop
+	tableHeader(page, y+rowHeight)
+	row := func(values [5]string, font pdf.Font) {
+		if y < tableBottom {
+			page = doc.AddPage()
+			heading(page)
+			y = continuedTop
+			tableHeader(page, y+rowHeight)
+		}
+		page.Text(colDate, y, font, fontSize, values[0])
+		page.Text(colDescription, y, font, fontSize, pdf.Truncate(values[1], fontSize, colType-colDescription-10))
+		page.Text(colType, y, font, fontSize, values[2])
+		page.TextRight(colAmountEnd, y, font, fontSize, values[3])
+		page.TextRight(colBalanceEnd, y, font, fontSize, values[4])
+		y -= rowHeight
+	}
+
+	row([5]string{date(statement.PeriodStart), "Opening balance", "", "", money(export.OpeningBalance)}, pdf.HelveticaBold)
+	for _, line := range export.Lines {
+		entry := line.Entry
+		row([5]string{date(entry.CreatedAt), entry.Description, entry.TransactionType, money(entry.Amount), money(line.RunningBalance)}, pdf.Helvetica)
+	}
+	if len(export.Lines) == 0 {
+		row([5]string{"", "No transactions in this period", "", "", ""}, pdf.Helvetica)
+	}
+	row([5]string{date(statement.PeriodEnd), "Closing balance", "", "", money(export.ClosingBalance)}, pdf.HelveticaBold)
+
+	// Footers need the final page count
+	pages := doc.Pages()
+	generated := time.Unix(statement.GeneratedAt, 0).UTC().Format("02 Jan 2006 15:04 MST")
+	for i, p := range pages {
+		p.Line(marginLeft, 45, marginRight, 45, 0.5)
+		p.Text(marginLeft, 32, pdf.Helvetica, 8, "Generated "+generated)
+		p.TextRight(marginRight, 32, pdf.Helvetica, 8, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+	return doc.Bytes()
+}
+
+// heading draws the title block at the top of every page
+func heading(page *pdf.Page) {
+	page.Text(marginLeft, 790, pdf.HelveticaBold, 18, "Account Statement")
+	page.TextRight(marginRight, 790, pdf.Helvetica, 10, "Digital Wallet")
+	page.Line(marginLeft, 778, marginRight, 778, 1)
+}
+
+// tableHeader draws the column titles with their baseline at y
+func tableHeader(page *pdf.Page, y float64) {
+	page.Rect(marginLeft-4, y-5, marginRight-marginLeft+8, rowHeight, 0.9)
+	page.Text(colDate, y, pdf.HelveticaBold, fontSize, "Date")
+	page.Text(colDescription, y, pdf.HelveticaBold, fontSize, "Description")
+	page.Text(colType, y, pdf.HelveticaBold, fontSize, "Type")
+	page.TextRight(colAmountEnd, y, pdf.HelveticaBold, fontSize, "Amount")
+	page.TextRight(colBalanceEnd, y, pdf.HelveticaBold, fontSize, "Balance")
+}
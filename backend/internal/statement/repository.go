@@ -0,0 +1,93 @@
+package statement
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrStatementNotFound = errors.New("statement not found")
+	ErrStatementExists   = errors.New("a statement for this wallet and month already exists")
+)
+
+// Repository stores generated statements for download
+type Repository interface {
+	Save(statement *MonthlyStatement) error // Fails with ErrStatementExists for a second statement of the same wallet and month
+	GetByID(id string) (*MonthlyStatement, error)
+	GetByWalletAndMonth(walletID, month string) (*MonthlyStatement, error)
+	ListByWalletID(walletID string) ([]*MonthlyStatement, error)
+}
+
+// inMemoryRepository implements Repository using in-memory storage
+type inMemoryRepository struct {
+	mu         sync.RWMutex
+	statements map[string]*MonthlyStatement
+	byMonth    map[string]string // wallet ID + month -> statement ID
+}
+
+// NewRepository creates a new in-memory statement repository
+func NewRepository() Repository {
+	return &inMemoryRepository{
+		statements: make(map[string]*MonthlyStatement),
+		byMonth:    make(map[string]string),
+	}
+}
+
+// Save stores a generated statement
+func (r *inMemoryRepository) Save(statement *MonthlyStatement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := statement.WalletID + "/" + statement.Month
+	if _, exists := r.byMonth[key]; exists {
+		return ErrStatementExists
+	}
+
+	stored := *statement
+	r.statements[statement.ID] = &stored
+	r.byMonth[key] = statement.ID
+	log.Printf("Statement stored: %s (wallet %s, %s, %d bytes)", statement.ID, statement.WalletID, statement.Month, statement.Size)
+	return nil
+}
+
+// GetByID retrieves a statement by ID
+func (r *inMemoryRepository) GetByID(id string) (*MonthlyStatement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	statement, exists := r.statements[id]
+	if !exists {
+		return nil, ErrStatementNotFound
+	}
+	result := *statement
+	return &result, nil
+}
+
+// GetByWalletAndMonth retrieves the statement of a wallet for a month
+func (r *inMemoryRepository) GetByWalletAndMonth(walletID, month string) (*MonthlyStatement, error) {
+	r.mu.RLock()
+	id, exists := r.byMonth[walletID+"/"+month]
+	r.mu.RUnlock()
+	if !exists {
+		return nil, ErrStatementNotFound
+	}
+	return r.GetByID(id)
+}
+
+// ListByWalletID retrieves every stored statement of a wallet, newest month first
+func (r *inMemoryRepository) ListByWalletID(walletID string) ([]*MonthlyStatement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var statements []*MonthlyStatement
+	for _, statement := range r.statements {
+		if statement.WalletID == walletID {
+			result := *statement
+			statements = append(statements, &result)
+		}
+	}
+	sort.Slice(statements, func(i, j int) bool { return statements[i].Month > statements[j].Month })
+	return statements, nil
+}
@@ -0,0 +1,15 @@
+package statement
+
+import (
+	"digitalwallet/backend/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes sets up the statement routes; every route acts on the caller's own wallet
+func RegisterRoutes(router *gin.Engine, statementHandler *Handler, authMiddleware *auth.Middleware) {
+	// Protected routes
+	router.GET("/statements", authMiddleware.Authenticate, statementHandler.List)
+	router.GET("/statements/monthly/:month", authMiddleware.Authenticate, statementHandler.Generate)
+	router.GET("/statements/:id", authMiddleware.Authenticate, statementHandler.Download)
+}
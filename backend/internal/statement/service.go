@@ -0,0 +1,190 @@
+package statement
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"digitalwallet/backend/pkg"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidMonth  = errors.New("invalid month: expected YYYY-MM")
+	ErrFutureMonth   = errors.New("statements can't be generated for months that haven't started")
+	ErrMonthNotEnded = errors.New("month-end statements can only be stored once the month has ended")
+)
+
+// LedgerService is the subset of ledger.Service used to read a wallet's entries for a period
+type LedgerService interface {
+	ExportStatement(accountID string, from, to int64, generatedAt time.Time) (*ledger.StatementExport, error)
+}
+
+// WalletService resolves wallets and lists them for the month-end batch
+type WalletService interface {
+	GetWalletByID(walletID string) (*wallet.Wallet, error)
+	GetWalletByUserID(userID string) (*wallet.Wallet, error)
+	GetAllWallets() ([]wallet.Wallet, error)
+}
+
+// UserService resolves the account holder printed on a statement
+type UserService interface {
+	GetByID(id string) (*pkg.UserDTO, error)
+}
+
+// Service generates PDF statements on demand and stores them at month end
+type Service struct {
+	repo    Repository
+	ledger  LedgerService
+	wallets WalletService
+	users   UserService
+}
+
+// NewService creates a new statement service
+func NewService(repo Repository, ledgerService LedgerService, walletService WalletService, userService UserService) *Service {
+	return &Service{repo: repo, ledger: ledgerService, wallets: walletService, users: userService}
+}
+
+// ParseMonth parses a YYYY-MM month into the first instant of that month (UTC)
+func ParseMonth(value string) (time.Time, error) {
+	month, err := time.Parse(MonthFormat, value)
+	if err != nil {
+		return time.Time{}, ErrInvalidMonth
+	}
+	return month, nil
+}
+
+// PreviousMonth returns the first instant of the month before now's (UTC)
+func PreviousMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Generate renders a wallet's statement for a month without storing it
+// The current month can be generated too; it then covers the month so far
+func (s *Service) Generate(walletID string, month time.Time, now time.Time) (*MonthlyStatement, error) {
+	month = month.UTC()
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0).Add(-time.Second)
+	if start.After(now) {
+		return nil, ErrFutureMonth
+	}
+
+	w, err := s.wallets.GetWalletByID(walletID)
+	if err != nil {
+		return nil, err
+	}
+	holder, err := s.users.GetByID(w.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := s.ledger.ExportStatement(walletID, start.Unix(), end.Unix(), now)
+	if err == ledger.ErrAccountBalanceNotFound {
+		// Nothing has been posted to the wallet yet
+		export = &ledger.StatementExport{Statement: &ledger.Statement{AccountID: walletID, Currency: w.Currency}}
+	} else if err != nil {
+		return nil, err
+	}
+
+	statement := &MonthlyStatement{
+		ID:          uuid.New().String(),
+		WalletID:    walletID,
+		UserID:      w.UserID,
+		Month:       start.Format(MonthFormat),
+		PeriodStart: start.Unix(),
+		PeriodEnd:   end.Unix(),
+		GeneratedAt: now.Unix(),
+	}
+	statement.PDF = renderPDF(holderName(holder), statement, export)
+	statement.Size = len(statement.PDF)
+	return statement, nil
+}
+
+// RunMonthEnd generates and stores the statement of every wallet for a month that has ended
+// Wallets that already have a stored statement for the month are skipped, so the job can be rerun
+// It returns how many statements were stored
+func (s *Service) RunMonthEnd(month time.Time, now time.Time) (int, error) {
+	month = month.UTC()
+	if time.Date(month.Year(), month.Month()+1, 1, 0, 0, 0, 0, time.UTC).After(now) {
+		return 0, ErrMonthNotEnded
+	}
+	wallets, err := s.wallets.GetAllWallets()
+	if err != nil {
+		return 0, err
+	}
+
+	stored := 0
+	var failures []error
+	for _, w := range wallets {
+		if _, err := s.repo.GetByWalletAndMonth(w.ID, month.Format(MonthFormat)); err == nil {
+			continue
+		}
+		statement, err := s.Generate(w.ID, month, now)
+		if err == nil {
+			err = s.repo.Save(statement)
+		}
+		if err == ErrStatementExists {
+			continue
+		}
+		if err != nil {
+			log.Printf("Error generating %s statement for wallet %s: %v", month.Format(MonthFormat), w.ID, err)
+			failures = append(failures, err)
+			continue
+		}
+		stored++
+	}
+	return stored, errors.Join(failures...)
+}
+
+// StartMonthEndJob stores the previous month's statements every interval until the returned stop function is called
+// Runs are idempotent, so the first run after a month ends does the work and later ones find nothing to do
+func (s *Service) StartMonthEndJob(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				month := PreviousMonth(now)
+				stored, err := s.RunMonthEnd(month, now)
+				if err != nil {
+					log.Printf("Error running month-end statements for %s: %v", month.Format(MonthFormat), err)
+				}
+				if stored > 0 {
+					log.Printf("Stored %d statements for %s", stored, month.Format(MonthFormat))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// GetStatement retrieves a stored statement
+func (s *Service) GetStatement(id string) (*MonthlyStatement, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListStatements retrieves a wallet's stored statements, newest month first
+func (s *Service) ListStatements(walletID string) ([]*MonthlyStatement, error) {
+	return s.repo.ListByWalletID(walletID)
+}
+
+// holderName is the name printed on the statement, falling back to the email address
+func holderName(user *pkg.UserDTO) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		return user.Email
+	}
+	return name
+}
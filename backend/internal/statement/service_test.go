@@ -0,0 +1,116 @@
+package statement
+
+import (
+	"bytes"
+	"compress/zlib"
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/user"
+	"digitalwallet/backend/internal/wallet"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// johnID is one of the users the in-memory user repository is seeded with
+const johnID = "b18b851a-c8c4-4957-b68a-14362a1810c6"
+
+func newTestService(t *testing.T) (*Service, *ledger.Service, string) {
+	t.Helper()
+	ledgerService := ledger.NewService(ledger.NewRepository())
+	walletService := wallet.NewService(wallet.NewRepository(), ledgerService)
+	walletID, err := walletService.CreateWallet(johnID, "EUR")
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	service := NewService(NewRepository(), ledgerService, walletService, user.NewService(user.NewRepository()))
+	return service, ledgerService, walletID
+}
+
+// pdfText returns the decompressed content streams of a PDF
+func pdfText(t *testing.T, document []byte) string {
+	t.Helper()
+	var text strings.Builder
+	for _, stream := range regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindAllSubmatch(document, -1) {
+		reader, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		if err != nil {
+			t.Fatalf("Failed to decompress content stream: %v", err)
+		}
+		content, _ := io.ReadAll(reader)
+		text.Write(content)
+	}
+	return text.String()
+}
+
+// TestGenerateStatement tests the statement contents and that long statements span several pages
+func TestGenerateStatement(t *testing.T) {
+	service, ledgerService, walletID := newTestService(t)
+
+	for i := 1; i <= 80; i++ {
+		_, err := ledgerService.RecordDeposit(&ledger.DepositRequest{
+			AccountID: walletID, Amount: 1250, Currency: "EUR", Source: "bank", Description: fmt.Sprintf("Top-up %d", i),
+		})
+		if err != nil {
+			t.Fatalf("Failed to record deposit: %v", err)
+		}
+	}
+
+	now := time.Now()
+	statement, err := service.Generate(walletID, now, now)
+	if err != nil {
+		t.Fatalf("Failed to generate statement: %v", err)
+	}
+	if statement.Month != now.UTC().Format(MonthFormat) || statement.UserID != johnID {
+		t.Errorf("Expected John's statement for %s, got %s for %s", now.UTC().Format(MonthFormat), statement.Month, statement.UserID)
+	}
+	if !bytes.HasPrefix(statement.PDF, []byte("%PDF-")) || statement.Size != len(statement.PDF) {
+		t.Fatal("Expected a PDF document with its size recorded")
+	}
+	if !bytes.Contains(statement.PDF, []byte("/Count 3")) {
+		t.Error("Expected 82 table rows to span 3 pages")
+	}
+
+	text := pdfText(t, statement.PDF)
+	for _, want := range []string{"(John Doe)", "(" + walletID + ")", "(\\2000.00)", "(\\2001000.00)", "(Deposit from bank: Top-up 80)", "(Page 3 of 3)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the statement to contain %s", want)
+		}
+	}
+
+	if _, err := service.Generate(walletID, now.AddDate(0, 2, 0), now); err != ErrFutureMonth {
+		t.Errorf("Expected ErrFutureMonth, got %v", err)
+	}
+}
+
+// TestRunMonthEnd tests that the batch stores one statement per wallet and month and can be rerun
+func TestRunMonthEnd(t *testing.T) {
+	service, _, walletID := newTestService(t)
+	now := time.Date(2024, time.March, 1, 2, 0, 0, 0, time.UTC)
+	month := PreviousMonth(now)
+
+	stored, err := service.RunMonthEnd(month, now)
+	if err != nil || stored != 1 {
+		t.Fatalf("Expected 1 statement stored, got %d (%v)", stored, err)
+	}
+	if stored, err := service.RunMonthEnd(month, now); err != nil || stored != 0 {
+		t.Errorf("Expected a rerun to store nothing, got %d (%v)", stored, err)
+	}
+
+	statements, _ := service.ListStatements(walletID)
+	if len(statements) != 1 || statements[0].Month != "2024-02" {
+		t.Fatalf("Expected the February statement, got %v", statements)
+	}
+	if end := time.Unix(statements[0].PeriodEnd, 0).UTC(); end != time.Date(2024, time.February, 29, 23, 59, 59, 0, time.UTC) {
+		t.Errorf("Expected the period to end on 29 Feb, got %v", end)
+	}
+	download, _ := service.GetStatement(statements[0].ID)
+	if !strings.Contains(pdfText(t, download.PDF), "(No transactions in this period)") {
+		t.Error("Expected an empty statement for a wallet without entries")
+	}
+
+	if _, err := service.RunMonthEnd(now, now); err != ErrMonthNotEnded {
+		t.Errorf("Expected ErrMonthNotEnded, got %v", err)
+	}
+}
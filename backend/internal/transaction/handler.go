@@ -1 +1,215 @@
 package transaction
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"digitalwallet/backend/pkg"
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletService resolves wallets so users only see and move money from their own wallet
+type WalletService interface {
+	GetWalletByID(walletID string) (*wallet.Wallet, error)
+	GetWalletByUserID(userID string) (*wallet.Wallet, error)
+}
+
+// Handler handles HTTP requests for transaction operations
+type Handler struct {
+	service       *Service
+	walletService WalletService
+	approvals     ApprovalQueue // Optional: without it withdrawals needing approval are refused
+}
+
+// NewHandler creates a new transaction handler
+func NewHandler(service *Service, walletService WalletService, approvals ApprovalQueue) *Handler {
+	return &Handler{service: service, walletService: walletService, approvals: approvals}
+}
+
+// Create starts a transaction from the caller's wallet and runs it through to completion
+// POST /transactions
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateTransactionDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to CreateTransactionDTO:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c)
+	if !ok {
+		return
+	}
+
+	// Amounts are parsed in the wallet's currency so its minor unit precision applies
+	amount, err := currency.Parse(req.Amount, callerWallet.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	initiateReq := &InitiateRequest{
+		UserID:          c.GetString("userId"),
+		TransactionType: req.TransactionType,
+		Amount:          amount.Amount(),
+		Currency:        callerWallet.Currency,
+		Description:     req.Description,
+	}
+	switch req.TransactionType {
+	case TransactionTypeDeposit:
+		initiateReq.ToAccountID = callerWallet.ID
+	case TransactionTypeWithdrawal:
+		initiateReq.FromAccountID = callerWallet.ID
+		// Large withdrawals wait for an admin instead of being refused; the approval posts them
+		if h.approvals != nil && h.service.NeedsApproval(initiateReq.Amount, initiateReq.Currency) {
+			txn, err := h.service.AwaitApproval(initiateReq, h.approvals)
+			if err != nil {
+				log.Printf("Error queueing withdrawal from %s for approval: %v", callerWallet.ID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+				return
+			}
+			c.JSON(http.StatusAccepted, gin.H{
+				"message":     "Withdrawal is waiting for approval",
+				"approval_id": txn.ApprovalID,
+				"transaction": txn.ToDTO(),
+			})
+			return
+		}
+	case TransactionTypeTransfer:
+		recipientWallet, err := h.walletService.GetWalletByID(req.ToWalletID)
+		if err != nil {
+			if err == pkg.ErrWalletNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Recipient wallet not found"})
+				return
+			}
+			log.Printf("Error getting recipient wallet %s: %v", req.ToWalletID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if recipientWallet.Currency != callerWallet.Currency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Recipient wallet holds a different currency"})
+			return
+		}
+		initiateReq.FromAccountID = callerWallet.ID
+		initiateReq.ToAccountID = req.ToWalletID
+	}
+
+	txn, err := h.service.Process(initiateReq)
+	if err != nil {
+		var limitErr *ledger.LimitError
+		if errors.As(err, &limitErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": ledger.ErrLimitExceeded.Error(), "limit": limitErr.ToDTO(), "transaction": txn.ToDTO()})
+			return
+		}
+		switch err {
+		case ErrInvalidTransactionType, ErrInvalidAmount, ErrMissingAccountID,
+			ledger.ErrInvalidAmount, ledger.ErrMissingAccountID, ledger.ErrSameAccountTransfer,
+			ledger.ErrUnsupportedCurrency, ledger.ErrCurrencyMismatch, ledger.ErrFeeExceedsAmount:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case ledger.ErrInsufficientBalance:
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Insufficient balance", "transaction": txn.ToDTO()})
+		case ledger.ErrApprovalRequired:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "transaction": txn.ToDTO()})
+		case ErrConcurrentUpdate, ErrInvalidTransition:
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Println("Error processing transaction:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Transaction completed successfully",
+		"transaction": txn.ToDTO(),
+	})
+}
+
+// List retrieves the caller's transaction history, newest first
+// GET /transactions
+func (h *Handler) List(c *gin.Context) {
+	callerWallet, ok := h.callerWallet(c)
+	if !ok {
+		return
+	}
+
+	txns, err := h.service.ListByAccountID(callerWallet.ID)
+	if err != nil {
+		log.Printf("Error listing transactions for wallet %s: %v", callerWallet.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	dtos := make([]*TransactionDTO, len(txns))
+	for i, txn := range txns {
+		dtos[i] = txn.ToDTO()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": dtos,
+		"count":        len(dtos),
+	})
+}
+
+// Get retrieves one of the caller's transactions
+// GET /transactions/:id
+func (h *Handler) Get(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		log.Println("Error: Request missing required parameter (id)")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameters"})
+		return
+	}
+
+	callerWallet, ok := h.callerWallet(c)
+	if !ok {
+		return
+	}
+
+	txn, err := h.service.GetByID(id)
+	if err != nil {
+		if err == ErrTransactionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+			return
+		}
+		log.Printf("Error getting transaction %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// Other users' transactions are reported as not found rather than forbidden, so IDs can't be probed
+	if txn.FromAccountID != callerWallet.ID && txn.ToAccountID != callerWallet.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transaction": txn.ToDTO(),
+	})
+}
+
+// callerWallet resolves the authenticated user's wallet, writing the error response if it can't
+func (h *Handler) callerWallet(c *gin.Context) (*wallet.Wallet, bool) {
+	userID := c.GetString("userId")
+	if userID == "" {
+		log.Println("Error: User is not set in the user context")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+
+	callerWallet, err := h.walletService.GetWalletByUserID(userID)
+	if err != nil {
+		if err == pkg.ErrWalletNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+			return nil, false
+		}
+		log.Printf("Error getting wallet for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return callerWallet, true
+}
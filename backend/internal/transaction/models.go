@@ -1,20 +1,118 @@
 package transaction
 
+import (
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+)
+
 const (
 	TransactionTypeDeposit    = "deposit"
 	TransactionTypeWithdrawal = "withdrawal"
 	TransactionTypeTransfer   = "transfer"
 )
 
+// Transaction statuses
+// initiated -> pending -> completed | failed, and completed -> reversed
+const (
+	StatusInitiated = "initiated" // Created and validated, nothing reserved or posted yet
+	StatusPending   = "pending"   // Accepted for processing
+	StatusCompleted = "completed" // Posted to the ledger
+	StatusFailed    = "failed"    // Rejected, nothing posted to the ledger
+	StatusReversed  = "reversed"  // Posted, then undone by a compensating ledger posting
+)
+
+// transitions lists the statuses each status may move to
+var transitions = map[string][]string{
+	StatusInitiated: {StatusPending, StatusFailed},
+	StatusPending:   {StatusCompleted, StatusFailed},
+	StatusCompleted: {StatusReversed},
+}
+
+// Validation errors
+var (
+	ErrInvalidTransactionType = errors.New("transaction type must be deposit, withdrawal or transfer")
+	ErrInvalidAmount          = errors.New("amount must be positive")
+	ErrMissingAccountID       = errors.New("account ID is required")
+	ErrInvalidTransition      = errors.New("invalid transaction status transition")
+)
+
 type Transaction struct {
-	ID              string `json:"id"`
+	ID                  string             `json:"id"`
+	UserID              string             `json:"user_id"` // User who initiated the transaction
+	TransactionType     string             `json:"transaction_type"`
+	Amount              int64              `json:"amount"` // Amount in cents
+	Currency            string             `json:"currency"`
+	Description         string             `json:"description"`
+	CreatedAt           int64              `json:"created_at"`
+	UpdatedAt           int64              `json:"updated_at"`
+	FromAccountID       string             `json:"from_account_id"`
+	ToAccountID         string             `json:"to_account_id"`
+	Status              string             `json:"status"` // initiated, pending, completed, failed, reversed
+	LedgerTransactionID string             `json:"ledger_transaction_id,omitempty"`
+	ReversalLedgerTxnID string             `json:"reversal_ledger_transaction_id,omitempty"`
+	FailureReason       string             `json:"failure_reason,omitempty"`
+	ApprovalID          string             `json:"approval_id,omitempty"` // Approval request the transaction waits on
+	History             []StatusTransition `json:"history"`
+}
+
+// StatusTransition records one change of status
+type StatusTransition struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+	At     int64  `json:"at"` // Unix timestamp
+}
+
+// CanTransitionTo reports whether the state machine allows moving to status
+func (t *Transaction) CanTransitionTo(status string) bool {
+	for _, allowed := range transitions[t.Status] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ToDTO converts the transaction to the API response format
+func (t *Transaction) ToDTO() *TransactionDTO {
+	return &TransactionDTO{
+		ID:              t.ID,
+		TransactionType: t.TransactionType,
+		Amount:          currency.New(t.Amount, t.Currency).String(),
+		Currency:        t.Currency,
+		Description:     t.Description,
+		FromAccountID:   t.FromAccountID,
+		ToAccountID:     t.ToAccountID,
+		Status:          t.Status,
+		FailureReason:   t.FailureReason,
+		ApprovalID:      t.ApprovalID,
+		CreatedAt:       t.CreatedAt,
+		UpdatedAt:       t.UpdatedAt,
+		History:         t.History,
+	}
+}
+
+// TransactionDTO is the API response format with decimal string amounts instead of minor units
+type TransactionDTO struct {
+	ID              string             `json:"id"`
+	TransactionType string             `json:"transaction_type"`
+	Amount          string             `json:"amount"` // Decimal string (e.g., "50.00")
+	Currency        string             `json:"currency"`
+	Description     string             `json:"description"`
+	FromAccountID   string             `json:"from_account_id,omitempty"`
+	ToAccountID     string             `json:"to_account_id,omitempty"`
+	Status          string             `json:"status"`
+	FailureReason   string             `json:"failure_reason,omitempty"`
+	ApprovalID      string             `json:"approval_id,omitempty"`
+	CreatedAt       int64              `json:"created_at"`
+	UpdatedAt       int64              `json:"updated_at"`
+	History         []StatusTransition `json:"history"`
+}
+
+// CreateTransactionDTO is the API payload for starting a transaction from the caller's wallet
+type CreateTransactionDTO struct {
 	TransactionType string `json:"transaction_type"`
-	Amount          int64  `json:"amount"` // Amount in cents
-	Currency        string `json:"currency"`
+	Amount          string `json:"amount"`                 // Decimal string (e.g., "50.00")
+	ToWalletID      string `json:"to_wallet_id,omitempty"` // Transfers only
 	Description     string `json:"description"`
-	CreatedAt       int64  `json:"created_at"`
-	UpdatedAt       int64  `json:"updated_at"`
-	FromAccountID   string `json:"from_account_id"`
-	ToAccountID     string `json:"to_account_id"`
-	Status          string `json:"status"` // e.g., "pending", "completed", "failed"
 }
@@ -1 +1,106 @@
 package transaction
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrConcurrentUpdate    = errors.New("transaction was modified concurrently")
+)
+
+// Repository defines the interface for transaction data access
+type Repository interface {
+	Create(txn *Transaction) error
+	GetByID(id string) (*Transaction, error)
+	Update(txn *Transaction, expectedStatus string) error // Fails with ErrConcurrentUpdate if the stored status differs
+	ListByAccountID(accountID string) ([]*Transaction, error)
+}
+
+// inMemoryRepository implements Repository using in-memory storage
+type inMemoryRepository struct {
+	mu           sync.RWMutex
+	transactions map[string]Transaction
+}
+
+// NewRepository creates a new in-memory transaction repository
+func NewRepository() Repository {
+	return &inMemoryRepository{
+		transactions: make(map[string]Transaction),
+	}
+}
+
+// Create stores a new transaction
+func (r *inMemoryRepository) Create(txn *Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transactions[txn.ID] = copyTransaction(txn)
+	log.Printf("Transaction created: %s (%s, %d cents, status: %s)", txn.ID, txn.TransactionType, txn.Amount, txn.Status)
+	return nil
+}
+
+// GetByID retrieves a transaction by ID
+func (r *inMemoryRepository) GetByID(id string) (*Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	txn, exists := r.transactions[id]
+	if !exists {
+		log.Println("Error: Transaction not found", id)
+		return nil, ErrTransactionNotFound
+	}
+	result := copyTransaction(&txn)
+	return &result, nil
+}
+
+// Update replaces a transaction if its stored status still matches expectedStatus
+func (r *inMemoryRepository) Update(txn *Transaction, expectedStatus string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.transactions[txn.ID]
+	if !exists {
+		return ErrTransactionNotFound
+	}
+	if current.Status != expectedStatus {
+		log.Printf("Error: Transaction %s is %s, expected %s", txn.ID, current.Status, expectedStatus)
+		return ErrConcurrentUpdate
+	}
+
+	r.transactions[txn.ID] = copyTransaction(txn)
+	log.Printf("Transaction %s: %s -> %s", txn.ID, expectedStatus, txn.Status)
+	return nil
+}
+
+// ListByAccountID retrieves every transaction moving money in or out of an account, newest first
+func (r *inMemoryRepository) ListByAccountID(accountID string) ([]*Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*Transaction
+	for _, txn := range r.transactions {
+		if txn.FromAccountID == accountID || txn.ToAccountID == accountID {
+			t := copyTransaction(&txn)
+			result = append(result, &t)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt != result[j].CreatedAt {
+			return result[i].CreatedAt > result[j].CreatedAt
+		}
+		return result[i].ID > result[j].ID
+	})
+	return result, nil
+}
+
+// copyTransaction copies a transaction including its history, so stored state can't be mutated by callers
+func copyTransaction(txn *Transaction) Transaction {
+	c := *txn
+	c.History = append([]StatusTransition(nil), txn.History...)
+	return c
+}
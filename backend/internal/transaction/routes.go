@@ -1 +1,16 @@
 package transaction
+
+import (
+	"digitalwallet/backend/internal/auth"
+	"digitalwallet/backend/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes sets up all transaction-related routes
+func RegisterRoutes(router *gin.Engine, transactionHandler *Handler, authMiddleware *auth.Middleware, idempotencyMiddleware *idempotency.Middleware) {
+	// Protected routes
+	router.POST("/transactions", authMiddleware.Authenticate, idempotencyMiddleware.Enforce, transactionHandler.Create)
+	router.GET("/transactions", authMiddleware.Authenticate, transactionHandler.List)
+	router.GET("/transactions/:id", authMiddleware.Authenticate, transactionHandler.Get)
+}
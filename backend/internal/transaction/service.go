@@ -1 +1,347 @@
 package transaction
+
+import (
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/pkg/currency"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LedgerService is the subset of ledger.Service used to post completed transactions
+type LedgerService interface {
+	RecordDeposit(req *ledger.DepositRequest) (string, error)
+	RecordWithdrawal(req *ledger.WithdrawalRequest) (string, error)
+	RecordTransfer(req *ledger.TransferRequest) (string, error)
+	ReverseTransaction(transactionID, reason string) (string, error)
+	NeedsApproval(amount int64, cur string) bool
+}
+
+// ApprovalQueue holds withdrawals above the approval threshold until an admin approves them
+type ApprovalQueue interface {
+	QueueWithdrawal(withdrawal ledger.WithdrawalRequest, requestedBy string) (string, error)
+}
+
+// Service handles the transaction lifecycle
+// Money only moves when a transaction completes: that is the single point where it is posted to the ledger
+type Service struct {
+	repo   Repository
+	ledger LedgerService
+}
+
+// NewService creates a new transaction service
+func NewService(repo Repository, ledgerService LedgerService) *Service {
+	return &Service{repo: repo, ledger: ledgerService}
+}
+
+// InitiateRequest represents a request to start a new transaction
+type InitiateRequest struct {
+	UserID          string
+	TransactionType string // deposit, withdrawal or transfer
+	Amount          int64  // Amount in cents
+	Currency        string // Defaults to USD
+	FromAccountID   string // Required for withdrawals and transfers
+	ToAccountID     string // Required for deposits and transfers
+	Description     string
+}
+
+// Initiate validates a request and stores it as an initiated transaction
+func (s *Service) Initiate(req *InitiateRequest) (*Transaction, error) {
+	switch req.TransactionType {
+	case TransactionTypeDeposit:
+		if req.ToAccountID == "" {
+			return nil, ErrMissingAccountID
+		}
+	case TransactionTypeWithdrawal:
+		if req.FromAccountID == "" {
+			return nil, ErrMissingAccountID
+		}
+	case TransactionTypeTransfer:
+		if req.FromAccountID == "" || req.ToAccountID == "" {
+			return nil, ErrMissingAccountID
+		}
+	default:
+		return nil, ErrInvalidTransactionType
+	}
+	if req.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	txnCurrency := req.Currency
+	if txnCurrency == "" {
+		txnCurrency = currency.CurrencyUSD
+	}
+
+	now := time.Now().Unix()
+	txn := &Transaction{
+		ID:              uuid.New().String(),
+		UserID:          req.UserID,
+		TransactionType: req.TransactionType,
+		Amount:          req.Amount,
+		Currency:        txnCurrency,
+		Description:     req.Description,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		FromAccountID:   req.FromAccountID,
+		ToAccountID:     req.ToAccountID,
+		Status:          StatusInitiated,
+		History:         []StatusTransition{{To: StatusInitiated, At: now}},
+	}
+
+	if err := s.repo.Create(txn); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// MarkPending accepts an initiated transaction for processing
+func (s *Service) MarkPending(id string) (*Transaction, error) {
+	txn, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transition(txn, StatusPending, ""); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// Complete posts a pending transaction to the ledger
+// If the ledger rejects the posting the transaction is marked failed and the ledger error is returned with it;
+// any other error (a database timeout, a deadlock) leaves it pending so completing it can be retried
+func (s *Service) Complete(id string) (*Transaction, error) {
+	txn, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !txn.CanTransitionTo(StatusCompleted) {
+		return nil, ErrInvalidTransition
+	}
+
+	// The transaction ID doubles as the ledger transaction ID, so the ledger
+	// rejects a second posting if Complete races with itself
+	ledgerTxnID, postErr := s.post(txn, txn.ID)
+	if postErr == ledger.ErrDuplicateTransaction {
+		return nil, ErrConcurrentUpdate
+	}
+	if postErr != nil && !rejected(postErr) {
+		log.Printf("Error posting transaction %s to the ledger, leaving it pending: %v", txn.ID, postErr)
+		return txn, postErr
+	}
+	if postErr != nil {
+		log.Printf("Error posting transaction %s to the ledger: %v", txn.ID, postErr)
+		txn.FailureReason = postErr.Error()
+		if err := s.transition(txn, StatusFailed, postErr.Error()); err != nil {
+			return nil, err
+		}
+		return txn, postErr
+	}
+
+	txn.LedgerTransactionID = ledgerTxnID
+	if err := s.transition(txn, StatusCompleted, ""); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// rejections are the ledger errors that refuse a posting for good; retrying it can't succeed
+var rejections = []error{
+	ledger.ErrInsufficientBalance, ledger.ErrInvalidAmount, ledger.ErrMissingAccountID, ledger.ErrSameAccountTransfer,
+	ledger.ErrUnsupportedCurrency, ledger.ErrCurrencyMismatch, ledger.ErrFeeExceedsAmount, ledger.ErrNotFeeable,
+	ledger.ErrAccountNotFound, ledger.ErrAccountClosed, ledger.ErrAccountFrozen, ledger.ErrAccountNotPostable,
+	ledger.ErrAccountTypeMismatch, ledger.ErrApprovalRequired, ledger.ErrSelfApproval, ledger.ErrApprovalMismatch,
+}
+
+// rejected reports whether the ledger refused a posting for a business reason rather than failing to make it
+func rejected(err error) bool {
+	var limitErr *ledger.LimitError
+	if errors.As(err, &limitErr) {
+		return true
+	}
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fail marks an initiated or pending transaction as failed without posting anything
+func (s *Service) Fail(id, reason string) (*Transaction, error) {
+	txn, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	txn.FailureReason = reason
+	if err := s.transition(txn, StatusFailed, reason); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// Reverse undoes a completed transaction by posting a linked reversal to the ledger
+func (s *Service) Reverse(id, reason string) (*Transaction, error) {
+	txn, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !txn.CanTransitionTo(StatusReversed) {
+		return nil, ErrInvalidTransition
+	}
+
+	// The ledger posting of a completed transaction uses the transaction ID, and can be reversed at most once
+	reversalTxnID, err := s.ledger.ReverseTransaction(txn.ID, reason)
+	if err == ledger.ErrAlreadyReversed {
+		return nil, ErrConcurrentUpdate
+	}
+	if err != nil {
+		log.Printf("Error posting reversal of transaction %s: %v", txn.ID, err)
+		return nil, err
+	}
+
+	txn.ReversalLedgerTxnID = reversalTxnID
+	if err := s.transition(txn, StatusReversed, reason); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// Process runs a new transaction through the whole lifecycle: initiated -> pending -> completed/failed
+func (s *Service) Process(req *InitiateRequest) (*Transaction, error) {
+	txn, err := s.Initiate(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.MarkPending(txn.ID); err != nil {
+		return nil, err
+	}
+	return s.Complete(txn.ID)
+}
+
+// AwaitApproval starts a withdrawal an admin must approve: it is left pending and queued for approval
+// under its own ID, and ApprovalDecided completes or fails it once the request is decided
+func (s *Service) AwaitApproval(req *InitiateRequest, approvals ApprovalQueue) (*Transaction, error) {
+	if req.TransactionType != TransactionTypeWithdrawal {
+		return nil, ErrInvalidTransactionType
+	}
+	txn, err := s.Initiate(req)
+	if err != nil {
+		return nil, err
+	}
+	// Linked before it is queued, so an approval decided straight away finds it
+	txn.ApprovalID = txn.ID
+	if err := s.transition(txn, StatusPending, "waiting for approval"); err != nil {
+		return nil, err
+	}
+
+	approvalID, err := approvals.QueueWithdrawal(ledger.WithdrawalRequest{
+		AccountID:     txn.FromAccountID,
+		Amount:        txn.Amount,
+		Currency:      txn.Currency,
+		Destination:   "external_bank",
+		Description:   txn.Description,
+		TransactionID: txn.ID,
+	}, txn.UserID)
+	if err == nil && approvalID != txn.ID {
+		err = fmt.Errorf("approval queued as %s rather than %s", approvalID, txn.ID)
+	}
+	if err != nil {
+		log.Printf("Error queueing withdrawal %s for approval: %v", txn.ID, err)
+		if _, failErr := s.Fail(txn.ID, "could not be queued for approval"); failErr != nil {
+			log.Printf("Error failing transaction %s: %v", txn.ID, failErr)
+		}
+		return nil, err
+	}
+	return txn, nil
+}
+
+// ApprovalDecided settles the pending transaction waiting on an approval request
+// An executed request posted the withdrawal under the transaction's ID; requests no transaction waits on are ignored
+func (s *Service) ApprovalDecided(approvalID string, executed bool, reason string) error {
+	txn, err := s.repo.GetByID(approvalID)
+	if err == ErrTransactionNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if txn.ApprovalID != approvalID || txn.Status != StatusPending {
+		return nil
+	}
+
+	if executed {
+		txn.LedgerTransactionID = approvalID
+		return s.transition(txn, StatusCompleted, "approved")
+	}
+	txn.FailureReason = reason
+	return s.transition(txn, StatusFailed, reason)
+}
+
+// NeedsApproval reports whether the ledger only posts a withdrawal of amount once an admin approves it
+func (s *Service) NeedsApproval(amount int64, cur string) bool {
+	return s.ledger.NeedsApproval(amount, cur)
+}
+
+// GetByID retrieves a transaction by ID
+func (s *Service) GetByID(id string) (*Transaction, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListByAccountID retrieves every transaction moving money in or out of an account
+func (s *Service) ListByAccountID(accountID string) ([]*Transaction, error) {
+	return s.repo.ListByAccountID(accountID)
+}
+
+// transition moves a transaction to a new status and persists it with a timestamped history entry
+func (s *Service) transition(txn *Transaction, to, reason string) error {
+	if !txn.CanTransitionTo(to) {
+		log.Printf("Error: Transaction %s cannot move from %s to %s", txn.ID, txn.Status, to)
+		return ErrInvalidTransition
+	}
+
+	from := txn.Status
+	now := time.Now().Unix()
+	txn.Status = to
+	txn.UpdatedAt = now
+	txn.History = append(txn.History, StatusTransition{From: from, To: to, Reason: reason, At: now})
+
+	return s.repo.Update(txn, from)
+}
+
+// post records the transaction's money movement in the ledger
+func (s *Service) post(txn *Transaction, ledgerTxnID string) (string, error) {
+	switch txn.TransactionType {
+	case TransactionTypeDeposit:
+		return s.ledger.RecordDeposit(&ledger.DepositRequest{
+			AccountID:     txn.ToAccountID,
+			Amount:        txn.Amount,
+			Currency:      txn.Currency,
+			Source:        "external_bank",
+			Description:   txn.Description,
+			TransactionID: ledgerTxnID,
+		})
+	case TransactionTypeWithdrawal:
+		return s.ledger.RecordWithdrawal(&ledger.WithdrawalRequest{
+			AccountID:     txn.FromAccountID,
+			Amount:        txn.Amount,
+			Currency:      txn.Currency,
+			Destination:   "external_bank",
+			Description:   txn.Description,
+			TransactionID: ledgerTxnID,
+		})
+	case TransactionTypeTransfer:
+		return s.ledger.RecordTransfer(&ledger.TransferRequest{
+			FromAccountID: txn.FromAccountID,
+			ToAccountID:   txn.ToAccountID,
+			Amount:        txn.Amount,
+			Currency:      txn.Currency,
+			Description:   txn.Description,
+			TransactionID: ledgerTxnID,
+		})
+	default:
+		return "", ErrInvalidTransactionType
+	}
+}
@@ -0,0 +1,268 @@
+package transaction
+
+import (
+	"digitalwallet/backend/internal/approval"
+	"digitalwallet/backend/internal/ledger"
+	"digitalwallet/backend/internal/wallet"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T) (*Service, *ledger.Service) {
+	t.Helper()
+	ledgerService := ledger.NewService(ledger.NewRepository())
+	for _, walletID := range []string{"alice-wallet", "bob-wallet"} {
+		if err := ledgerService.OpenWalletAccount(walletID, "USD"); err != nil {
+			t.Fatalf("Failed to open %s: %v", walletID, err)
+		}
+	}
+	return NewService(NewRepository(), ledgerService), ledgerService
+}
+
+// TestTransferLifecycle tests that a transfer only reaches the ledger when it completes
+func TestTransferLifecycle(t *testing.T) {
+	service, ledgerService := newTestService(t)
+
+	if _, err := ledgerService.RecordDeposit(&ledger.DepositRequest{AccountID: "alice-wallet", Amount: 10000}); err != nil {
+		t.Fatalf("Failed to fund Alice: %v", err)
+	}
+
+	txn, err := service.Initiate(&InitiateRequest{
+		UserID:          "alice",
+		TransactionType: TransactionTypeTransfer,
+		Amount:          2500,
+		FromAccountID:   "alice-wallet",
+		ToAccountID:     "bob-wallet",
+		Description:     "Dinner",
+	})
+	if err != nil {
+		t.Fatalf("Failed to initiate transfer: %v", err)
+	}
+
+	// Nothing is posted while the transaction is initiated or pending
+	if _, err := service.MarkPending(txn.ID); err != nil {
+		t.Fatalf("Failed to mark transfer pending: %v", err)
+	}
+	if _, err := ledgerService.GetBalance("bob-wallet"); err != ledger.ErrAccountBalanceNotFound {
+		t.Errorf("Expected Bob to have no balance before completion, got: %v", err)
+	}
+
+	txn, err = service.Complete(txn.ID)
+	if err != nil {
+		t.Fatalf("Failed to complete transfer: %v", err)
+	}
+	if txn.Status != StatusCompleted || txn.LedgerTransactionID != txn.ID {
+		t.Errorf("Expected completed transfer posted as %s, got %s posted as %q", txn.ID, txn.Status, txn.LedgerTransactionID)
+	}
+
+	bobBalance, _ := ledgerService.GetBalance("bob-wallet")
+	if bobBalance.Balance != 2500 {
+		t.Errorf("Expected Bob's balance to be 2500, got %d", bobBalance.Balance)
+	}
+
+	expected := []string{StatusInitiated, StatusPending, StatusCompleted}
+	if len(txn.History) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %d", len(expected), len(txn.History))
+	}
+	for i, status := range expected {
+		if txn.History[i].To != status || txn.History[i].At == 0 {
+			t.Errorf("History entry %d: expected timestamped move to %s, got %+v", i, status, txn.History[i])
+		}
+	}
+
+	// A completed transaction cannot be completed again
+	if _, err := service.Complete(txn.ID); err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition on second completion, got: %v", err)
+	}
+	t.Logf("✓ Transfer history: %+v", txn.History)
+}
+
+// TestFailedTransaction tests that a ledger rejection marks the transaction failed
+func TestFailedTransaction(t *testing.T) {
+	service, ledgerService := newTestService(t)
+
+	txn, err := service.Process(&InitiateRequest{
+		UserID:          "alice",
+		TransactionType: TransactionTypeWithdrawal,
+		Amount:          5000,
+		FromAccountID:   "alice-wallet",
+	})
+	if err != ledger.ErrInsufficientBalance {
+		t.Fatalf("Expected ErrInsufficientBalance, got: %v", err)
+	}
+	if txn.Status != StatusFailed || txn.FailureReason == "" {
+		t.Errorf("Expected failed transaction with a reason, got %s (%q)", txn.Status, txn.FailureReason)
+	}
+	if entries, _ := ledgerService.GetTransactionDetails(txn.ID); len(entries) != 0 {
+		t.Errorf("Expected nothing posted for a failed transaction, got %d entries", len(entries))
+	}
+
+	// Failed is terminal
+	if _, err := service.Reverse(txn.ID, "oops"); err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition reversing a failed transaction, got: %v", err)
+	}
+}
+
+// flakyLedger fails the next posting as if the database had timed out
+type flakyLedger struct {
+	*ledger.Service
+	failNext bool
+}
+
+var errDatabaseTimeout = errors.New("pq: canceling statement due to statement timeout")
+
+func (l *flakyLedger) RecordWithdrawal(req *ledger.WithdrawalRequest) (string, error) {
+	if l.failNext {
+		l.failNext = false
+		return "", errDatabaseTimeout
+	}
+	return l.Service.RecordWithdrawal(req)
+}
+
+// TestInfrastructureErrorLeavesPending tests that a posting the ledger failed to make, rather than refused,
+// leaves the transaction pending so completing it can be retried
+func TestInfrastructureErrorLeavesPending(t *testing.T) {
+	_, ledgerService := newTestService(t)
+	if _, err := ledgerService.RecordDeposit(&ledger.DepositRequest{AccountID: "alice-wallet", Amount: 10000}); err != nil {
+		t.Fatalf("Failed to fund Alice: %v", err)
+	}
+	service := NewService(NewRepository(), &flakyLedger{Service: ledgerService, failNext: true})
+
+	txn, err := service.Process(&InitiateRequest{
+		UserID:          "alice",
+		TransactionType: TransactionTypeWithdrawal,
+		Amount:          5000,
+		FromAccountID:   "alice-wallet",
+	})
+	if err != errDatabaseTimeout {
+		t.Fatalf("Expected the database error, got: %v", err)
+	}
+	if txn.Status != StatusPending || txn.FailureReason != "" {
+		t.Fatalf("Expected the transaction to stay pending, got %s (%q)", txn.Status, txn.FailureReason)
+	}
+
+	if txn, err = service.Complete(txn.ID); err != nil || txn.Status != StatusCompleted {
+		t.Fatalf("Expected the retry to complete the transaction, got %+v (%v)", txn, err)
+	}
+	if balance, _ := ledgerService.GetBalance("alice-wallet"); balance.Balance != 5000 {
+		t.Errorf("Expected 50.00 left after one withdrawal, got %d", balance.Balance)
+	}
+}
+
+// TestReverseTransaction tests that reversing a completed transaction posts the opposite movement
+func TestReverseTransaction(t *testing.T) {
+	service, ledgerService := newTestService(t)
+
+	txn, err := service.Process(&InitiateRequest{
+		UserID:          "alice",
+		TransactionType: TransactionTypeDeposit,
+		Amount:          4000,
+		ToAccountID:     "alice-wallet",
+	})
+	if err != nil {
+		t.Fatalf("Failed to process deposit: %v", err)
+	}
+
+	txn, err = service.Reverse(txn.ID, "chargeback")
+	if err != nil {
+		t.Fatalf("Failed to reverse deposit: %v", err)
+	}
+	if txn.Status != StatusReversed {
+		t.Errorf("Expected reversed, got %s", txn.Status)
+	}
+
+	balance, _ := ledgerService.GetBalance("alice-wallet")
+	if balance.Balance != 0 {
+		t.Errorf("Expected Alice's balance to be 0 after reversal, got %d", balance.Balance)
+	}
+
+	if _, err := service.Reverse(txn.ID, "again"); err != ErrInvalidTransition {
+		t.Errorf("Expected ErrInvalidTransition on second reversal, got: %v", err)
+	}
+}
+
+// failingQueue refuses every withdrawal queued for approval
+type failingQueue struct{}
+
+func (failingQueue) QueueWithdrawal(ledger.WithdrawalRequest, string) (string, error) {
+	return "", errors.New("queue unavailable")
+}
+
+// TestWithdrawalAwaitingApproval tests that a withdrawal queued for approval is a pending transaction that the
+// approval completes, and that rejected or refused approvals fail it
+func TestWithdrawalAwaitingApproval(t *testing.T) {
+	approvalRepo := approval.NewRepository()
+	ledgerService := ledger.NewService(ledger.NewRepository(), ledger.WithApprovalThreshold(100),
+		ledger.WithApprovals(approval.NewLedgerApprovals(approvalRepo)))
+	walletService := wallet.NewService(wallet.NewRepository(), ledgerService)
+	walletID, err := walletService.CreateWallet("alice", "USD")
+	if err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+	if _, err := ledgerService.RecordDeposit(&ledger.DepositRequest{AccountID: walletID, Amount: 50000}); err != nil {
+		t.Fatalf("Failed to fund Alice: %v", err)
+	}
+	service := NewService(NewRepository(), ledgerService)
+	approvals := approval.NewService(approvalRepo, ledgerService, walletService, time.Hour, approval.SystemClock,
+		approval.WithListener(service))
+	withdraw := func(amount int64) *Transaction {
+		t.Helper()
+		txn, err := service.AwaitApproval(&InitiateRequest{
+			UserID: "alice", TransactionType: TransactionTypeWithdrawal, Amount: amount, FromAccountID: walletID,
+		}, approvals)
+		if err != nil {
+			t.Fatalf("Failed to queue withdrawal: %v", err)
+		}
+		return txn
+	}
+
+	txn := withdraw(20000)
+	if txn.Status != StatusPending || txn.ApprovalID != txn.ID {
+		t.Fatalf("Expected a pending withdrawal linked to its approval, got %+v", txn)
+	}
+	if listed, _ := service.ListByAccountID(walletID); len(listed) != 1 || listed[0].ID != txn.ID {
+		t.Errorf("Expected the pending withdrawal in the wallet's history, got %+v", listed)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 50000 {
+		t.Errorf("Expected nothing posted before approval, got a balance of %d", balance.Balance)
+	}
+	if _, err := approvals.Approve(txn.ApprovalID, "admin-1", ""); err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+	if txn, _ = service.GetByID(txn.ID); txn.Status != StatusCompleted || txn.LedgerTransactionID != txn.ID {
+		t.Errorf("Expected the approved withdrawal completed under its own ID, got %+v", txn)
+	}
+	if balance, _ := ledgerService.GetBalance(walletID); balance.Balance != 30000 {
+		t.Errorf("Expected 300.00 left, got %d", balance.Balance)
+	}
+
+	txn = withdraw(20000)
+	if _, err := approvals.Reject(txn.ApprovalID, "admin-1", "Unusual destination"); err != nil {
+		t.Fatalf("Failed to reject: %v", err)
+	}
+	if txn, _ = service.GetByID(txn.ID); txn.Status != StatusFailed || txn.FailureReason != "approval rejected: Unusual destination" {
+		t.Errorf("Expected the rejected withdrawal failed with the note, got %+v", txn)
+	}
+
+	// Approved, but more than the wallet holds by then
+	txn = withdraw(40000)
+	if _, err := approvals.Approve(txn.ApprovalID, "admin-1", ""); err != nil {
+		t.Fatalf("Failed to approve: %v", err)
+	}
+	if txn, _ = service.GetByID(txn.ID); txn.Status != StatusFailed || txn.FailureReason != ledger.ErrInsufficientBalance.Error() {
+		t.Errorf("Expected the refused withdrawal failed with the ledger's reason, got %+v", txn)
+	}
+
+	if _, err := service.AwaitApproval(&InitiateRequest{
+		UserID: "alice", TransactionType: TransactionTypeWithdrawal, Amount: 20000, FromAccountID: walletID,
+	}, failingQueue{}); err == nil {
+		t.Fatal("Expected an error when the withdrawal can't be queued")
+	}
+	listed, _ := service.ListByAccountID(walletID)
+	for _, txn := range listed {
+		if txn.Status == StatusPending {
+			t.Errorf("Expected no withdrawal left pending, got %+v", txn)
+		}
+	}
+}
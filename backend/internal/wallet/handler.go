@@ -1,6 +1,9 @@
 package wallet
 
 import (
+	"digitalwallet/backend/pkg"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 
@@ -23,8 +26,24 @@ func (h *Handler) Create(c *gin.Context) {
 		return
 	}
 
-	walletID, err := h.service.CreateWallet(userId)
+	// The body is optional: an empty request creates a USD wallet
+	var req CreateWalletDTO
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		log.Println("Error: binding the request payload to the CreateWalletDTO struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	walletID, err := h.service.CreateWallet(userId, req.Currency)
 	if err != nil {
+		if err == pkg.ErrUnsupportedCurrency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+			return
+		}
+		if err == pkg.ErrUserALreadyHasAWallet {
+			c.JSON(http.StatusConflict, gin.H{"error": "User already has a wallet"})
+			return
+		}
 		log.Println("Error creating a wallet:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
@@ -140,3 +159,90 @@ func (h *Handler) RemoveCard(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, nil)
 }
+
+// Freeze blocks outgoing payments from a wallet, e.g. during a compliance investigation (admin)
+func (h *Handler) Freeze(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		log.Println("Error: User is not set in the user context", userId)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req FreezeWalletDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to the FreezeWalletDTO struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	wallet, err := h.service.FreezeWallet(c.Param("walletID"), req.Reason, userId, req.AllowCredits)
+	if err != nil {
+		writeStatusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wallet frozen", "wallet": wallet})
+}
+
+// Unfreeze reopens a frozen wallet (admin)
+func (h *Handler) Unfreeze(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		log.Println("Error: User is not set in the user context", userId)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req UnfreezeWalletDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to the UnfreezeWalletDTO struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	wallet, err := h.service.UnfreezeWallet(c.Param("walletID"), req.Reason, userId)
+	if err != nil {
+		writeStatusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wallet unfrozen", "wallet": wallet})
+}
+
+// Close closes a wallet, sweeping any remaining balance to the given account first (admin)
+func (h *Handler) Close(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		log.Println("Error: User is not set in the user context", userId)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req CloseWalletDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Println("Error: binding the request payload to the CloseWalletDTO struct:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	wallet, sweepTransactionID, err := h.service.CloseWallet(c.Param("walletID"), req.Reason, userId, req.SweepToAccountID)
+	if err != nil {
+		writeStatusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wallet closed", "wallet": wallet, "sweep_transaction_id": sweepTransactionID})
+}
+
+// writeStatusError maps wallet status change errors to HTTP responses
+func writeStatusError(c *gin.Context, err error) {
+	switch {
+	case err == pkg.ErrMissingStatusReason, errors.Is(err, pkg.ErrInvalidSweepAccount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err == pkg.ErrWalletNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wallet not found"})
+	case errors.Is(err, pkg.ErrInvalidWalletStatus), err == pkg.ErrWalletNotEmpty:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Println("Error: changing wallet status:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	}
+}
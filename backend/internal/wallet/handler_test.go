@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"digitalwallet/backend/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeLedger accepts every status change and records the closures it was asked to make
+type fakeLedger struct {
+	closed map[string]string // wallet ID -> sweep account ID
+}
+
+func (l *fakeLedger) OpenWalletAccount(walletID, currency string) error { return nil }
+
+func (l *fakeLedger) FreezeWalletAccount(walletID, reason, changedBy string, allowCredits bool) error {
+	return nil
+}
+
+func (l *fakeLedger) UnfreezeWalletAccount(walletID, reason, changedBy string) error { return nil }
+
+func (l *fakeLedger) CloseWalletAccount(walletID, reason, changedBy, sweepToAccountID string) (string, error) {
+	l.closed[walletID] = sweepToAccountID
+	return "sweep-" + walletID, nil
+}
+
+// TestStatusChangesRequireAdmin tests that only admins can freeze or close a wallet, and that a
+// closing wallet can't be swept into another user's wallet
+func TestStatusChangesRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authService := auth.NewService(auth.NewRepository(), nil, "access-secret", "refresh-secret")
+	ledger := &fakeLedger{closed: make(map[string]string)}
+	service := NewService(NewRepository(), ledger)
+	r := gin.New()
+	RegisterRoutes(r, NewHandler(service), auth.NewMiddleware(authService, []string{"admin-1"}))
+
+	victim, _ := service.CreateWallet("victim", "USD")
+	attacker, _ := service.CreateWallet("attacker", "USD")
+
+	post := func(user, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		tokens, err := authService.GenerateTokens(user, user+"@example.com")
+		if err != nil {
+			t.Fatalf("Failed to generate tokens: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "access_token", Value: tokens.AccessToken})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, action := range []string{"freeze", "unfreeze", "close"} {
+		body := `{"reason": "Mine now", "sweep_to_account_id": "` + attacker + `"}`
+		if w := post("attacker", "/wallets/"+victim+"/"+action, body); w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected 403 for a non-admin, got %d: %s", action, w.Code, w.Body)
+		}
+	}
+	if len(ledger.closed) != 0 {
+		t.Fatalf("Expected nothing to be closed, got %v", ledger.closed)
+	}
+
+	// Not even an admin can sweep one user's balance into another user's wallet
+	w := post("admin-1", "/wallets/"+victim+"/close", `{"reason": "Customer request", "sweep_to_account_id": "`+attacker+`"}`)
+	if w.Code != http.StatusBadRequest || len(ledger.closed) != 0 {
+		t.Errorf("Expected 400 sweeping into another user's wallet, got %d: %s", w.Code, w.Body)
+	}
+	w = post("admin-1", "/wallets/"+victim+"/close", `{"reason": "Customer request", "sweep_to_account_id": "external-bank-pool-usd"}`)
+	if w.Code != http.StatusOK || ledger.closed[victim] != "external-bank-pool-usd" {
+		t.Errorf("Expected an admin to close the wallet, got %d: %s", w.Code, w.Body)
+	}
+}
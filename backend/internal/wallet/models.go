@@ -18,9 +18,42 @@ type CardDTO struct {
 	ExpiryDate string `json:"expiry_date"`
 }
 
+// Wallet statuses, the same as the status of the wallet's ledger account
+const (
+	StatusOpen   = "OPEN"
+	StatusFrozen = "FROZEN" // No outgoing payments; incoming ones only if AllowCredits is set
+	StatusClosed = "CLOSED" // No further payments
+)
+
 type Wallet struct {
-	ID        string `json:"id"`
-	UserID    string `json:"user_id"`
-	CreatedAt int64  `json:"created_at"`
-	Cards     []Card `json:"cards"`
+	ID              string `json:"id"`
+	UserID          string `json:"user_id"`
+	Currency        string `json:"currency"` // Fixed at creation: every posting to this wallet is in this currency
+	Status          string `json:"status"`
+	StatusReason    string `json:"status_reason,omitempty"`
+	AllowCredits    bool   `json:"allow_credits,omitempty"` // FROZEN only
+	StatusChangedAt int64  `json:"status_changed_at,omitempty"`
+	CreatedAt       int64  `json:"created_at"`
+	Cards           []Card `json:"cards"`
+}
+
+type CreateWalletDTO struct {
+	Currency string `json:"currency"` // Defaults to USD
+}
+
+// FreezeWalletDTO is the payload for freezing a wallet
+type FreezeWalletDTO struct {
+	Reason       string `json:"reason"`
+	AllowCredits bool   `json:"allow_credits"` // Keep accepting incoming payments while frozen
+}
+
+// UnfreezeWalletDTO is the payload for unfreezing a wallet
+type UnfreezeWalletDTO struct {
+	Reason string `json:"reason"`
+}
+
+// CloseWalletDTO is the payload for closing a wallet
+type CloseWalletDTO struct {
+	Reason           string `json:"reason"`
+	SweepToAccountID string `json:"sweep_to_account_id"` // Required if the wallet still holds funds
 }
@@ -12,10 +12,12 @@ import (
 type Repository interface {
 	GetByID(ID string) (*Wallet, error)
 	GetByUserID(userID string) (*Wallet, error)
-	Create(userID string) (string, error)
+	GetAll() ([]Wallet, error)
+	Create(userID, currency string) (string, error)
 	AddCard(ID string, card *CardDTO) (string, error)
 	RemoveCard(walletID, cardId string) error
 	GetCard(walletID, cardID string) (*Card, error)
+	UpdateStatus(walletID, status, reason string, allowCredits bool, changedAt int64) error
 }
 
 // inMemoryRepository implements Repository using in-memory storage
@@ -44,7 +46,7 @@ func NewRepository() Repository {
 var timeFormat = "02-01-2006"
 
 // Create implements Repository.
-func (r *inMemoryRepository) Create(userID string) (string, error) {
+func (r *inMemoryRepository) Create(userID, currency string) (string, error) {
 	for _, wallet := range r.wallets {
 		if wallet.UserID == userID {
 			log.Println("Error: User already has a wallet", userID)
@@ -55,6 +57,8 @@ func (r *inMemoryRepository) Create(userID string) (string, error) {
 	newWallet := Wallet{
 		ID:        uuid.New().String(),
 		UserID:    userID,
+		Currency:  currency,
+		Status:    StatusOpen,
 		CreatedAt: time.Now().Unix(),
 		Cards:     []Card{},
 	}
@@ -87,6 +91,13 @@ func (r *inMemoryRepository) GetByUserID(userID string) (*Wallet, error) {
 	return nil, pkg.ErrWalletNotFound
 }
 
+// GetAll implements Repository.
+func (r *inMemoryRepository) GetAll() ([]Wallet, error) {
+	wallets := make([]Wallet, len(r.wallets))
+	copy(wallets, r.wallets)
+	return wallets, nil
+}
+
 // AddCard implements Repository.
 func (r *inMemoryRepository) AddCard(walletID string, card *CardDTO) (string, error) {
 	// Validate entity exists
@@ -201,3 +212,22 @@ func (r *inMemoryRepository) RemoveCard(walletId string, cardId string) error {
 	log.Println("Error: Card not found in wallet when trying to remove it", cardId)
 	return pkg.ErrCardNotFound
 }
+
+// UpdateStatus implements Repository.
+func (r *inMemoryRepository) UpdateStatus(walletID, status, reason string, allowCredits bool, changedAt int64) error {
+	// Use index to get pointer to actual slice element (see AddCard for explanation)
+	for i := range r.wallets {
+		if r.wallets[i].ID == walletID {
+			wallet := &r.wallets[i]
+			wallet.Status = status
+			wallet.StatusReason = reason
+			wallet.AllowCredits = status == StatusFrozen && allowCredits
+			wallet.StatusChangedAt = changedAt
+			log.Println("Wallet status changed:", walletID, status)
+			return nil
+		}
+	}
+
+	log.Println("Error: Wallet not found when trying to change its status", walletID)
+	return pkg.ErrWalletNotFound
+}
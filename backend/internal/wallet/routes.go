@@ -13,4 +13,9 @@ func RegisterRoutes(router *gin.Engine, walletHandler *Handler, authMiddleware *
 	router.POST("/wallets/:walletID/cards", authMiddleware.Authenticate, walletHandler.CreateCard)
 	router.GET("/wallets/:walletID/cards/:cardID", authMiddleware.Authenticate, walletHandler.GetCard)
 	router.POST("/wallets/:walletID/cards/:cardID", authMiddleware.Authenticate, walletHandler.RemoveCard)
+
+	// Status changes (admin only), each with a mandatory reason
+	router.POST("/wallets/:walletID/freeze", authMiddleware.Authenticate, authMiddleware.RequireAdmin, walletHandler.Freeze)
+	router.POST("/wallets/:walletID/unfreeze", authMiddleware.Authenticate, authMiddleware.RequireAdmin, walletHandler.Unfreeze)
+	router.POST("/wallets/:walletID/close", authMiddleware.Authenticate, authMiddleware.RequireAdmin, walletHandler.Close)
 }
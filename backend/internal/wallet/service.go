@@ -1,23 +1,128 @@
 package wallet
 
+import (
+	"digitalwallet/backend/pkg"
+	"digitalwallet/backend/pkg/currency"
+	"log"
+	"time"
+)
+
+// LedgerAccounts opens the ledger account behind each new wallet and changes its status
+// The ledger enforces the status on every posting; the wallet keeps a copy to show it
+type LedgerAccounts interface {
+	OpenWalletAccount(walletID, currency string) error
+	FreezeWalletAccount(walletID, reason, changedBy string, allowCredits bool) error
+	UnfreezeWalletAccount(walletID, reason, changedBy string) error
+	CloseWalletAccount(walletID, reason, changedBy, sweepToAccountID string) (string, error)
+}
+
 type Service struct {
-	repo Repository
+	repo   Repository
+	ledger LedgerAccounts
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, ledger LedgerAccounts) *Service {
+	return &Service{repo: repo, ledger: ledger}
 }
 
-// Create a new wallet
-func (s *Service) CreateWallet(userId string) (string, error) {
-	walletID, err := s.repo.Create(userId)
+// Create a new wallet holding the given currency (USD if empty)
+func (s *Service) CreateWallet(userId, walletCurrency string) (string, error) {
+	if walletCurrency == "" {
+		walletCurrency = currency.CurrencyUSD
+	}
+	if !currency.IsSupported(walletCurrency) {
+		return "", pkg.ErrUnsupportedCurrency
+	}
+
+	walletID, err := s.repo.Create(userId, walletCurrency)
 	if err != nil {
 		return "", err
 	}
 
+	// Postings are only accepted on accounts registered in the ledger's chart of accounts
+	if err := s.ledger.OpenWalletAccount(walletID, walletCurrency); err != nil {
+		log.Printf("Error opening ledger account for wallet %s: %v", walletID, err)
+		return "", err
+	}
+
 	return walletID, nil
 }
 
+// FreezeWallet blocks outgoing payments from a wallet, and incoming ones unless allowCredits is set
+func (s *Service) FreezeWallet(walletID, reason, changedBy string, allowCredits bool) (*Wallet, error) {
+	wallet, err := s.walletForStatusChange(walletID, reason)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status == StatusClosed {
+		return nil, pkg.ErrInvalidWalletStatus
+	}
+
+	if err := s.ledger.FreezeWalletAccount(walletID, reason, changedBy, allowCredits); err != nil {
+		return nil, err
+	}
+	return s.updateStatus(walletID, StatusFrozen, reason, allowCredits)
+}
+
+// UnfreezeWallet reopens a frozen wallet
+func (s *Service) UnfreezeWallet(walletID, reason, changedBy string) (*Wallet, error) {
+	wallet, err := s.walletForStatusChange(walletID, reason)
+	if err != nil {
+		return nil, err
+	}
+	if wallet.Status != StatusFrozen {
+		return nil, pkg.ErrInvalidWalletStatus
+	}
+
+	if err := s.ledger.UnfreezeWalletAccount(walletID, reason, changedBy); err != nil {
+		return nil, err
+	}
+	return s.updateStatus(walletID, StatusOpen, reason, false)
+}
+
+// CloseWallet closes a wallet for good; any remaining balance is swept to sweepToAccountID first
+// It returns the closed wallet and the sweep transaction ID (empty if the wallet was already empty)
+func (s *Service) CloseWallet(walletID, reason, changedBy, sweepToAccountID string) (*Wallet, string, error) {
+	wallet, err := s.walletForStatusChange(walletID, reason)
+	if err != nil {
+		return nil, "", err
+	}
+	if wallet.Status == StatusClosed {
+		return nil, "", pkg.ErrInvalidWalletStatus
+	}
+	// Another wallet can only receive the balance if it belongs to the same user
+	if sweepToAccountID != "" {
+		if target, err := s.repo.GetByID(sweepToAccountID); err == nil && target.UserID != wallet.UserID {
+			log.Printf("Error: Wallet %s can't be swept into wallet %s of another user", walletID, sweepToAccountID)
+			return nil, "", pkg.ErrInvalidSweepAccount
+		}
+	}
+
+	sweepTransactionID, err := s.ledger.CloseWalletAccount(walletID, reason, changedBy, sweepToAccountID)
+	if err != nil {
+		return nil, "", err
+	}
+	wallet, err = s.updateStatus(walletID, StatusClosed, reason, false)
+	return wallet, sweepTransactionID, err
+}
+
+// walletForStatusChange checks the reason and returns the wallet whose status is changing
+func (s *Service) walletForStatusChange(walletID, reason string) (*Wallet, error) {
+	if reason == "" {
+		return nil, pkg.ErrMissingStatusReason
+	}
+	return s.repo.GetByID(walletID)
+}
+
+// updateStatus copies the ledger account's new status onto the wallet
+func (s *Service) updateStatus(walletID, status, reason string, allowCredits bool) (*Wallet, error) {
+	if err := s.repo.UpdateStatus(walletID, status, reason, allowCredits, time.Now().Unix()); err != nil {
+		log.Printf("Error: wallet %s is %s in the ledger but its status was not updated: %v", walletID, status, err)
+		return nil, err
+	}
+	return s.repo.GetByID(walletID)
+}
+
 func (s *Service) GetWalletByID(walletId string) (*Wallet, error) {
 	wallet, err := s.repo.GetByID(walletId)
 	if err != nil {
@@ -36,6 +141,11 @@ func (s *Service) GetWalletByUserID(userID string) (*Wallet, error) {
 	return wallet, nil
 }
 
+// GetAllWallets retrieves every wallet, e.g. for month-end batch jobs
+func (s *Service) GetAllWallets() ([]Wallet, error) {
+	return s.repo.GetAll()
+}
+
 func (s *Service) AddCard(walletID string, card *CardDTO) (string, error) {
 	cardId, err := s.repo.AddCard(walletID, card)
 	if err != nil {
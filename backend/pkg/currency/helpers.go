@@ -1,7 +1,7 @@
 package currency
 
 import (
-	"fmt"
+	"errors"
 )
 
 const (
@@ -10,30 +10,36 @@ const (
 	CurrencyGBP = "GBP"
 )
 
-// Helper functions for converting between cents and standard currency format
+// supported lists the currencies accounts can be held in
+var supported = []string{CurrencyUSD, CurrencyEUR, CurrencyGBP}
 
-// StandardCurrencyFormatToCents converts a currency amount to cents
-// Example: 50.00 -> 5000
-func StandardCurrencyFormatToCents(amount float64) int64 {
-	return int64(amount * 100)
+// Supported returns the currencies accounts can be held in
+func Supported() []string {
+	return append([]string(nil), supported...)
 }
 
-// CentsToStandardCurrencyFormat converts cents to dollars
-// Example: 5000 -> 50.00
-func CentsToStandardCurrencyFormat(cents int64) float64 {
-	return float64(cents) / 100.0
+// IsSupported reports whether accounts can be held in the given currency
+func IsSupported(code string) bool {
+	for _, supportedCode := range supported {
+		if code == supportedCode {
+			return true
+		}
+	}
+	return false
 }
 
-// FormatAmount formats a cent amount as a currency string
+var ErrInvalidAmount = errors.New("invalid amount: expected a decimal string within the currency's minor unit precision")
+
+// FormatAmount formats a minor-unit amount as a currency string
 // Example: 5000 -> "$50.00"
-func FormatAmount(cents int64, currency string) string {
+func FormatAmount(minorUnits int64, code string) string {
 	symbol := "$" // Default to USD
-	switch currency {
+	switch code {
 	case CurrencyEUR:
 		symbol = "€"
 	case CurrencyGBP:
 		symbol = "£"
 	}
 
-	return fmt.Sprintf("%s%.2f", symbol, CentsToStandardCurrencyFormat(cents))
+	return symbol + New(minorUnits, code).String()
 }
@@ -0,0 +1,250 @@
+package currency
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency code")
+	ErrMismatch        = errors.New("cannot combine amounts in different currencies")
+	ErrOverflow        = errors.New("amount is out of range")
+)
+
+// exponents holds the number of minor-unit digits for each ISO 4217 code we know about
+// Only the codes in IsSupported can hold accounts; the rest are here for pricing and display
+var exponents = map[string]int{
+	CurrencyUSD: 2,
+	CurrencyEUR: 2,
+	CurrencyGBP: 2,
+	"CHF":       2,
+	"CAD":       2,
+	"JPY":       0,
+	"KRW":       0,
+	"BHD":       3,
+	"KWD":       3,
+	"JOD":       3,
+}
+
+// Exponent returns the number of decimal places in the currency's minor unit
+func Exponent(code string) (int, error) {
+	exponent, ok := exponents[code]
+	if !ok {
+		return 0, ErrUnknownCurrency
+	}
+	return exponent, nil
+}
+
+// RoundingMode selects how amounts that fall between two minor units are rounded
+type RoundingMode int
+
+const (
+	RoundHalfEven RoundingMode = iota // Ties go to the even neighbour (banker's rounding)
+	RoundHalfUp                       // Ties go away from zero
+	RoundDown                         // Toward zero (truncate)
+	RoundUp                           // Away from zero
+	RoundFloor                        // Toward negative infinity
+	RoundCeiling                      // Toward positive infinity
+)
+
+// Money is an exact amount in integer minor units of an ISO 4217 currency
+type Money struct {
+	amount   int64
+	currency string
+}
+
+// New creates a Money from minor units (cents for USD, yen for JPY, fils for BHD)
+func New(minorUnits int64, code string) Money {
+	return Money{amount: minorUnits, currency: code}
+}
+
+// Parse converts a decimal string such as "50.29" to Money without going through float64
+// Amounts with more decimals than the currency's minor unit are rejected
+func Parse(amount, code string) (Money, error) {
+	value, err := parseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	minor, err := toMinorUnits(value, code)
+	if err != nil {
+		return Money{}, err
+	}
+	if !minor.IsInt() {
+		return Money{}, ErrInvalidAmount
+	}
+	units, err := Round(minor, RoundDown)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	return New(units, code), nil
+}
+
+// ParseRounded converts a decimal string to Money, rounding extra decimals with mode
+func ParseRounded(amount, code string, mode RoundingMode) (Money, error) {
+	value, err := parseDecimal(amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return FromRat(value, code, mode)
+}
+
+// FromRat converts an amount in major units (e.g. dollars) to Money, rounding with mode
+func FromRat(value *big.Rat, code string, mode RoundingMode) (Money, error) {
+	minor, err := toMinorUnits(value, code)
+	if err != nil {
+		return Money{}, err
+	}
+	units, err := Round(minor, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return New(units, code), nil
+}
+
+// Amount returns the amount in minor units
+func (m Money) Amount() int64 {
+	return m.amount
+}
+
+// Currency returns the ISO 4217 code
+func (m Money) Currency() string {
+	return m.currency
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.amount < 0
+}
+
+// Neg returns the amount with the opposite sign
+func (m Money) Neg() Money {
+	return New(-m.amount, m.currency)
+}
+
+// Add returns m + other; both must be in the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.currency != other.currency {
+		return Money{}, ErrMismatch
+	}
+	sum := m.amount + other.amount
+	if (other.amount > 0 && sum < m.amount) || (other.amount < 0 && sum > m.amount) {
+		return Money{}, ErrOverflow
+	}
+	return New(sum, m.currency), nil
+}
+
+// Sub returns m - other; both must be in the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	if m.currency != other.currency {
+		return Money{}, ErrMismatch
+	}
+	diff := m.amount - other.amount
+	if (other.amount > 0 && diff > m.amount) || (other.amount < 0 && diff < m.amount) {
+		return Money{}, ErrOverflow
+	}
+	return New(diff, m.currency), nil
+}
+
+// String formats the amount as a plain decimal string with the currency's exponent
+// Example: 5029 USD -> "50.29", 500 JPY -> "500", -5 BHD -> "-0.005"
+// Unknown currencies are formatted with two decimals
+func (m Money) String() string {
+	exponent, err := Exponent(m.currency)
+	if err != nil {
+		exponent = 2
+	}
+
+	digits := new(big.Int).Abs(big.NewInt(m.amount)).String()
+	if exponent > 0 {
+		if len(digits) <= exponent {
+			digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+	}
+	if m.amount < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// Round rounds a rational number of minor units to an integer using mode
+func Round(value *big.Rat, mode RoundingMode) (int64, error) {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+
+	if remainder.Sign() != 0 {
+		sign := int64(value.Sign())
+		// Compare the discarded fraction against one half: 2*|remainder| vs denominator
+		half := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2)).Cmp(value.Denom())
+
+		var step int64
+		switch mode {
+		case RoundDown:
+		case RoundUp:
+			step = sign
+		case RoundFloor:
+			if sign < 0 {
+				step = -1
+			}
+		case RoundCeiling:
+			if sign > 0 {
+				step = 1
+			}
+		case RoundHalfUp:
+			if half >= 0 {
+				step = sign
+			}
+		case RoundHalfEven:
+			if half > 0 || (half == 0 && quotient.Bit(0) == 1) {
+				step = sign
+			}
+		}
+		quotient.Add(quotient, big.NewInt(step))
+	}
+
+	if !quotient.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return quotient.Int64(), nil
+}
+
+// toMinorUnits scales an amount in major units by the currency's exponent
+func toMinorUnits(value *big.Rat, code string) (*big.Rat, error) {
+	exponent, err := Exponent(code)
+	if err != nil {
+		return nil, err
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+	return new(big.Rat).Mul(value, new(big.Rat).SetInt(scale)), nil
+}
+
+// parseDecimal accepts an optionally signed decimal string like "-12.345"
+// Exponents, fractions and empty parts ("1.", ".5") are rejected
+func parseDecimal(amount string) (*big.Rat, error) {
+	s := strings.TrimSpace(amount)
+	unsigned := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if len(s)-len(unsigned) > 1 {
+		return nil, ErrInvalidAmount
+	}
+
+	whole, fraction, hasPoint := strings.Cut(unsigned, ".")
+	if whole == "" || (hasPoint && fraction == "") {
+		return nil, ErrInvalidAmount
+	}
+	for _, digit := range whole + fraction {
+		if digit < '0' || digit > '9' {
+			return nil, ErrInvalidAmount
+		}
+	}
+
+	value, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, ErrInvalidAmount
+	}
+	return value, nil
+}
@@ -0,0 +1,174 @@
+package currency
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// TestParse checks exact parsing, including values float64 gets wrong
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount string
+		code   string
+		want   int64
+		err    error
+	}{
+		{"0.29", CurrencyUSD, 29, nil},
+		{"50.29", CurrencyUSD, 5029, nil},
+		{"-3.5", CurrencyEUR, -350, nil},
+		{"+7", CurrencyGBP, 700, nil},
+		{"1000", "JPY", 1000, nil},
+		{"1.234", "BHD", 1234, nil},
+		{"1.5", "JPY", 0, ErrInvalidAmount},
+		{"0.291", CurrencyUSD, 0, ErrInvalidAmount},
+		{"1.", CurrencyUSD, 0, ErrInvalidAmount},
+		{".5", CurrencyUSD, 0, ErrInvalidAmount},
+		{"1e3", CurrencyUSD, 0, ErrInvalidAmount},
+		{"1/3", CurrencyUSD, 0, ErrInvalidAmount},
+		{"--1", CurrencyUSD, 0, ErrInvalidAmount},
+		{"92233720368547758.08", CurrencyUSD, 0, ErrInvalidAmount},
+		{"1.00", "XXX", 0, ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.amount, tt.code)
+		if err != tt.err {
+			t.Errorf("Parse(%q, %s): expected error %v, got %v", tt.amount, tt.code, tt.err, err)
+			continue
+		}
+		if err == nil && got.Amount() != tt.want {
+			t.Errorf("Parse(%q, %s): expected %d, got %d", tt.amount, tt.code, tt.want, got.Amount())
+		}
+	}
+}
+
+// TestString checks formatting with each currency's exponent
+func TestString(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{New(5029, CurrencyUSD), "50.29"},
+		{New(-5, CurrencyUSD), "-0.05"},
+		{New(0, CurrencyEUR), "0.00"},
+		{New(500, "JPY"), "500"},
+		{New(-5, "BHD"), "-0.005"},
+		{New(math.MinInt64, CurrencyUSD), "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("%d %s: expected %q, got %q", tt.money.Amount(), tt.money.Currency(), tt.want, got)
+		}
+	}
+}
+
+// TestRoundingModes checks each mode on ties and non-ties, positive and negative
+func TestRoundingModes(t *testing.T) {
+	tests := []struct {
+		value string
+		mode  RoundingMode
+		want  int64
+	}{
+		{"2.5", RoundHalfEven, 2},
+		{"3.5", RoundHalfEven, 4},
+		{"-2.5", RoundHalfEven, -2},
+		{"2.51", RoundHalfEven, 3},
+		{"2.5", RoundHalfUp, 3},
+		{"-2.5", RoundHalfUp, -3},
+		{"2.49", RoundHalfUp, 2},
+		{"2.9", RoundDown, 2},
+		{"-2.9", RoundDown, -2},
+		{"2.1", RoundUp, 3},
+		{"-2.1", RoundUp, -3},
+		{"-2.1", RoundFloor, -3},
+		{"2.9", RoundFloor, 2},
+		{"2.1", RoundCeiling, 3},
+		{"-2.9", RoundCeiling, -2},
+		{"4", RoundUp, 4},
+	}
+
+	for _, tt := range tests {
+		value, _ := new(big.Rat).SetString(tt.value)
+		got, err := Round(value, tt.mode)
+		if err != nil {
+			t.Fatalf("Round(%s, %d): %v", tt.value, tt.mode, err)
+		}
+		if got != tt.want {
+			t.Errorf("Round(%s, %d): expected %d, got %d", tt.value, tt.mode, tt.want, got)
+		}
+	}
+
+	money, err := ParseRounded("0.125", CurrencyUSD, RoundHalfEven)
+	if err != nil || money.Amount() != 12 {
+		t.Errorf("ParseRounded(0.125, HalfEven): expected 12, got %d (%v)", money.Amount(), err)
+	}
+}
+
+// TestAddSub checks currency and overflow guards
+func TestAddSub(t *testing.T) {
+	sum, err := New(150, CurrencyUSD).Add(New(-50, CurrencyUSD))
+	if err != nil || sum.Amount() != 100 {
+		t.Errorf("Expected 100, got %d (%v)", sum.Amount(), err)
+	}
+	if _, err := New(1, CurrencyUSD).Add(New(1, CurrencyEUR)); err != ErrMismatch {
+		t.Errorf("Expected ErrMismatch, got %v", err)
+	}
+	if _, err := New(math.MaxInt64, CurrencyUSD).Add(New(1, CurrencyUSD)); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+	if _, err := New(math.MinInt64, CurrencyUSD).Sub(New(1, CurrencyUSD)); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+}
+
+var fuzzCurrencies = []string{CurrencyUSD, "JPY", "BHD"}
+
+// FuzzMinorUnitsRoundTrip proves formatting then parsing returns the exact minor units
+func FuzzMinorUnitsRoundTrip(f *testing.F) {
+	f.Add(int64(29), uint8(0))
+	f.Add(int64(-5), uint8(2))
+	f.Add(int64(math.MaxInt64), uint8(1))
+	f.Add(int64(math.MinInt64), uint8(0))
+
+	f.Fuzz(func(t *testing.T, minorUnits int64, currencyIndex uint8) {
+		code := fuzzCurrencies[int(currencyIndex)%len(fuzzCurrencies)]
+		formatted := New(minorUnits, code).String()
+
+		parsed, err := Parse(formatted, code)
+		if err != nil {
+			t.Fatalf("Parse(%q, %s): %v", formatted, code, err)
+		}
+		if parsed.Amount() != minorUnits {
+			t.Fatalf("Round trip of %d %s via %q gave %d", minorUnits, code, formatted, parsed.Amount())
+		}
+	})
+}
+
+// FuzzParseRoundTrip proves any accepted decimal string formats back to an equal value
+func FuzzParseRoundTrip(f *testing.F) {
+	for _, seed := range []string{"0.29", "-3.5", "+7", "1000", "1.234", "0.1", "abc", "1e3"} {
+		f.Add(seed, uint8(0))
+	}
+
+	f.Fuzz(func(t *testing.T, amount string, currencyIndex uint8) {
+		code := fuzzCurrencies[int(currencyIndex)%len(fuzzCurrencies)]
+		money, err := Parse(amount, code)
+		if err != nil {
+			return
+		}
+
+		// The canonical string must parse to the same amount and denote the same number
+		reparsed, err := Parse(money.String(), code)
+		if err != nil || reparsed.Amount() != money.Amount() {
+			t.Fatalf("Canonical form %q of %q did not round trip: %d vs %d (%v)",
+				money.String(), amount, reparsed.Amount(), money.Amount(), err)
+		}
+		original, _ := parseDecimal(amount)
+		canonical, _ := parseDecimal(money.String())
+		if original.Cmp(canonical) != 0 {
+			t.Fatalf("%q formatted as %q, which is a different number", amount, money.String())
+		}
+	})
+}
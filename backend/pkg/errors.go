@@ -17,6 +17,11 @@ var (
 	ErrCardNotFound          = errors.New("card not found in wallet")
 	ErrEntityNotFound        = errors.New("entity not found")
 	ErrInvalidExpiryDate     = errors.New("invalid expiry date")
+	ErrUnsupportedCurrency   = errors.New("unsupported currency")
+	ErrMissingStatusReason   = errors.New("a reason is required to change a wallet's status")
+	ErrInvalidWalletStatus   = errors.New("wallet status cannot change this way")
+	ErrWalletNotEmpty        = errors.New("wallet must have a zero balance and no active holds to close")
+	ErrInvalidSweepAccount   = errors.New("invalid sweep account")
 )
 
 // Auth errors
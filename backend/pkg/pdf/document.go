@@ -0,0 +1,185 @@
+// Package pdf writes simple text-and-line PDF documents without external tools
+// Only the standard Helvetica fonts are used, so nothing needs to be embedded
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+// Font is one of the standard PDF fonts every reader ships with
+type Font string
+
+const (
+	Helvetica     Font = "F1"
+	HelveticaBold Font = "F2"
+)
+
+var fontNames = map[Font]string{
+	Helvetica:     "Helvetica",
+	HelveticaBold: "Helvetica-Bold",
+}
+
+// Document is a PDF built up page by page in memory
+type Document struct {
+	title string
+	pages []*Page
+}
+
+// Page holds the drawing operators of one page; coordinates start at the bottom left
+type Page struct {
+	content bytes.Buffer
+}
+
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage appends a blank A4 page
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages added so far, e.g. to stamp "page x of y" footers
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Text draws s with its baseline starting at (x, y)
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, number(size), number(x), number(y), escape(s))
+}
+
+// TextRight draws s so that it ends at x, for right-aligned columns
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+	p.Text(x-TextWidth(s, size), y, font, size, s)
+}
+
+// Line draws a straight line of the given width
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", number(width), number(x1), number(y1), number(x2), number(y2))
+}
+
+// Rect fills a rectangle with a grey level between 0 (black) and 1 (white)
+func (p *Page) Rect(x, y, width, height, grey float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n", number(grey), number(x), number(y), number(width), number(height))
+}
+
+// WriteTo serializes the document; the output only depends on what was drawn
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-5 are fixed (catalog, page tree, info, two fonts); each page then adds itself and its content
+	pageCount := len(d.pages)
+	firstPage := 6
+	kids := make([]string, pageCount)
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	object(fmt.Sprintf("<< /Title (%s) /Producer (digitalwallet) >>", escape(d.title)))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[Helvetica]))
+	object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[HelveticaBold]))
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /%s 4 0 R /%s 5 0 R >> >> /Contents %d 0 R >>",
+			number(PageWidth), number(PageHeight), Helvetica, HelveticaBold, firstPage+2*i+1))
+
+		var stream bytes.Buffer
+		compressor := zlib.NewWriter(&stream)
+		compressor.Write(page.content.Bytes())
+		compressor.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+// Bytes returns the serialized document
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	d.WriteTo(&out)
+	return out.Bytes()
+}
+
+// number formats a coordinate without trailing zeros
+func number(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// escape encodes s as the contents of a PDF string literal in WinAnsiEncoding
+// Characters outside the encoding are replaced with '?'
+func escape(s string) string {
+	var out strings.Builder
+	for _, r := range s {
+		b, ok := winAnsi(r)
+		if !ok {
+			b = '?'
+		}
+		switch b {
+		case '\\', '(', ')':
+			out.WriteByte('\\')
+			out.WriteByte(b)
+		default:
+			if b < 0x20 || b >= 0x7f {
+				fmt.Fprintf(&out, "\\%03o", b)
+			} else {
+				out.WriteByte(b)
+			}
+		}
+	}
+	return out.String()
+}
+
+// winAnsiExtras maps the characters WinAnsiEncoding places in 0x80-0x9f
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+func winAnsi(r rune) (byte, bool) {
+	if b, ok := winAnsiExtras[r]; ok {
+		return b, true
+	}
+	if r == '\t' {
+		return ' ', true
+	}
+	if (r >= 0x20 && r < 0x7f) || (r >= 0xa0 && r <= 0xff) {
+		return byte(r), true
+	}
+	return 0, false
+}
@@ -0,0 +1,73 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestDocumentStructure tests that the cross-reference table points at every object
+func TestDocumentStructure(t *testing.T) {
+	doc := New("Statement (October)")
+	for i := 0; i < 3; i++ {
+		page := doc.AddPage()
+		page.Text(50, 800, HelveticaBold, 14, "Café (£12.50) \\ €3")
+		page.Line(50, 790, 545, 790, 0.5)
+	}
+	out := doc.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("Missing PDF header or trailer")
+	}
+	if !bytes.Contains(out, []byte("/Count 3")) {
+		t.Error("Expected a page tree with 3 pages")
+	}
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if startxref == nil {
+		t.Fatal("Missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(offsets) != 5+2*3 {
+		t.Fatalf("Expected %d objects, got %d", 5+2*3, len(offsets))
+	}
+	for i, match := range offsets {
+		offset, _ := strconv.Atoi(string(match[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("Object %d: offset %d does not start with %q", i+1, offset, want)
+		}
+	}
+
+	// Content streams decompress to the drawing operators, with strings escaped in WinAnsiEncoding
+	stream := regexp.MustCompile(`(?s)stream\n(.*?)\nendstream`).FindSubmatch(out)
+	reader, err := zlib.NewReader(bytes.NewReader(stream[1]))
+	if err != nil {
+		t.Fatalf("Content stream is not zlib compressed: %v", err)
+	}
+	content, _ := io.ReadAll(reader)
+	if want := `(Caf\351 \(\24312.50\) \\ \2003) Tj`; !strings.Contains(string(content), want) {
+		t.Errorf("Expected content to contain %s, got:\n%s", want, content)
+	}
+}
+
+// TestTextMeasurement tests widths and truncation against the Helvetica metrics
+func TestTextMeasurement(t *testing.T) {
+	if got := TextWidth("1,000.00", 10); got != 38.92 {
+		t.Errorf("Expected 1,000.00 to be 38.92pt wide at 10pt, got %v", got)
+	}
+	if got := Truncate("short", 10, 100); got != "short" {
+		t.Errorf("Expected short text to be kept, got %q", got)
+	}
+	got := Truncate("A description that is far too long for its column", 10, 80)
+	if !strings.HasSuffix(got, "…") || TextWidth(got, 10) > 80 {
+		t.Errorf("Expected an ellipsized string within 80pt, got %q (%vpt)", got, TextWidth(got, 10))
+	}
+}
@@ -0,0 +1,49 @@
+package pdf
+
+// helveticaWidths are the Helvetica advance widths of ASCII 0x20-0x7e, in 1/1000 em (from the Adobe AFM)
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// TextWidth measures s in points when set in Helvetica at size
+// Bold text is measured with the same widths: digits and punctuation match exactly, letters are close
+func TextWidth(s string, size float64) float64 {
+	units := 0
+	for _, r := range s {
+		units += runeWidth(r)
+	}
+	return float64(units) * size / 1000
+}
+
+// Truncate shortens s with an ellipsis so it fits in width points at size
+func Truncate(s string, size, width float64) string {
+	if TextWidth(s, size) <= width {
+		return s
+	}
+	limit := width - TextWidth("…", size)
+	runes := []rune(s)
+	used := 0.0
+	for i, r := range runes {
+		used += float64(runeWidth(r)) * size / 1000
+		if used > limit {
+			return string(runes[:i]) + "…"
+		}
+	}
+	return s
+}
+
+func runeWidth(r rune) int {
+	if r >= 0x20 && r <= 0x7e {
+		return helveticaWidths[r-0x20]
+	}
+	if r == '…' {
+		return 1000
+	}
+	// Accented letters and currency symbols are about as wide as a digit
+	return 556
+}